@@ -32,12 +32,18 @@ type AlertmanagerPayload struct {
 	Alerts            []Alert           `json:"alerts"`
 }
 
-// SendAlert sends a single alert to Alertmanager
+// SendAlert sends a single alert to Alertmanager. Alerts with Status
+// "resolved" are sent as a resolved notification.
 func SendAlert(alertmanagerURL string, alert Alert) error {
+	status := "firing"
+	if alert.Status == "resolved" {
+		status = "resolved"
+	}
+
 	payload := AlertmanagerPayload{
 		Version:           "4", // Alertmanager webhook API version
 		GroupKey:          "<generated>",
-		Status:            "firing",
+		Status:            status,
 		Receiver:          "monitron-receiver", // This should match a receiver in your Alertmanager config
 		GroupLabels:       map[string]string{"alertname": alert.Labels["alertname"]},
 		CommonLabels:      alert.Labels,
@@ -9,8 +9,10 @@ import (
 
 type Config struct {
 	App struct {
-		Host string
-		Port int
+		Host        string
+		Port        int
+		FrontendURL string // Base URL of the UI, used for links in emails
+		PublicURL   string // Base URL of this server, used for status page links in emails
 	}
 	Database struct {
 		Host     string
@@ -31,10 +33,36 @@ type Config struct {
 		URL string
 	}
 	Email struct {
-		Host     string
-		Port     int
-		From     string
-		Password string
+		Host          string
+		Port          int
+		Username      string
+		Password      string
+		From          string
+		FromName      string
+		TLSMode       string // none, starttls or tls
+		SkipTLSVerify bool
+	}
+	Storage struct {
+		Backend     string // local or s3
+		LocalDir    string
+		S3Endpoint  string
+		S3AccessKey string
+		S3SecretKey string
+		S3Bucket    string
+		S3Region    string
+		S3UseSSL    bool
+	}
+	Reports struct {
+		RetentionDays int // Generated reports older than this are deleted; 0 keeps them forever
+	}
+	GraphQL struct {
+		GraphiQL             bool   // Serves the GraphiQL IDE to admins at GET /api/v1/graphql
+		Introspection        bool   // Lets every caller run introspection queries, not only admins
+		MaxDepth             int    // Deepest nesting of fields a query may select
+		MaxComplexity        int    // Highest estimated cost of a query
+		CostBudget           int    // Query cost each user or IP may spend per minute; 0 disables the budget
+		PersistedQueries     string // off, apq or allowlist
+		PersistedQueriesFile string // Apollo or Relay manifest of persisted queries, loaded at startup
 	}
 }
 
@@ -47,6 +75,8 @@ func LoadConfig() *Config {
 	// App config
 	cfg.App.Host = getEnv("APP_HOST", "localhost")
 	cfg.App.Port = getEnvAsInt("APP_PORT", 7770)
+	cfg.App.FrontendURL = getEnv("FRONTEND_URL", "http://localhost:3000")
+	cfg.App.PublicURL = getEnv("PUBLIC_URL", "http://localhost:7770")
 
 	// Database Config
 	cfg.Database.Host = getEnv("DB_HOST", "localhost")
@@ -72,7 +102,33 @@ func LoadConfig() *Config {
 	cfg.Email.Host = getEnv("EMAIL_HOST", "smtp.mailtrap.io")
 	cfg.Email.Port = getEnvAsInt("EMAIL_PORT", 2525)
 	cfg.Email.From = getEnv("EMAIL_FROM", "no-reply@monitron.com")
+	cfg.Email.FromName = getEnv("EMAIL_FROM_NAME", "Monitron")
+	cfg.Email.Username = getEnv("EMAIL_USERNAME", cfg.Email.From)
 	cfg.Email.Password = getEnv("EMAIL_PASSWORD", "your_email_password")
+	cfg.Email.TLSMode = getEnv("EMAIL_TLS_MODE", "starttls")
+	cfg.Email.SkipTLSVerify = getEnvAsBool("EMAIL_TLS_SKIP_VERIFY", false)
+
+	// Storage Config
+	cfg.Storage.Backend = getEnv("STORAGE_BACKEND", "local")
+	cfg.Storage.LocalDir = getEnv("STORAGE_LOCAL_DIR", "reports")
+	cfg.Storage.S3Endpoint = getEnv("S3_ENDPOINT", "localhost:9000")
+	cfg.Storage.S3AccessKey = getEnv("S3_ACCESS_KEY", "")
+	cfg.Storage.S3SecretKey = getEnv("S3_SECRET_KEY", "")
+	cfg.Storage.S3Bucket = getEnv("S3_BUCKET", "monitron-reports")
+	cfg.Storage.S3Region = getEnv("S3_REGION", "us-east-1")
+	cfg.Storage.S3UseSSL = getEnvAsBool("S3_USE_SSL", false)
+
+	// Reports Config
+	cfg.Reports.RetentionDays = getEnvAsInt("REPORT_RETENTION_DAYS", 30)
+
+	// GraphQL Config
+	cfg.GraphQL.GraphiQL = getEnvAsBool("GRAPHQL_GRAPHIQL", false)
+	cfg.GraphQL.Introspection = getEnvAsBool("GRAPHQL_INTROSPECTION", false)
+	cfg.GraphQL.MaxDepth = getEnvAsInt("GRAPHQL_MAX_DEPTH", 10)
+	cfg.GraphQL.MaxComplexity = getEnvAsInt("GRAPHQL_MAX_COMPLEXITY", 5000)
+	cfg.GraphQL.CostBudget = getEnvAsInt("GRAPHQL_COST_BUDGET", 50000)
+	cfg.GraphQL.PersistedQueries = getEnv("GRAPHQL_PERSISTED_QUERIES", "apq")
+	cfg.GraphQL.PersistedQueriesFile = getEnv("GRAPHQL_PERSISTED_QUERIES_FILE", "")
 
 	return cfg
 }
@@ -92,3 +148,12 @@ func getEnvAsInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+func getEnvAsBool(key string, defaultValue bool) bool {
+	if value, exists := os.LookupEnv(key); exists {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
@@ -0,0 +1,8 @@
+-- Reset tokens are now stored as SHA-256 digests; plaintext tokens issued
+-- before this migration can no longer be verified and are dropped.
+DELETE FROM password_reset_tokens;
+
+ALTER TABLE password_reset_tokens RENAME COLUMN token TO token_hash;
+ALTER TABLE password_reset_tokens ALTER COLUMN token_hash TYPE VARCHAR(64);
+
+CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_user_id ON password_reset_tokens(user_id);
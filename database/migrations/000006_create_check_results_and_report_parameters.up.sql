@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS check_results (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    target_type VARCHAR(50) NOT NULL, -- service, instance, domain_ssl
+    target_id UUID NOT NULL,
+    status VARCHAR(20) NOT NULL, -- up, down
+    response_time DECIMAL(10, 2) NOT NULL DEFAULT 0, -- in milliseconds
+    message TEXT,
+    checked_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_check_results_target_checked_at ON check_results(target_type, target_id, checked_at);
+
+CREATE INDEX IF NOT EXISTS idx_instance_metrics_timestamp ON instance_metrics(timestamp);
+
+ALTER TABLE reports ADD COLUMN IF NOT EXISTS status VARCHAR(50) NOT NULL DEFAULT 'pending';
+ALTER TABLE reports ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE reports ADD COLUMN IF NOT EXISTS range_start TIMESTAMP WITH TIME ZONE;
+ALTER TABLE reports ADD COLUMN IF NOT EXISTS range_end TIMESTAMP WITH TIME ZONE;
+ALTER TABLE reports ADD COLUMN IF NOT EXISTS target_ids TEXT; -- JSON array of target IDs
+ALTER TABLE reports ADD COLUMN IF NOT EXISTS target_groups TEXT; -- JSON array of group names
+ALTER TABLE reports ALTER COLUMN file_path SET DEFAULT '';
@@ -0,0 +1,9 @@
+ALTER TABLE reports ADD COLUMN IF NOT EXISTS error_message TEXT NOT NULL DEFAULT '';
+ALTER TABLE reports ADD COLUMN IF NOT EXISTS file_size BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE reports ALTER COLUMN generated_at DROP DEFAULT;
+
+-- Reports that never ran have no generation time
+UPDATE reports SET generated_at = NULL WHERE status <> 'completed';
+
+CREATE INDEX IF NOT EXISTS idx_reports_user_id ON reports(user_id);
+CREATE INDEX IF NOT EXISTS idx_reports_created_at ON reports(created_at);
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS report_schedules (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(255) NOT NULL,
+    cron_expression VARCHAR(255) NOT NULL,
+    timezone VARCHAR(100) NOT NULL DEFAULT 'UTC',
+    report_type VARCHAR(255) NOT NULL,
+    format VARCHAR(50) NOT NULL DEFAULT 'CSV',
+    range_days INTEGER NOT NULL DEFAULT 7,
+    target_ids TEXT, -- JSON array of target IDs
+    target_groups TEXT, -- JSON array of group names
+    recipients TEXT, -- JSON array of email addresses
+    channel_ids TEXT, -- JSON array of notification channel IDs
+    delivery_mode VARCHAR(50) NOT NULL DEFAULT 'attachment', -- attachment or link
+    is_enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    last_run_at TIMESTAMP WITH TIME ZONE,
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_schedules_user_id ON report_schedules(user_id);
+
+ALTER TABLE reports ADD COLUMN IF NOT EXISTS schedule_id UUID REFERENCES report_schedules(id) ON DELETE SET NULL;
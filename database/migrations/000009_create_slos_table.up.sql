@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS slos (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(255) NOT NULL,
+    target_type VARCHAR(50) NOT NULL, -- service or operational_page
+    target_id UUID NOT NULL,
+    objective DECIMAL(7, 4) NOT NULL, -- percentage of good checks, e.g. 99.9
+    "window" VARCHAR(50) NOT NULL DEFAULT 'rolling_30d', -- rolling_30d or calendar_month
+    latency_threshold DECIMAL(10, 2) NOT NULL DEFAULT 0, -- milliseconds, 0 disables the latency objective
+    burn_rate_threshold DECIMAL(10, 2) NOT NULL DEFAULT 14.4,
+    alert_firing BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_slos_target ON slos(target_type, target_id);
@@ -0,0 +1,2 @@
+ALTER TABLE operational_page_components
+    ADD COLUMN IF NOT EXISTS weight DECIMAL(6, 2) NOT NULL DEFAULT 1; -- share of the component in page-level uptime
@@ -0,0 +1,15 @@
+ALTER TABLE operational_pages
+    ADD COLUMN IF NOT EXISTS report_reasons TEXT; -- JSON array of reasons offered to visitors, NULL for the defaults
+
+CREATE TABLE IF NOT EXISTS problem_reports (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    page_id UUID NOT NULL REFERENCES operational_pages(id) ON DELETE CASCADE,
+    reason VARCHAR(255) NOT NULL,
+    comment TEXT,
+    ip_hash VARCHAR(64) NOT NULL, -- SHA-256 of the visitor IP, used for rate limiting
+    user_agent VARCHAR(512),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_problem_reports_page_created ON problem_reports(page_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_problem_reports_ip_created ON problem_reports(ip_hash, created_at);
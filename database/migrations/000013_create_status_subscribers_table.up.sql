@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS status_subscribers (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    page_id UUID NOT NULL REFERENCES operational_pages(id) ON DELETE CASCADE,
+    type VARCHAR(20) NOT NULL, -- email or webhook
+    email VARCHAR(255),
+    webhook_url TEXT,
+    secret TEXT, -- Encrypted webhook signing secret
+    component_ids TEXT, -- JSON array of page component IDs, NULL or empty for all
+    confirmed BOOLEAN NOT NULL DEFAULT FALSE,
+    confirm_token_hash VARCHAR(64),
+    confirmed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_status_subscribers_page_email ON status_subscribers(page_id, email) WHERE type = 'email';
+CREATE INDEX IF NOT EXISTS idx_status_subscribers_page ON status_subscribers(page_id, confirmed);
@@ -0,0 +1,9 @@
+ALTER TABLE operational_pages
+    ADD COLUMN IF NOT EXISTS custom_domains TEXT, -- JSON array of hostnames serving the page, NULL for none
+    ADD COLUMN IF NOT EXISTS logo_url VARCHAR(1024),
+    ADD COLUMN IF NOT EXISTS favicon_url VARCHAR(1024),
+    ADD COLUMN IF NOT EXISTS color_theme VARCHAR(20), -- light_blue, orange or light_green, NULL for the default
+    ADD COLUMN IF NOT EXISTS custom_css TEXT,
+    ADD COLUMN IF NOT EXISTS footer_text VARCHAR(1000);
+
+CREATE INDEX IF NOT EXISTS idx_operational_pages_custom_domains ON operational_pages USING GIN ((custom_domains::jsonb));
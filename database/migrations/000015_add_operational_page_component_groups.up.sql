@@ -0,0 +1,4 @@
+ALTER TABLE operational_page_components
+    ADD COLUMN IF NOT EXISTS parent_id UUID REFERENCES operational_page_components(id) ON DELETE SET NULL; -- group the component is shown in
+
+CREATE INDEX IF NOT EXISTS idx_operational_page_components_target ON operational_page_components(component_type, component_id);
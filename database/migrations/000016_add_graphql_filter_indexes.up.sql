@@ -0,0 +1,5 @@
+-- Indexes behind the filters and orderings of the GraphQL list fields
+CREATE INDEX IF NOT EXISTS idx_log_entries_timestamp ON log_entries(timestamp DESC);
+CREATE INDEX IF NOT EXISTS idx_log_entries_level_timestamp ON log_entries(level, timestamp DESC);
+CREATE INDEX IF NOT EXISTS idx_log_entries_message_fts ON log_entries USING GIN (to_tsvector('simple', message));
+CREATE INDEX IF NOT EXISTS idx_reports_user_created_at ON reports(user_id, created_at DESC);
@@ -0,0 +1,3 @@
+-- Marks scheduled reports already sent to their recipients, so redelivered
+-- queue messages do not send them again
+ALTER TABLE reports ADD COLUMN IF NOT EXISTS delivered_at TIMESTAMP WITH TIME ZONE;
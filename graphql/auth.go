@@ -0,0 +1,74 @@
+package graphql
+
+import (
+	"context"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/kinds"
+	"github.com/graphql-go/graphql/language/visitor"
+
+	"monitron-server/internal/realtime"
+)
+
+type contextKey string
+
+const viewerKey contextKey = "viewer"
+
+// WithViewer stores the caller of a GraphQL operation in its context
+func WithViewer(ctx context.Context, viewer realtime.Viewer) context.Context {
+	return context.WithValue(ctx, viewerKey, viewer)
+}
+
+// viewerFrom returns the caller stored by WithViewer, anonymous if none
+func viewerFrom(ctx context.Context) realtime.Viewer {
+	viewer, _ := ctx.Value(viewerKey).(realtime.Viewer)
+	return viewer
+}
+
+// Field guards. Fields of a type are reachable only through the root fields
+// returning it, so guarding those guards the type.
+
+// authenticated resolves a field only for signed-in callers
+func authenticated(resolve graphql.FieldResolveFn) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		if !viewerFrom(p.Context).Authenticated {
+			return nil, realtime.ErrUnauthorized
+		}
+		return resolve(p)
+	}
+}
+
+// adminOnly resolves a field only for admins
+func adminOnly(resolve graphql.FieldResolveFn) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		viewer := viewerFrom(p.Context)
+		if !viewer.Authenticated {
+			return nil, realtime.ErrUnauthorized
+		}
+		if viewer.Role != "admin" {
+			return nil, realtime.ErrForbidden
+		}
+		return resolve(p)
+	}
+}
+
+// introspects reports whether a document selects __schema or __type
+func introspects(document *ast.Document) bool {
+	found := false
+	visitor.Visit(document, &visitor.VisitorOptions{
+		KindFuncMap: map[string]visitor.NamedVisitFuncs{
+			kinds.Field: {
+				Kind: func(p visitor.VisitFuncParams) (string, interface{}) {
+					field, ok := p.Node.(*ast.Field)
+					if ok && field.Name != nil && (field.Name.Value == "__schema" || field.Name.Value == "__type") {
+						found = true
+						return visitor.ActionBreak, nil
+					}
+					return visitor.ActionNoChange, nil
+				},
+			},
+		},
+	}, nil)
+	return found
+}
@@ -0,0 +1,325 @@
+package graphql
+
+import (
+	"encoding/base64"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/graphql-go/graphql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"monitron-server/internal/realtime"
+)
+
+// Page sizes of connections
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 500
+)
+
+// cursorPrefix is prepended to offsets before encoding them as cursors, as
+// graphql-relay does
+const cursorPrefix = "cursor:"
+
+// PageInfoType defines the GraphQL type for Relay page info
+var PageInfoType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "PageInfo",
+	Fields: graphql.Fields{
+		"hasNextPage":     &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+		"hasPreviousPage": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+		"startCursor":     &graphql.Field{Type: graphql.String},
+		"endCursor":       &graphql.Field{Type: graphql.String},
+	},
+})
+
+// OrderDirectionEnum defines the GraphQL enum for sort directions
+var OrderDirectionEnum = graphql.NewEnum(graphql.EnumConfig{
+	Name: "OrderDirection",
+	Values: graphql.EnumValueConfigMap{
+		"ASC":  &graphql.EnumValueConfig{Value: "ASC"},
+		"DESC": &graphql.EnumValueConfig{Value: "DESC"},
+	},
+})
+
+// connection is the resolved value of a connection field
+type connection struct {
+	Edges      []edge   `json:"edges"`
+	PageInfo   pageInfo `json:"pageInfo"`
+	TotalCount int64    `json:"totalCount"`
+}
+
+type edge struct {
+	Cursor string      `json:"cursor"`
+	Node   interface{} `json:"node"`
+}
+
+type pageInfo struct {
+	HasNextPage     bool    `json:"hasNextPage"`
+	HasPreviousPage bool    `json:"hasPreviousPage"`
+	StartCursor     *string `json:"startCursor"`
+	EndCursor       *string `json:"endCursor"`
+}
+
+// NewConnectionType defines the Relay connection and edge types of a node
+// type, named <name>Connection and <name>Edge
+func NewConnectionType(name string, node *graphql.Object) *graphql.Object {
+	edgeType := graphql.NewObject(graphql.ObjectConfig{
+		Name: name + "Edge",
+		Fields: graphql.Fields{
+			"cursor": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"node":   &graphql.Field{Type: node},
+		},
+	})
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: name + "Connection",
+		Fields: graphql.Fields{
+			"edges":      &graphql.Field{Type: graphql.NewList(edgeType)},
+			"pageInfo":   &graphql.Field{Type: graphql.NewNonNull(PageInfoType)},
+			"totalCount": &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "Number of nodes matching the filter, on all pages"},
+		},
+	})
+}
+
+// NewOrderType defines the input type ordering a connection, named
+// <name>Order. fields maps the values of its <name>OrderField enum to columns.
+func NewOrderType(name string, fields map[string]string) *graphql.InputObject {
+	values := graphql.EnumValueConfigMap{}
+	for value, column := range fields {
+		values[value] = &graphql.EnumValueConfig{Value: column}
+	}
+	fieldEnum := graphql.NewEnum(graphql.EnumConfig{Name: name + "OrderField", Values: values})
+
+	return graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: name + "Order",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"field":     &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(fieldEnum)},
+			"direction": &graphql.InputObjectFieldConfig{Type: OrderDirectionEnum, DefaultValue: "ASC"},
+		},
+	})
+}
+
+// ConnectionArgs returns the Relay pagination arguments plus a filter and a
+// list of orderings applied in turn
+func ConnectionArgs(filter, order *graphql.InputObject) graphql.FieldConfigArgument {
+	return graphql.FieldConfigArgument{
+		"first":   &graphql.ArgumentConfig{Type: graphql.Int, Description: fmt.Sprintf("Defaults to %d when last is not set, at most %d", DefaultPageSize, MaxPageSize)},
+		"after":   &graphql.ArgumentConfig{Type: graphql.String},
+		"last":    &graphql.ArgumentConfig{Type: graphql.Int, Description: fmt.Sprintf("At most %d", MaxPageSize)},
+		"before":  &graphql.ArgumentConfig{Type: graphql.String},
+		"filter":  &graphql.ArgumentConfig{Type: filter},
+		"orderBy": &graphql.ArgumentConfig{Type: graphql.NewList(graphql.NewNonNull(order))},
+	}
+}
+
+// filterSpec describes how the fields of a filter input constrain a query
+type filterSpec struct {
+	equals   map[string]string // Field to column, matched exactly
+	ids      map[string]string // Field to UUID column
+	from     map[string]string // Field to time column, matched inclusively
+	to       map[string]string // Field to time column, matched exclusively
+	search   []string          // Columns matched case-insensitively by the search field
+	fullText string            // Column matched by the search field with Postgres full-text search instead
+	owner    string            // UUID column limiting non-admins to their own rows
+	public   string            // Boolean column limiting anonymous callers to public rows
+}
+
+// likeEscaper escapes the wildcards of LIKE patterns
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// scope limits a query to the rows the caller may read
+func (s filterSpec) scope(query *gorm.DB, viewer realtime.Viewer) *gorm.DB {
+	if s.owner != "" && viewer.Role != "admin" {
+		query = query.Where(s.owner+" = ?", viewer.UserID)
+	}
+	if s.public != "" && !viewer.Authenticated {
+		query = query.Where(s.public + " = true")
+	}
+	return query
+}
+
+// apply constrains a query by the filter argument of a connection field
+func (s filterSpec) apply(query *gorm.DB, filter map[string]interface{}) (*gorm.DB, error) {
+	for field, column := range s.equals {
+		if value, ok := filter[field]; ok && value != nil {
+			query = query.Where(column+" = ?", value)
+		}
+	}
+	for field, column := range s.ids {
+		if value, ok := filter[field]; ok && value != nil {
+			id, err := uuid.Parse(fmt.Sprint(value))
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s", field)
+			}
+			query = query.Where(column+" = ?", id)
+		}
+	}
+	for field, column := range s.from {
+		if t, ok := filter[field].(time.Time); ok {
+			query = query.Where(column+" >= ?", t)
+		}
+	}
+	for field, column := range s.to {
+		if t, ok := filter[field].(time.Time); ok {
+			query = query.Where(column+" < ?", t)
+		}
+	}
+
+	term, _ := filter["search"].(string)
+	if term = strings.TrimSpace(term); term == "" {
+		return query, nil
+	}
+	if s.fullText != "" {
+		return query.Where("to_tsvector('simple', "+s.fullText+") @@ websearch_to_tsquery('simple', ?)", term), nil
+	}
+	pattern := "%" + likeEscaper.Replace(term) + "%"
+	conditions := make([]string, len(s.search))
+	args := make([]interface{}, len(s.search))
+	for i, column := range s.search {
+		conditions[i] = column + " ILIKE ?"
+		args[i] = pattern
+	}
+	return query.Where("("+strings.Join(conditions, " OR ")+")", args...), nil
+}
+
+// pageArgs are the parsed pagination arguments of a connection field
+type pageArgs struct {
+	after, before *int
+	first, last   *int
+	orders        []clause.OrderByColumn
+}
+
+// parsePageArgs reads the pagination and ordering arguments of a field
+func parsePageArgs(p graphql.ResolveParams) (pageArgs, error) {
+	args := pageArgs{}
+	for name, target := range map[string]**int{"after": &args.after, "before": &args.before} {
+		cursor, ok := p.Args[name].(string)
+		if !ok {
+			continue
+		}
+		offset, err := decodeCursor(cursor)
+		if err != nil {
+			return args, fmt.Errorf("invalid %s cursor", name)
+		}
+		*target = &offset
+	}
+	for name, target := range map[string]**int{"first": &args.first, "last": &args.last} {
+		n, ok := p.Args[name].(int)
+		if !ok {
+			continue
+		}
+		if n < 0 || n > MaxPageSize {
+			return args, fmt.Errorf("%s must be between 0 and %d", name, MaxPageSize)
+		}
+		*target = &n
+	}
+	if args.first == nil && args.last == nil {
+		n := DefaultPageSize
+		args.first = &n
+	}
+
+	orderBy, _ := p.Args["orderBy"].([]interface{})
+	for _, item := range orderBy {
+		order, _ := item.(map[string]interface{})
+		column, _ := order["field"].(string)
+		if column == "" {
+			continue
+		}
+		args.orders = append(args.orders, clause.OrderByColumn{Column: clause.Column{Name: column}, Desc: order["direction"] == "DESC"})
+	}
+	return args, nil
+}
+
+// listResolver resolves a connection over the rows of model T the caller may
+// read. Rows are filtered by spec, ordered by the orderBy argument or
+// defaultOrder, then by ID so pages are stable. columns limits the selected
+// columns if given.
+func listResolver[T any](db *gorm.DB, what string, spec filterSpec, defaultOrder []clause.OrderByColumn, columns ...string) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		args, err := parsePageArgs(p)
+		if err != nil {
+			return nil, err
+		}
+		filter, _ := p.Args["filter"].(map[string]interface{})
+		query, err := spec.apply(spec.scope(db.Model(new(T)), viewerFrom(p.Context)), filter)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Session(&gorm.Session{})
+
+		result := &connection{Edges: []edge{}}
+		if err := query.Count(&result.TotalCount).Error; err != nil {
+			log.Printf("Error counting %s for GraphQL: %v", what, err)
+			return nil, err
+		}
+
+		start, end := args.window(int(result.TotalCount))
+		result.PageInfo.HasPreviousPage = start > 0
+		result.PageInfo.HasNextPage = end < int(result.TotalCount)
+		if end <= start {
+			return result, nil
+		}
+
+		orders := append([]clause.OrderByColumn{}, args.orders...)
+		if len(orders) == 0 {
+			orders = append(orders, defaultOrder...)
+		}
+		orders = append(orders, clause.OrderByColumn{Column: clause.Column{Name: "id"}})
+		if len(columns) > 0 {
+			query = query.Select(columns)
+		}
+
+		var rows []T
+		err = query.Clauses(clause.OrderBy{Columns: orders}).Offset(start).Limit(end - start).Find(&rows).Error
+		if err != nil {
+			log.Printf("Error fetching %s for GraphQL: %v", what, err)
+			return nil, err
+		}
+		for i, row := range rows {
+			result.Edges = append(result.Edges, edge{Cursor: encodeCursor(start + i), Node: row})
+		}
+		if len(result.Edges) > 0 {
+			result.PageInfo.StartCursor = &result.Edges[0].Cursor
+			result.PageInfo.EndCursor = &result.Edges[len(result.Edges)-1].Cursor
+		}
+		return result, nil
+	}
+}
+
+// window returns the offsets of the first and past the last row of the page
+func (args pageArgs) window(total int) (start, end int) {
+	start, end = 0, total
+	if args.after != nil {
+		start = max(start, *args.after+1)
+	}
+	if args.before != nil {
+		end = min(end, *args.before)
+	}
+	if args.first != nil {
+		end = min(end, start+*args.first)
+	}
+	if args.last != nil {
+		start = max(start, end-*args.last)
+	}
+	return start, end
+}
+
+func encodeCursor(offset int) string {
+	return base64.StdEncoding.EncodeToString([]byte(cursorPrefix + strconv.Itoa(offset)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	data, err := base64.StdEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, err
+	}
+	offset, err := strconv.Atoi(strings.TrimPrefix(string(data), cursorPrefix))
+	if err != nil || offset < 0 || !strings.HasPrefix(string(data), cursorPrefix) {
+		return 0, fmt.Errorf("invalid cursor")
+	}
+	return offset, nil
+}
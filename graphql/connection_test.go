@@ -0,0 +1,105 @@
+package graphql
+
+import (
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+func intPtr(n int) *int {
+	return &n
+}
+
+func TestPageArgsWindow(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       pageArgs
+		total      int
+		start, end int
+	}{
+		{name: "first", args: pageArgs{first: intPtr(10)}, total: 100, start: 0, end: 10},
+		{name: "first beyond the total", args: pageArgs{first: intPtr(10)}, total: 4, start: 0, end: 4},
+		{name: "first zero", args: pageArgs{first: intPtr(0)}, total: 100, start: 0, end: 0},
+		{name: "first after", args: pageArgs{first: intPtr(10), after: intPtr(9)}, total: 100, start: 10, end: 20},
+		{name: "first after the end", args: pageArgs{first: intPtr(10), after: intPtr(99)}, total: 100, start: 100, end: 100},
+		{name: "first after beyond the total", args: pageArgs{first: intPtr(10), after: intPtr(500)}, total: 100, start: 501, end: 100},
+		{name: "last", args: pageArgs{last: intPtr(10)}, total: 100, start: 90, end: 100},
+		{name: "last beyond the total", args: pageArgs{last: intPtr(10)}, total: 4, start: 0, end: 4},
+		{name: "last before", args: pageArgs{last: intPtr(10), before: intPtr(50)}, total: 100, start: 40, end: 50},
+		{name: "last before the start", args: pageArgs{last: intPtr(10), before: intPtr(5)}, total: 100, start: 0, end: 5},
+		{name: "after and before", args: pageArgs{after: intPtr(9), before: intPtr(20)}, total: 100, start: 10, end: 20},
+		{name: "first within after and before", args: pageArgs{first: intPtr(5), after: intPtr(9), before: intPtr(20)}, total: 100, start: 10, end: 15},
+		{name: "last within after and before", args: pageArgs{last: intPtr(5), after: intPtr(9), before: intPtr(20)}, total: 100, start: 15, end: 20},
+		{name: "first and last", args: pageArgs{first: intPtr(20), last: intPtr(5)}, total: 100, start: 15, end: 20},
+		{name: "before beyond the total", args: pageArgs{before: intPtr(500)}, total: 100, start: 0, end: 100},
+		{name: "no rows", args: pageArgs{first: intPtr(10)}, total: 0, start: 0, end: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := tt.args.window(tt.total)
+			if start != tt.start || end != tt.end {
+				t.Errorf("window(%d) = %d, %d, want %d, %d", tt.total, start, end, tt.start, tt.end)
+			}
+		})
+	}
+}
+
+func TestParsePageArgs(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        map[string]interface{}
+		wantErr     bool
+		first, last *int
+		after       *int
+		before      *int
+	}{
+		{name: "defaults to a page", args: map[string]interface{}{}, first: intPtr(DefaultPageSize)},
+		{name: "first", args: map[string]interface{}{"first": 10}, first: intPtr(10)},
+		{name: "first at the maximum", args: map[string]interface{}{"first": MaxPageSize}, first: intPtr(MaxPageSize)},
+		{name: "first over the maximum", args: map[string]interface{}{"first": MaxPageSize + 1}, wantErr: true},
+		{name: "negative first", args: map[string]interface{}{"first": -1}, wantErr: true},
+		{name: "last only", args: map[string]interface{}{"last": 5}, last: intPtr(5)},
+		{name: "negative last", args: map[string]interface{}{"last": -1}, wantErr: true},
+		{name: "after cursor", args: map[string]interface{}{"after": encodeCursor(41)}, first: intPtr(DefaultPageSize), after: intPtr(41)},
+		{name: "before cursor", args: map[string]interface{}{"before": encodeCursor(7), "last": 3}, last: intPtr(3), before: intPtr(7)},
+		{name: "cursor without prefix", args: map[string]interface{}{"after": "NDI="}, wantErr: true},
+		{name: "cursor that is not base64", args: map[string]interface{}{"after": "%%%"}, wantErr: true},
+		{name: "negative cursor", args: map[string]interface{}{"after": encodeCursor(-1)}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, err := parsePageArgs(graphql.ResolveParams{Args: tt.args})
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parsePageArgs() = nil error, want one")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parsePageArgs() = %v", err)
+			}
+			for _, check := range []struct {
+				field     string
+				got, want *int
+			}{
+				{"first", args.first, tt.first},
+				{"last", args.last, tt.last},
+				{"after", args.after, tt.after},
+				{"before", args.before, tt.before},
+			} {
+				if (check.got == nil) != (check.want == nil) || (check.got != nil && *check.got != *check.want) {
+					t.Errorf("%s = %v, want %v", check.field, deref(check.got), deref(check.want))
+				}
+			}
+		})
+	}
+}
+
+func deref(n *int) interface{} {
+	if n == nil {
+		return nil
+	}
+	return *n
+}
@@ -0,0 +1,258 @@
+package graphql
+
+import (
+	"context"
+	"math"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+
+	"monitron-server/internal/statuspage"
+)
+
+// Field costs
+const (
+	objectCost      = 1  // Fields returning objects, unless annotated
+	aggregateCost   = 5  // Fields aggregating the check history of a target
+	pointsPerCost   = 10 // Points of a time series per unit of cost
+	defaultListSize = 10 // Assumed length of lists without a page size
+	maxCost         = math.MaxInt32
+)
+
+// fieldCost estimates one resolution of a field from its arguments: its own
+// cost, and how many times its selections are resolved
+type fieldCost func(ctx context.Context, args map[string]interface{}) (cost, multiplier int)
+
+// fieldCosts are the cost annotations of fields, keyed by <type>.<field>.
+// Fields without one cost objectCost when they return objects and nothing
+// otherwise.
+var fieldCosts = map[string]fieldCost{
+	"RootQuery.instances":                 connectionCost,
+	"RootQuery.services":                  connectionCost,
+	"RootQuery.domainSSLs":                connectionCost,
+	"RootQuery.users":                     connectionCost,
+	"RootQuery.reports":                   connectionCost,
+	"RootQuery.logEntries":                connectionCost,
+	"RootQuery.operationalPages":          connectionCost,
+	"RootQuery.slos":                      listCost,
+	"RootQuery.operationalPageComponents": listCost,
+	"SLO.status":                          fixedCost(aggregateCost),
+	"Instance.metrics":                    seriesCost,
+}
+
+func init() {
+	for _, target := range []string{"Service", "Instance", "DomainSSL"} {
+		fieldCosts[target+".stats"] = fixedCost(aggregateCost)
+		fieldCosts[target+".results"] = seriesCost
+		fieldCosts[target+".uptime"] = uptimeCost
+		fieldCosts[target+".incidents"] = incidentsCost
+	}
+}
+
+func fixedCost(cost int) fieldCost {
+	return func(context.Context, map[string]interface{}) (int, int) {
+		return cost, 1
+	}
+}
+
+// connectionCost resolves the nodes of a connection once per node of a page
+func connectionCost(_ context.Context, args map[string]interface{}) (int, int) {
+	size := DefaultPageSize
+	if first, ok := args["first"].(int); ok {
+		size = first
+	} else if last, ok := args["last"].(int); ok {
+		size = last
+	}
+	return objectCost, max(min(size, MaxPageSize), 0)
+}
+
+func listCost(context.Context, map[string]interface{}) (int, int) {
+	return objectCost, defaultListSize
+}
+
+func incidentsCost(_ context.Context, args map[string]interface{}) (int, int) {
+	first, ok := args["first"].(int)
+	if !ok {
+		first = DefaultIncidents
+	}
+	return objectCost, max(min(first, MaxIncidents), 0)
+}
+
+// seriesCost grows with the points a time series returns and the days of
+// history it scans
+func seriesCost(ctx context.Context, args map[string]interface{}) (int, int) {
+	r, err := parseSeriesRange(graphql.ResolveParams{Context: ctx, Args: args})
+	if err != nil {
+		return objectCost, 1
+	}
+	points := int(r.to.Sub(r.from) / r.step)
+	days := int(math.Ceil(r.to.Sub(r.from).Hours() / 24))
+	return objectCost + (points+pointsPerCost-1)/pointsPerCost + days, 1
+}
+
+// uptimeCost grows with every 30 days of history the window scans
+func uptimeCost(_ context.Context, args map[string]interface{}) (int, int) {
+	window, _ := args["window"].(string)
+	period, err := statuspage.ParsePeriod(window)
+	if err != nil {
+		return aggregateCost, 1
+	}
+	return aggregateCost * int(math.Ceil(period.Hours()/(30*24))), 1
+}
+
+// analysis estimates the depth and cost of an operation before it runs
+type analysis struct {
+	ctx       context.Context
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+	schema    *graphql.Schema
+}
+
+// analyze returns the depth and cost of the operation of a document, or zeros
+// if it has none by that name, leaving the error to graphql
+func analyze(ctx context.Context, schema *graphql.Schema, document *ast.Document, operationName string, variables map[string]interface{}) (depth, cost int) {
+	a := &analysis{ctx: ctx, fragments: map[string]*ast.FragmentDefinition{}, variables: variables, schema: schema}
+	var operation *ast.OperationDefinition
+	for _, definition := range document.Definitions {
+		switch definition := definition.(type) {
+		case *ast.FragmentDefinition:
+			if definition.Name != nil {
+				a.fragments[definition.Name.Value] = definition
+			}
+		case *ast.OperationDefinition:
+			if operationName == "" || (definition.Name != nil && definition.Name.Value == operationName) {
+				operation = definition
+			}
+		}
+	}
+	if operation == nil {
+		return 0, 0
+	}
+
+	var root *graphql.Object
+	switch operation.Operation {
+	case ast.OperationTypeQuery:
+		root = schema.QueryType()
+	case ast.OperationTypeMutation:
+		root = schema.MutationType()
+	case ast.OperationTypeSubscription:
+		root = schema.SubscriptionType()
+	}
+	if root == nil {
+		return 0, 0
+	}
+	return a.selections(root, operation.SelectionSet, map[string]bool{})
+}
+
+// selections returns the depth and cost of a selection set on a type.
+// visiting holds the fragments being expanded, so cycles end.
+func (a *analysis) selections(parent graphql.Type, set *ast.SelectionSet, visiting map[string]bool) (depth, cost int) {
+	if set == nil {
+		return 0, 0
+	}
+	for _, selection := range set.Selections {
+		var d, c int
+		switch selection := selection.(type) {
+		case *ast.Field:
+			d, c = a.field(parent, selection, visiting)
+		case *ast.InlineFragment:
+			on := parent
+			if selection.TypeCondition != nil && selection.TypeCondition.Name != nil {
+				if t := a.schema.Type(selection.TypeCondition.Name.Value); t != nil {
+					on = t
+				}
+			}
+			d, c = a.selections(on, selection.SelectionSet, visiting)
+		case *ast.FragmentSpread:
+			if selection.Name == nil {
+				continue
+			}
+			name := selection.Name.Value
+			fragment, ok := a.fragments[name]
+			if !ok || visiting[name] {
+				continue
+			}
+			on := parent
+			if fragment.TypeCondition != nil && fragment.TypeCondition.Name != nil {
+				if t := a.schema.Type(fragment.TypeCondition.Name.Value); t != nil {
+					on = t
+				}
+			}
+			visiting[name] = true
+			d, c = a.selections(on, fragment.SelectionSet, visiting)
+			delete(visiting, name)
+		}
+		depth = max(depth, d)
+		cost = min(cost+c, maxCost)
+	}
+	return depth, cost
+}
+
+// field returns the depth and cost of a field and its selections. Meta fields
+// such as __typename are free, introspection being limited on its own.
+func (a *analysis) field(parent graphql.Type, field *ast.Field, visiting map[string]bool) (depth, cost int) {
+	if field.Name == nil || strings.HasPrefix(field.Name.Value, "__") {
+		return 0, 0
+	}
+	object, ok := parent.(*graphql.Object)
+	if !ok {
+		return 0, 0
+	}
+	definition, ok := object.Fields()[field.Name.Value]
+	if !ok {
+		return 0, 0
+	}
+
+	named, _ := graphql.GetNamed(definition.Type).(graphql.Type)
+	childDepth, childCost := a.selections(named, field.SelectionSet, visiting)
+	own, multiplier := 0, 1
+	if field.SelectionSet != nil {
+		own = objectCost
+	}
+	if annotated, ok := fieldCosts[object.Name()+"."+definition.Name]; ok {
+		own, multiplier = annotated(a.ctx, a.arguments(definition, field))
+	}
+
+	cost = maxCost
+	if multiplier == 0 || childCost <= (maxCost-own)/multiplier {
+		cost = own + multiplier*childCost
+	}
+	return childDepth + 1, cost
+}
+
+// arguments returns the values of the scalar and enum arguments of a field,
+// defaults included, as its resolver would receive them
+func (a *analysis) arguments(definition *graphql.FieldDefinition, field *ast.Field) map[string]interface{} {
+	values := map[string]interface{}{}
+	for _, argument := range definition.Args {
+		if argument.DefaultValue != nil {
+			values[argument.Name()] = argument.DefaultValue
+		}
+	}
+	for _, argument := range field.Arguments {
+		if argument.Name == nil {
+			continue
+		}
+		for _, def := range definition.Args {
+			if def.Name() != argument.Name.Value {
+				continue
+			}
+			leaf, ok := graphql.GetNullable(def.Type).(interface {
+				ParseValue(interface{}) interface{}
+				ParseLiteral(ast.Value) interface{}
+			})
+			if !ok {
+				break
+			}
+			if variable, ok := argument.Value.(*ast.Variable); ok {
+				if variable.Name != nil && a.variables[variable.Name.Value] != nil {
+					values[def.Name()] = leaf.ParseValue(a.variables[variable.Name.Value])
+				}
+			} else {
+				values[def.Name()] = leaf.ParseLiteral(argument.Value)
+			}
+		}
+	}
+	return values
+}
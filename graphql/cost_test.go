@@ -0,0 +1,255 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+// testItemType is a recursive object whose children field multiplies the
+// cost of its selections by its size argument, without a cap
+var testItemType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "TestItem",
+	Fields: graphql.Fields{
+		"id": &graphql.Field{Type: graphql.String},
+	},
+})
+
+func init() {
+	testItemType.AddFieldConfig("children", &graphql.Field{
+		Type: graphql.NewList(testItemType),
+		Args: graphql.FieldConfigArgument{
+			"size": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 10},
+		},
+	})
+}
+
+var testItemEdgeType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "TestItemEdge",
+	Fields: graphql.Fields{
+		"cursor": &graphql.Field{Type: graphql.String},
+		"node":   &graphql.Field{Type: testItemType},
+	},
+})
+
+var testItemConnectionType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "TestItemConnection",
+	Fields: graphql.Fields{
+		"edges":      &graphql.Field{Type: graphql.NewList(testItemEdgeType)},
+		"pageInfo":   &graphql.Field{Type: PageInfoType},
+		"totalCount": &graphql.Field{Type: graphql.Int},
+	},
+})
+
+// newTestSchema returns a schema whose root is named like the real one, so
+// the connection annotation of RootQuery.instances applies to it
+func newTestSchema(t *testing.T) *graphql.Schema {
+	t.Helper()
+	fieldCosts["TestItem.children"] = func(_ context.Context, args map[string]interface{}) (int, int) {
+		size, _ := args["size"].(int)
+		return objectCost, size
+	}
+	t.Cleanup(func() { delete(fieldCosts, "TestItem.children") })
+
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name: "RootQuery",
+			Fields: graphql.Fields{
+				"item": &graphql.Field{Type: testItemType},
+				"instances": &graphql.Field{
+					Type: testItemConnectionType,
+					Args: graphql.FieldConfigArgument{
+						"first":  &graphql.ArgumentConfig{Type: graphql.Int},
+						"last":   &graphql.ArgumentConfig{Type: graphql.Int},
+						"after":  &graphql.ArgumentConfig{Type: graphql.String},
+						"before": &graphql.ArgumentConfig{Type: graphql.String},
+					},
+				},
+			},
+		}),
+	})
+	if err != nil {
+		t.Fatalf("could not create test schema: %v", err)
+	}
+	return &schema
+}
+
+func TestAnalyze(t *testing.T) {
+	schema := newTestSchema(t)
+
+	tests := []struct {
+		name          string
+		query         string
+		operationName string
+		variables     map[string]interface{}
+		depth, cost   int
+	}{
+		{
+			name:  "leaf fields are free",
+			query: `{ item { id } }`,
+			depth: 2, cost: 1,
+		},
+		{
+			name:  "meta fields are free",
+			query: `{ __typename item { __typename id } }`,
+			depth: 2, cost: 1,
+		},
+		{
+			name:  "argument defaults apply",
+			query: `{ item { children { children { id } } } }`,
+			depth: 4, cost: 1 + (1 + 10*1),
+		},
+		{
+			name:  "literal arguments",
+			query: `{ item { children(size: 3) { children(size: 1) { id } } } }`,
+			depth: 4, cost: 1 + (1 + 3*1),
+		},
+		{
+			name:      "variables replace arguments",
+			query:     `query Q($n: Int) { item { children(size: $n) { children(size: 1) { id } } } }`,
+			variables: map[string]interface{}{"n": float64(3)},
+			depth:     4, cost: 1 + (1 + 3*1),
+		},
+		{
+			name:  "missing variables fall back to defaults",
+			query: `query Q($n: Int) { item { children(size: $n) { children(size: 1) { id } } } }`,
+			depth: 4, cost: 1 + (1 + 10*1),
+		},
+		{
+			name:  "aliases add up",
+			query: `{ a: item { id } b: item { id } c: item { id } }`,
+			depth: 2, cost: 3,
+		},
+		{
+			name:  "multiplied costs are clamped",
+			query: `{ item { children(size: 100000) { children(size: 100000) { children(size: 100000) { id } } } } }`,
+			depth: 5, cost: maxCost,
+		},
+		{
+			name:  "summed costs are clamped",
+			query: `{ a: item { children(size: 1000000000) { children { id } } } b: item { children(size: 1000000000) { children { id } } } c: item { children(size: 1000000000) { children { id } } } }`,
+			depth: 4, cost: maxCost,
+		},
+		{
+			name:  "connections default to a page of nodes",
+			query: `{ instances { edges { node { id } } } }`,
+			depth: 4, cost: 1 + DefaultPageSize*2,
+		},
+		{
+			name:  "connections cost first nodes",
+			query: `{ instances(first: 10) { edges { node { id } } } }`,
+			depth: 4, cost: 1 + 10*2,
+		},
+		{
+			name:  "connections cost last nodes",
+			query: `{ instances(last: 5) { edges { node { id } } } }`,
+			depth: 4, cost: 1 + 5*2,
+		},
+		{
+			name:  "connection sizes are capped",
+			query: `{ instances(first: 1000000) { edges { node { id } } } }`,
+			depth: 4, cost: 1 + MaxPageSize*2,
+		},
+		{
+			name:  "negative connection sizes cost no nodes",
+			query: `{ instances(first: -5) { edges { node { id } } } }`,
+			depth: 4, cost: 1,
+		},
+		{
+			name:      "connection sizes from variables",
+			query:     `query Q($n: Int) { instances(first: $n) { edges { node { id } } } }`,
+			variables: map[string]interface{}{"n": float64(7)},
+			depth:     4, cost: 1 + 7*2,
+		},
+		{
+			name:  "fragments are expanded",
+			query: `{ ...Root } fragment Root on RootQuery { item { children(size: 2) { id } } }`,
+			depth: 3, cost: 1 + 1,
+		},
+		{
+			name:  "inline fragments are expanded",
+			query: `{ ... on RootQuery { item { id } } }`,
+			depth: 2, cost: 1,
+		},
+		{
+			name:  "self-referencing fragments end",
+			query: `{ ...A } fragment A on RootQuery { item { id } ...A }`,
+			depth: 2, cost: 1,
+		},
+		{
+			name:  "fragment cycles end",
+			query: `{ ...A } fragment A on RootQuery { item { id } ...B } fragment B on RootQuery { a: item { id } ...A }`,
+			depth: 2, cost: 2,
+		},
+		{
+			name:  "unknown fragments are skipped",
+			query: `{ item { id } ...Missing }`,
+			depth: 2, cost: 1,
+		},
+		{
+			name:          "the named operation is analyzed",
+			query:         `query A { item { id } } query B { instances(first: 1) { edges { node { id } } } }`,
+			operationName: "B",
+			depth:         4, cost: 1 + 1*2,
+		},
+		{
+			name:          "unknown operations are left to graphql",
+			query:         `query A { item { id } }`,
+			operationName: "B",
+			depth:         0, cost: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			document, err := parser.Parse(parser.ParseParams{Source: tt.query})
+			if err != nil {
+				t.Fatalf("could not parse query: %v", err)
+			}
+			depth, cost := analyze(context.Background(), schema, document, tt.operationName, tt.variables)
+			if depth != tt.depth || cost != tt.cost {
+				t.Errorf("analyze() = depth %d, cost %d, want depth %d, cost %d", depth, cost, tt.depth, tt.cost)
+			}
+		})
+	}
+}
+
+func TestGateAdmitLimits(t *testing.T) {
+	schema := newTestSchema(t)
+	persisted, err := NewPersistedQueries(PersistedQueriesOff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gate := &Gate{Schema: schema, MaxDepth: 3, MaxComplexity: 100, Persisted: persisted}
+
+	tests := []struct {
+		name  string
+		query string
+		code  string // Expected RequestError code, empty when admitted
+	}{
+		{name: "within limits", query: `{ item { id } }`},
+		{name: "at the depth limit", query: `{ item { children { id } } }`},
+		{name: "too deep", query: `{ item { children { children { id } } } }`, code: "QUERY_TOO_DEEP"},
+		{name: "too complex", query: `{ instances(first: 100) { pageInfo { hasNextPage } } }`, code: "QUERY_TOO_COMPLEX"},
+		{name: "deep through fragments", query: `{ ...A } fragment A on RootQuery { item { children { children { id } } } }`, code: "QUERY_TOO_DEEP"},
+		{name: "unparsable queries are left to graphql", query: `{ item {`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := gate.Admit(context.Background(), &Request{Query: tt.query}, "192.0.2.1")
+			var requestErr *RequestError
+			switch {
+			case tt.code == "" && err != nil:
+				t.Errorf("Admit() = %v, want nil", err)
+			case tt.code != "" && !errors.As(err, &requestErr):
+				t.Errorf("Admit() = %v, want %s", err, tt.code)
+			case tt.code != "" && requestErr.Code != tt.code:
+				t.Errorf("Admit() code = %s, want %s", requestErr.Code, tt.code)
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package graphql
+
+import (
+	"github.com/graphql-go/graphql"
+	"gorm.io/gorm/clause"
+)
+
+// Filter, order and connection types of the list fields of RootQuery
+
+func stringFilter(description string) *graphql.InputObjectFieldConfig {
+	return &graphql.InputObjectFieldConfig{Type: graphql.String, Description: description}
+}
+
+func timeFilter(description string) *graphql.InputObjectFieldConfig {
+	return &graphql.InputObjectFieldConfig{Type: graphql.DateTime, Description: description}
+}
+
+func orderBy(column string, desc bool) []clause.OrderByColumn {
+	return []clause.OrderByColumn{{Column: clause.Column{Name: column}, Desc: desc}}
+}
+
+// InstanceFilterType defines the GraphQL filter for instances
+var InstanceFilterType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "InstanceFilter",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"label":       stringFilter("Exact label"),
+		"group":       stringFilter("Exact group"),
+		"search":      stringFilter("Case-insensitive match on name, host, label and description"),
+		"createdFrom": timeFilter("Created at or after"),
+		"createdTo":   timeFilter("Created before"),
+	},
+})
+
+var instanceFilter = filterSpec{
+	equals: map[string]string{"label": "label", "group": `"group"`},
+	from:   map[string]string{"createdFrom": "created_at"},
+	to:     map[string]string{"createdTo": "created_at"},
+	search: []string{"name", "host", "label", "description"},
+}
+
+// InstanceOrderType defines the GraphQL ordering of instances
+var InstanceOrderType = NewOrderType("Instance", map[string]string{
+	"NAME":       "name",
+	"HOST":       "host",
+	"LABEL":      "label",
+	"GROUP":      "group",
+	"CREATED_AT": "created_at",
+	"UPDATED_AT": "updated_at",
+})
+
+// InstanceConnectionType defines the GraphQL connection of instances
+var InstanceConnectionType = NewConnectionType("Instance", InstanceType)
+
+// ServiceFilterType defines the GraphQL filter for services
+var ServiceFilterType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "ServiceFilter",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"label":       stringFilter("Exact label"),
+		"group":       stringFilter("Exact group"),
+		"apiType":     stringFilter("Exact API type, e.g. http, grpc, mqtt, tcp, dns or ping"),
+		"search":      stringFilter("Case-insensitive match on name, label and description"),
+		"createdFrom": timeFilter("Created at or after"),
+		"createdTo":   timeFilter("Created before"),
+	},
+})
+
+var serviceFilter = filterSpec{
+	equals: map[string]string{"label": "label", "group": `"group"`, "apiType": "api_type"},
+	from:   map[string]string{"createdFrom": "created_at"},
+	to:     map[string]string{"createdTo": "created_at"},
+	search: []string{"name", "label", "description"},
+}
+
+// ServiceOrderType defines the GraphQL ordering of services
+var ServiceOrderType = NewOrderType("Service", map[string]string{
+	"NAME":       "name",
+	"API_TYPE":   "api_type",
+	"LABEL":      "label",
+	"GROUP":      "group",
+	"CREATED_AT": "created_at",
+	"UPDATED_AT": "updated_at",
+})
+
+// ServiceConnectionType defines the GraphQL connection of services
+var ServiceConnectionType = NewConnectionType("Service", ServiceType)
+
+// DomainSSLFilterType defines the GraphQL filter for domain/SSL entries
+var DomainSSLFilterType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "DomainSSLFilter",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"label":       stringFilter("Exact label"),
+		"search":      stringFilter("Case-insensitive match on domain, label and issuer"),
+		"expiresFrom": timeFilter("Certificate expires at or after"),
+		"expiresTo":   timeFilter("Certificate expires before"),
+	},
+})
+
+var domainSSLFilter = filterSpec{
+	equals: map[string]string{"label": "label"},
+	from:   map[string]string{"expiresFrom": "expiry"},
+	to:     map[string]string{"expiresTo": "expiry"},
+	search: []string{"domain", "label", "issuer"},
+}
+
+// DomainSSLOrderType defines the GraphQL ordering of domain/SSL entries
+var DomainSSLOrderType = NewOrderType("DomainSSL", map[string]string{
+	"DOMAIN":     "domain",
+	"LABEL":      "label",
+	"EXPIRY":     "expiry",
+	"CREATED_AT": "created_at",
+})
+
+// DomainSSLConnectionType defines the GraphQL connection of domain/SSL entries
+var DomainSSLConnectionType = NewConnectionType("DomainSSL", DomainSSLType)
+
+// UserFilterType defines the GraphQL filter for users
+var UserFilterType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "UserFilter",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"role":   stringFilter("Exact role, admin or user"),
+		"status": stringFilter("Exact status, e.g. active or inactive"),
+		"search": stringFilter("Case-insensitive match on username and email"),
+	},
+})
+
+var userFilter = filterSpec{
+	equals: map[string]string{"role": "role", "status": "status"},
+	search: []string{"username", "email"},
+}
+
+// UserOrderType defines the GraphQL ordering of users
+var UserOrderType = NewOrderType("User", map[string]string{
+	"USERNAME":   "username",
+	"EMAIL":      "email",
+	"ROLE":       "role",
+	"STATUS":     "status",
+	"LAST_LOGIN": "last_login",
+	"CREATED_AT": "created_at",
+})
+
+// UserConnectionType defines the GraphQL connection of users
+var UserConnectionType = NewConnectionType("User", UserType)
+
+// ReportFilterType defines the GraphQL filter for reports
+var ReportFilterType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "ReportFilter",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"reportType":  stringFilter("Exact report type"),
+		"format":      stringFilter("Exact format, CSV, PDF or Excel"),
+		"status":      stringFilter("Exact status: pending, generating, completed or failed"),
+		"userId":      &graphql.InputObjectFieldConfig{Type: graphql.ID, Description: "Owner"},
+		"search":      stringFilter("Case-insensitive match on name"),
+		"createdFrom": timeFilter("Created at or after"),
+		"createdTo":   timeFilter("Created before"),
+	},
+})
+
+var reportFilter = filterSpec{
+	equals: map[string]string{"reportType": "report_type", "format": "format", "status": "status"},
+	ids:    map[string]string{"userId": "user_id"},
+	from:   map[string]string{"createdFrom": "created_at"},
+	to:     map[string]string{"createdTo": "created_at"},
+	search: []string{"name"},
+	owner:  "user_id",
+}
+
+// ReportOrderType defines the GraphQL ordering of reports
+var ReportOrderType = NewOrderType("Report", map[string]string{
+	"NAME":         "name",
+	"REPORT_TYPE":  "report_type",
+	"STATUS":       "status",
+	"GENERATED_AT": "generated_at",
+	"CREATED_AT":   "created_at",
+})
+
+// ReportConnectionType defines the GraphQL connection of reports
+var ReportConnectionType = NewConnectionType("Report", ReportType)
+
+// LogEntryFilterType defines the GraphQL filter for log entries
+var LogEntryFilterType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "LogEntryFilter",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"level":     stringFilter("Exact level, e.g. info, warn or error"),
+		"service":   stringFilter("Exact service, e.g. monitron-server"),
+		"requestId": stringFilter("Exact request ID"),
+		"search":    stringFilter("Full-text search on the message, in web search syntax"),
+		"from":      timeFilter("Logged at or after"),
+		"to":        timeFilter("Logged before"),
+	},
+})
+
+var logEntryFilter = filterSpec{
+	equals:   map[string]string{"level": "level", "service": "service", "requestId": "request_id"},
+	from:     map[string]string{"from": "timestamp"},
+	to:       map[string]string{"to": "timestamp"},
+	fullText: "message",
+}
+
+// LogEntryOrderType defines the GraphQL ordering of log entries
+var LogEntryOrderType = NewOrderType("LogEntry", map[string]string{
+	"TIMESTAMP": "timestamp",
+	"LEVEL":     "level",
+	"SERVICE":   "service",
+})
+
+// LogEntryConnectionType defines the GraphQL connection of log entries
+var LogEntryConnectionType = NewConnectionType("LogEntry", LogEntryType)
+
+// OperationalPageFilterType defines the GraphQL filter for operational pages
+var OperationalPageFilterType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "OperationalPageFilter",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"isPublic": &graphql.InputObjectFieldConfig{Type: graphql.Boolean},
+		"search":   stringFilter("Case-insensitive match on name, slug and description"),
+	},
+})
+
+var operationalPageFilter = filterSpec{
+	equals: map[string]string{"isPublic": "is_public"},
+	search: []string{"name", "slug", "description"},
+	public: "is_public",
+}
+
+// OperationalPageOrderType defines the GraphQL ordering of operational pages
+var OperationalPageOrderType = NewOrderType("OperationalPage", map[string]string{
+	"NAME":       "name",
+	"SLUG":       "slug",
+	"CREATED_AT": "created_at",
+	"UPDATED_AT": "updated_at",
+})
+
+// OperationalPageConnectionType defines the GraphQL connection of operational pages
+var OperationalPageConnectionType = NewConnectionType("OperationalPage", OperationalPageType)
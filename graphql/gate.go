@@ -0,0 +1,97 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/parser"
+
+	"monitron-server/utils"
+)
+
+// Request is a GraphQL request as sent over HTTP or WebSocket
+type Request struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
+	Extensions    struct {
+		PersistedQuery *PersistedQuery `json:"persistedQuery"`
+	} `json:"extensions"`
+}
+
+// RequestError rejects a whole request. Its code is sent as the code
+// extension of the error, which clients match on.
+type RequestError struct {
+	Code    string
+	Message string
+}
+
+func (e *RequestError) Error() string {
+	return e.Message
+}
+
+// Extensions implements gqlerrors.ExtendedError
+func (e *RequestError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": e.Code}
+}
+
+// ErrBudgetExceeded rejects requests of callers who spent their cost budget
+var ErrBudgetExceeded = &RequestError{Code: "BUDGET_EXCEEDED", Message: "Query cost budget exceeded, try again later"}
+
+// ErrorResult returns the response to a rejected request
+func ErrorResult(err error) *graphql.Result {
+	formatted := gqlerrors.FormatError(err)
+	if extended, ok := err.(gqlerrors.ExtendedError); ok {
+		formatted.Extensions = extended.Extensions()
+	}
+	return &graphql.Result{Errors: []gqlerrors.FormattedError{formatted}}
+}
+
+// Gate admits GraphQL requests before they run. It is shared by the HTTP and
+// WebSocket endpoints so a caller has one budget.
+type Gate struct {
+	Schema        *graphql.Schema
+	Introspection bool // Lets every caller run introspection queries, not only admins
+	MaxDepth      int
+	MaxComplexity int
+	Budget        *utils.RateLimiter // Cost each caller may spend per window, unlimited if nil
+	Persisted     *PersistedQueries
+}
+
+// Admit resolves the persisted query of a request, then checks it against
+// the introspection setting and the depth and complexity limits, and charges
+// its cost to the caller's budget, keyed by user or else by IP. Requests that
+// do not parse are let through for graphql to report.
+func (g *Gate) Admit(ctx context.Context, request *Request, ip string) error {
+	viewer := viewerFrom(ctx)
+	if err := g.Persisted.resolve(request, viewer.Role == "admin"); err != nil {
+		return err
+	}
+
+	document, err := parser.Parse(parser.ParseParams{Source: request.Query})
+	if err != nil {
+		return nil
+	}
+	if !g.Introspection && viewer.Role != "admin" && introspects(document) {
+		return &RequestError{Code: "INTROSPECTION_DISABLED", Message: "Introspection is only available to admins"}
+	}
+
+	depth, cost := analyze(ctx, g.Schema, document, request.OperationName, request.Variables)
+	if depth > g.MaxDepth {
+		return &RequestError{Code: "QUERY_TOO_DEEP", Message: fmt.Sprintf("Query depth %d exceeds the limit of %d", depth, g.MaxDepth)}
+	}
+	if cost > g.MaxComplexity {
+		return &RequestError{Code: "QUERY_TOO_COMPLEX", Message: fmt.Sprintf("Query cost %d exceeds the limit of %d", cost, g.MaxComplexity)}
+	}
+
+	client := "ip:" + ip
+	if viewer.Authenticated {
+		client = "user:" + viewer.UserID.String()
+	}
+	if g.Budget != nil && !g.Budget.AllowN(client, cost) {
+		return ErrBudgetExceeded
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package graphql
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const loadersKey contextKey = "loaders"
+
+// Loaders batches the lookups of nested fields within one GraphQL execution.
+// Resolvers register the key they need and return a thunk. graphql-go calls
+// the thunks only after resolving every sibling, so the first thunk called
+// loads the keys of all of them with one query.
+type Loaders struct {
+	mu      sync.Mutex
+	batches map[string]interface{}
+	now     time.Time // Shared by the default time ranges of the execution
+}
+
+// WithLoaders stores fresh loaders in the context of a GraphQL execution
+func WithLoaders(ctx context.Context) context.Context {
+	return context.WithValue(ctx, loadersKey, newLoaders())
+}
+
+// loadersFrom returns the loaders of an execution. Without WithLoaders every
+// call gets its own, which still works but batches nothing.
+func loadersFrom(ctx context.Context) *Loaders {
+	if loaders, ok := ctx.Value(loadersKey).(*Loaders); ok {
+		return loaders
+	}
+	return newLoaders()
+}
+
+func newLoaders() *Loaders {
+	return &Loaders{batches: map[string]interface{}{}, now: time.Now()}
+}
+
+// batch is the set of keys waiting for one fetch
+type batch[K comparable, V any] struct {
+	once    sync.Once
+	keys    []K
+	results map[K]V
+	err     error
+}
+
+// load registers a key with the open batch of name, which fetch loads, and
+// returns a thunk resolving to its value, or null if fetch returned none.
+// A batch closes when it is fetched, so keys registered later, such as those
+// of deeper fields, form a new batch.
+func load[K comparable, V any](ctx context.Context, name string, key K, fetch func(keys []K) (map[K]V, error)) func() (interface{}, error) {
+	loaders := loadersFrom(ctx)
+
+	loaders.mu.Lock()
+	b, ok := loaders.batches[name].(*batch[K, V])
+	if !ok {
+		b = &batch[K, V]{}
+		loaders.batches[name] = b
+	}
+	b.keys = append(b.keys, key)
+	loaders.mu.Unlock()
+
+	return func() (interface{}, error) {
+		b.once.Do(func() {
+			loaders.mu.Lock()
+			if loaders.batches[name] == b {
+				delete(loaders.batches, name)
+			}
+			keys := b.keys
+			loaders.mu.Unlock()
+
+			b.results, b.err = fetch(unique(keys))
+		})
+		if b.err != nil {
+			return nil, b.err
+		}
+		if value, ok := b.results[key]; ok {
+			return value, nil
+		}
+		return nil, nil
+	}
+}
+
+func unique[K comparable](keys []K) []K {
+	seen := map[K]bool{}
+	result := make([]K, 0, len(keys))
+	for _, key := range keys {
+		if !seen[key] {
+			seen[key] = true
+			result = append(result, key)
+		}
+	}
+	return result
+}
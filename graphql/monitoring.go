@@ -0,0 +1,528 @@
+package graphql
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/graphql-go/graphql"
+	"gorm.io/gorm"
+
+	"monitron-server/internal/pagecomponents"
+	"monitron-server/internal/statuspage"
+	"monitron-server/models"
+)
+
+// Time series limits
+const (
+	DefaultSeriesRange = 24 * time.Hour
+	MinSeriesStep      = 10 * time.Second
+	MaxSeriesPoints    = 1000
+	defaultSeriesSteps = 100 // Points of a series when no step is given
+	DefaultIncidents   = 20
+	MaxIncidents       = 100
+)
+
+// statsWindow is the window of the aggregates of TargetStats
+const statsWindow = 24 * time.Hour
+
+// TargetStatsType defines the GraphQL type for the current state of a
+// monitored target
+var TargetStatsType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "TargetStats",
+	Fields: graphql.Fields{
+		"status":                &graphql.Field{Type: graphql.String, Description: "Status of the latest check, up or down"},
+		"last_checked_at":       &graphql.Field{Type: graphql.DateTime},
+		"last_response_time":    &graphql.Field{Type: graphql.Float},
+		"checks_24h":            &graphql.Field{Type: graphql.Int},
+		"uptime_24h":            &graphql.Field{Type: graphql.Float, Description: "Percentage, null without checks"},
+		"avg_response_time_24h": &graphql.Field{Type: graphql.Float},
+		"open_incidents":        &graphql.Field{Type: graphql.Int},
+	},
+})
+
+// CheckResultBucketType defines the GraphQL type for the check results of a
+// target aggregated over one step of a time series
+var CheckResultBucketType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "CheckResultBucket",
+	Fields: graphql.Fields{
+		"time":              &graphql.Field{Type: graphql.DateTime, Description: "Start of the step"},
+		"checks":            &graphql.Field{Type: graphql.Int},
+		"up_checks":         &graphql.Field{Type: graphql.Int},
+		"uptime":            &graphql.Field{Type: graphql.Float},
+		"avg_response_time": &graphql.Field{Type: graphql.Float},
+		"max_response_time": &graphql.Field{Type: graphql.Float},
+	},
+})
+
+// MetricPointType defines the GraphQL type for one step of a metric series
+var MetricPointType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "MetricPoint",
+	Fields: graphql.Fields{
+		"time":  &graphql.Field{Type: graphql.DateTime, Description: "Start of the step"},
+		"value": &graphql.Field{Type: graphql.Float},
+	},
+})
+
+// DeviceInfoType defines the GraphQL type for the hardware an agent reported
+var DeviceInfoType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "DeviceInfo",
+	Fields: graphql.Fields{
+		"os":        &graphql.Field{Type: graphql.String},
+		"cpu":       &graphql.Field{Type: graphql.String},
+		"gpu":       &graphql.Field{Type: graphql.String},
+		"memory":    &graphql.Field{Type: graphql.String},
+		"storages":  &graphql.Field{Type: graphql.String},
+		"networks":  &graphql.Field{Type: graphql.String},
+		"timestamp": &graphql.Field{Type: graphql.DateTime},
+	},
+})
+
+// MetricAggregateEnum defines the GraphQL enum for combining the metric
+// values of one step
+var MetricAggregateEnum = graphql.NewEnum(graphql.EnumConfig{
+	Name: "MetricAggregate",
+	Values: graphql.EnumValueConfigMap{
+		"AVG":   &graphql.EnumValueConfig{Value: "AVG(value)"},
+		"MIN":   &graphql.EnumValueConfig{Value: "MIN(value)"},
+		"MAX":   &graphql.EnumValueConfig{Value: "MAX(value)"},
+		"SUM":   &graphql.EnumValueConfig{Value: "SUM(value)"},
+		"COUNT": &graphql.EnumValueConfig{Value: "COUNT(*)"},
+	},
+})
+
+type targetStats struct {
+	Status             *string    `json:"status"`
+	LastCheckedAt      *time.Time `json:"last_checked_at"`
+	LastResponseTime   *float64   `json:"last_response_time"`
+	Checks24h          int64      `json:"checks_24h"`
+	Uptime24h          *float64   `json:"uptime_24h"`
+	AvgResponseTime24h *float64   `json:"avg_response_time_24h"`
+	OpenIncidents      int64      `json:"open_incidents"`
+}
+
+type checkBucket struct {
+	TargetID        uuid.UUID `json:"-"`
+	Bucket          time.Time `json:"time"`
+	Checks          int64     `json:"checks"`
+	UpChecks        int64     `json:"up_checks"`
+	Uptime          float64   `json:"uptime" gorm:"-"`
+	AvgResponseTime float64   `json:"avg_response_time"`
+	MaxResponseTime float64   `json:"max_response_time"`
+}
+
+type metricPoint struct {
+	InstanceID uuid.UUID `json:"-"`
+	Bucket     time.Time `json:"time"`
+	Value      float64   `json:"value"`
+}
+
+// deviceInfo is a row of the device_info table, one per instance
+type deviceInfo struct {
+	InstanceID uuid.UUID  `json:"-" gorm:"column:instance_id"`
+	OS         *string    `json:"os" gorm:"column:os"`
+	CPU        *string    `json:"cpu" gorm:"column:cpu"`
+	GPU        *string    `json:"gpu" gorm:"column:gpu"`
+	Memory     *string    `json:"memory" gorm:"column:memory"`
+	Storages   *string    `json:"storages" gorm:"column:storages"`
+	Networks   *string    `json:"networks" gorm:"column:networks"`
+	Timestamp  *time.Time `json:"timestamp" gorm:"column:timestamp"`
+}
+
+// seriesRange is the span and step of a time series
+type seriesRange struct {
+	from, to time.Time
+	step     time.Duration
+}
+
+// key identifies the range in loader batch names
+func (r seriesRange) key() string {
+	return fmt.Sprintf("%d:%d:%d", r.from.Unix(), r.to.Unix(), int64(r.step/time.Second))
+}
+
+// seriesArgs are the arguments of time series fields
+var seriesArgs = graphql.FieldConfigArgument{
+	"from": &graphql.ArgumentConfig{Type: graphql.DateTime, Description: "Defaults to 24 hours before to"},
+	"to":   &graphql.ArgumentConfig{Type: graphql.DateTime, Description: "Defaults to now"},
+	"step": &graphql.ArgumentConfig{Type: graphql.String, Description: fmt.Sprintf("Width of a point such as 30s, 5m, 1h or 1d. Defaults to a %dth of the range.", defaultSeriesSteps)},
+}
+
+// parseSeriesRange reads the from, to and step arguments of a field
+func parseSeriesRange(p graphql.ResolveParams) (seriesRange, error) {
+	r := seriesRange{to: loadersFrom(p.Context).now}
+	if to, ok := p.Args["to"].(time.Time); ok {
+		r.to = to
+	}
+	r.from = r.to.Add(-DefaultSeriesRange)
+	if from, ok := p.Args["from"].(time.Time); ok {
+		r.from = from
+	}
+	if !r.from.Before(r.to) {
+		return r, fmt.Errorf("from must be before to")
+	}
+
+	if step, ok := p.Args["step"].(string); ok {
+		d, err := parseStep(step)
+		if err != nil {
+			return r, err
+		}
+		r.step = d
+	} else {
+		r.step = max((r.to.Sub(r.from) / defaultSeriesSteps).Round(time.Minute), time.Minute)
+	}
+	if r.step < MinSeriesStep {
+		return r, fmt.Errorf("step must be at least %s", MinSeriesStep)
+	}
+	if r.to.Sub(r.from)/r.step > MaxSeriesPoints {
+		return r, fmt.Errorf("step is too small for the range, at most %d points are returned", MaxSeriesPoints)
+	}
+	return r, nil
+}
+
+// parseStep parses a Go duration, or a number of days such as 1d
+func parseStep(step string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(step, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 1 {
+			return 0, fmt.Errorf("invalid step")
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(step)
+	if err != nil {
+		return 0, fmt.Errorf("invalid step")
+	}
+	return d, nil
+}
+
+// bucketExpr truncates a timestamp column to the start of its step, given
+// twice in seconds
+func bucketExpr(column string) string {
+	return "to_timestamp(floor(extract(epoch from " + column + ") / ?) * ?) AS bucket"
+}
+
+// targetID returns the ID of a monitored target
+func targetID(source interface{}) (uuid.UUID, bool) {
+	switch target := source.(type) {
+	case models.Service:
+		return target.ID, true
+	case models.Instance:
+		return target.ID, true
+	case models.DomainSSL:
+		return target.ID, true
+	}
+	return uuid.Nil, false
+}
+
+// AddMonitoringFields adds the stats, time series and incident fields of
+// monitored targets to the Service, Instance and DomainSSL types. Nested
+// fields are loaded in batches, one query per field and list.
+func AddMonitoringFields(db *gorm.DB) {
+	addTargetFields(db, ServiceType, pagecomponents.TypeService)
+	addTargetFields(db, InstanceType, pagecomponents.TypeInstance)
+	addTargetFields(db, DomainSSLType, pagecomponents.TypeDomainSSL)
+
+	InstanceType.AddFieldConfig("metrics", &graphql.Field{
+		Type:        graphql.NewList(MetricPointType),
+		Description: "Series of one metric reported by the instance's agent",
+		Args: graphql.FieldConfigArgument{
+			"name":      &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String), Description: "Metric type, e.g. cpu_usage, memory_usage or disk_usage"},
+			"from":      seriesArgs["from"],
+			"to":        seriesArgs["to"],
+			"step":      seriesArgs["step"],
+			"aggregate": &graphql.ArgumentConfig{Type: MetricAggregateEnum, DefaultValue: "AVG(value)"},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			id, ok := targetID(p.Source)
+			if !ok {
+				return nil, fmt.Errorf("invalid instance")
+			}
+			r, err := parseSeriesRange(p)
+			if err != nil {
+				return nil, err
+			}
+			name, _ := p.Args["name"].(string)
+			aggregate, _ := p.Args["aggregate"].(string)
+			batch := "instance.metrics:" + name + ":" + aggregate + ":" + r.key()
+			return load(p.Context, batch, id, func(ids []uuid.UUID) (map[uuid.UUID][]metricPoint, error) {
+				return fetchMetrics(db, ids, name, aggregate, r)
+			}), nil
+		},
+	})
+	InstanceType.AddFieldConfig("deviceInfo", &graphql.Field{
+		Type:        DeviceInfoType,
+		Description: "Hardware last reported by the instance's agent",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			id, ok := targetID(p.Source)
+			if !ok {
+				return nil, fmt.Errorf("invalid instance")
+			}
+			return load(p.Context, "instance.deviceInfo", id, func(ids []uuid.UUID) (map[uuid.UUID]deviceInfo, error) {
+				return fetchDeviceInfo(db, ids)
+			}), nil
+		},
+	})
+	DomainSSLType.AddFieldConfig("daysLeft", &graphql.Field{
+		Type:        graphql.Int,
+		Description: "Whole days until the certificate expires, negative once expired",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			domainSSL, ok := p.Source.(models.DomainSSL)
+			if !ok || domainSSL.Expiry.IsZero() {
+				return nil, nil
+			}
+			return int(math.Floor(domainSSL.Expiry.Sub(loadersFrom(p.Context).now).Hours() / 24)), nil
+		},
+	})
+}
+
+// addTargetFields adds the fields shared by all monitored targets
+func addTargetFields(db *gorm.DB, object *graphql.Object, targetType string) {
+	object.AddFieldConfig("stats", &graphql.Field{
+		Type:        TargetStatsType,
+		Description: "Latest check and aggregates over the past 24 hours",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			id, ok := targetID(p.Source)
+			if !ok {
+				return nil, fmt.Errorf("invalid %s", targetType)
+			}
+			now := loadersFrom(p.Context).now
+			return load(p.Context, targetType+".stats", id, func(ids []uuid.UUID) (map[uuid.UUID]targetStats, error) {
+				return fetchStats(db, targetType, ids, now)
+			}), nil
+		},
+	})
+	object.AddFieldConfig("results", &graphql.Field{
+		Type:        graphql.NewList(CheckResultBucketType),
+		Description: "Check results aggregated per step. Steps without checks are left out.",
+		Args:        seriesArgs,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			id, ok := targetID(p.Source)
+			if !ok {
+				return nil, fmt.Errorf("invalid %s", targetType)
+			}
+			r, err := parseSeriesRange(p)
+			if err != nil {
+				return nil, err
+			}
+			return load(p.Context, targetType+".results:"+r.key(), id, func(ids []uuid.UUID) (map[uuid.UUID][]checkBucket, error) {
+				return fetchResults(db, targetType, ids, r)
+			}), nil
+		},
+	})
+	object.AddFieldConfig("uptime", &graphql.Field{
+		Type:        graphql.Float,
+		Description: "Percentage of successful checks, null without checks",
+		Args: graphql.FieldConfigArgument{
+			"window": &graphql.ArgumentConfig{Type: graphql.String, Description: "Such as 24h or 30d, at most 90d", DefaultValue: "30d"},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			id, ok := targetID(p.Source)
+			if !ok {
+				return nil, fmt.Errorf("invalid %s", targetType)
+			}
+			window, _ := p.Args["window"].(string)
+			period, err := statuspage.ParsePeriod(window)
+			if err != nil {
+				return nil, fmt.Errorf("window must look like 24h or 30d and be at most 90d")
+			}
+			since := loadersFrom(p.Context).now.Add(-period)
+			return load(p.Context, targetType+".uptime:"+window, id, func(ids []uuid.UUID) (map[uuid.UUID]float64, error) {
+				return fetchUptime(db, targetType, ids, since)
+			}), nil
+		},
+	})
+	object.AddFieldConfig("incidents", &graphql.Field{
+		Type:        graphql.NewList(IncidentType),
+		Description: "Latest incidents, newest first",
+		Args: graphql.FieldConfigArgument{
+			"status": &graphql.ArgumentConfig{Type: graphql.String, Description: "open or resolved"},
+			"first":  &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: DefaultIncidents, Description: fmt.Sprintf("At most %d", MaxIncidents)},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			id, ok := targetID(p.Source)
+			if !ok {
+				return nil, fmt.Errorf("invalid %s", targetType)
+			}
+			first, _ := p.Args["first"].(int)
+			if first < 0 || first > MaxIncidents {
+				return nil, fmt.Errorf("first must be between 0 and %d", MaxIncidents)
+			}
+			status, _ := p.Args["status"].(string)
+			batch := fmt.Sprintf("%s.incidents:%s:%d", targetType, status, first)
+			return load(p.Context, batch, id, func(ids []uuid.UUID) (map[uuid.UUID][]models.Incident, error) {
+				return fetchIncidents(db, targetType, ids, status, first)
+			}), nil
+		},
+	})
+}
+
+func fetchStats(db *gorm.DB, targetType string, ids []uuid.UUID, now time.Time) (map[uuid.UUID]targetStats, error) {
+	stats := map[uuid.UUID]targetStats{}
+	for _, id := range ids {
+		stats[id] = targetStats{}
+	}
+
+	latest := []models.CheckResult{}
+	err := db.Raw(`SELECT DISTINCT ON (target_id) target_id, status, response_time, checked_at
+		FROM check_results WHERE target_type = ? AND target_id IN ? ORDER BY target_id, checked_at DESC`, targetType, ids).
+		Scan(&latest).Error
+	if err != nil {
+		log.Printf("Error fetching latest checks for GraphQL: %v", err)
+		return nil, err
+	}
+	for _, result := range latest {
+		s := stats[result.TargetID]
+		s.Status, s.LastCheckedAt, s.LastResponseTime = &result.Status, &result.CheckedAt, &result.ResponseTime
+		stats[result.TargetID] = s
+	}
+
+	totals := []struct {
+		TargetID        uuid.UUID
+		Checks          int64
+		UpChecks        int64
+		AvgResponseTime float64
+	}{}
+	err = db.Model(&models.CheckResult{}).
+		Select("target_id, COUNT(*) AS checks, COUNT(*) FILTER (WHERE status = 'up') AS up_checks, AVG(response_time) AS avg_response_time").
+		Where("target_type = ? AND target_id IN ? AND checked_at >= ?", targetType, ids, now.Add(-statsWindow)).
+		Group("target_id").Scan(&totals).Error
+	if err != nil {
+		log.Printf("Error fetching check totals for GraphQL: %v", err)
+		return nil, err
+	}
+	for _, total := range totals {
+		s := stats[total.TargetID]
+		uptime := float64(total.UpChecks) / float64(total.Checks) * 100
+		avg := total.AvgResponseTime
+		s.Checks24h, s.Uptime24h, s.AvgResponseTime24h = total.Checks, &uptime, &avg
+		stats[total.TargetID] = s
+	}
+
+	open := []struct {
+		TargetID uuid.UUID
+		Count    int64
+	}{}
+	err = db.Model(&models.Incident{}).
+		Select("target_id, COUNT(*) AS count").
+		Where("target_type = ? AND target_id IN ? AND status = 'open'", targetType, ids).
+		Group("target_id").Scan(&open).Error
+	if err != nil {
+		log.Printf("Error fetching open incidents for GraphQL: %v", err)
+		return nil, err
+	}
+	for _, row := range open {
+		s := stats[row.TargetID]
+		s.OpenIncidents = row.Count
+		stats[row.TargetID] = s
+	}
+	return stats, nil
+}
+
+func fetchResults(db *gorm.DB, targetType string, ids []uuid.UUID, r seriesRange) (map[uuid.UUID][]checkBucket, error) {
+	rows := []checkBucket{}
+	err := db.Model(&models.CheckResult{}).
+		Select("target_id, "+bucketExpr("checked_at")+", COUNT(*) AS checks, COUNT(*) FILTER (WHERE status = 'up') AS up_checks, "+
+			"AVG(response_time) AS avg_response_time, MAX(response_time) AS max_response_time", r.step.Seconds(), r.step.Seconds()).
+		Where("target_type = ? AND target_id IN ? AND checked_at >= ? AND checked_at < ?", targetType, ids, r.from, r.to).
+		Group("target_id, bucket").Order("bucket").Scan(&rows).Error
+	if err != nil {
+		log.Printf("Error fetching check results for GraphQL: %v", err)
+		return nil, err
+	}
+
+	results := map[uuid.UUID][]checkBucket{}
+	for _, id := range ids {
+		results[id] = []checkBucket{}
+	}
+	for _, row := range rows {
+		row.Uptime = float64(row.UpChecks) / float64(row.Checks) * 100
+		results[row.TargetID] = append(results[row.TargetID], row)
+	}
+	return results, nil
+}
+
+func fetchUptime(db *gorm.DB, targetType string, ids []uuid.UUID, since time.Time) (map[uuid.UUID]float64, error) {
+	totals := []struct {
+		TargetID uuid.UUID
+		Checks   int64
+		UpChecks int64
+	}{}
+	err := db.Model(&models.CheckResult{}).
+		Select("target_id, COUNT(*) AS checks, COUNT(*) FILTER (WHERE status = 'up') AS up_checks").
+		Where("target_type = ? AND target_id IN ? AND checked_at >= ?", targetType, ids, since).
+		Group("target_id").Scan(&totals).Error
+	if err != nil {
+		log.Printf("Error fetching uptime for GraphQL: %v", err)
+		return nil, err
+	}
+
+	uptime := map[uuid.UUID]float64{}
+	for _, total := range totals {
+		uptime[total.TargetID] = float64(total.UpChecks) / float64(total.Checks) * 100
+	}
+	return uptime, nil
+}
+
+func fetchIncidents(db *gorm.DB, targetType string, ids []uuid.UUID, status string, first int) (map[uuid.UUID][]models.Incident, error) {
+	ranked := db.Model(&models.Incident{}).
+		Select("incidents.*, ROW_NUMBER() OVER (PARTITION BY target_id ORDER BY started_at DESC) AS position").
+		Where("target_type = ? AND target_id IN ?", targetType, ids)
+	if status != "" {
+		ranked = ranked.Where("status = ?", status)
+	}
+
+	rows := []models.Incident{}
+	err := db.Table("(?) AS ranked", ranked).Where("position <= ?", first).Order("started_at DESC").Find(&rows).Error
+	if err != nil {
+		log.Printf("Error fetching incidents for GraphQL: %v", err)
+		return nil, err
+	}
+
+	incidents := map[uuid.UUID][]models.Incident{}
+	for _, id := range ids {
+		incidents[id] = []models.Incident{}
+	}
+	for _, incident := range rows {
+		incidents[incident.TargetID] = append(incidents[incident.TargetID], incident)
+	}
+	return incidents, nil
+}
+
+func fetchMetrics(db *gorm.DB, ids []uuid.UUID, name, aggregate string, r seriesRange) (map[uuid.UUID][]metricPoint, error) {
+	rows := []metricPoint{}
+	err := db.Model(&models.InstanceMetric{}).
+		Select("instance_id, "+bucketExpr("timestamp")+", "+aggregate+" AS value", r.step.Seconds(), r.step.Seconds()).
+		Where("metric_type = ? AND instance_id IN ? AND timestamp >= ? AND timestamp < ?", name, ids, r.from, r.to).
+		Group("instance_id, bucket").Order("bucket").Scan(&rows).Error
+	if err != nil {
+		log.Printf("Error fetching instance metrics for GraphQL: %v", err)
+		return nil, err
+	}
+
+	metrics := map[uuid.UUID][]metricPoint{}
+	for _, id := range ids {
+		metrics[id] = []metricPoint{}
+	}
+	for _, row := range rows {
+		metrics[row.InstanceID] = append(metrics[row.InstanceID], row)
+	}
+	return metrics, nil
+}
+
+func fetchDeviceInfo(db *gorm.DB, ids []uuid.UUID) (map[uuid.UUID]deviceInfo, error) {
+	rows := []deviceInfo{}
+	if err := db.Table("device_info").Where("instance_id IN ?", ids).Find(&rows).Error; err != nil {
+		log.Printf("Error fetching device info for GraphQL: %v", err)
+		return nil, err
+	}
+
+	info := map[uuid.UUID]deviceInfo{}
+	for _, row := range rows {
+		info[row.InstanceID] = row
+	}
+	return info, nil
+}
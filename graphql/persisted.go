@@ -0,0 +1,137 @@
+package graphql
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Persisted query modes
+const (
+	PersistedQueriesOff       = "off"       // Only full queries are accepted
+	PersistedQueriesAutomatic = "apq"       // Clients register queries by sending them with their hash, as in Apollo APQ
+	PersistedQueriesAllowList = "allowlist" // Only the queries of the manifest run, except for admins
+)
+
+// MaxPersistedQueries bounds the queries registered by clients
+const MaxPersistedQueries = 10000
+
+// PersistedQuery is the persistedQuery extension of a request
+type PersistedQuery struct {
+	Version    int    `json:"version"`
+	SHA256Hash string `json:"sha256Hash"`
+}
+
+// PersistedQueries stores queries by the hex SHA-256 hash of their text
+type PersistedQueries struct {
+	mode       string
+	mu         sync.RWMutex
+	queries    map[string]string
+	registered int // Queries registered by clients, as opposed to the manifest
+}
+
+// NewPersistedQueries returns an empty store working in a mode
+func NewPersistedQueries(mode string) (*PersistedQueries, error) {
+	switch mode {
+	case PersistedQueriesOff, PersistedQueriesAutomatic, PersistedQueriesAllowList:
+		return &PersistedQueries{mode: mode, queries: map[string]string{}}, nil
+	}
+	return nil, fmt.Errorf("persisted query mode must be %s, %s or %s", PersistedQueriesOff, PersistedQueriesAutomatic, PersistedQueriesAllowList)
+}
+
+// LoadManifest adds the queries of a manifest file, either an Apollo
+// persisted query manifest ({"operations": [{"id", "body"}]}) or a map of
+// hashes to queries as generated by Relay. Hashes are checked.
+func (s *PersistedQueries) LoadManifest(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	queries := map[string]string{}
+	apollo := struct {
+		Operations []struct {
+			ID   string `json:"id"`
+			Body string `json:"body"`
+		} `json:"operations"`
+	}{}
+	if err := json.Unmarshal(data, &apollo); err == nil && apollo.Operations != nil {
+		for _, operation := range apollo.Operations {
+			queries[operation.ID] = operation.Body
+		}
+	} else if err := json.Unmarshal(data, &queries); err != nil {
+		return fmt.Errorf("invalid persisted query manifest: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for hash, query := range queries {
+		if hashQuery(query) != strings.ToLower(hash) {
+			return fmt.Errorf("persisted query %s does not match its hash", hash)
+		}
+		s.queries[strings.ToLower(hash)] = query
+	}
+	return nil
+}
+
+// resolve fills in the query of a request sent by hash, registers queries
+// sent with their hash in automatic mode, and in allow-list mode rejects
+// queries missing from the store unless the caller is an admin
+func (s *PersistedQueries) resolve(request *Request, admin bool) error {
+	persisted := request.Extensions.PersistedQuery
+	if persisted != nil && s.mode != PersistedQueriesOff {
+		if persisted.Version != 1 {
+			return &RequestError{Code: "PERSISTED_QUERY_VERSION_NOT_SUPPORTED", Message: "Only version 1 of persisted queries is supported"}
+		}
+		hash := strings.ToLower(persisted.SHA256Hash)
+
+		if request.Query == "" {
+			s.mu.RLock()
+			query, ok := s.queries[hash]
+			s.mu.RUnlock()
+			if !ok {
+				return &RequestError{Code: "PERSISTED_QUERY_NOT_FOUND", Message: "PersistedQueryNotFound"}
+			}
+			request.Query = query
+			return nil
+		}
+		if hashQuery(request.Query) != hash {
+			return &RequestError{Code: "INVALID_PERSISTED_QUERY_HASH", Message: "provided sha does not match query"}
+		}
+		if s.mode == PersistedQueriesAutomatic {
+			s.register(hash, request.Query)
+		}
+	} else if persisted != nil && request.Query == "" {
+		return &RequestError{Code: "PERSISTED_QUERY_NOT_SUPPORTED", Message: "PersistedQueryNotSupported"}
+	}
+
+	if s.mode == PersistedQueriesAllowList && !admin {
+		s.mu.RLock()
+		_, ok := s.queries[hashQuery(request.Query)]
+		s.mu.RUnlock()
+		if !ok {
+			return &RequestError{Code: "PERSISTED_QUERY_NOT_ALLOWED", Message: "Only persisted queries are allowed"}
+		}
+	}
+	return nil
+}
+
+// register stores a query sent by a client, unless the store is full
+func (s *PersistedQueries) register(hash, query string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.queries[hash]; ok || s.registered >= MaxPersistedQueries {
+		return
+	}
+	s.queries[hash] = query
+	s.registered++
+}
+
+func hashQuery(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:])
+}
@@ -3,10 +3,13 @@ package graphql
 import (
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/graphql-go/graphql"
 	"gorm.io/gorm"
 
+	"monitron-server/internal/realtime"
+	"monitron-server/internal/slo"
 	"monitron-server/models"
 )
 
@@ -20,7 +23,6 @@ var InstanceType = graphql.NewObject(graphql.ObjectConfig{
 		"check_interval": &graphql.Field{Type: graphql.Int},
 		"check_timeout":  &graphql.Field{Type: graphql.Int},
 		"agent_port":     &graphql.Field{Type: graphql.Int},
-		"agent_auth":     &graphql.Field{Type: graphql.String},
 		"description":    &graphql.Field{Type: graphql.String},
 		"label":          &graphql.Field{Type: graphql.String},
 		"group":          &graphql.Field{Type: graphql.String},
@@ -46,13 +48,11 @@ var ServiceType = graphql.NewObject(graphql.ObjectConfig{
 		"http_expected_status": &graphql.Field{Type: graphql.Int},
 		"grpc_host":            &graphql.Field{Type: graphql.String},
 		"grpc_port":            &graphql.Field{Type: graphql.Int},
-		"grpc_auth":            &graphql.Field{Type: graphql.String},
 		"grpc_proto":           &graphql.Field{Type: graphql.String},
 		"mqtt_host":            &graphql.Field{Type: graphql.String},
 		"mqtt_port":            &graphql.Field{Type: graphql.Int},
 		"mqtt_qos":             &graphql.Field{Type: graphql.Int},
 		"mqtt_topic":           &graphql.Field{Type: graphql.String},
-		"mqtt_auth":            &graphql.Field{Type: graphql.String},
 		"tcp_host":             &graphql.Field{Type: graphql.String},
 		"tcp_port":             &graphql.Field{Type: graphql.Int},
 		"dns_domain_name":      &graphql.Field{Type: graphql.String},
@@ -86,9 +86,22 @@ var DomainSSLType = graphql.NewObject(graphql.ObjectConfig{
 var UserType = graphql.NewObject(graphql.ObjectConfig{
 	Name: "User",
 	Fields: graphql.Fields{
-		"id":         &graphql.Field{Type: graphql.ID},
-		"username":   &graphql.Field{Type: graphql.String},
-		"email":      &graphql.Field{Type: graphql.String},
+		"id":       &graphql.Field{Type: graphql.ID},
+		"username": &graphql.Field{Type: graphql.String},
+		"email": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Only readable by admins and the user",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				user, ok := p.Source.(models.User)
+				if !ok {
+					return nil, fmt.Errorf("invalid user")
+				}
+				if viewer := viewerFrom(p.Context); viewer.Role != "admin" && viewer.UserID != user.ID {
+					return nil, realtime.ErrForbidden
+				}
+				return user.Email, nil
+			},
+		},
 		"role":       &graphql.Field{Type: graphql.String},
 		"status":     &graphql.Field{Type: graphql.String},
 		"last_login": &graphql.Field{Type: graphql.DateTime},
@@ -107,7 +120,7 @@ var ReportType = graphql.NewObject(graphql.ObjectConfig{
 		"format":       &graphql.Field{Type: graphql.String},
 		"status":       &graphql.Field{Type: graphql.String},
 		"generated_at": &graphql.Field{Type: graphql.DateTime},
-		"file_path":    &graphql.Field{Type: graphql.String},
+		"file_path":    &graphql.Field{Type: graphql.String, Description: "Storage key, only readable by admins", Resolve: adminOnly(graphql.DefaultResolveFn)},
 		"user_id":      &graphql.Field{Type: graphql.ID},
 		"created_at":   &graphql.Field{Type: graphql.DateTime},
 		"updated_at":   &graphql.Field{Type: graphql.DateTime},
@@ -149,6 +162,15 @@ var OperationalPageComponentType = graphql.NewObject(graphql.ObjectConfig{
 		"page_id":        &graphql.Field{Type: graphql.ID},
 		"component_type": &graphql.Field{Type: graphql.String},
 		"component_id":   &graphql.Field{Type: graphql.ID},
+		"parent_id": &graphql.Field{
+			Type: graphql.ID,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if component, ok := p.Source.(models.OperationalPageComponent); ok && component.ParentID != nil {
+					return component.ParentID.String(), nil
+				}
+				return nil, nil
+			},
+		},
 		"component_name": &graphql.Field{Type: graphql.String},
 		"display_order":  &graphql.Field{Type: graphql.Int},
 		"description":    &graphql.Field{Type: graphql.String},
@@ -157,23 +179,123 @@ var OperationalPageComponentType = graphql.NewObject(graphql.ObjectConfig{
 	},
 })
 
+// SLOStatusType defines the GraphQL type for the computed state of an SLO
+var SLOStatusType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "SLOStatus",
+	Fields: graphql.Fields{
+		"window_start":           &graphql.Field{Type: graphql.DateTime},
+		"window_end":             &graphql.Field{Type: graphql.DateTime},
+		"total_checks":           &graphql.Field{Type: graphql.Int},
+		"good_checks":            &graphql.Field{Type: graphql.Int},
+		"availability":           &graphql.Field{Type: graphql.Float},
+		"attainment":             &graphql.Field{Type: graphql.Float},
+		"error_budget":           &graphql.Field{Type: graphql.Float},
+		"error_budget_remaining": &graphql.Field{Type: graphql.Float},
+		"burn_rate_1h":           &graphql.Field{Type: graphql.Float},
+		"burn_rate_6h":           &graphql.Field{Type: graphql.Float},
+		"met":                    &graphql.Field{Type: graphql.Boolean},
+	},
+})
+
+// NewSLOType defines the GraphQL type for an SLO. The status field is only
+// computed when it is requested.
+func NewSLOType(db *gorm.DB) *graphql.Object {
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: "SLO",
+		Fields: graphql.Fields{
+			"id":                  &graphql.Field{Type: graphql.ID},
+			"name":                &graphql.Field{Type: graphql.String},
+			"target_type":         &graphql.Field{Type: graphql.String},
+			"target_id":           &graphql.Field{Type: graphql.ID},
+			"objective":           &graphql.Field{Type: graphql.Float},
+			"window":              &graphql.Field{Type: graphql.String},
+			"latency_threshold":   &graphql.Field{Type: graphql.Float},
+			"burn_rate_threshold": &graphql.Field{Type: graphql.Float},
+			"alert_firing":        &graphql.Field{Type: graphql.Boolean},
+			"created_at":          &graphql.Field{Type: graphql.DateTime},
+			"updated_at":          &graphql.Field{Type: graphql.DateTime},
+			"status": &graphql.Field{
+				Type:        SLOStatusType,
+				Description: "Current attainment, error budget and burn rate",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					s, ok := p.Source.(models.SLO)
+					if !ok {
+						return nil, fmt.Errorf("invalid SLO")
+					}
+					status, err := slo.Compute(db, s, time.Now())
+					if err != nil {
+						log.Printf("Error computing SLO for GraphQL: %v", err)
+						return nil, err
+					}
+					return status, nil
+				},
+			},
+		},
+	})
+}
+
 // RootQuery defines the root query for GraphQL
 func RootQuery(db *gorm.DB) *graphql.Object {
+	sloType := NewSLOType(db)
+
 	return graphql.NewObject(graphql.ObjectConfig{
 		Name: "RootQuery",
 		Fields: graphql.Fields{
-			"instances": &graphql.Field{
-				Type:        graphql.NewList(InstanceType),
-				Description: "Get all instances",
-				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					var instances []models.Instance
-					err := db.Find(&instances).Error
+			"slos": &graphql.Field{
+				Type:        graphql.NewList(sloType),
+				Description: "Get all SLOs, optionally for one target",
+				Args: graphql.FieldConfigArgument{
+					"targetType": &graphql.ArgumentConfig{
+						Type: graphql.String,
+					},
+					"targetID": &graphql.ArgumentConfig{
+						Type: graphql.ID,
+					},
+				},
+				Resolve: authenticated(func(p graphql.ResolveParams) (interface{}, error) {
+					query := db.Order("name ASC")
+					if targetType, ok := p.Args["targetType"].(string); ok {
+						query = query.Where("target_type = ?", targetType)
+					}
+					if targetID, ok := p.Args["targetID"].(string); ok {
+						query = query.Where("target_id = ?", targetID)
+					}
+					var slos []models.SLO
+					err := query.Find(&slos).Error
 					if err != nil {
-						log.Printf("Error fetching instances for GraphQL: %v", err)
+						log.Printf("Error fetching SLOs for GraphQL: %v", err)
 						return nil, err
 					}
-					return instances, nil
+					return slos, nil
+				}),
+			},
+			"slo": &graphql.Field{
+				Type:        sloType,
+				Description: "Get a single SLO by ID",
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{
+						Type: graphql.ID,
+					},
 				},
+				Resolve: authenticated(func(p graphql.ResolveParams) (interface{}, error) {
+					id, ok := p.Args["id"].(string)
+					if !ok {
+						return nil, fmt.Errorf("invalid SLO ID")
+					}
+					var s models.SLO
+					err := db.First(&s, "id = ?", id).Error
+					if err != nil {
+						log.Printf("Error fetching SLO for GraphQL: %v", err)
+						return nil, err
+					}
+					return s, nil
+				}),
+			},
+			"instances": &graphql.Field{
+				Type:        InstanceConnectionType,
+				Description: "List instances",
+				Args:        ConnectionArgs(InstanceFilterType, InstanceOrderType),
+				Resolve:     authenticated(listResolver[models.Instance](db, "instances", instanceFilter, orderBy("name", false))),
 			},
 			"instance": &graphql.Field{
 				Type:        InstanceType,
@@ -183,32 +305,25 @@ func RootQuery(db *gorm.DB) *graphql.Object {
 						Type: graphql.ID,
 					},
 				},
-				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				Resolve: authenticated(func(p graphql.ResolveParams) (interface{}, error) {
 					id, ok := p.Args["id"].(string)
 					if !ok {
 						return nil, fmt.Errorf("invalid instance ID")
 					}
 					var instance models.Instance
-					err := db.Find(&instance, id).Error
+					err := db.Find(&instance, "id = ?", id).Error
 					if err != nil {
 						log.Printf("Error fetching instance for GraphQL: %v", err)
 						return nil, err
 					}
 					return instance, nil
-				},
+				}),
 			},
 			"services": &graphql.Field{
-				Type:        graphql.NewList(ServiceType),
-				Description: "Get all services",
-				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					var services []models.Service
-					err := db.Find(&services).Error
-					if err != nil {
-						log.Printf("Error fetching services for GraphQL: %v", err)
-						return nil, err
-					}
-					return services, nil
-				},
+				Type:        ServiceConnectionType,
+				Description: "List services",
+				Args:        ConnectionArgs(ServiceFilterType, ServiceOrderType),
+				Resolve:     authenticated(listResolver[models.Service](db, "services", serviceFilter, orderBy("name", false))),
 			},
 			"service": &graphql.Field{
 				Type:        ServiceType,
@@ -218,32 +333,25 @@ func RootQuery(db *gorm.DB) *graphql.Object {
 						Type: graphql.ID,
 					},
 				},
-				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				Resolve: authenticated(func(p graphql.ResolveParams) (interface{}, error) {
 					id, ok := p.Args["id"].(string)
 					if !ok {
 						return nil, fmt.Errorf("invalid service ID")
 					}
 					var service models.Service
-					err := db.Find(&service, id).Error
+					err := db.Find(&service, "id = ?", id).Error
 					if err != nil {
 						log.Printf("Error fetching service for GraphQL: %v", err)
 						return nil, err
 					}
 					return service, nil
-				},
+				}),
 			},
 			"domainSSLs": &graphql.Field{
-				Type:        graphql.NewList(DomainSSLType),
-				Description: "Get all domain/SSL entries",
-				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					var domainSSLs []models.DomainSSL
-					err := db.Find(&domainSSLs).Error
-					if err != nil {
-						log.Printf("Error fetching domain/SSLs for GraphQL: %v", err)
-						return nil, err
-					}
-					return domainSSLs, nil
-				},
+				Type:        DomainSSLConnectionType,
+				Description: "List domain/SSL entries",
+				Args:        ConnectionArgs(DomainSSLFilterType, DomainSSLOrderType),
+				Resolve:     authenticated(listResolver[models.DomainSSL](db, "domain/SSLs", domainSSLFilter, orderBy("domain", false))),
 			},
 			"domainSSL": &graphql.Field{
 				Type:        DomainSSLType,
@@ -253,67 +361,56 @@ func RootQuery(db *gorm.DB) *graphql.Object {
 						Type: graphql.ID,
 					},
 				},
-				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				Resolve: authenticated(func(p graphql.ResolveParams) (interface{}, error) {
 					id, ok := p.Args["id"].(string)
 					if !ok {
 						return nil, fmt.Errorf("invalid domain/SSL ID")
 					}
 					var domainSSL models.DomainSSL
-					err := db.Find(&domainSSL, id).Error
+					err := db.Find(&domainSSL, "id = ?", id).Error
 					if err != nil {
 						log.Printf("Error fetching domain/SSL for GraphQL: %v", err)
 						return nil, err
 					}
 					return domainSSL, nil
-				},
+				}),
 			},
 			"users": &graphql.Field{
-				Type:        graphql.NewList(UserType),
-				Description: "Get all users",
-				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					var users []models.User
-					err := db.Select("id", "username", "role", "status", "last_login", "created_at", "updated_at").Find(&users).Error
-					if err != nil {
-						log.Printf("Error fetching users for GraphQL: %v", err)
-						return nil, err
-					}
-					return users, nil
-				},
+				Type:        UserConnectionType,
+				Description: "List users",
+				Args:        ConnectionArgs(UserFilterType, UserOrderType),
+				Resolve:     adminOnly(listResolver[models.User](db, "users", userFilter, orderBy("username", false), "id", "username", "email", "role", "status", "last_login", "created_at", "updated_at")),
 			},
 			"user": &graphql.Field{
 				Type:        UserType,
-				Description: "Get a single user by ID",
+				Description: "Get a single user by ID. Users other than admins can only get themselves.",
 				Args: graphql.FieldConfigArgument{
 					"id": &graphql.ArgumentConfig{
 						Type: graphql.ID,
 					},
 				},
-				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				Resolve: authenticated(func(p graphql.ResolveParams) (interface{}, error) {
 					id, ok := p.Args["id"].(string)
 					if !ok {
 						return nil, fmt.Errorf("invalid user ID")
 					}
+					if viewer := viewerFrom(p.Context); viewer.Role != "admin" && viewer.UserID.String() != id {
+						return nil, realtime.ErrForbidden
+					}
 					var user models.User
-					err := db.Select("id", "username", "role", "status", "last_login", "created_at", "updated_at").Find(&user, id).Error
+					err := db.Select("id", "username", "email", "role", "status", "last_login", "created_at", "updated_at").Find(&user, "id = ?", id).Error
 					if err != nil {
 						log.Printf("Error fetching user for GraphQL: %v", err)
 						return nil, err
 					}
 					return user, nil
-				},
+				}),
 			},
 			"reports": &graphql.Field{
-				Type:        graphql.NewList(ReportType),
-				Description: "Get all reports",
-				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					var reports []models.Report
-					err := db.Find(&reports).Error
-					if err != nil {
-						log.Printf("Error fetching reports for GraphQL: %v", err)
-						return nil, err
-					}
-					return reports, nil
-				},
+				Type:        ReportConnectionType,
+				Description: "List reports, newest first. Users other than admins only see their own.",
+				Args:        ConnectionArgs(ReportFilterType, ReportOrderType),
+				Resolve:     authenticated(listResolver[models.Report](db, "reports", reportFilter, orderBy("created_at", true))),
 			},
 			"report": &graphql.Field{
 				Type:        ReportType,
@@ -323,32 +420,25 @@ func RootQuery(db *gorm.DB) *graphql.Object {
 						Type: graphql.ID,
 					},
 				},
-				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				Resolve: authenticated(func(p graphql.ResolveParams) (interface{}, error) {
 					id, ok := p.Args["id"].(string)
 					if !ok {
 						return nil, fmt.Errorf("invalid report ID")
 					}
 					var report models.Report
-					err := db.Find(&report, id).Error
+					err := reportFilter.scope(db, viewerFrom(p.Context)).Find(&report, "id = ?", id).Error
 					if err != nil {
 						log.Printf("Error fetching report for GraphQL: %v", err)
 						return nil, err
 					}
 					return report, nil
-				},
+				}),
 			},
 			"logEntries": &graphql.Field{
-				Type:        graphql.NewList(LogEntryType),
-				Description: "Get all log entries",
-				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					var logEntries []models.LogEntry
-					err := db.Order("timestamp DESC").Find(&logEntries).Error
-					if err != nil {
-						log.Printf("Error fetching log entries for GraphQL: %v", err)
-						return nil, err
-					}
-					return logEntries, nil
-				},
+				Type:        LogEntryConnectionType,
+				Description: "List log entries, newest first",
+				Args:        ConnectionArgs(LogEntryFilterType, LogEntryOrderType),
+				Resolve:     adminOnly(listResolver[models.LogEntry](db, "log entries", logEntryFilter, orderBy("timestamp", true))),
 			},
 			"logEntry": &graphql.Field{
 				Type:        LogEntryType,
@@ -358,32 +448,25 @@ func RootQuery(db *gorm.DB) *graphql.Object {
 						Type: graphql.ID,
 					},
 				},
-				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				Resolve: adminOnly(func(p graphql.ResolveParams) (interface{}, error) {
 					id, ok := p.Args["id"].(string)
 					if !ok {
 						return nil, fmt.Errorf("invalid log entry ID")
 					}
 					var logEntry models.LogEntry
-					err := db.Find(&logEntry, id).Error
+					err := db.Find(&logEntry, "id = ?", id).Error
 					if err != nil {
 						log.Printf("Error fetching log entry for GraphQL: %v", err)
 						return nil, err
 					}
 					return logEntry, nil
-				},
+				}),
 			},
 			"operationalPages": &graphql.Field{
-				Type:        graphql.NewList(OperationalPageType),
-				Description: "Get all operational pages",
-				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					var pages []models.OperationalPage
-					err := db.Find(&pages).Error
-					if err != nil {
-						log.Printf("Error fetching operational pages for GraphQL: %v", err)
-						return nil, err
-					}
-					return pages, nil
-				},
+				Type:        OperationalPageConnectionType,
+				Description: "List operational pages. Anonymous callers only see public pages.",
+				Args:        ConnectionArgs(OperationalPageFilterType, OperationalPageOrderType),
+				Resolve:     listResolver[models.OperationalPage](db, "operational pages", operationalPageFilter, orderBy("name", false)),
 			},
 			"operationalPage": &graphql.Field{
 				Type:        OperationalPageType,
@@ -399,7 +482,7 @@ func RootQuery(db *gorm.DB) *graphql.Object {
 						return nil, fmt.Errorf("invalid operational page ID or slug")
 					}
 					var page models.OperationalPage
-					err := db.Where("id = ? OR slug = ?", idOrSlug, idOrSlug).Find(&page).Error
+					err := operationalPageFilter.scope(db, viewerFrom(p.Context)).Where("id = ? OR slug = ?", idOrSlug, idOrSlug).Find(&page).Error
 					if err != nil {
 						log.Printf("Error fetching operational page for GraphQL: %v", err)
 						return nil, err
@@ -420,8 +503,9 @@ func RootQuery(db *gorm.DB) *graphql.Object {
 					if !ok {
 						return nil, fmt.Errorf("invalid page ID")
 					}
+					pages := operationalPageFilter.scope(db.Model(&models.OperationalPage{}), viewerFrom(p.Context)).Select("id")
 					var components []models.OperationalPageComponent
-					err := db.Where("page_id = ?", pageID).Order("display_order ASC").Find(&components).Error
+					err := db.Where("page_id = ? AND page_id IN (?)", pageID, pages).Order("display_order ASC").Find(&components).Error
 					if err != nil {
 						log.Printf("Error fetching operational page components for GraphQL: %v", err)
 						return nil, err
@@ -435,7 +519,9 @@ func RootQuery(db *gorm.DB) *graphql.Object {
 
 // CreateSchema defines the executable GraphQL schema
 func CreateSchema(db *gorm.DB) (graphql.Schema, error) {
+	AddMonitoringFields(db)
 	return graphql.NewSchema(graphql.SchemaConfig{
-		Query: RootQuery(db),
+		Query:        RootQuery(db),
+		Subscription: RootSubscription(db, realtime.Default),
 	})
 }
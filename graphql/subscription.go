@@ -0,0 +1,188 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/graphql-go/graphql"
+	"gorm.io/gorm"
+
+	"monitron-server/internal/pagecomponents"
+	"monitron-server/internal/realtime"
+)
+
+// CheckResultType defines the GraphQL type for a CheckResult
+var CheckResultType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "CheckResult",
+	Fields: graphql.Fields{
+		"id":            &graphql.Field{Type: graphql.ID},
+		"target_type":   &graphql.Field{Type: graphql.String},
+		"target_id":     &graphql.Field{Type: graphql.ID},
+		"status":        &graphql.Field{Type: graphql.String},
+		"response_time": &graphql.Field{Type: graphql.Float},
+		"message":       &graphql.Field{Type: graphql.String},
+		"checked_at":    &graphql.Field{Type: graphql.DateTime},
+	},
+})
+
+// InstanceMetricType defines the GraphQL type for an InstanceMetric
+var InstanceMetricType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "InstanceMetric",
+	Fields: graphql.Fields{
+		"instance_id": &graphql.Field{Type: graphql.ID},
+		"metric_type": &graphql.Field{Type: graphql.String},
+		"value":       &graphql.Field{Type: graphql.Float},
+		"timestamp":   &graphql.Field{Type: graphql.DateTime},
+	},
+})
+
+// IncidentType defines the GraphQL type for an Incident
+var IncidentType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Incident",
+	Fields: graphql.Fields{
+		"id":          &graphql.Field{Type: graphql.ID},
+		"target_type": &graphql.Field{Type: graphql.String},
+		"target_id":   &graphql.Field{Type: graphql.ID},
+		"title":       &graphql.Field{Type: graphql.String},
+		"severity":    &graphql.Field{Type: graphql.String},
+		"status":      &graphql.Field{Type: graphql.String},
+		"started_at":  &graphql.Field{Type: graphql.DateTime},
+		"resolved_at": &graphql.Field{Type: graphql.DateTime},
+		"created_at":  &graphql.Field{Type: graphql.DateTime},
+		"updated_at":  &graphql.Field{Type: graphql.DateTime},
+	},
+})
+
+// IncidentChangeType defines the GraphQL type for an incident that was
+// opened or resolved
+var IncidentChangeType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "IncidentChange",
+	Fields: graphql.Fields{
+		"event":    &graphql.Field{Type: graphql.String, Description: "incident.opened or incident.resolved"},
+		"incident": &graphql.Field{Type: IncidentType},
+	},
+})
+
+// RootSubscription defines the root subscription for GraphQL. Its fields
+// follow the topics of the realtime hub and are authorized the same way.
+func RootSubscription(db *gorm.DB, hub *realtime.Hub) *graphql.Object {
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: "RootSubscription",
+		Fields: graphql.Fields{
+			"checkResult": &graphql.Field{
+				Type:        CheckResultType,
+				Description: "Check results of a service, instance or domain/SSL entry as they are recorded",
+				Args: graphql.FieldConfigArgument{
+					"targetId": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.ID),
+					},
+				},
+				Subscribe: func(p graphql.ResolveParams) (interface{}, error) {
+					targetID, err := uuid.Parse(fmt.Sprint(p.Args["targetId"]))
+					if err != nil {
+						return nil, fmt.Errorf("invalid target ID")
+					}
+					topics := []string{
+						realtime.TargetTopic(pagecomponents.TypeService, targetID),
+						realtime.TargetTopic(pagecomponents.TypeInstance, targetID),
+						realtime.TargetTopic(pagecomponents.TypeDomainSSL, targetID),
+					}
+					return subscribe(db, hub, p.Context, topics, realtime.EventCheckResult)
+				},
+				Resolve: resolveSource,
+			},
+			"incidentChanged": &graphql.Field{
+				Type:        IncidentChangeType,
+				Description: "Incidents as they are opened or resolved, of one target or, for admins, of all targets",
+				Args: graphql.FieldConfigArgument{
+					"targetId": &graphql.ArgumentConfig{
+						Type: graphql.ID,
+					},
+				},
+				Subscribe: func(p graphql.ResolveParams) (interface{}, error) {
+					topics := []string{realtime.TopicAll}
+					if id, ok := p.Args["targetId"]; ok {
+						targetID, err := uuid.Parse(fmt.Sprint(id))
+						if err != nil {
+							return nil, fmt.Errorf("invalid target ID")
+						}
+						topics = []string{
+							realtime.TargetTopic(pagecomponents.TypeService, targetID),
+							realtime.TargetTopic(pagecomponents.TypeInstance, targetID),
+							realtime.TargetTopic(pagecomponents.TypeDomainSSL, targetID),
+						}
+					}
+					return subscribe(db, hub, p.Context, topics, realtime.EventIncident)
+				},
+				Resolve: resolveSource,
+			},
+			"instanceMetrics": &graphql.Field{
+				Type:        InstanceMetricType,
+				Description: "Metrics of an instance as they are collected",
+				Args: graphql.FieldConfigArgument{
+					"instanceId": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.ID),
+					},
+				},
+				Subscribe: func(p graphql.ResolveParams) (interface{}, error) {
+					instanceID, err := uuid.Parse(fmt.Sprint(p.Args["instanceId"]))
+					if err != nil {
+						return nil, fmt.Errorf("invalid instance ID")
+					}
+					topics := []string{realtime.TargetTopic(pagecomponents.TypeInstance, instanceID)}
+					return subscribe(db, hub, p.Context, topics, realtime.EventMetric)
+				},
+				Resolve: resolveSource,
+			},
+		},
+	})
+}
+
+// subscribe joins the topics on the hub and returns a channel of the data of
+// their events of one type. The subscription ends with the context.
+func subscribe(db *gorm.DB, hub *realtime.Hub, ctx context.Context, topics []string, eventType string) (interface{}, error) {
+	viewer := viewerFrom(ctx)
+	for i, topic := range topics {
+		canonical, err := realtime.Authorize(db, topic, viewer)
+		if err != nil {
+			return nil, err
+		}
+		topics[i] = canonical
+	}
+
+	sub := hub.Subscribe()
+	for _, topic := range topics {
+		hub.Join(sub, topic)
+	}
+
+	out := make(chan interface{})
+	go func() {
+		defer close(out)
+		defer hub.Close(sub)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-sub.Events():
+				if !ok {
+					return
+				}
+				if event.Type != eventType {
+					continue
+				}
+				select {
+				case out <- event.Data:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
+
+// resolveSource resolves a subscription field to the event data it received
+func resolveSource(p graphql.ResolveParams) (interface{}, error) {
+	return p.Source, nil
+}
@@ -11,6 +11,7 @@ import (
 
 	"monitron-server/models"
 	"monitron-server/utils"
+	"monitron-server/utils/response"
 	"monitron-server/utils/validate"
 )
 
@@ -21,25 +22,25 @@ import (
 // @Accept json
 // @Produce json
 // @Param user body models.User true "User object for registration"
-// @Success 201 {object} models.User
-// @Failure 400 {object} map[string]string "error": "Cannot parse JSON"
-// @Failure 500 {object} map[string]string "error": "Could not register user"
+// @Success 201 {object} response.Response{data=models.User}
+// @Failure 400 {object} response.Response "Cannot parse JSON"
+// @Failure 500 {object} response.Response "Could not register user"
 // @Router /auth/register [post]
 func RegisterUser(db *gorm.DB) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		user := new(models.User)
 		if err := c.BodyParser(user); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON"})
+			return response.InvalidBody(c)
 		}
 
 		if err := validate.V.Struct(user); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+			return response.Invalid(c, err)
 		}
 		// Hash password
 		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
 		if err != nil {
 			log.Printf("Error hashing password: %v", err)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not hash password"})
+			return response.Error(c, fiber.StatusInternalServerError, "Could not hash password")
 		}
 		user.Password = string(hashedPassword)
 
@@ -51,12 +52,12 @@ func RegisterUser(db *gorm.DB) fiber.Handler {
 
 		if result := db.Create(&user); result.Error != nil {
 			log.Printf("Error inserting user: %v", result.Error)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not register user"})
+			return response.Error(c, fiber.StatusInternalServerError, "Could not register user")
 		}
 
 		// Do not return password hash
 		user.Password = ""
-		return c.Status(fiber.StatusCreated).JSON(user)
+		return response.Created(c, user)
 	}
 }
 
@@ -67,10 +68,10 @@ func RegisterUser(db *gorm.DB) fiber.Handler {
 // @Accept json
 // @Produce json
 // @Param credentials body object{email:string,password:string} true "User credentials"
-// @Success 200 {object} map[string]interface{} "message": "Login successful", "token": "<JWT_TOKEN>", "user": "<USER_OBJECT>"
-// @Failure 400 {object} map[string]string "error": "Cannot parse JSON"
-// @Failure 401 {object} map[string]string "error": "Invalid credentials"
-// @Failure 500 {object} map[string]string "error": "Could not generate token"
+// @Success 200 {object} response.Response{data=map[string]interface{}} "Login successful, data holds the token and user"
+// @Failure 400 {object} response.Response "Cannot parse JSON"
+// @Failure 401 {object} response.Response "Invalid credentials"
+// @Failure 500 {object} response.Response "Could not generate token"
 // @Router /auth/login [post]
 func LoginUser(db *gorm.DB) fiber.Handler {
 	return func(c *fiber.Ctx) error {
@@ -80,21 +81,21 @@ func LoginUser(db *gorm.DB) fiber.Handler {
 		}{}
 
 		if err := c.BodyParser(&loginRequest); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON"})
+			return response.InvalidBody(c)
 		}
 
 		if err := validate.V.Struct(loginRequest); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+			return response.Invalid(c, err)
 		}
 		user := models.User{}
 		if result := db.First(&user, "email = ?", loginRequest.Email); result.Error != nil {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid credentials"})
+			return response.Fail(c, fiber.StatusUnauthorized, response.CodeInvalidCredentials, "Invalid credentials", nil)
 		}
 
 		// Compare password
 		err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(loginRequest.Password))
 		if err != nil {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid credentials"})
+			return response.Fail(c, fiber.StatusUnauthorized, response.CodeInvalidCredentials, "Invalid credentials", nil)
 		}
 
 		// Update last login time
@@ -107,10 +108,10 @@ func LoginUser(db *gorm.DB) fiber.Handler {
 		token, err := utils.GenerateJWT(user.ID, user.Role)
 		if err != nil {
 			log.Printf("Error generating JWT: %v", err)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not generate token"})
+			return response.Error(c, fiber.StatusInternalServerError, "Could not generate token")
 		}
 
 		user.Password = ""
-		return c.JSON(fiber.Map{"message": "Login successful", "token": token, "user": user})
+		return response.Send(c, fiber.StatusOK, "Login successful", fiber.Map{"token": token, "user": user})
 	}
 }
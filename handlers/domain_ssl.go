@@ -10,7 +10,9 @@ import (
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 
+	"monitron-server/internal/pagecomponents"
 	"monitron-server/models"
+	"monitron-server/utils/response"
 )
 
 // CreateDomainSSL
@@ -20,16 +22,16 @@ import (
 // @Accept json
 // @Produce json
 // @Param domainSSL body models.DomainSSL true "Domain/SSL object to be created"
-// @Success 201 {object} models.DomainSSL
-// @Failure 400 {object} map[string]string "error": "Cannot parse JSON"
-// @Failure 500 {object} map[string]string "error": "Could not create domain/SSL entry"
+// @Success 201 {object} response.Response{data=models.DomainSSL}
+// @Failure 400 {object} response.Response "Cannot parse JSON"
+// @Failure 500 {object} response.Response "Could not create domain/SSL entry"
 // @Security ApiKeyAuth
 // @Router /domain-ssl [post]
 func CreateDomainSSL(db *gorm.DB) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		domainSSL := new(models.DomainSSL)
 		if err := c.BodyParser(domainSSL); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON"})
+			return response.InvalidBody(c)
 		}
 
 		domainSSL.ID = uuid.New()
@@ -38,10 +40,10 @@ func CreateDomainSSL(db *gorm.DB) fiber.Handler {
 
 		if result := db.Create(&domainSSL); result.Error != nil {
 			log.Printf("Error creating domain/SSL entry: %v", result.Error)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not create domain/SSL entry"})
+			return response.Error(c, fiber.StatusInternalServerError, "Could not create domain/SSL entry")
 		}
 
-		return c.Status(fiber.StatusCreated).JSON(domainSSL)
+		return response.Created(c, domainSSL)
 	}
 }
 
@@ -50,8 +52,8 @@ func CreateDomainSSL(db *gorm.DB) fiber.Handler {
 // @Description Retrieve a list of all domain and SSL certificate monitoring entries
 // @Tags Domain & SSL
 // @Produce json
-// @Success 200 {array} models.DomainSSL
-// @Failure 500 {object} map[string]string "error": "Could not retrieve domain/SSL entries"
+// @Success 200 {object} response.Response{data=[]models.DomainSSL}
+// @Failure 500 {object} response.Response "Could not retrieve domain/SSL entries"
 // @Security ApiKeyAuth
 // @Router /domain-ssl [get]
 func GetDomainSSLs(db *gorm.DB) fiber.Handler {
@@ -59,10 +61,10 @@ func GetDomainSSLs(db *gorm.DB) fiber.Handler {
 		domainSSLs := []models.DomainSSL{}
 		if result := db.Find(&domainSSLs); result.Error != nil {
 			log.Printf("Error fetching domain/SSLs: %v", result.Error)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not retrieve domain/SSL entries"})
+			return response.Error(c, fiber.StatusInternalServerError, "Could not retrieve domain/SSL entries")
 		}
 
-		return c.JSON(domainSSLs)
+		return response.OK(c, domainSSLs)
 	}
 }
 
@@ -72,10 +74,10 @@ func GetDomainSSLs(db *gorm.DB) fiber.Handler {
 // @Tags Domain & SSL
 // @Produce json
 // @Param id path string true "Domain/SSL ID"
-// @Success 200 {object} models.DomainSSL
-// @Failure 400 {object} map[string]string "error": "Invalid domain/SSL ID"
-// @Failure 404 {object} map[string]string "error": "Domain/SSL entry not found"
-// @Failure 500 {object} map[string]string "error": "Could not retrieve domain/SSL entry"
+// @Success 200 {object} response.Response{data=models.DomainSSL}
+// @Failure 400 {object} response.Response "Invalid domain/SSL ID"
+// @Failure 404 {object} response.Response "Domain/SSL entry not found"
+// @Failure 500 {object} response.Response "Could not retrieve domain/SSL entry"
 // @Security ApiKeyAuth
 // @Router /domain-ssl/{id} [get]
 func GetDomainSSL(db *gorm.DB) fiber.Handler {
@@ -83,19 +85,19 @@ func GetDomainSSL(db *gorm.DB) fiber.Handler {
 		id := c.Params("id")
 		uuidID, err := uuid.Parse(id)
 		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid domain/SSL ID"})
+			return response.Fail(c, fiber.StatusBadRequest, response.CodeInvalidParam, "Invalid domain/SSL ID", nil)
 		}
 
 		domainSSL := models.DomainSSL{}
 		if result := db.First(&domainSSL, "id = ?", uuidID); result.Error != nil {
 			if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Domain/SSL entry not found"})
+				return response.Error(c, fiber.StatusNotFound, "Domain/SSL entry not found")
 			}
 			log.Printf("Error fetching domain/SSL: %v", result.Error)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not retrieve domain/SSL entry"})
+			return response.Error(c, fiber.StatusInternalServerError, "Could not retrieve domain/SSL entry")
 		}
 
-		return c.JSON(domainSSL)
+		return response.OK(c, domainSSL)
 	}
 }
 
@@ -107,10 +109,10 @@ func GetDomainSSL(db *gorm.DB) fiber.Handler {
 // @Produce json
 // @Param id path string true "Domain/SSL ID"
 // @Param domainSSL body models.DomainSSL true "Domain/SSL object with updated fields"
-// @Success 200 {object} models.DomainSSL
-// @Failure 400 {object} map[string]string "error": "Invalid domain/SSL ID" or "Cannot parse JSON"
-// @Failure 404 {object} map[string]string "error": "Domain/SSL entry not found"
-// @Failure 500 {object} map[string]string "error": "Could not update domain/SSL entry"
+// @Success 200 {object} response.Response{data=models.DomainSSL}
+// @Failure 400 {object} response.Response "Invalid domain/SSL ID" or "Cannot parse JSON"
+// @Failure 404 {object} response.Response "Domain/SSL entry not found"
+// @Failure 500 {object} response.Response "Could not update domain/SSL entry"
 // @Security ApiKeyAuth
 // @Router /domain-ssl/{id} [put]
 func UpdateDomainSSL(db *gorm.DB) fiber.Handler {
@@ -118,29 +120,29 @@ func UpdateDomainSSL(db *gorm.DB) fiber.Handler {
 		id := c.Params("id")
 		uuidID, err := uuid.Parse(id)
 		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid domain/SSL ID"})
+			return response.Fail(c, fiber.StatusBadRequest, response.CodeInvalidParam, "Invalid domain/SSL ID", nil)
 		}
 
 		domainSSL := new(models.DomainSSL)
 		if err := c.BodyParser(domainSSL); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON"})
+			return response.InvalidBody(c)
 		}
 
 		var existingDomainSSL models.DomainSSL
 		if result := db.First(&existingDomainSSL, "id = ?", uuidID); result.Error != nil {
 			if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Domain/SSL entry not found"})
+				return response.Error(c, fiber.StatusNotFound, "Domain/SSL entry not found")
 			}
 			log.Printf("Error finding domain/SSL for update: %v", result.Error)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not update domain/SSL entry"})
+			return response.Error(c, fiber.StatusInternalServerError, "Could not update domain/SSL entry")
 		}
 
 		if result := db.Model(&existingDomainSSL).Updates(domainSSL); result.Error != nil {
 			log.Printf("Error updating domain/SSL: %v", result.Error)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not update domain/SSL entry"})
+			return response.Error(c, fiber.StatusInternalServerError, "Could not update domain/SSL entry")
 		}
 
-		return c.JSON(existingDomainSSL)
+		return response.OK(c, existingDomainSSL)
 	}
 }
 
@@ -151,9 +153,9 @@ func UpdateDomainSSL(db *gorm.DB) fiber.Handler {
 // @Produce json
 // @Param id path string true "Domain/SSL ID"
 // @Success 204 "No Content"
-// @Failure 400 {object} map[string]string "error": "Invalid domain/SSL ID"
-// @Failure 404 {object} map[string]string "error": "Domain/SSL entry not found"
-// @Failure 500 {object} map[string]string "error": "Could not delete domain/SSL entry"
+// @Failure 400 {object} response.Response "Invalid domain/SSL ID"
+// @Failure 404 {object} response.Response "Domain/SSL entry not found"
+// @Failure 500 {object} response.Response "Could not delete domain/SSL entry"
 // @Security ApiKeyAuth
 // @Router /domain-ssl/{id} [delete]
 func DeleteDomainSSL(db *gorm.DB) fiber.Handler {
@@ -161,16 +163,18 @@ func DeleteDomainSSL(db *gorm.DB) fiber.Handler {
 		id := c.Params("id")
 		uuidID, err := uuid.Parse(id)
 		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid domain/SSL ID"})
+			return response.Fail(c, fiber.StatusBadRequest, response.CodeInvalidParam, "Invalid domain/SSL ID", nil)
 		}
 
 		if result := db.Delete(&models.DomainSSL{}, "id = ?", uuidID); result.Error != nil {
 			log.Printf("Error deleting domain/SSL: %v", result.Error)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not delete domain/SSL entry"})
+			return response.Error(c, fiber.StatusInternalServerError, "Could not delete domain/SSL entry")
 		} else if result.RowsAffected == 0 {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Domain/SSL entry not found"})
+			return response.Error(c, fiber.StatusNotFound, "Domain/SSL entry not found")
 		}
 
+		removeTargetComponents(db, pagecomponents.TypeDomainSSL, uuidID)
+
 		return c.Status(fiber.StatusNoContent).SendString("")
 	}
 }
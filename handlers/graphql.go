@@ -1,42 +1,149 @@
 package handlers
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"log"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/gofiber/contrib/websocket"
 	"github.com/gofiber/fiber/v2"
+	"github.com/graphql-go/graphql"
 	"github.com/graphql-go/handler"
 	"gorm.io/gorm"
 
+	"monitron-server/config"
 	monitrongraphql "monitron-server/graphql"
 	"monitron-server/utils"
+	"monitron-server/utils/response"
 )
 
+var (
+	graphqlGateOnce sync.Once
+	graphqlGate     *monitrongraphql.Gate
+)
+
+// sharedGraphQLGate returns the schema and limits shared by the GraphQL
+// endpoints, so a caller has one cost budget over HTTP and WebSocket
+func sharedGraphQLGate(db *gorm.DB) *monitrongraphql.Gate {
+	graphqlGateOnce.Do(func() {
+		cfg := config.LoadConfig()
+
+		// Create a GraphQL schema
+		schema, err := monitrongraphql.CreateSchema(db)
+		if err != nil {
+			log.Fatalf("failed to create graphql schema, error: %v", err)
+		}
+		persisted, err := monitrongraphql.NewPersistedQueries(cfg.GraphQL.PersistedQueries)
+		if err != nil {
+			log.Fatalf("invalid GRAPHQL_PERSISTED_QUERIES, error: %v", err)
+		}
+		if cfg.GraphQL.PersistedQueriesFile != "" {
+			if err := persisted.LoadManifest(cfg.GraphQL.PersistedQueriesFile); err != nil {
+				log.Fatalf("failed to load persisted queries, error: %v", err)
+			}
+		}
+
+		graphqlGate = &monitrongraphql.Gate{
+			Schema:        &schema,
+			Introspection: cfg.GraphQL.Introspection,
+			MaxDepth:      cfg.GraphQL.MaxDepth,
+			MaxComplexity: cfg.GraphQL.MaxComplexity,
+			Persisted:     persisted,
+		}
+		if cfg.GraphQL.CostBudget > 0 {
+			graphqlGate.Budget = utils.NewRateLimiter(cfg.GraphQL.CostBudget, time.Minute)
+		}
+	})
+	return graphqlGate
+}
+
 // GraphQLHandler handles GraphQL requests
 // @Summary GraphQL Endpoint
-// @Description Access the GraphQL API for querying data
+// @Description Access the GraphQL API for querying data. Fields are authorized per caller: anonymous callers can only read public operational pages, users can read monitored targets, SLOs and their own reports, and admins can read everything, including users and logs. Introspection is admin-only unless GRAPHQL_INTROSPECTION is set.
+// @Description Queries are limited in depth (GRAPHQL_MAX_DEPTH) and estimated cost (GRAPHQL_MAX_COMPLEXITY), where lists cost per item of a page and time series per point and day scanned, and each user or IP may spend GRAPHQL_COST_BUDGET per minute. Queries may be sent by SHA-256 hash with the persistedQuery extension of Apollo APQ; with GRAPHQL_PERSISTED_QUERIES=allowlist only the queries of GRAPHQL_PERSISTED_QUERIES_FILE run, except for admins.
 // @Tags GraphQL
 // @Accept json
 // @Produce json
 // @Param query body string true "GraphQL query"
 // @Success 200 {object} map[string]interface{}
-// @Failure 400 {object} map[string]string "error": "Invalid request"
+// @Failure 400 {object} response.Response "Invalid request"
+// @Failure 429 {object} map[string]interface{} "Query cost budget exceeded"
+// @Security ApiKeyAuth
 // @Router /graphql [post]
 func GraphQLHandler(db *gorm.DB) fiber.Handler {
-	// Create a GraphQL schema
-	schema, err := monitrongraphql.CreateSchema(db)
-	if err != nil {
-		log.Fatalf("failed to create graphql schema, error: %v", err)
+	gate := sharedGraphQLGate(db)
+
+	return func(c *fiber.Ctx) error {
+		request := monitrongraphql.Request{}
+		if strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEApplicationJSON) {
+			if err := json.Unmarshal(c.Body(), &request); err != nil {
+				return response.Error(c, fiber.StatusBadRequest, "Invalid request")
+			}
+		} else {
+			_, r := utils.AdaptFiberToHTTP(c)
+			opts := handler.NewRequestOptions(r)
+			request.Query, request.Variables, request.OperationName = opts.Query, opts.Variables, opts.OperationName
+		}
+
+		ctx := graphqlContext(c)
+		if err := gate.Admit(ctx, &request, c.IP()); err != nil {
+			if errors.Is(err, monitrongraphql.ErrBudgetExceeded) {
+				c.Status(fiber.StatusTooManyRequests)
+			}
+			return c.JSON(monitrongraphql.ErrorResult(err))
+		}
+
+		return c.JSON(graphql.Do(graphql.Params{
+			Schema:         *gate.Schema,
+			RequestString:  request.Query,
+			VariableValues: request.Variables,
+			OperationName:  request.OperationName,
+			Context:        ctx,
+		}))
+	}
+}
+
+// GraphiQL serves the GraphiQL IDE to admins when GRAPHQL_GRAPHIQL is set.
+// Other GET requests, such as WebSocket upgrades, go on to the next handler.
+// Browsers authenticate with the token cookie.
+func GraphiQL(db *gorm.DB) fiber.Handler {
+	if !config.LoadConfig().GraphQL.GraphiQL {
+		return func(c *fiber.Ctx) error {
+			return c.Next()
+		}
 	}
 
 	h := handler.New(&handler.Config{
-		Schema:   &schema,
+		Schema:   sharedGraphQLGate(db).Schema,
 		Pretty:   true,
 		GraphiQL: true,
 	})
 
 	return func(c *fiber.Ctx) error {
+		if websocket.IsWebSocketUpgrade(c) || !strings.Contains(c.Get(fiber.HeaderAccept), "text/html") {
+			return c.Next()
+		}
+		if c.Locals("user_role") != "admin" {
+			return response.Error(c, fiber.StatusForbidden, "Forbidden: Admin access required")
+		}
+
+		// The page is rendered without running the query of its URL, which
+		// would skip the gate
+		c.Type("html")
 		w, r := utils.AdaptFiberToHTTP(c)
-		h.ServeHTTP(w, r)
+		r.URL.RawQuery = ""
+		h.ContextHandler(graphqlContext(c), w, r)
 		return nil
 	}
 }
+
+// graphqlContext returns the context of a GraphQL execution for the caller
+// of a request
+func graphqlContext(c *fiber.Ctx) context.Context {
+	viewer := realtimeViewer(c.Locals("user_id"), c.Locals("user_role"))
+	return monitrongraphql.WithLoaders(monitrongraphql.WithViewer(context.Background(), viewer))
+}
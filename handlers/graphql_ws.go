@@ -0,0 +1,363 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"gorm.io/gorm"
+
+	monitrongraphql "monitron-server/graphql"
+	"monitron-server/internal/realtime"
+	"monitron-server/utils"
+)
+
+// GraphQL WebSocket subprotocols: graphql-transport-ws is the protocol of the
+// graphql-ws library, graphql-ws the older one of subscriptions-transport-ws
+const (
+	graphqlTransportWS = "graphql-transport-ws"
+	graphqlWS          = "graphql-ws"
+)
+
+// GraphQL WebSocket limits
+const (
+	graphqlWSInitTimeout = 10 * time.Second
+	graphqlWSKeepAlive   = 25 * time.Second
+	graphqlWSMaxMessage  = 64 * 1024
+)
+
+// graphqlWSMessage is a message sent by a GraphQL WebSocket client
+type graphqlWSMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// graphqlWSReply is a message sent to a GraphQL WebSocket client
+type graphqlWSReply struct {
+	ID      string      `json:"id,omitempty"`
+	Type    string      `json:"type"`
+	Payload interface{} `json:"payload,omitempty"`
+}
+
+// graphqlWSInit is the payload of a connection_init message. Clients pass
+// their JWT either way.
+type graphqlWSInit struct {
+	Authorization string `json:"Authorization"`
+	Token         string `json:"token"`
+}
+
+// graphqlWSOperation is a running operation of a session
+type graphqlWSOperation struct {
+	cancel context.CancelFunc
+}
+
+// graphqlWSSession is one GraphQL WebSocket connection. The handler goroutine
+// reads, write sends the replies of all operations.
+type graphqlWSSession struct {
+	gate       *monitrongraphql.Gate
+	conn       *websocket.Conn
+	ip         string
+	legacy     bool
+	viewer     realtime.Viewer
+	ctx        context.Context
+	out        chan graphqlWSReply
+	acked      atomic.Bool
+	mu         sync.Mutex
+	operations map[string]*graphqlWSOperation
+}
+
+// GraphQLWebSocketUpgrade rejects GraphQL WebSocket requests that are not
+// upgrades, and accepts the JWT as the token query parameter
+func GraphQLWebSocketUpgrade() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		viewer, err := websocketViewer(c)
+		if viewer == nil {
+			return err
+		}
+		return c.Next()
+	}
+}
+
+// GraphQLWebSocket serves GraphQL over WebSocket
+// @Summary GraphQL over WebSocket
+// @Description Runs GraphQL subscriptions (checkResult, incidentChanged, instanceMetrics) and single-result queries over the graphql-transport-ws protocol, or the older graphql-ws protocol when the client asks for it. The JWT comes from the Authorization header, the token query parameter or the Authorization or token field of the connection_init payload. Subscriptions are authorized like the topics of /ws and queries like those of POST /graphql. When GRAPHQL_GRAPHIQL is set, browsers of admins get the GraphiQL IDE instead.
+// @Tags GraphQL
+// @Param token query string false "JWT, for clients that cannot set headers"
+// @Success 101 "Switching Protocols"
+// @Failure 401 {object} response.Response "Invalid or expired JWT"
+// @Failure 426 {object} response.Response "WebSocket upgrade required"
+// @Router /graphql [get]
+func GraphQLWebSocket(db *gorm.DB) fiber.Handler {
+	gate := sharedGraphQLGate(db)
+
+	return websocket.New(func(conn *websocket.Conn) {
+		ctx, cancel := context.WithCancel(context.Background())
+		s := &graphqlWSSession{
+			gate:       gate,
+			conn:       conn,
+			ip:         conn.IP(),
+			legacy:     conn.Subprotocol() == graphqlWS,
+			viewer:     realtimeViewer(conn.Locals("user_id"), conn.Locals("user_role")),
+			ctx:        ctx,
+			out:        make(chan graphqlWSReply, realtime.SubscriberBuffer),
+			operations: map[string]*graphqlWSOperation{},
+		}
+
+		written := make(chan struct{})
+		go s.write(written)
+		s.read()
+		cancel()
+		<-written
+	}, websocket.Config{Subprotocols: []string{graphqlTransportWS, graphqlWS}})
+}
+
+// read handles client messages until the client goes away or breaks the
+// protocol, which closes the connection with the protocol's close code
+func (s *graphqlWSSession) read() {
+	s.conn.SetReadLimit(graphqlWSMaxMessage)
+	s.conn.SetReadDeadline(time.Now().Add(graphqlWSInitTimeout))
+
+	for {
+		_, data, err := s.conn.ReadMessage()
+		if err != nil {
+			var netErr net.Error
+			if !s.acked.Load() && errors.As(err, &netErr) && netErr.Timeout() {
+				s.close(4408, "Connection initialisation timeout")
+			}
+			return
+		}
+
+		message := graphqlWSMessage{}
+		if err := json.Unmarshal(data, &message); err != nil {
+			s.close(4400, "Invalid message")
+			return
+		}
+
+		switch message.Type {
+		case "connection_init":
+			if s.acked.Load() {
+				s.close(4429, "Too many initialisation requests")
+				return
+			}
+			if !s.authenticate(message.Payload) {
+				s.close(4403, "Forbidden")
+				return
+			}
+			s.acked.Store(true)
+			s.conn.SetReadDeadline(time.Now().Add(realtimePongWait))
+			s.conn.SetPongHandler(func(string) error {
+				return s.conn.SetReadDeadline(time.Now().Add(realtimePongWait))
+			})
+			s.send(s.ctx, graphqlWSReply{Type: "connection_ack"})
+			if s.legacy {
+				s.send(s.ctx, graphqlWSReply{Type: "ka"})
+			}
+		case "ping":
+			s.send(s.ctx, graphqlWSReply{Type: "pong", Payload: message.Payload})
+		case "pong":
+		case "subscribe", "start":
+			if !s.acked.Load() {
+				s.close(4401, "Unauthorized")
+				return
+			}
+			request := monitrongraphql.Request{}
+			if err := json.Unmarshal(message.Payload, &request); err != nil || message.ID == "" {
+				s.close(4400, "Invalid message")
+				return
+			}
+			if !s.start(message.ID, request) {
+				s.close(4409, "Subscriber for "+message.ID+" already exists")
+				return
+			}
+		case "complete", "stop":
+			s.stop(message.ID, nil)
+		case "connection_terminate":
+			return
+		default:
+			s.close(4400, "Unknown message type "+message.Type)
+			return
+		}
+	}
+}
+
+// write sends replies, WebSocket pings and, for the legacy protocol,
+// keep-alive messages until the session ends
+func (s *graphqlWSSession) write(written chan struct{}) {
+	defer close(written)
+
+	ping := time.NewTicker(realtimePingPeriod)
+	defer ping.Stop()
+	keepAlive := time.NewTicker(graphqlWSKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		var err error
+		select {
+		case <-s.ctx.Done():
+			return
+		case reply := <-s.out:
+			s.conn.SetWriteDeadline(time.Now().Add(realtimeWriteWait))
+			err = s.conn.WriteJSON(reply)
+		case <-keepAlive.C:
+			if s.legacy && s.acked.Load() {
+				s.conn.SetWriteDeadline(time.Now().Add(realtimeWriteWait))
+				err = s.conn.WriteJSON(graphqlWSReply{Type: "ka"})
+			}
+		case <-ping.C:
+			s.conn.SetWriteDeadline(time.Now().Add(realtimeWriteWait))
+			err = s.conn.WriteMessage(websocket.PingMessage, nil)
+		}
+		if err != nil {
+			// Unblocks read, which ends the session
+			s.conn.Close()
+			return
+		}
+	}
+}
+
+// authenticate applies the JWT of a connection_init payload, if any. The
+// JWT of the upgrade request is kept when the payload has none.
+func (s *graphqlWSSession) authenticate(payload json.RawMessage) bool {
+	init := graphqlWSInit{}
+	if len(payload) > 0 {
+		if err := json.Unmarshal(payload, &init); err != nil {
+			return false
+		}
+	}
+
+	token := init.Token
+	if bearer, ok := strings.CutPrefix(init.Authorization, "Bearer "); ok {
+		token = bearer
+	}
+	if token == "" {
+		return true
+	}
+
+	claims, err := utils.ParseJWT(token)
+	if err != nil {
+		return false
+	}
+	s.viewer = realtime.Viewer{Authenticated: true, UserID: claims.UserID, Role: claims.Role}
+	return true
+}
+
+// start runs an operation. It returns false when the ID is already in use.
+func (s *graphqlWSSession) start(id string, request monitrongraphql.Request) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.operations[id]; ok {
+		return false
+	}
+
+	ctx, cancel := context.WithCancel(monitrongraphql.WithLoaders(monitrongraphql.WithViewer(s.ctx, s.viewer)))
+	operation := &graphqlWSOperation{cancel: cancel}
+	s.operations[id] = operation
+	go s.run(ctx, id, operation, request)
+	return true
+}
+
+// stop cancels an operation. With a non-nil operation, only that one is
+// cancelled, so an operation that ends cannot stop a newer one reusing its ID.
+func (s *graphqlWSSession) stop(id string, operation *graphqlWSOperation) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current, ok := s.operations[id]
+	if !ok || (operation != nil && current != operation) {
+		return
+	}
+	current.cancel()
+	delete(s.operations, id)
+}
+
+// run admits an operation, executes it and sends its results. Subscriptions
+// send one result per event, queries and mutations a single one. Results are
+// drained after a cancellation so the executor can finish.
+func (s *graphqlWSSession) run(ctx context.Context, id string, operation *graphqlWSOperation, request monitrongraphql.Request) {
+	if err := s.gate.Admit(ctx, &request, s.ip); err != nil {
+		s.send(ctx, graphqlWSReply{ID: id, Type: "error", Payload: monitrongraphql.ErrorResult(err).Errors})
+		s.stop(id, operation)
+		return
+	}
+
+	params := graphql.Params{
+		Schema:         *s.gate.Schema,
+		RequestString:  request.Query,
+		VariableValues: request.Variables,
+		OperationName:  request.OperationName,
+		Context:        ctx,
+	}
+
+	var results chan *graphql.Result
+	if operationType(request.Query, request.OperationName) == ast.OperationTypeSubscription {
+		results = graphql.Subscribe(params)
+	} else {
+		results = make(chan *graphql.Result, 1)
+		results <- graphql.Do(params)
+		close(results)
+	}
+
+	next := "next"
+	if s.legacy {
+		next = "data"
+	}
+	first, failed := true, false
+	for result := range results {
+		if first && result.Data == nil && len(result.Errors) > 0 {
+			failed = s.send(ctx, graphqlWSReply{ID: id, Type: "error", Payload: result.Errors})
+		} else {
+			s.send(ctx, graphqlWSReply{ID: id, Type: next, Payload: result})
+		}
+		first = false
+	}
+
+	if ctx.Err() == nil && !failed {
+		s.send(ctx, graphqlWSReply{ID: id, Type: "complete"})
+	}
+	s.stop(id, operation)
+}
+
+// send queues a reply unless ctx ends first, and reports whether it was queued
+func (s *graphqlWSSession) send(ctx context.Context, reply graphqlWSReply) bool {
+	select {
+	case s.out <- reply:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// close sends a close frame with a protocol close code. It is safe to call
+// while write is running.
+func (s *graphqlWSSession) close(code int, reason string) {
+	s.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(realtimeWriteWait))
+}
+
+// operationType returns the type of the operation a request runs, or an
+// empty string when the query does not parse, leaving the error to graphql
+func operationType(query, operationName string) string {
+	document, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		return ""
+	}
+	for _, definition := range document.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if operationName == "" || (operation.Name != nil && operation.Name.Value == operationName) {
+			return operation.Operation
+		}
+	}
+	return ""
+}
@@ -11,8 +11,10 @@ import (
 	"gorm.io/gorm"
 
 	"monitron-server/config"
+	"monitron-server/internal/pagecomponents"
 	"monitron-server/models"
 	"monitron-server/utils"
+	"monitron-server/utils/response"
 )
 
 // CreateInstance
@@ -22,16 +24,16 @@ import (
 // @Accept json
 // @Produce json
 // @Param instance body models.Instance true "Instance object to be created"
-// @Success 201 {object} models.Instance
-// @Failure 400 {object} map[string]string "error": "Cannot parse JSON"
-// @Failure 500 {object} map[string]string "error": "Could not create instance"
+// @Success 201 {object} response.Response{data=models.Instance}
+// @Failure 400 {object} response.Response "Cannot parse JSON"
+// @Failure 500 {object} response.Response "Could not create instance"
 // @Security ApiKeyAuth
 // @Router /instances [post]
 func CreateInstance(db *gorm.DB) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		instance := new(models.Instance)
 		if err := c.BodyParser(instance); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON"})
+			return response.InvalidBody(c)
 		}
 
 		cfg := config.LoadConfig()
@@ -39,7 +41,7 @@ func CreateInstance(db *gorm.DB) fiber.Handler {
 			encryptedAuth, err := utils.Encrypt([]byte(instance.AgentAuth), cfg)
 			if err != nil {
 				log.Printf("Error encrypting agent auth: %v", err)
-				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not encrypt agent authentication"})
+				return response.Error(c, fiber.StatusInternalServerError, "Could not encrypt agent authentication")
 			}
 			instance.AgentAuth = encryptedAuth
 		}
@@ -50,7 +52,7 @@ func CreateInstance(db *gorm.DB) fiber.Handler {
 
 		if result := db.Create(&instance); result.Error != nil {
 			log.Printf("Error creating instance: %v", result.Error)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not create instance"})
+			return response.Error(c, fiber.StatusInternalServerError, "Could not create instance")
 		}
 
 		// Decrypt agent_auth before returning
@@ -65,7 +67,7 @@ func CreateInstance(db *gorm.DB) fiber.Handler {
 			}
 		}
 
-		return c.Status(fiber.StatusCreated).JSON(instance)
+		return response.Created(c, instance)
 	}
 }
 
@@ -74,8 +76,8 @@ func CreateInstance(db *gorm.DB) fiber.Handler {
 // @Description Retrieve a list of all monitoring instances
 // @Tags Instances
 // @Produce json
-// @Success 200 {array} models.Instance
-// @Failure 500 {object} map[string]string "error": "Could not retrieve instances"
+// @Success 200 {object} response.Response{data=[]models.Instance}
+// @Failure 500 {object} response.Response "Could not retrieve instances"
 // @Security ApiKeyAuth
 // @Router /instances [get]
 func GetInstances(db *gorm.DB) fiber.Handler {
@@ -83,7 +85,7 @@ func GetInstances(db *gorm.DB) fiber.Handler {
 		instances := []models.Instance{}
 		if result := db.Find(&instances); result.Error != nil {
 			log.Printf("Error fetching instances: %v", result.Error)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not retrieve instances"})
+			return response.Error(c, fiber.StatusInternalServerError, "Could not retrieve instances")
 		}
 
 		cfg := config.LoadConfig()
@@ -99,7 +101,7 @@ func GetInstances(db *gorm.DB) fiber.Handler {
 			}
 		}
 
-		return c.JSON(instances)
+		return response.OK(c, instances)
 	}
 }
 
@@ -109,10 +111,10 @@ func GetInstances(db *gorm.DB) fiber.Handler {
 // @Tags Instances
 // @Produce json
 // @Param id path string true "Instance ID"
-// @Success 200 {object} models.Instance
-// @Failure 400 {object} map[string]string "error": "Invalid instance ID"
-// @Failure 404 {object} map[string]string "error": "Instance not found"
-// @Failure 500 {object} map[string]string "error": "Could not retrieve instance"
+// @Success 200 {object} response.Response{data=models.Instance}
+// @Failure 400 {object} response.Response "Invalid instance ID"
+// @Failure 404 {object} response.Response "Instance not found"
+// @Failure 500 {object} response.Response "Could not retrieve instance"
 // @Security ApiKeyAuth
 // @Router /instances/{id} [get]
 func GetInstance(db *gorm.DB) fiber.Handler {
@@ -120,16 +122,16 @@ func GetInstance(db *gorm.DB) fiber.Handler {
 		id := c.Params("id")
 		uuidID, err := uuid.Parse(id)
 		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid instance ID"})
+			return response.Fail(c, fiber.StatusBadRequest, response.CodeInvalidParam, "Invalid instance ID", nil)
 		}
 
 		instance := models.Instance{}
 		if result := db.First(&instance, "id = ?", uuidID); result.Error != nil {
 			if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Instance not found"})
+				return response.Error(c, fiber.StatusNotFound, "Instance not found")
 			}
 			log.Printf("Error fetching instance: %v", result.Error)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not retrieve instance"})
+			return response.Error(c, fiber.StatusInternalServerError, "Could not retrieve instance")
 		}
 
 		cfg := config.LoadConfig()
@@ -143,7 +145,7 @@ func GetInstance(db *gorm.DB) fiber.Handler {
 			}
 		}
 
-		return c.JSON(instance)
+		return response.OK(c, instance)
 	}
 }
 
@@ -155,10 +157,10 @@ func GetInstance(db *gorm.DB) fiber.Handler {
 // @Produce json
 // @Param id path string true "Instance ID"
 // @Param instance body models.Instance true "Instance object with updated fields"
-// @Success 200 {object} models.Instance
-// @Failure 400 {object} map[string]string "error": "Invalid instance ID" or "Cannot parse JSON"
-// @Failure 404 {object} map[string]string "error": "Instance not found"
-// @Failure 500 {object} map[string]string "error": "Could not update instance"
+// @Success 200 {object} response.Response{data=models.Instance}
+// @Failure 400 {object} response.Response "Invalid instance ID" or "Cannot parse JSON"
+// @Failure 404 {object} response.Response "Instance not found"
+// @Failure 500 {object} response.Response "Could not update instance"
 // @Security ApiKeyAuth
 // @Router /instances/{id} [put]
 func UpdateInstance(db *gorm.DB) fiber.Handler {
@@ -166,12 +168,12 @@ func UpdateInstance(db *gorm.DB) fiber.Handler {
 		id := c.Params("id")
 		uuidID, err := uuid.Parse(id)
 		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid instance ID"})
+			return response.Fail(c, fiber.StatusBadRequest, response.CodeInvalidParam, "Invalid instance ID", nil)
 		}
 
 		instance := new(models.Instance)
 		if err := c.BodyParser(instance); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON"})
+			return response.InvalidBody(c)
 		}
 
 		cfg := config.LoadConfig()
@@ -179,7 +181,7 @@ func UpdateInstance(db *gorm.DB) fiber.Handler {
 			encryptedAuth, err := utils.Encrypt([]byte(instance.AgentAuth), cfg)
 			if err != nil {
 				log.Printf("Error encrypting agent auth: %v", err)
-				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not encrypt agent authentication"})
+				return response.Error(c, fiber.StatusInternalServerError, "Could not encrypt agent authentication")
 			}
 			instance.AgentAuth = encryptedAuth
 		}
@@ -187,15 +189,15 @@ func UpdateInstance(db *gorm.DB) fiber.Handler {
 		var existingInstance models.Instance
 		if result := db.First(&existingInstance, "id = ?", uuidID); result.Error != nil {
 			if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Instance not found"})
+				return response.Error(c, fiber.StatusNotFound, "Instance not found")
 			}
 			log.Printf("Error finding instance for update: %v", result.Error)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not update instance"})
+			return response.Error(c, fiber.StatusInternalServerError, "Could not update instance")
 		}
 
 		if result := db.Model(&existingInstance).Updates(instance); result.Error != nil {
 			log.Printf("Error updating instance: %v", result.Error)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not update instance"})
+			return response.Error(c, fiber.StatusInternalServerError, "Could not update instance")
 		}
 
 		// Decrypt agent_auth before returning
@@ -209,7 +211,7 @@ func UpdateInstance(db *gorm.DB) fiber.Handler {
 			}
 		}
 
-		return c.JSON(existingInstance)
+		return response.OK(c, existingInstance)
 	}
 }
 
@@ -220,9 +222,9 @@ func UpdateInstance(db *gorm.DB) fiber.Handler {
 // @Produce json
 // @Param id path string true "Instance ID"
 // @Success 204 "No Content"
-// @Failure 400 {object} map[string]string "error": "Invalid instance ID"
-// @Failure 404 {object} map[string]string "error": "Instance not found"
-// @Failure 500 {object} map[string]string "error": "Could not delete instance"
+// @Failure 400 {object} response.Response "Invalid instance ID"
+// @Failure 404 {object} response.Response "Instance not found"
+// @Failure 500 {object} response.Response "Could not delete instance"
 // @Security ApiKeyAuth
 // @Router /instances/{id} [delete]
 func DeleteInstance(db *gorm.DB) fiber.Handler {
@@ -230,16 +232,18 @@ func DeleteInstance(db *gorm.DB) fiber.Handler {
 		id := c.Params("id")
 		uuidID, err := uuid.Parse(id)
 		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid instance ID"})
+			return response.Fail(c, fiber.StatusBadRequest, response.CodeInvalidParam, "Invalid instance ID", nil)
 		}
 
 		if result := db.Delete(&models.Instance{}, "id = ?", uuidID); result.Error != nil {
 			log.Printf("Error deleting instance: %v", result.Error)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not delete instance"})
+			return response.Error(c, fiber.StatusInternalServerError, "Could not delete instance")
 		} else if result.RowsAffected == 0 {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Instance not found"})
+			return response.Error(c, fiber.StatusNotFound, "Instance not found")
 		}
 
+		removeTargetComponents(db, pagecomponents.TypeInstance, uuidID)
+
 		return c.Status(fiber.StatusNoContent).SendString("")
 	}
 }
@@ -9,6 +9,7 @@ import (
 	"gorm.io/gorm"
 
 	"monitron-server/models"
+	"monitron-server/utils/response"
 )
 
 // CreateLogEntry handles the creation of a new log entry
@@ -16,7 +17,7 @@ func CreateLogEntry(db *gorm.DB) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		logEntry := new(models.LogEntry)
 		if err := c.BodyParser(logEntry); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON"})
+			return response.InvalidBody(c)
 		}
 
 		logEntry.ID = uuid.New()
@@ -26,10 +27,10 @@ func CreateLogEntry(db *gorm.DB) fiber.Handler {
 		err := db.Create(logEntry).Error
 		if err != nil {
 			log.Printf("Error inserting log entry: %v", err)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not create log entry"})
+			return response.Error(c, fiber.StatusInternalServerError, "Could not create log entry")
 		}
 
-		return c.Status(fiber.StatusCreated).JSON(logEntry)
+		return response.Created(c, logEntry)
 	}
 }
 
@@ -40,10 +41,10 @@ func GetLogEntries(db *gorm.DB) fiber.Handler {
 		err := db.Select(&logEntries, `SELECT * FROM log_entries ORDER BY timestamp DESC`)
 		if err != nil {
 			log.Printf("Error fetching log entries: %v", err)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not retrieve log entries"})
+			return response.Error(c, fiber.StatusInternalServerError, "Could not retrieve log entries")
 		}
 
-		return c.JSON(logEntries)
+		return response.OK(c, logEntries)
 	}
 }
 
@@ -53,16 +54,16 @@ func GetLogEntry(db *gorm.DB) fiber.Handler {
 		id := c.Params("id")
 		uuidID, err := uuid.Parse(id)
 		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid log entry ID"})
+			return response.Fail(c, fiber.StatusBadRequest, response.CodeInvalidParam, "Invalid log entry ID", nil)
 		}
 
 		logEntry := models.LogEntry{}
 		err = db.Where("id = ?", uuidID).First(&logEntry).Error
 		if err != nil {
 			log.Printf("Error fetching log entry: %v", err)
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Log entry not found"})
+			return response.Error(c, fiber.StatusNotFound, "Log entry not found")
 		}
 
-		return c.JSON(logEntry)
+		return response.OK(c, logEntry)
 	}
 }
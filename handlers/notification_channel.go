@@ -0,0 +1,322 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"monitron-server/config"
+	"monitron-server/internal/notifier"
+	"monitron-server/models"
+	"monitron-server/utils/response"
+	"monitron-server/utils/validate"
+)
+
+// notificationChannelRequest is the payload for creating or updating a channel.
+// Config is accepted in plain form and encrypted before it is stored.
+type notificationChannelRequest struct {
+	Name      string                           `json:"name" validate:"required"`
+	Type      string                           `json:"type" validate:"required,oneof=email telegram discord slack webhook"`
+	Config    models.NotificationChannelConfig `json:"config"`
+	IsEnabled *bool                            `json:"is_enabled"`
+}
+
+// notificationChannelResponse exposes a channel with its credentials masked
+type notificationChannelResponse struct {
+	models.NotificationChannel
+	Config models.NotificationChannelConfig `json:"config"`
+}
+
+// maskSecret hides all but the last four characters of a credential
+func maskSecret(secret string) string {
+	if len(secret) <= 4 {
+		return ""
+	}
+	return "****" + secret[len(secret)-4:]
+}
+
+// maskURL keeps the scheme and host of a webhook URL, whose path and query
+// act as a credential for Discord and Slack
+func maskURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host + "/****"
+}
+
+func toNotificationChannelResponse(channel models.NotificationChannel, cfg *config.Config) notificationChannelResponse {
+	channelCfg, err := notifier.DecryptConfig(channel.Config, cfg)
+	if err != nil {
+		log.Printf("Error decrypting config for notification channel %s: %v", channel.ID, err)
+	}
+	channelCfg.BotToken = maskSecret(channelCfg.BotToken)
+	channelCfg.Secret = maskSecret(channelCfg.Secret)
+	channelCfg.WebhookURL = maskURL(channelCfg.WebhookURL)
+
+	return notificationChannelResponse{NotificationChannel: channel, Config: channelCfg}
+}
+
+// CreateNotificationChannel
+// @Summary Create a new notification channel
+// @Description Create an Email, Telegram, Discord, Slack or generic webhook notification channel
+// @Tags Notification Channels
+// @Accept json
+// @Produce json
+// @Param channel body notificationChannelRequest true "Notification channel to be created"
+// @Success 201 {object} response.Response{data=notificationChannelResponse}
+// @Failure 400 {object} response.Response "Cannot parse JSON"
+// @Failure 500 {object} response.Response "Could not create notification channel"
+// @Security ApiKeyAuth
+// @Router /notification-channels [post]
+func CreateNotificationChannel(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		req := new(notificationChannelRequest)
+		if err := c.BodyParser(req); err != nil {
+			return response.InvalidBody(c)
+		}
+
+		if err := validate.V.Struct(req); err != nil {
+			return response.Invalid(c, err)
+		}
+
+		cfg := config.LoadConfig()
+		if _, err := notifier.New(req.Type, req.Config, cfg); err != nil {
+			return response.Error(c, fiber.StatusBadRequest, err.Error())
+		}
+
+		encryptedConfig, err := notifier.EncryptConfig(req.Config, cfg)
+		if err != nil {
+			log.Printf("Error encrypting notification channel config: %v", err)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not encrypt channel configuration")
+		}
+
+		channel := models.NotificationChannel{
+			ID:        uuid.New(),
+			Name:      req.Name,
+			Type:      req.Type,
+			Config:    encryptedConfig,
+			IsEnabled: req.IsEnabled == nil || *req.IsEnabled,
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		}
+
+		if result := db.Create(&channel); result.Error != nil {
+			log.Printf("Error creating notification channel: %v", result.Error)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not create notification channel")
+		}
+
+		return response.Created(c, toNotificationChannelResponse(channel, cfg))
+	}
+}
+
+// GetNotificationChannels
+// @Summary Get all notification channels
+// @Description Retrieve a list of all notification channels with masked credentials
+// @Tags Notification Channels
+// @Produce json
+// @Success 200 {object} response.Response{data=[]notificationChannelResponse}
+// @Failure 500 {object} response.Response "Could not retrieve notification channels"
+// @Security ApiKeyAuth
+// @Router /notification-channels [get]
+func GetNotificationChannels(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		channels := []models.NotificationChannel{}
+		if result := db.Order("created_at ASC").Find(&channels); result.Error != nil {
+			log.Printf("Error fetching notification channels: %v", result.Error)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not retrieve notification channels")
+		}
+
+		cfg := config.LoadConfig()
+		views := make([]notificationChannelResponse, 0, len(channels))
+		for _, channel := range channels {
+			views = append(views, toNotificationChannelResponse(channel, cfg))
+		}
+
+		return response.OK(c, views)
+	}
+}
+
+// GetNotificationChannel
+// @Summary Get notification channel by ID
+// @Description Retrieve a single notification channel by its ID
+// @Tags Notification Channels
+// @Produce json
+// @Param id path string true "Notification Channel ID"
+// @Success 200 {object} response.Response{data=notificationChannelResponse}
+// @Failure 400 {object} response.Response "Invalid notification channel ID"
+// @Failure 404 {object} response.Response "Notification channel not found"
+// @Failure 500 {object} response.Response "Could not retrieve notification channel"
+// @Security ApiKeyAuth
+// @Router /notification-channels/{id} [get]
+func GetNotificationChannel(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uuidID, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return response.Fail(c, fiber.StatusBadRequest, response.CodeInvalidParam, "Invalid notification channel ID", nil)
+		}
+
+		channel := models.NotificationChannel{}
+		if result := db.First(&channel, "id = ?", uuidID); result.Error != nil {
+			if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+				return response.Error(c, fiber.StatusNotFound, "Notification channel not found")
+			}
+			log.Printf("Error fetching notification channel: %v", result.Error)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not retrieve notification channel")
+		}
+
+		return response.OK(c, toNotificationChannelResponse(channel, config.LoadConfig()))
+	}
+}
+
+// UpdateNotificationChannel
+// @Summary Update an existing notification channel
+// @Description Update a notification channel by its ID. The configuration is replaced as a whole.
+// @Tags Notification Channels
+// @Accept json
+// @Produce json
+// @Param id path string true "Notification Channel ID"
+// @Param channel body notificationChannelRequest true "Notification channel with updated fields"
+// @Success 200 {object} response.Response{data=notificationChannelResponse}
+// @Failure 400 {object} response.Response "Invalid notification channel ID" or "Cannot parse JSON"
+// @Failure 404 {object} response.Response "Notification channel not found"
+// @Failure 500 {object} response.Response "Could not update notification channel"
+// @Security ApiKeyAuth
+// @Router /notification-channels/{id} [put]
+func UpdateNotificationChannel(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uuidID, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return response.Fail(c, fiber.StatusBadRequest, response.CodeInvalidParam, "Invalid notification channel ID", nil)
+		}
+
+		req := new(notificationChannelRequest)
+		if err := c.BodyParser(req); err != nil {
+			return response.InvalidBody(c)
+		}
+
+		if err := validate.V.Struct(req); err != nil {
+			return response.Invalid(c, err)
+		}
+
+		cfg := config.LoadConfig()
+		if _, err := notifier.New(req.Type, req.Config, cfg); err != nil {
+			return response.Error(c, fiber.StatusBadRequest, err.Error())
+		}
+
+		var existingChannel models.NotificationChannel
+		if result := db.First(&existingChannel, "id = ?", uuidID); result.Error != nil {
+			if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+				return response.Error(c, fiber.StatusNotFound, "Notification channel not found")
+			}
+			log.Printf("Error finding notification channel for update: %v", result.Error)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not update notification channel")
+		}
+
+		encryptedConfig, err := notifier.EncryptConfig(req.Config, cfg)
+		if err != nil {
+			log.Printf("Error encrypting notification channel config: %v", err)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not encrypt channel configuration")
+		}
+
+		existingChannel.Name = req.Name
+		existingChannel.Type = req.Type
+		existingChannel.Config = encryptedConfig
+		if req.IsEnabled != nil {
+			existingChannel.IsEnabled = *req.IsEnabled
+		}
+		existingChannel.UpdatedAt = time.Now()
+
+		if result := db.Save(&existingChannel); result.Error != nil {
+			log.Printf("Error updating notification channel: %v", result.Error)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not update notification channel")
+		}
+
+		return response.OK(c, toNotificationChannelResponse(existingChannel, cfg))
+	}
+}
+
+// DeleteNotificationChannel
+// @Summary Delete a notification channel
+// @Description Delete a notification channel by its ID
+// @Tags Notification Channels
+// @Produce json
+// @Param id path string true "Notification Channel ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} response.Response "Invalid notification channel ID"
+// @Failure 404 {object} response.Response "Notification channel not found"
+// @Failure 500 {object} response.Response "Could not delete notification channel"
+// @Security ApiKeyAuth
+// @Router /notification-channels/{id} [delete]
+func DeleteNotificationChannel(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uuidID, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return response.Fail(c, fiber.StatusBadRequest, response.CodeInvalidParam, "Invalid notification channel ID", nil)
+		}
+
+		if result := db.Delete(&models.NotificationChannel{}, "id = ?", uuidID); result.Error != nil {
+			log.Printf("Error deleting notification channel: %v", result.Error)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not delete notification channel")
+		} else if result.RowsAffected == 0 {
+			return response.Error(c, fiber.StatusNotFound, "Notification channel not found")
+		}
+
+		return c.Status(fiber.StatusNoContent).SendString("")
+	}
+}
+
+// TestNotificationChannel
+// @Summary Send a test notification
+// @Description Synchronously send a test message through a notification channel and report the provider result
+// @Tags Notification Channels
+// @Produce json
+// @Param id path string true "Notification Channel ID"
+// @Success 200 {object} response.Response "Test notification sent"
+// @Failure 400 {object} response.Response "Invalid notification channel ID"
+// @Failure 404 {object} response.Response "Notification channel not found"
+// @Failure 502 {object} response.Response "<provider error>"
+// @Security ApiKeyAuth
+// @Router /notification-channels/{id}/test [post]
+func TestNotificationChannel(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uuidID, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return response.Fail(c, fiber.StatusBadRequest, response.CodeInvalidParam, "Invalid notification channel ID", nil)
+		}
+
+		channel := models.NotificationChannel{}
+		if result := db.First(&channel, "id = ?", uuidID); result.Error != nil {
+			if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+				return response.Error(c, fiber.StatusNotFound, "Notification channel not found")
+			}
+			log.Printf("Error fetching notification channel: %v", result.Error)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not retrieve notification channel")
+		}
+
+		provider, err := notifier.FromChannel(channel, config.LoadConfig())
+		if err != nil {
+			return response.Error(c, fiber.StatusBadRequest, err.Error())
+		}
+
+		ctx, cancel := context.WithTimeout(c.Context(), 15*time.Second)
+		defer cancel()
+
+		msg := notifier.Message{
+			Subject: "Monitron test notification",
+			Body:    "This is a test notification from Monitron for channel \"" + channel.Name + "\".",
+		}
+		if err := provider.Send(ctx, msg); err != nil {
+			log.Printf("Error sending test notification to channel %s: %v", channel.ID, err)
+			return response.Error(c, fiber.StatusBadGateway, err.Error())
+		}
+
+		return response.Send(c, fiber.StatusOK, "Test notification sent", nil)
+	}
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"monitron-server/internal/notifier"
+	"monitron-server/models"
+	"monitron-server/utils/response"
+	"monitron-server/utils/validate"
+)
+
+// notificationTemplateView is the effective template for an event type and format
+type notificationTemplateView struct {
+	EventType string `json:"event_type"`
+	Format    string `json:"format"`
+	Subject   string `json:"subject"`
+	Body      string `json:"body"`
+	IsDefault bool   `json:"is_default"`
+}
+
+// GetNotificationTemplates
+// @Summary Get all notification templates
+// @Description Retrieve the effective template for every event type and format, marking which ones are built-in defaults
+// @Tags Notification Templates
+// @Produce json
+// @Success 200 {object} response.Response{data=[]notificationTemplateView}
+// @Failure 500 {object} response.Response "Could not retrieve notification templates"
+// @Security ApiKeyAuth
+// @Router /notification-templates [get]
+func GetNotificationTemplates(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		stored := []models.NotificationTemplate{}
+		if result := db.Find(&stored); result.Error != nil {
+			log.Printf("Error fetching notification templates: %v", result.Error)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not retrieve notification templates")
+		}
+
+		overrides := make(map[string]models.NotificationTemplate, len(stored))
+		for _, tmpl := range stored {
+			overrides[tmpl.EventType+"/"+tmpl.Format] = tmpl
+		}
+
+		views := []notificationTemplateView{}
+		for _, eventType := range notifier.EventTypes {
+			for _, format := range notifier.Formats {
+				if override, ok := overrides[eventType+"/"+format]; ok {
+					views = append(views, notificationTemplateView{EventType: eventType, Format: format, Subject: override.Subject, Body: override.Body})
+					continue
+				}
+				tmpl := notifier.DefaultTemplates[eventType][format]
+				views = append(views, notificationTemplateView{EventType: eventType, Format: format, Subject: tmpl.Subject, Body: tmpl.Body, IsDefault: true})
+			}
+		}
+
+		return response.OK(c, views)
+	}
+}
+
+// UpsertNotificationTemplate
+// @Summary Create or update a notification template
+// @Description Store a template override for an event type and format. The template is validated by rendering it with sample data.
+// @Tags Notification Templates
+// @Accept json
+// @Produce json
+// @Param eventType path string true "Event type"
+// @Param format path string true "Format (html, markdown, plain)"
+// @Param template body object{subject:string,body:string} true "Template subject and body"
+// @Success 200 {object} response.Response{data=models.NotificationTemplate}
+// @Failure 400 {object} response.Response "Cannot parse JSON" or template error
+// @Failure 500 {object} response.Response "Could not save notification template"
+// @Security ApiKeyAuth
+// @Router /notification-templates/{eventType}/{format} [put]
+func UpsertNotificationTemplate(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		req := struct {
+			Subject string `json:"subject"`
+			Body    string `json:"body"`
+		}{}
+		if err := c.BodyParser(&req); err != nil {
+			return response.InvalidBody(c)
+		}
+
+		tmpl := models.NotificationTemplate{}
+		err := db.First(&tmpl, "event_type = ? AND format = ?", c.Params("eventType"), c.Params("format")).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("Error finding notification template: %v", err)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not save notification template")
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			tmpl.ID = uuid.New()
+			tmpl.EventType = c.Params("eventType")
+			tmpl.Format = c.Params("format")
+			tmpl.CreatedAt = time.Now()
+		}
+		tmpl.Subject = req.Subject
+		tmpl.Body = req.Body
+		tmpl.UpdatedAt = time.Now()
+
+		if err := validate.V.Struct(tmpl); err != nil {
+			return response.Invalid(c, err)
+		}
+
+		if _, err := notifier.RenderTemplate(notifier.Template{Subject: tmpl.Subject, Body: tmpl.Body}, tmpl.Format, notifier.SampleTemplateData()); err != nil {
+			return response.Error(c, fiber.StatusBadRequest, err.Error())
+		}
+
+		if result := db.Save(&tmpl); result.Error != nil {
+			log.Printf("Error saving notification template: %v", result.Error)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not save notification template")
+		}
+
+		return response.OK(c, tmpl)
+	}
+}
+
+// DeleteNotificationTemplate
+// @Summary Reset a notification template
+// @Description Delete the stored override so the built-in default is used again
+// @Tags Notification Templates
+// @Produce json
+// @Param eventType path string true "Event type"
+// @Param format path string true "Format (html, markdown, plain)"
+// @Success 204 "No Content"
+// @Failure 404 {object} response.Response "Notification template not found"
+// @Failure 500 {object} response.Response "Could not delete notification template"
+// @Security ApiKeyAuth
+// @Router /notification-templates/{eventType}/{format} [delete]
+func DeleteNotificationTemplate(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		result := db.Where("event_type = ? AND format = ?", c.Params("eventType"), c.Params("format")).Delete(&models.NotificationTemplate{})
+		if result.Error != nil {
+			log.Printf("Error deleting notification template: %v", result.Error)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not delete notification template")
+		}
+
+		if result.RowsAffected == 0 {
+			return response.Error(c, fiber.StatusNotFound, "Notification template not found")
+		}
+
+		return c.Status(fiber.StatusNoContent).SendString("")
+	}
+}
+
+// PreviewNotificationTemplate
+// @Summary Preview a notification template
+// @Description Render a template with sample data. When subject and body are omitted, the stored or default template is rendered.
+// @Tags Notification Templates
+// @Accept json
+// @Produce json
+// @Param preview body object{event_type:string,format:string,subject:string,body:string} true "Template to preview"
+// @Success 200 {object} response.Response{data=notifier.Message}
+// @Failure 400 {object} response.Response "Cannot parse JSON" or template error
+// @Security ApiKeyAuth
+// @Router /notification-templates/preview [post]
+func PreviewNotificationTemplate(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		req := struct {
+			EventType string `json:"event_type" validate:"required,oneof=incident_opened incident_resolved cert_expiring report_ready password_reset status_subscription_confirm status_component_changed status_incident_updated"`
+			Format    string `json:"format" validate:"required,oneof=html markdown plain"`
+			Subject   string `json:"subject"`
+			Body      string `json:"body"`
+		}{}
+		if err := c.BodyParser(&req); err != nil {
+			return response.InvalidBody(c)
+		}
+
+		if err := validate.V.Struct(req); err != nil {
+			return response.Invalid(c, err)
+		}
+
+		tmpl := notifier.Template{Subject: req.Subject, Body: req.Body}
+		if req.Body == "" {
+			var err error
+			tmpl, err = notifier.LoadTemplate(db, req.EventType, req.Format)
+			if err != nil {
+				log.Printf("Error loading notification template for preview: %v", err)
+				return response.Error(c, fiber.StatusInternalServerError, "Could not load notification template")
+			}
+		}
+
+		msg, err := notifier.RenderTemplate(tmpl, req.Format, notifier.SampleTemplateData())
+		if err != nil {
+			return response.Error(c, fiber.StatusBadRequest, err.Error())
+		}
+
+		return response.OK(c, msg)
+	}
+}
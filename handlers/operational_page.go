@@ -1,14 +1,22 @@
 package handlers
 
 import (
+	"errors"
 	"log"
+	"strconv"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 
+	"monitron-server/config"
+	"monitron-server/internal/pagecomponents"
+	"monitron-server/internal/pagestats"
+	"monitron-server/internal/statuspage"
 	"monitron-server/models"
+	"monitron-server/utils/response"
+	"monitron-server/utils/validate"
 )
 
 // CreateOperationalPage handles the creation of a new operational page
@@ -16,20 +24,29 @@ func CreateOperationalPage(db *gorm.DB) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		page := new(models.OperationalPage)
 		if err := c.BodyParser(page); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON"})
+			return response.InvalidBody(c)
+		}
+
+		page.CustomDomains = statuspage.NormalizeDomains(page.CustomDomains)
+		if err := validate.V.Struct(page); err != nil {
+			return response.Invalid(c, err)
 		}
 
 		page.ID = uuid.New()
+		if msg, status := checkCustomDomains(db, page.CustomDomains, page.ID); msg != "" {
+			return response.Error(c, status, msg)
+		}
+
 		page.CreatedAt = time.Now()
 		page.UpdatedAt = time.Now()
 
 		err := db.Create(page).Error
 		if err != nil {
 			log.Printf("Error inserting operational page: %v", err)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not create operational page"})
+			return response.Error(c, fiber.StatusInternalServerError, "Could not create operational page")
 		}
 
-		return c.Status(fiber.StatusCreated).JSON(page)
+		return response.Created(c, page)
 	}
 }
 
@@ -40,10 +57,10 @@ func GetOperationalPages(db *gorm.DB) fiber.Handler {
 		err := db.Select(&pages, `SELECT * FROM operational_pages`)
 		if err != nil {
 			log.Printf("Error fetching operational pages: %v", err)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not retrieve operational pages"})
+			return response.Error(c, fiber.StatusInternalServerError, "Could not retrieve operational pages")
 		}
 
-		return c.JSON(pages)
+		return response.OK(c, pages)
 	}
 }
 
@@ -56,10 +73,10 @@ func GetOperationalPage(db *gorm.DB) fiber.Handler {
 		err := db.Where("id = ? OR slug = ?", idOrSlug, idOrSlug).Error
 		if err != nil {
 			log.Printf("Error fetching operational page: %v", err)
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Operational page not found"})
+			return response.Error(c, fiber.StatusNotFound, "Operational page not found")
 		}
 
-		return c.JSON(page)
+		return response.OK(c, page)
 	}
 }
 
@@ -70,22 +87,47 @@ func UpdateOperationalPage(db *gorm.DB) fiber.Handler {
 
 		page := new(models.OperationalPage)
 		if err := c.BodyParser(page); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON"})
+			return response.InvalidBody(c)
+		}
+
+		page.CustomDomains = statuspage.NormalizeDomains(page.CustomDomains)
+		if err := validate.V.Struct(page); err != nil {
+			return response.Invalid(c, err)
 		}
 
+		existing, err := findOperationalPage(db, idOrSlug)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return response.Error(c, fiber.StatusNotFound, "Operational page not found")
+		}
+		if err != nil {
+			log.Printf("Error fetching operational page: %v", err)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not update operational page")
+		}
+
+		if msg, status := checkCustomDomains(db, page.CustomDomains, existing.ID); msg != "" {
+			return response.Error(c, status, msg)
+		}
+
+		page.ID = existing.ID
+		page.CreatedAt = existing.CreatedAt
 		page.UpdatedAt = time.Now()
 
-		result := db.Where("id = ? OR slug = ?", idOrSlug, idOrSlug).Updates(page)
+		// Selecting the editable columns also writes their zero values, so
+		// branding such as the custom CSS or logo can be cleared
+		result := db.Model(&models.OperationalPage{}).Where("id = ?", existing.ID).
+			Select("slug", "name", "description", "is_public", "report_reasons", "custom_domains",
+				"logo_url", "favicon_url", "color_theme", "custom_css", "footer_text", "updated_at").
+			Updates(page)
 		if result.Error != nil {
 			log.Printf("Error updating operational page: %v", result.Error)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not update operational page"})
+			return response.Error(c, fiber.StatusInternalServerError, "Could not update operational page")
 		}
 
 		if result.RowsAffected == 0 {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Operational page not found"})
+			return response.Error(c, fiber.StatusNotFound, "Operational page not found")
 		}
 
-		return c.JSON(page)
+		return response.OK(c, page)
 	}
 }
 
@@ -97,11 +139,11 @@ func DeleteOperationalPage(db *gorm.DB) fiber.Handler {
 		result := db.Where("id = ? OR slug = ?", idOrSlug, idOrSlug).Delete(&models.OperationalPage{})
 		if result.Error != nil {
 			log.Printf("Error deleting operational page: %v", result.Error)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not delete operational page"})
+			return response.Error(c, fiber.StatusInternalServerError, "Could not delete operational page")
 		}
 
 		if result.RowsAffected == 0 {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Operational page not found"})
+			return response.Error(c, fiber.StatusNotFound, "Operational page not found")
 		}
 
 		return c.Status(fiber.StatusNoContent).SendString("")
@@ -114,26 +156,57 @@ func AddComponentToOperationalPage(db *gorm.DB) fiber.Handler {
 		pageID := c.Params("pageID")
 		uuidPageID, err := uuid.Parse(pageID)
 		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid page ID"})
+			return response.Fail(c, fiber.StatusBadRequest, response.CodeInvalidParam, "Invalid page ID", nil)
 		}
 
 		component := new(models.OperationalPageComponent)
 		if err := c.BodyParser(component); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON"})
+			return response.InvalidBody(c)
+		}
+
+		if err := validate.V.Struct(component); err != nil {
+			return response.Invalid(c, err)
+		}
+
+		if component.Weight < 0 {
+			return response.Error(c, fiber.StatusBadRequest, "Weight must not be negative")
+		}
+		if component.Weight == 0 {
+			component.Weight = 1
 		}
 
 		component.ID = uuid.New()
 		component.PageID = uuidPageID
 		component.CreatedAt = time.Now()
 		component.UpdatedAt = time.Now()
+		if component.ComponentType == pagecomponents.TypeGroup {
+			component.ComponentID = component.ID
+		}
+
+		err = pagecomponents.Validate(db, *component)
+		switch {
+		case errors.Is(err, pagecomponents.ErrUnknownTarget):
+			return response.Error(c, fiber.StatusNotFound, "No "+component.ComponentType+" with this ID exists")
+		case errors.Is(err, pagecomponents.ErrAlreadyOnPage):
+			return response.Error(c, fiber.StatusConflict, err.Error())
+		case errors.Is(err, pagecomponents.ErrInvalidParent), errors.Is(err, pagecomponents.ErrNestedGroup):
+			return response.Error(c, fiber.StatusBadRequest, err.Error())
+		case err != nil:
+			log.Printf("Error validating operational page component: %v", err)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not add component to operational page")
+		}
 
-		err = db.Create(&component).Error
+		err = db.Create(component).Error
 		if err != nil {
 			log.Printf("Error adding component to operational page: %v", err)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not add component to operational page"})
+			return response.Error(c, fiber.StatusInternalServerError, "Could not add component to operational page")
+		}
+
+		if _, err := pagestats.Refresh(db, uuidPageID, time.Now()); err != nil {
+			log.Printf("Error refreshing operational page stats: %v", err)
 		}
 
-		return c.Status(fiber.StatusCreated).JSON(component)
+		return response.Created(c, component)
 	}
 }
 
@@ -143,17 +216,17 @@ func GetComponentsForOperationalPage(db *gorm.DB) fiber.Handler {
 		pageID := c.Params("pageID")
 		uuidPageID, err := uuid.Parse(pageID)
 		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid page ID"})
+			return response.Fail(c, fiber.StatusBadRequest, response.CodeInvalidParam, "Invalid page ID", nil)
 		}
 
 		components := []models.OperationalPageComponent{}
 		err = db.Where("page_id = ?", uuidPageID).Order("display_order ASC").Find(&components).Error
 		if err != nil {
 			log.Printf("Error fetching components for operational page: %v", err)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not retrieve components"})
+			return response.Error(c, fiber.StatusInternalServerError, "Could not retrieve components")
 		}
 
-		return c.JSON(components)
+		return response.OK(c, components)
 	}
 }
 
@@ -165,24 +238,233 @@ func RemoveComponentFromOperationalPage(db *gorm.DB) fiber.Handler {
 
 		uuidPageID, err := uuid.Parse(pageID)
 		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid page ID"})
+			return response.Fail(c, fiber.StatusBadRequest, response.CodeInvalidParam, "Invalid page ID", nil)
 		}
 		uuidComponentID, err := uuid.Parse(componentID)
 		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid component ID"})
+			return response.Fail(c, fiber.StatusBadRequest, response.CodeInvalidParam, "Invalid component ID", nil)
 		}
 
 		// result, err := db.Exec(`DELETE FROM operational_page_components WHERE page_id = $1 AND id = $2`, uuidPageID, uuidComponentID)
 		result := db.Where("page_id = ? AND id = ?", uuidPageID, uuidComponentID).Delete(&models.OperationalPageComponent{})
 		if result.Error != nil {
 			log.Printf("Error removing component from operational page: %v", result.Error)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not remove component"})
+			return response.Error(c, fiber.StatusInternalServerError, "Could not remove component")
 		}
 
 		if result.RowsAffected == 0 {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Component not found on this page"})
+			return response.Error(c, fiber.StatusNotFound, "Component not found on this page")
+		}
+
+		if _, err := pagestats.Refresh(db, uuidPageID, time.Now()); err != nil {
+			log.Printf("Error refreshing operational page stats: %v", err)
 		}
 
 		return c.Status(fiber.StatusNoContent).SendString("")
 	}
 }
+
+// ReorderOperationalPageComponents
+// @Summary Reorder operational page components
+// @Description Set the display order and group of several components of an operational page at once. Each item gives a component's new display_order and parent_id, the group it is shown in or null for the top level. Components that are not listed keep their place.
+// @Tags Operational Pages
+// @Accept json
+// @Produce json
+// @Param pageID path string true "Operational Page ID"
+// @Param order body []pagecomponents.OrderItem true "New positions"
+// @Success 200 {object} response.Response{data=[]models.OperationalPageComponent}
+// @Failure 400 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Security ApiKeyAuth
+// @Router /operational-pages/{pageID}/components/order [put]
+func ReorderOperationalPageComponents(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uuidPageID, err := uuid.Parse(c.Params("pageID"))
+		if err != nil {
+			return response.Fail(c, fiber.StatusBadRequest, response.CodeInvalidParam, "Invalid page ID", nil)
+		}
+
+		items := []pagecomponents.OrderItem{}
+		if err := c.BodyParser(&items); err != nil {
+			return response.InvalidBody(c)
+		}
+		if len(items) == 0 {
+			return response.Error(c, fiber.StatusBadRequest, "No components to reorder")
+		}
+
+		err = pagecomponents.Reorder(db, uuidPageID, items)
+		switch {
+		case errors.Is(err, pagecomponents.ErrUnknownOnPage), errors.Is(err, pagecomponents.ErrDuplicateInList),
+			errors.Is(err, pagecomponents.ErrInvalidParent), errors.Is(err, pagecomponents.ErrNestedGroup):
+			return response.Error(c, fiber.StatusBadRequest, err.Error())
+		case err != nil:
+			log.Printf("Error reordering operational page components: %v", err)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not reorder components")
+		}
+
+		components := []models.OperationalPageComponent{}
+		if err := db.Where("page_id = ?", uuidPageID).Order("display_order ASC").Find(&components).Error; err != nil {
+			log.Printf("Error fetching components for operational page: %v", err)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not retrieve components")
+		}
+		return response.OK(c, components)
+	}
+}
+
+// GetOperationalPageStats
+// @Summary Operational page stats
+// @Description Aggregated stats of an operational page over the last 30 UTC days: component-weighted overall uptime and average response time, incident count and the daily uptime history of the page and each component. Stats are computed on first request and refreshed periodically and on incident changes. Private pages require a JWT.
+// @Tags Operational Pages
+// @Produce json
+// @Param idOrSlug path string true "Operational page ID or slug"
+// @Success 200 {object} response.Response{data=models.OperationalPageStats}
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /operational-pages/{idOrSlug}/stats [get]
+func GetOperationalPageStats(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		idOrSlug := c.Params("idOrSlug")
+
+		page, err := findOperationalPage(db, idOrSlug)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return response.Error(c, fiber.StatusNotFound, "Operational page not found")
+		}
+		if err != nil {
+			log.Printf("Error fetching operational page: %v", err)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not retrieve operational page")
+		}
+
+		if !page.IsPublic && c.Locals("user_id") == nil {
+			return response.Error(c, fiber.StatusUnauthorized, "Authentication required")
+		}
+
+		stats := models.OperationalPageStats{}
+		err = db.Where("page_id = ?", page.ID).First(&stats).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			stats, err = pagestats.Refresh(db, page.ID, time.Now())
+		}
+		if err != nil {
+			log.Printf("Error fetching operational page stats: %v", err)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not retrieve operational page stats")
+		}
+
+		return response.OK(c, stats)
+	}
+}
+
+// GetProblemReports
+// @Summary Problem reports of an operational page (Admin Only)
+// @Description Lists visitor problem reports of an operational page, newest first, with the current "users are reporting problems" signal and report counts per reason in the selected period.
+// @Tags Operational Pages
+// @Produce json
+// @Param idOrSlug path string true "Operational page ID or slug"
+// @Param since query string false "RFC 3339 start of the period, defaults to 7 days ago"
+// @Param limit query int false "Maximum number of reports, 1 to 1000, defaults to 100"
+// @Success 200 {object} response.Response{data=map[string]interface{}}
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Security ApiKeyAuth
+// @Router /operational-pages/{idOrSlug}/problem-reports [get]
+func GetProblemReports(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		since := time.Now().AddDate(0, 0, -7)
+		if value := c.Query("since"); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return response.Fail(c, fiber.StatusBadRequest, response.CodeInvalidParam, "Invalid since, expected RFC 3339", nil)
+			}
+			since = parsed
+		}
+		limit := 100
+		if value := c.Query("limit"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 1 || parsed > 1000 {
+				return response.Error(c, fiber.StatusBadRequest, "Limit must be between 1 and 1000")
+			}
+			limit = parsed
+		}
+
+		page, err := findOperationalPage(db, c.Params("idOrSlug"))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return response.Error(c, fiber.StatusNotFound, "Operational page not found")
+		}
+		if err != nil {
+			log.Printf("Error fetching operational page: %v", err)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not retrieve operational page")
+		}
+
+		reports := []models.ProblemReport{}
+		err = db.Where("page_id = ? AND created_at >= ?", page.ID, since).
+			Order("created_at DESC").Limit(limit).Find(&reports).Error
+		if err != nil {
+			log.Printf("Error fetching problem reports: %v", err)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not retrieve problem reports")
+		}
+
+		reasons := []struct {
+			Reason string `json:"reason"`
+			Count  int64  `json:"count"`
+		}{}
+		err = db.Model(&models.ProblemReport{}).
+			Select("reason, COUNT(*) AS count").
+			Where("page_id = ? AND created_at >= ?", page.ID, since).
+			Group("reason").Order("count DESC, reason").
+			Scan(&reasons).Error
+		if err != nil {
+			log.Printf("Error aggregating problem reports: %v", err)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not retrieve problem reports")
+		}
+
+		signal, err := statuspage.Signal(db, page.ID, time.Now())
+		if err != nil {
+			log.Printf("Error computing problem report signal: %v", err)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not retrieve problem reports")
+		}
+
+		return response.OK(c, fiber.Map{"signal": signal, "reasons": reasons, "reports": reports})
+	}
+}
+
+// removeTargetComponents removes the page components showing a deleted
+// monitored target and refreshes the stats of the pages they were on
+func removeTargetComponents(db *gorm.DB, targetType string, targetID uuid.UUID) {
+	pageIDs, err := pagecomponents.RemoveTarget(db, targetType, targetID)
+	if err != nil {
+		log.Printf("Error removing page components of %s %s: %v", targetType, targetID, err)
+		return
+	}
+	for _, pageID := range pageIDs {
+		if _, err := pagestats.Refresh(db, pageID, time.Now()); err != nil {
+			log.Printf("Error refreshing operational page stats: %v", err)
+		}
+	}
+}
+
+// checkCustomDomains checks that a page with pageID may serve domains. It
+// returns a client error message and status, or "" if they are free.
+func checkCustomDomains(db *gorm.DB, domains []string, pageID uuid.UUID) (string, int) {
+	if domain := statuspage.ReservedDomain(config.LoadConfig(), domains); domain != "" {
+		return "Custom domain " + domain + " is the host of this server", fiber.StatusBadRequest
+	}
+
+	domain, err := statuspage.DomainInUse(db, domains, pageID)
+	if err != nil {
+		log.Printf("Error checking custom domains: %v", err)
+		return "Could not check custom domains", fiber.StatusInternalServerError
+	}
+	if domain != "" {
+		return "Custom domain " + domain + " is already used by another page", fiber.StatusConflict
+	}
+	return "", 0
+}
+
+// findOperationalPage loads an operational page by ID or slug
+func findOperationalPage(db *gorm.DB, idOrSlug string) (models.OperationalPage, error) {
+	page := models.OperationalPage{}
+	query := db.Where("slug = ?", idOrSlug)
+	if id, err := uuid.Parse(idOrSlug); err == nil {
+		query = db.Where("id = ? OR slug = ?", id, idOrSlug)
+	}
+	err := query.First(&page).Error
+	return page, err
+}
@@ -0,0 +1,636 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"monitron-server/internal/pagesubscribers"
+	"monitron-server/internal/statuspage"
+	"monitron-server/models"
+	"monitron-server/utils/response"
+	"monitron-server/utils/validate"
+)
+
+// pageIncidentRequest is the body of create requests. Message becomes the
+// first update of the incident.
+type pageIncidentRequest struct {
+	models.PageIncident
+	Message string `json:"message" validate:"required"`
+}
+
+// pageIncidentResponse is a page incident with its updates, newest first
+type pageIncidentResponse struct {
+	models.PageIncident
+	Updates []models.PageIncidentUpdate `json:"updates"`
+}
+
+// GetPageIncidents
+// @Summary Get incidents of an operational page
+// @Description Retrieve the staff-written incidents of an operational page with their updates, newest first. Filter with status=active or status=resolved. Private pages require a JWT.
+// @Tags Operational Pages
+// @Produce json
+// @Param pageID path string true "Operational Page ID"
+// @Param status query string false "active or resolved"
+// @Success 200 {object} response.Response{data=[]pageIncidentResponse}
+// @Failure 400 {object} response.Response "Invalid page ID"
+// @Failure 404 {object} response.Response "Operational page not found"
+// @Failure 500 {object} response.Response "Could not retrieve incidents"
+// @Router /operational-pages/{pageID}/incidents [get]
+func GetPageIncidents(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		page, err := findReadablePage(c, db)
+		if page == nil {
+			return err
+		}
+
+		query := db.Where("page_id = ?", page.ID).Order("started_at DESC")
+		switch c.Query("status") {
+		case "":
+		case "active":
+			query = query.Where("status <> ?", statuspage.IncidentResolved)
+		case "resolved":
+			query = query.Where("status = ?", statuspage.IncidentResolved)
+		default:
+			return response.Error(c, fiber.StatusBadRequest, "Status must be active or resolved")
+		}
+
+		incidents := []models.PageIncident{}
+		if err := query.Find(&incidents).Error; err != nil {
+			log.Printf("Error fetching page incidents: %v", err)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not retrieve incidents")
+		}
+
+		views, err := withPageIncidentUpdates(db, incidents)
+		if err != nil {
+			log.Printf("Error fetching page incident updates: %v", err)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not retrieve incidents")
+		}
+		return response.OK(c, views)
+	}
+}
+
+// GetPageIncident
+// @Summary Get an incident of an operational page
+// @Description Retrieve a staff-written incident with its updates. Private pages require a JWT.
+// @Tags Operational Pages
+// @Produce json
+// @Param pageID path string true "Operational Page ID"
+// @Param incidentID path string true "Incident ID"
+// @Success 200 {object} response.Response{data=pageIncidentResponse}
+// @Failure 400 {object} response.Response "Invalid incident ID"
+// @Failure 404 {object} response.Response "Incident not found"
+// @Failure 500 {object} response.Response "Could not retrieve incident"
+// @Router /operational-pages/{pageID}/incidents/{incidentID} [get]
+func GetPageIncident(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		page, err := findReadablePage(c, db)
+		if page == nil {
+			return err
+		}
+
+		incident, err := findPageIncident(c, db, page.ID)
+		if incident == nil {
+			return err
+		}
+
+		views, err := withPageIncidentUpdates(db, []models.PageIncident{*incident})
+		if err != nil {
+			log.Printf("Error fetching page incident updates: %v", err)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not retrieve incident")
+		}
+		return response.OK(c, views[0])
+	}
+}
+
+// CreatePageIncident
+// @Summary Create an incident on an operational page (Admin Only)
+// @Description Publish an incident with a status (investigating, identified, monitoring or resolved), an impact (none, minor, major or critical), the affected page components and a first message.
+// @Tags Operational Pages
+// @Accept json
+// @Produce json
+// @Param pageID path string true "Operational Page ID"
+// @Param incident body pageIncidentRequest true "Incident to create"
+// @Success 201 {object} response.Response{data=pageIncidentResponse}
+// @Failure 400 {object} response.Response "Cannot parse JSON"
+// @Failure 404 {object} response.Response "Operational page not found"
+// @Failure 500 {object} response.Response "Could not create incident"
+// @Security ApiKeyAuth
+// @Router /operational-pages/{pageID}/incidents [post]
+func CreatePageIncident(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		page, err := findReadablePage(c, db)
+		if page == nil {
+			return err
+		}
+
+		req := new(pageIncidentRequest)
+		if err := c.BodyParser(req); err != nil {
+			return response.InvalidBody(c)
+		}
+
+		if err := validate.V.Struct(req); err != nil {
+			return response.Invalid(c, err)
+		}
+		if msg := checkPageComponents(db, page.ID, req.ComponentIDs); msg != "" {
+			return response.Error(c, fiber.StatusBadRequest, msg)
+		}
+
+		now := time.Now()
+		userID := c.Locals("user_id").(uuid.UUID)
+		incident := req.PageIncident
+		incident.ID = uuid.New()
+		incident.PageID = page.ID
+		incident.CreatedBy = userID
+		incident.StartedAt = now
+		incident.ResolvedAt = nil
+		if incident.Status == statuspage.IncidentResolved {
+			incident.ResolvedAt = &now
+		}
+		incident.CreatedAt = now
+		incident.UpdatedAt = now
+
+		update := models.PageIncidentUpdate{
+			ID:         uuid.New(),
+			IncidentID: incident.ID,
+			Status:     incident.Status,
+			Message:    req.Message,
+			CreatedBy:  userID,
+			CreatedAt:  now,
+		}
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&incident).Error; err != nil {
+				return err
+			}
+			return tx.Create(&update).Error
+		})
+		if err != nil {
+			log.Printf("Error creating page incident: %v", err)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not create incident")
+		}
+
+		if err := pagesubscribers.NotifyIncident(db, incident, update); err != nil {
+			log.Printf("Error notifying subscribers of page incident %s: %v", incident.ID, err)
+		}
+
+		return response.Created(c, pageIncidentResponse{PageIncident: incident, Updates: []models.PageIncidentUpdate{update}})
+	}
+}
+
+// UpdatePageIncident
+// @Summary Update an incident of an operational page (Admin Only)
+// @Description Change the title, impact and affected components of an incident. The status only changes by posting an update.
+// @Tags Operational Pages
+// @Accept json
+// @Produce json
+// @Param pageID path string true "Operational Page ID"
+// @Param incidentID path string true "Incident ID"
+// @Param incident body models.PageIncident true "Incident with updated fields"
+// @Success 200 {object} response.Response{data=models.PageIncident}
+// @Failure 400 {object} response.Response "Cannot parse JSON"
+// @Failure 404 {object} response.Response "Incident not found"
+// @Failure 500 {object} response.Response "Could not update incident"
+// @Security ApiKeyAuth
+// @Router /operational-pages/{pageID}/incidents/{incidentID} [put]
+func UpdatePageIncident(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		page, err := findReadablePage(c, db)
+		if page == nil {
+			return err
+		}
+
+		incident, err := findPageIncident(c, db, page.ID)
+		if incident == nil {
+			return err
+		}
+
+		req := new(models.PageIncident)
+		if err := c.BodyParser(req); err != nil {
+			return response.InvalidBody(c)
+		}
+
+		req.Status = incident.Status
+		if err := validate.V.Struct(req); err != nil {
+			return response.Invalid(c, err)
+		}
+		if msg := checkPageComponents(db, page.ID, req.ComponentIDs); msg != "" {
+			return response.Error(c, fiber.StatusBadRequest, msg)
+		}
+
+		incident.Title = req.Title
+		incident.Impact = req.Impact
+		incident.ComponentIDs = req.ComponentIDs
+		incident.UpdatedAt = time.Now()
+
+		if result := db.Save(incident); result.Error != nil {
+			log.Printf("Error updating page incident: %v", result.Error)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not update incident")
+		}
+
+		return response.OK(c, incident)
+	}
+}
+
+// DeletePageIncident
+// @Summary Delete an incident of an operational page (Admin Only)
+// @Description Delete an incident and its updates
+// @Tags Operational Pages
+// @Param pageID path string true "Operational Page ID"
+// @Param incidentID path string true "Incident ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} response.Response "Invalid incident ID"
+// @Failure 404 {object} response.Response "Incident not found"
+// @Failure 500 {object} response.Response "Could not delete incident"
+// @Security ApiKeyAuth
+// @Router /operational-pages/{pageID}/incidents/{incidentID} [delete]
+func DeletePageIncident(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		page, err := findReadablePage(c, db)
+		if page == nil {
+			return err
+		}
+
+		incident, err := findPageIncident(c, db, page.ID)
+		if incident == nil {
+			return err
+		}
+
+		if result := db.Delete(incident); result.Error != nil {
+			log.Printf("Error deleting page incident: %v", result.Error)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not delete incident")
+		}
+
+		return c.Status(fiber.StatusNoContent).SendString("")
+	}
+}
+
+// AddPageIncidentUpdate
+// @Summary Post an update on an incident (Admin Only)
+// @Description Add a timestamped message to an incident and move it to the given status. Resolving sets the resolution time; any other status reopens a resolved incident.
+// @Tags Operational Pages
+// @Accept json
+// @Produce json
+// @Param pageID path string true "Operational Page ID"
+// @Param incidentID path string true "Incident ID"
+// @Param update body models.PageIncidentUpdate true "Update to post"
+// @Success 201 {object} response.Response{data=pageIncidentResponse}
+// @Failure 400 {object} response.Response "Cannot parse JSON"
+// @Failure 404 {object} response.Response "Incident not found"
+// @Failure 500 {object} response.Response "Could not post update"
+// @Security ApiKeyAuth
+// @Router /operational-pages/{pageID}/incidents/{incidentID}/updates [post]
+func AddPageIncidentUpdate(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		page, err := findReadablePage(c, db)
+		if page == nil {
+			return err
+		}
+
+		incident, err := findPageIncident(c, db, page.ID)
+		if incident == nil {
+			return err
+		}
+
+		update := new(models.PageIncidentUpdate)
+		if err := c.BodyParser(update); err != nil {
+			return response.InvalidBody(c)
+		}
+
+		if err := validate.V.Struct(update); err != nil {
+			return response.Invalid(c, err)
+		}
+
+		now := time.Now()
+		update.ID = uuid.New()
+		update.IncidentID = incident.ID
+		update.CreatedBy = c.Locals("user_id").(uuid.UUID)
+		update.CreatedAt = now
+
+		incident.Status = update.Status
+		incident.ResolvedAt = nil
+		if update.Status == statuspage.IncidentResolved {
+			incident.ResolvedAt = &now
+		}
+		incident.UpdatedAt = now
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(update).Error; err != nil {
+				return err
+			}
+			return tx.Save(incident).Error
+		})
+		if err != nil {
+			log.Printf("Error posting page incident update: %v", err)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not post update")
+		}
+
+		if err := pagesubscribers.NotifyIncident(db, *incident, *update); err != nil {
+			log.Printf("Error notifying subscribers of page incident %s: %v", incident.ID, err)
+		}
+
+		views, err := withPageIncidentUpdates(db, []models.PageIncident{*incident})
+		if err != nil {
+			log.Printf("Error fetching page incident updates: %v", err)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not retrieve incident")
+		}
+		return response.Created(c, views[0])
+	}
+}
+
+// GetPageMaintenances
+// @Summary Get maintenance announcements of an operational page
+// @Description Retrieve the scheduled maintenance windows of an operational page, latest first. Pass upcoming=true for windows that have not ended yet. Private pages require a JWT.
+// @Tags Operational Pages
+// @Produce json
+// @Param pageID path string true "Operational Page ID"
+// @Param upcoming query bool false "Only windows that have not ended"
+// @Success 200 {object} response.Response{data=[]models.PageMaintenance}
+// @Failure 400 {object} response.Response "Invalid page ID"
+// @Failure 404 {object} response.Response "Operational page not found"
+// @Failure 500 {object} response.Response "Could not retrieve maintenance"
+// @Router /operational-pages/{pageID}/maintenance [get]
+func GetPageMaintenances(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		page, err := findReadablePage(c, db)
+		if page == nil {
+			return err
+		}
+
+		query := db.Where("page_id = ?", page.ID).Order("starts_at DESC")
+		if c.QueryBool("upcoming") {
+			query = query.Where("ends_at >= ?", time.Now())
+		}
+
+		maintenances := []models.PageMaintenance{}
+		if err := query.Find(&maintenances).Error; err != nil {
+			log.Printf("Error fetching page maintenance: %v", err)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not retrieve maintenance")
+		}
+		return response.OK(c, maintenances)
+	}
+}
+
+// CreatePageMaintenance
+// @Summary Announce maintenance on an operational page (Admin Only)
+// @Description Announce a scheduled maintenance window with the affected page components
+// @Tags Operational Pages
+// @Accept json
+// @Produce json
+// @Param pageID path string true "Operational Page ID"
+// @Param maintenance body models.PageMaintenance true "Maintenance to announce"
+// @Success 201 {object} response.Response{data=models.PageMaintenance}
+// @Failure 400 {object} response.Response "Cannot parse JSON"
+// @Failure 404 {object} response.Response "Operational page not found"
+// @Failure 500 {object} response.Response "Could not create maintenance"
+// @Security ApiKeyAuth
+// @Router /operational-pages/{pageID}/maintenance [post]
+func CreatePageMaintenance(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		page, err := findReadablePage(c, db)
+		if page == nil {
+			return err
+		}
+
+		maintenance := new(models.PageMaintenance)
+		if err := c.BodyParser(maintenance); err != nil {
+			return response.InvalidBody(c)
+		}
+
+		if err := validate.V.Struct(maintenance); err != nil {
+			return response.Invalid(c, err)
+		}
+		if msg := checkPageComponents(db, page.ID, maintenance.ComponentIDs); msg != "" {
+			return response.Error(c, fiber.StatusBadRequest, msg)
+		}
+
+		maintenance.ID = uuid.New()
+		maintenance.PageID = page.ID
+		maintenance.CreatedBy = c.Locals("user_id").(uuid.UUID)
+		maintenance.CreatedAt = time.Now()
+		maintenance.UpdatedAt = time.Now()
+
+		if result := db.Create(maintenance); result.Error != nil {
+			log.Printf("Error creating page maintenance: %v", result.Error)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not create maintenance")
+		}
+
+		return response.Created(c, maintenance)
+	}
+}
+
+// UpdatePageMaintenance
+// @Summary Update a maintenance announcement (Admin Only)
+// @Description Replace the title, description, window and affected components of a maintenance announcement
+// @Tags Operational Pages
+// @Accept json
+// @Produce json
+// @Param pageID path string true "Operational Page ID"
+// @Param maintenanceID path string true "Maintenance ID"
+// @Param maintenance body models.PageMaintenance true "Maintenance with updated fields"
+// @Success 200 {object} response.Response{data=models.PageMaintenance}
+// @Failure 400 {object} response.Response "Cannot parse JSON"
+// @Failure 404 {object} response.Response "Maintenance not found"
+// @Failure 500 {object} response.Response "Could not update maintenance"
+// @Security ApiKeyAuth
+// @Router /operational-pages/{pageID}/maintenance/{maintenanceID} [put]
+func UpdatePageMaintenance(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		page, err := findReadablePage(c, db)
+		if page == nil {
+			return err
+		}
+
+		existing, err := findPageMaintenance(c, db, page.ID)
+		if existing == nil {
+			return err
+		}
+
+		maintenance := new(models.PageMaintenance)
+		if err := c.BodyParser(maintenance); err != nil {
+			return response.InvalidBody(c)
+		}
+
+		if err := validate.V.Struct(maintenance); err != nil {
+			return response.Invalid(c, err)
+		}
+		if msg := checkPageComponents(db, page.ID, maintenance.ComponentIDs); msg != "" {
+			return response.Error(c, fiber.StatusBadRequest, msg)
+		}
+
+		maintenance.ID = existing.ID
+		maintenance.PageID = existing.PageID
+		maintenance.CreatedBy = existing.CreatedBy
+		maintenance.CreatedAt = existing.CreatedAt
+		maintenance.UpdatedAt = time.Now()
+
+		if result := db.Save(maintenance); result.Error != nil {
+			log.Printf("Error updating page maintenance: %v", result.Error)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not update maintenance")
+		}
+
+		return response.OK(c, maintenance)
+	}
+}
+
+// DeletePageMaintenance
+// @Summary Delete a maintenance announcement (Admin Only)
+// @Description Delete a maintenance announcement by its ID
+// @Tags Operational Pages
+// @Param pageID path string true "Operational Page ID"
+// @Param maintenanceID path string true "Maintenance ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} response.Response "Invalid maintenance ID"
+// @Failure 404 {object} response.Response "Maintenance not found"
+// @Failure 500 {object} response.Response "Could not delete maintenance"
+// @Security ApiKeyAuth
+// @Router /operational-pages/{pageID}/maintenance/{maintenanceID} [delete]
+func DeletePageMaintenance(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		page, err := findReadablePage(c, db)
+		if page == nil {
+			return err
+		}
+
+		maintenance, err := findPageMaintenance(c, db, page.ID)
+		if maintenance == nil {
+			return err
+		}
+
+		if result := db.Delete(maintenance); result.Error != nil {
+			log.Printf("Error deleting page maintenance: %v", result.Error)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not delete maintenance")
+		}
+
+		return c.Status(fiber.StatusNoContent).SendString("")
+	}
+}
+
+// findReadablePage loads the operational page of the request and checks that
+// a private page is only read by signed-in users. It writes the error
+// response itself and returns a nil page when the request cannot continue.
+func findReadablePage(c *fiber.Ctx, db *gorm.DB) (*models.OperationalPage, error) {
+	uuidPageID, err := uuid.Parse(c.Params("pageID"))
+	if err != nil {
+		return nil, response.Fail(c, fiber.StatusBadRequest, response.CodeInvalidParam, "Invalid page ID", nil)
+	}
+
+	page := models.OperationalPage{}
+	if result := db.First(&page, "id = ?", uuidPageID); result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, response.Error(c, fiber.StatusNotFound, "Operational page not found")
+		}
+		log.Printf("Error fetching operational page: %v", result.Error)
+		return nil, response.Error(c, fiber.StatusInternalServerError, "Could not retrieve operational page")
+	}
+
+	if !page.IsPublic && c.Locals("user_id") == nil {
+		return nil, response.Error(c, fiber.StatusUnauthorized, "Authentication required")
+	}
+	return &page, nil
+}
+
+// findPageIncident loads the incident of the request on a page, writing the
+// error response itself when it cannot
+func findPageIncident(c *fiber.Ctx, db *gorm.DB, pageID uuid.UUID) (*models.PageIncident, error) {
+	uuidID, err := uuid.Parse(c.Params("incidentID"))
+	if err != nil {
+		return nil, response.Fail(c, fiber.StatusBadRequest, response.CodeInvalidParam, "Invalid incident ID", nil)
+	}
+
+	incident := models.PageIncident{}
+	if result := db.First(&incident, "id = ? AND page_id = ?", uuidID, pageID); result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, response.Error(c, fiber.StatusNotFound, "Incident not found")
+		}
+		log.Printf("Error fetching page incident: %v", result.Error)
+		return nil, response.Error(c, fiber.StatusInternalServerError, "Could not retrieve incident")
+	}
+	return &incident, nil
+}
+
+// findPageMaintenance loads the maintenance announcement of the request on a
+// page, writing the error response itself when it cannot
+func findPageMaintenance(c *fiber.Ctx, db *gorm.DB, pageID uuid.UUID) (*models.PageMaintenance, error) {
+	uuidID, err := uuid.Parse(c.Params("maintenanceID"))
+	if err != nil {
+		return nil, response.Fail(c, fiber.StatusBadRequest, response.CodeInvalidParam, "Invalid maintenance ID", nil)
+	}
+
+	maintenance := models.PageMaintenance{}
+	if result := db.First(&maintenance, "id = ? AND page_id = ?", uuidID, pageID); result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, response.Error(c, fiber.StatusNotFound, "Maintenance not found")
+		}
+		log.Printf("Error fetching page maintenance: %v", result.Error)
+		return nil, response.Error(c, fiber.StatusInternalServerError, "Could not retrieve maintenance")
+	}
+	return &maintenance, nil
+}
+
+// checkPageComponents checks that component IDs belong to a page. It returns
+// a message on failure.
+func checkPageComponents(db *gorm.DB, pageID uuid.UUID, ids []string) string {
+	if len(ids) == 0 {
+		return ""
+	}
+
+	var count int64
+	err := db.Model(&models.OperationalPageComponent{}).
+		Where("page_id = ? AND id IN ?", pageID, ids).
+		Count(&count).Error
+	if err != nil {
+		log.Printf("Error checking page components: %v", err)
+		return "Could not check components"
+	}
+	if int(count) != len(uniqueStrings(ids)) {
+		return "Components must belong to this page"
+	}
+	return ""
+}
+
+// withPageIncidentUpdates attaches the updates of each incident, newest first
+func withPageIncidentUpdates(db *gorm.DB, incidents []models.PageIncident) ([]pageIncidentResponse, error) {
+	response := make([]pageIncidentResponse, len(incidents))
+	if len(incidents) == 0 {
+		return response, nil
+	}
+
+	ids := make([]uuid.UUID, len(incidents))
+	for i, incident := range incidents {
+		ids[i] = incident.ID
+	}
+	updates := []models.PageIncidentUpdate{}
+	if err := db.Where("incident_id IN ?", ids).Order("created_at DESC").Find(&updates).Error; err != nil {
+		return nil, err
+	}
+
+	byIncident := map[uuid.UUID][]models.PageIncidentUpdate{}
+	for _, update := range updates {
+		byIncident[update.IncidentID] = append(byIncident[update.IncidentID], update)
+	}
+	for i, incident := range incidents {
+		response[i] = pageIncidentResponse{PageIncident: incident, Updates: byIncident[incident.ID]}
+		if response[i].Updates == nil {
+			response[i].Updates = []models.PageIncidentUpdate{}
+		}
+	}
+	return response, nil
+}
+
+// uniqueStrings returns values without duplicates, keeping their order
+func uniqueStrings(values []string) []string {
+	seen := map[string]bool{}
+	result := []string{}
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			result = append(result, value)
+		}
+	}
+	return result
+}
@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"monitron-server/internal/realtime"
+	"monitron-server/utils"
+	"monitron-server/utils/response"
+)
+
+// Realtime connection limits
+const (
+	realtimeMaxTopics  = 50
+	realtimeMaxMessage = 4096
+	realtimeWriteWait  = 10 * time.Second
+	realtimePongWait   = 60 * time.Second
+	realtimePingPeriod = 50 * time.Second
+)
+
+// Replies to client messages, sent alongside hub events
+const (
+	realtimeSubscribed   = "subscribed"
+	realtimeUnsubscribed = "unsubscribed"
+	realtimeError        = "error"
+)
+
+// realtimeMessage is a message sent by a realtime client
+type realtimeMessage struct {
+	Action string `json:"action"` // subscribe or unsubscribe
+	Topic  string `json:"topic"`
+}
+
+// RealtimeUpgrade
+// @Summary Realtime event stream
+// @Description Upgrades to a WebSocket delivering status changes, check results, metrics and incidents. Topics are all (admins only), target:<type>:<id> and group:<name> (signed-in users) and page:<id or slug> (anyone for public pages). Pass initial topics as a comma-separated topics parameter and send {"action":"subscribe","topic":"..."} or {"action":"unsubscribe","topic":"..."} to change them. Browsers may pass the JWT as the token parameter. Clients that fall behind get a lagged event with the number of dropped events.
+// @Tags Realtime
+// @Param topics query string false "Comma-separated topics"
+// @Param token query string false "JWT, for clients that cannot set headers"
+// @Success 101 "Switching Protocols"
+// @Failure 400 {object} response.Response "Unknown topic"
+// @Failure 401 {object} response.Response "Authentication required"
+// @Failure 403 {object} response.Response "Admin access required"
+// @Failure 404 {object} response.Response "Operational page not found"
+// @Failure 426 {object} response.Response "WebSocket upgrade required"
+// @Router /ws [get]
+func RealtimeUpgrade(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		viewer, err := websocketViewer(c)
+		if viewer == nil {
+			return err
+		}
+
+		topics := []string{}
+		for _, topic := range strings.Split(c.Query("topics"), ",") {
+			if topic = strings.TrimSpace(topic); topic == "" {
+				continue
+			}
+			canonical, err := realtime.Authorize(db, topic, *viewer)
+			if err != nil {
+				if realtimeErrorStatus(err) == fiber.StatusInternalServerError {
+					log.Printf("Error authorizing realtime topic %s: %v", topic, err)
+				}
+				return response.Error(c, realtimeErrorStatus(err), realtimeErrorMessage(topic, err))
+			}
+			topics = append(topics, canonical)
+		}
+		if len(topics) > realtimeMaxTopics {
+			return response.Error(c, fiber.StatusBadRequest, "Too many topics")
+		}
+
+		c.Locals("realtime_topics", topics)
+		return c.Next()
+	}
+}
+
+// RealtimeStream serves an upgraded realtime connection. The handler
+// goroutine writes, a second one reads client messages and hands its
+// replies over, since a connection supports only one writer at a time.
+func RealtimeStream(db *gorm.DB) fiber.Handler {
+	return websocket.New(func(conn *websocket.Conn) {
+		hub := realtime.Default
+		sub := hub.Subscribe()
+		defer hub.Close(sub)
+
+		replies := make(chan realtime.Event, realtimeMaxTopics)
+		for _, topic := range conn.Locals("realtime_topics").([]string) {
+			hub.Join(sub, topic)
+			replies <- realtime.Event{Type: realtimeSubscribed, Topic: topic, Time: time.Now()}
+		}
+
+		done := make(chan struct{})
+		quit := make(chan struct{})
+		go readRealtime(db, conn, hub, sub, replies, done, quit)
+		defer func() {
+			close(quit)
+			conn.Close()
+			<-done
+		}()
+
+		write := func(event realtime.Event) error {
+			conn.SetWriteDeadline(time.Now().Add(realtimeWriteWait))
+			return conn.WriteJSON(event)
+		}
+
+		ping := time.NewTicker(realtimePingPeriod)
+		defer ping.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case reply := <-replies:
+				if err := write(reply); err != nil {
+					return
+				}
+			case event, ok := <-sub.Events():
+				if !ok {
+					return
+				}
+				if dropped := sub.TakeDropped(); dropped > 0 {
+					lagged := realtime.Event{Type: realtime.EventLagged, Data: fiber.Map{"dropped": dropped}, Time: time.Now()}
+					if err := write(lagged); err != nil {
+						return
+					}
+				}
+				if err := write(event); err != nil {
+					return
+				}
+			case <-ping.C:
+				conn.SetWriteDeadline(time.Now().Add(realtimeWriteWait))
+				if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+					return
+				}
+			}
+		}
+	})
+}
+
+// readRealtime handles subscribe and unsubscribe messages until the client
+// goes away, then closes done. It stops early when the writer quits.
+func readRealtime(db *gorm.DB, conn *websocket.Conn, hub *realtime.Hub, sub *realtime.Subscriber, replies chan<- realtime.Event, done, quit chan struct{}) {
+	defer close(done)
+
+	viewer := realtimeViewer(conn.Locals("user_id"), conn.Locals("user_role"))
+	conn.SetReadLimit(realtimeMaxMessage)
+	conn.SetReadDeadline(time.Now().Add(realtimePongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(realtimePongWait))
+	})
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				log.Printf("Error reading realtime message: %v", err)
+			}
+			return
+		}
+
+		reply := realtime.Event{Time: time.Now()}
+		message := realtimeMessage{}
+		if err := json.Unmarshal(data, &message); err != nil {
+			reply.Type, reply.Data = realtimeError, fiber.Map{"error": "Invalid message"}
+		} else {
+			reply = handleRealtimeMessage(db, hub, sub, viewer, message)
+		}
+
+		select {
+		case replies <- reply:
+		case <-quit:
+			return
+		}
+	}
+}
+
+// handleRealtimeMessage applies one client message and returns the reply
+func handleRealtimeMessage(db *gorm.DB, hub *realtime.Hub, sub *realtime.Subscriber, viewer realtime.Viewer, message realtimeMessage) realtime.Event {
+	reply := realtime.Event{Topic: message.Topic, Time: time.Now()}
+	fail := func(text string) realtime.Event {
+		reply.Type, reply.Data = realtimeError, fiber.Map{"error": text}
+		return reply
+	}
+
+	switch message.Action {
+	case "subscribe", "unsubscribe":
+	default:
+		return fail("action must be subscribe or unsubscribe")
+	}
+
+	// Leaving needs no authorization, so topics can be left after their page
+	// was deleted or made private
+	if message.Action == "unsubscribe" {
+		topic, err := realtime.Canonical(db, message.Topic)
+		if err != nil {
+			if realtimeErrorStatus(err) == fiber.StatusInternalServerError {
+				log.Printf("Error resolving realtime topic %s: %v", message.Topic, err)
+			}
+			return fail(realtimeErrorMessage(message.Topic, err))
+		}
+		hub.Leave(sub, topic)
+		reply.Topic, reply.Type = topic, realtimeUnsubscribed
+		return reply
+	}
+
+	topic, err := realtime.Authorize(db, message.Topic, viewer)
+	if err != nil {
+		if realtimeErrorStatus(err) == fiber.StatusInternalServerError {
+			log.Printf("Error authorizing realtime topic %s: %v", message.Topic, err)
+		}
+		return fail(realtimeErrorMessage(message.Topic, err))
+	}
+	reply.Topic = topic
+
+	if hub.Topics(sub) >= realtimeMaxTopics {
+		return fail("Too many topics")
+	}
+	hub.Join(sub, topic)
+	reply.Type = realtimeSubscribed
+	return reply
+}
+
+// websocketViewer rejects requests that are not WebSocket upgrades and
+// returns the caller. Browsers cannot set headers on WebSocket requests, so
+// the JWT may also come as the token query parameter. A nil viewer means the
+// response was already sent.
+func websocketViewer(c *fiber.Ctx) (*realtime.Viewer, error) {
+	if !websocket.IsWebSocketUpgrade(c) {
+		return nil, response.Error(c, fiber.StatusUpgradeRequired, "WebSocket upgrade required")
+	}
+
+	if token := c.Query("token"); token != "" && c.Locals("user_id") == nil {
+		claims, err := utils.ParseJWT(token)
+		if err != nil {
+			return nil, response.Error(c, fiber.StatusUnauthorized, "Invalid or expired JWT")
+		}
+		c.Locals("user_id", claims.UserID)
+		c.Locals("user_role", claims.Role)
+	}
+
+	viewer := realtimeViewer(c.Locals("user_id"), c.Locals("user_role"))
+	return &viewer, nil
+}
+
+func realtimeViewer(userID, role interface{}) realtime.Viewer {
+	viewer := realtime.Viewer{Authenticated: userID != nil}
+	viewer.UserID, _ = userID.(uuid.UUID)
+	viewer.Role, _ = role.(string)
+	return viewer
+}
+
+func realtimeErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, realtime.ErrUnknownTopic):
+		return fiber.StatusBadRequest
+	case errors.Is(err, realtime.ErrUnauthorized):
+		return fiber.StatusUnauthorized
+	case errors.Is(err, realtime.ErrForbidden):
+		return fiber.StatusForbidden
+	case errors.Is(err, realtime.ErrPageNotFound):
+		return fiber.StatusNotFound
+	}
+	return fiber.StatusInternalServerError
+}
+
+func realtimeErrorMessage(topic string, err error) string {
+	if realtimeErrorStatus(err) == fiber.StatusInternalServerError {
+		return "Could not subscribe to " + topic
+	}
+	return topic + ": " + err.Error()
+}
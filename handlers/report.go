@@ -1,107 +1,222 @@
 package handlers
 
 import (
-	"encoding/json"
+	"errors"
 	"log"
+	"path"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 
+	"monitron-server/internal/reportgen"
+	"monitron-server/internal/storage"
 	"monitron-server/messaging"
 	"monitron-server/models"
+	"monitron-server/utils/response"
+	"monitron-server/utils/validate"
 )
 
+// reportRequest holds the fields of a report its creator sets. The others,
+// such as the file path and schedule, are only set by the server.
+type reportRequest struct {
+	Name         string     `json:"name" validate:"required"`
+	ReportType   string     `json:"report_type" validate:"required,oneof=instance_summary service_uptime domain_ssl_expiry operational_page_sla incident_summary"`
+	Format       string     `json:"format"` // "CSV", "PDF" or "Excel" (case-insensitive), defaults to CSV
+	RangeStart   *time.Time `json:"range_start"`
+	RangeEnd     *time.Time `json:"range_end"`
+	TargetIDs    []string   `json:"target_ids" validate:"dive,uuid"`
+	TargetGroups []string   `json:"target_groups"`
+}
+
 // CreateReport
 // @Summary Create a new report
-// @Description Create a new report entry and queue it for generation
+// @Description Create a new report entry and queue it for generation. Reports cover range_start to range_end (default: the last 30 days) and can be limited to target_ids and target_groups.
 // @Tags Reports
 // @Accept json
 // @Produce json
-// @Param report body models.Report true "Report object to be created"
-// @Success 201 {object} models.Report
-// @Failure 400 {object} map[string]string "error": "Cannot parse JSON"
-// @Failure 500 {object} map[string]string "error": "Could not create report"
+// @Param report body reportRequest true "Report to be created"
+// @Success 201 {object} response.Response{data=models.Report}
+// @Failure 400 {object} response.Response "Cannot parse JSON"
+// @Failure 500 {object} response.Response "Could not create report"
 // @Security ApiKeyAuth
 // @Router /reports [post]
 func CreateReport(db *gorm.DB) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		report := new(models.Report)
-		if err := c.BodyParser(report); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON"})
+		req := reportRequest{}
+		if err := c.BodyParser(&req); err != nil {
+			return response.InvalidBody(c)
+		}
+
+		if err := validate.V.Struct(req); err != nil {
+			return response.Invalid(c, err)
+		}
+
+		if req.RangeStart != nil && req.RangeEnd != nil && !req.RangeStart.Before(*req.RangeEnd) {
+			return response.Error(c, fiber.StatusBadRequest, "range_start must be before range_end")
+		}
+
+		if len(req.TargetGroups) > 0 && !reportgen.SupportsGroups(req.ReportType) {
+			return response.Error(c, fiber.StatusBadRequest, req.ReportType+" reports cannot be limited to target_groups")
 		}
 
-		report.ID = uuid.New()
-		report.Status = "pending"
-		report.CreatedAt = time.Now()
-		report.UpdatedAt = time.Now()
+		if req.Format == "" {
+			req.Format = "CSV"
+		}
+		if _, err := reportgen.RendererFor(req.Format); err != nil {
+			return response.Error(c, fiber.StatusBadRequest, err.Error())
+		}
+
+		report := &models.Report{
+			ID:           uuid.New(),
+			Name:         req.Name,
+			ReportType:   req.ReportType,
+			Format:       req.Format,
+			Status:       reportgen.StatusPending,
+			UserID:       c.Locals("user_id").(uuid.UUID),
+			CreatedAt:    time.Now(),
+			UpdatedAt:    time.Now(),
+			RangeStart:   req.RangeStart,
+			RangeEnd:     req.RangeEnd,
+			TargetIDs:    req.TargetIDs,
+			TargetGroups: req.TargetGroups,
+		}
 
 		err := db.Create(report).Error
 		if err != nil {
 			log.Printf("Error inserting report: %v", err)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not create report"})
+			return response.Error(c, fiber.StatusInternalServerError, "Could not create report")
 		}
 
 		// Publish message to RabbitMQ for report generation
-		reportJSON, _ := json.Marshal(report)
-		err = messaging.PublishMessage("report_generation_queue", reportJSON)
-		if err != nil {
+		if err := messaging.PublishReport(*report); err != nil {
 			log.Printf("Error publishing report generation message: %v", err)
-			// Optionally update report status to failed if message couldn't be published
+			report.Status = reportgen.StatusFailed
+			report.ErrorMessage = "Could not queue report for generation"
+			if err := db.Model(report).Updates(map[string]interface{}{"status": report.Status, "error_message": report.ErrorMessage}).Error; err != nil {
+				log.Printf("Error marking report %s as failed: %v", report.ID, err)
+			}
 		}
 
-		return c.Status(fiber.StatusCreated).JSON(report)
+		return response.Created(c, report)
 	}
 }
 
 // GetReports
 // @Summary Get all reports
-// @Description Retrieve a list of all reports
+// @Description Retrieve the authenticated user's reports, or every report for admins
 // @Tags Reports
 // @Produce json
-// @Success 200 {array} models.Report
-// @Failure 500 {object} map[string]string "error": "Could not retrieve reports"
+// @Success 200 {object} response.Response{data=[]models.Report}
+// @Failure 500 {object} response.Response "Could not retrieve reports"
 // @Security ApiKeyAuth
 // @Router /reports [get]
 func GetReports(db *gorm.DB) fiber.Handler {
 	return func(c *fiber.Ctx) error {
+		query := db.Order("created_at DESC")
+		if c.Locals("user_role") != "admin" {
+			query = query.Where("user_id = ?", c.Locals("user_id").(uuid.UUID))
+		}
+
 		reports := []models.Report{}
-		err := db.Select(&reports).Error
+		err := query.Find(&reports).Error
 		if err != nil {
 			log.Printf("Error fetching reports: %v", err)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not retrieve reports"})
+			return response.Error(c, fiber.StatusInternalServerError, "Could not retrieve reports")
 		}
-		return c.JSON(reports)
+		return response.OK(c, reports)
 	}
 }
 
 // GetReport
 // @Summary Get report by ID
-// @Description Retrieve a single report by its ID
+// @Description Retrieve a single report by its ID, including its generation status
 // @Tags Reports
 // @Produce json
 // @Param id path string true "Report ID"
-// @Success 200 {object} models.Report
-// @Failure 400 {object} map[string]string "error": "Invalid report ID"
-// @Failure 404 {object} map[string]string "error": "Report not found"
-// @Failure 500 {object} map[string]string "error": "Could not retrieve report"
+// @Success 200 {object} response.Response{data=models.Report}
+// @Failure 400 {object} response.Response "Invalid report ID"
+// @Failure 404 {object} response.Response "Report not found"
+// @Failure 500 {object} response.Response "Could not retrieve report"
 // @Security ApiKeyAuth
 // @Router /reports/{id} [get]
 func GetReport(db *gorm.DB) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		id := c.Params("id")
-		_, err := uuid.Parse(id)
-		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid report ID"})
+		report, err := findUserReport(c, db)
+		if report == nil {
+			return err
 		}
+		return response.OK(c, report)
+	}
+}
 
-		report := models.Report{}
-		err = db.Where("id = ?", id).First(&report).Error
+// DownloadReport
+// @Summary Download a generated report
+// @Description Stream the generated report file. Only the report owner and admins can download it.
+// @Tags Reports
+// @Produce octet-stream
+// @Param id path string true "Report ID"
+// @Success 200 {file} file
+// @Failure 400 {object} response.Response "Invalid report ID"
+// @Failure 404 {object} response.Response "Report not found"
+// @Failure 409 {object} response.Response "Report is not ready for download"
+// @Failure 500 {object} response.Response "Could not download report"
+// @Security ApiKeyAuth
+// @Router /reports/{id}/download [get]
+func DownloadReport(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		report, err := findUserReport(c, db)
+		if report == nil {
+			return err
+		}
+
+		if report.Status != reportgen.StatusCompleted || report.FilePath == "" {
+			return response.Error(c, fiber.StatusConflict, "Report is not ready for download, its status is "+report.Status)
+		}
+
+		file, err := storage.Store.Get(c.Context(), report.FilePath)
+		if errors.Is(err, storage.ErrNotFound) {
+			return response.Error(c, fiber.StatusNotFound, "Report file not found")
+		}
 		if err != nil {
-			log.Printf("Error fetching report: %v", err)
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Report not found"})
+			log.Printf("Error opening report file %s: %v", report.FilePath, err)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not download report")
+		}
+
+		contentType := fiber.MIMEOctetStream
+		if renderer, err := reportgen.RendererFor(report.Format); err == nil {
+			contentType = renderer.ContentType()
 		}
-		return c.JSON(report)
+		c.Set(fiber.HeaderContentType, contentType)
+		c.Attachment(path.Base(report.FilePath))
+		// The stream is closed by fasthttp once the body has been sent
+		return c.SendStream(file, int(report.FileSize))
+	}
+}
+
+// findUserReport loads the report in the :id path parameter if the current
+// user owns it or is an admin. Other users get a 404 so report IDs are not
+// disclosed. A nil report means the error response has already been written.
+func findUserReport(c *fiber.Ctx, db *gorm.DB) (*models.Report, error) {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, response.Fail(c, fiber.StatusBadRequest, response.CodeInvalidParam, "Invalid report ID", nil)
+	}
+
+	query := db.Where("id = ?", id)
+	if c.Locals("user_role") != "admin" {
+		query = query.Where("user_id = ?", c.Locals("user_id").(uuid.UUID))
+	}
+
+	report := models.Report{}
+	err = query.First(&report).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, response.Error(c, fiber.StatusNotFound, "Report not found")
+	}
+	if err != nil {
+		log.Printf("Error fetching report: %v", err)
+		return nil, response.Error(c, fiber.StatusInternalServerError, "Could not retrieve report")
 	}
+	return &report, nil
 }
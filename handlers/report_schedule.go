@@ -0,0 +1,318 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"monitron-server/internal/reportgen"
+	"monitron-server/internal/reportschedule"
+	"monitron-server/messaging"
+	"monitron-server/models"
+	"monitron-server/utils/response"
+	"monitron-server/utils/validate"
+)
+
+// reportScheduleRequest is the body of create and update requests. IsEnabled
+// is a pointer so that omitting it keeps new schedules enabled.
+type reportScheduleRequest struct {
+	models.ReportSchedule
+	IsEnabled *bool `json:"is_enabled"`
+}
+
+// reportScheduleResponse adds the next run time of the schedule
+type reportScheduleResponse struct {
+	models.ReportSchedule
+	NextRunAt *time.Time `json:"next_run_at"`
+}
+
+func toReportScheduleResponse(schedule models.ReportSchedule) reportScheduleResponse {
+	return reportScheduleResponse{ReportSchedule: schedule, NextRunAt: reportschedule.NextRun(schedule)}
+}
+
+// GetReportSchedules
+// @Summary Get report schedules
+// @Description Retrieve the authenticated user's report schedules, or every schedule for admins
+// @Tags Reports
+// @Produce json
+// @Success 200 {object} response.Response{data=[]reportScheduleResponse}
+// @Failure 500 {object} response.Response "Could not retrieve report schedules"
+// @Security ApiKeyAuth
+// @Router /report-schedules [get]
+func GetReportSchedules(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		query := db.Order("name ASC")
+		if c.Locals("user_role") != "admin" {
+			query = query.Where("user_id = ?", c.Locals("user_id").(uuid.UUID))
+		}
+
+		schedules := []models.ReportSchedule{}
+		if err := query.Find(&schedules).Error; err != nil {
+			log.Printf("Error fetching report schedules: %v", err)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not retrieve report schedules")
+		}
+
+		views := make([]reportScheduleResponse, len(schedules))
+		for i, schedule := range schedules {
+			views[i] = toReportScheduleResponse(schedule)
+		}
+		return response.OK(c, views)
+	}
+}
+
+// GetReportSchedule
+// @Summary Get report schedule by ID
+// @Description Retrieve a single report schedule by its ID
+// @Tags Reports
+// @Produce json
+// @Param id path string true "Report Schedule ID"
+// @Success 200 {object} response.Response{data=reportScheduleResponse}
+// @Failure 400 {object} response.Response "Invalid report schedule ID"
+// @Failure 404 {object} response.Response "Report schedule not found"
+// @Failure 500 {object} response.Response "Could not retrieve report schedule"
+// @Security ApiKeyAuth
+// @Router /report-schedules/{id} [get]
+func GetReportSchedule(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		schedule, err := findUserReportSchedule(c, db)
+		if schedule == nil {
+			return err
+		}
+		return response.OK(c, toReportScheduleResponse(*schedule))
+	}
+}
+
+// CreateReportSchedule
+// @Summary Create a report schedule
+// @Description Generate a report on a cron schedule (e.g. "0 8 * * 1" or "@daily") in the given timezone and deliver it to email recipients as an attachment or link, and to notification channels as a link. Schedules may run at most hourly. Only admins can deliver to notification channels or to other email addresses than their own.
+// @Tags Reports
+// @Accept json
+// @Produce json
+// @Param schedule body reportScheduleRequest true "Report schedule to create"
+// @Success 201 {object} response.Response{data=reportScheduleResponse}
+// @Failure 400 {object} response.Response "Cannot parse JSON"
+// @Failure 500 {object} response.Response "Could not create report schedule"
+// @Security ApiKeyAuth
+// @Router /report-schedules [post]
+func CreateReportSchedule(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		req := new(reportScheduleRequest)
+		if err := c.BodyParser(req); err != nil {
+			return response.InvalidBody(c)
+		}
+
+		schedule := req.ReportSchedule
+		schedule.IsEnabled = req.IsEnabled == nil || *req.IsEnabled
+		if msg := prepareReportSchedule(c, db, &schedule); msg != "" {
+			return response.Error(c, fiber.StatusBadRequest, msg)
+		}
+
+		schedule.ID = uuid.New()
+		schedule.UserID = c.Locals("user_id").(uuid.UUID)
+		schedule.LastRunAt = nil
+		schedule.CreatedAt = time.Now()
+		schedule.UpdatedAt = time.Now()
+
+		if result := db.Create(&schedule); result.Error != nil {
+			log.Printf("Error creating report schedule: %v", result.Error)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not create report schedule")
+		}
+
+		return response.Created(c, toReportScheduleResponse(schedule))
+	}
+}
+
+// UpdateReportSchedule
+// @Summary Update a report schedule
+// @Description Replace the settings of a report schedule by its ID
+// @Tags Reports
+// @Accept json
+// @Produce json
+// @Param id path string true "Report Schedule ID"
+// @Param schedule body reportScheduleRequest true "Report schedule with updated fields"
+// @Success 200 {object} response.Response{data=reportScheduleResponse}
+// @Failure 400 {object} response.Response "Invalid report schedule ID" or "Cannot parse JSON"
+// @Failure 404 {object} response.Response "Report schedule not found"
+// @Failure 500 {object} response.Response "Could not update report schedule"
+// @Security ApiKeyAuth
+// @Router /report-schedules/{id} [put]
+func UpdateReportSchedule(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		existing, err := findUserReportSchedule(c, db)
+		if existing == nil {
+			return err
+		}
+
+		req := new(reportScheduleRequest)
+		if err := c.BodyParser(req); err != nil {
+			return response.InvalidBody(c)
+		}
+
+		schedule := req.ReportSchedule
+		schedule.IsEnabled = existing.IsEnabled
+		if req.IsEnabled != nil {
+			schedule.IsEnabled = *req.IsEnabled
+		}
+		if msg := prepareReportSchedule(c, db, &schedule); msg != "" {
+			return response.Error(c, fiber.StatusBadRequest, msg)
+		}
+
+		schedule.ID = existing.ID
+		schedule.UserID = existing.UserID
+		schedule.LastRunAt = existing.LastRunAt
+		schedule.CreatedAt = existing.CreatedAt
+		schedule.UpdatedAt = time.Now()
+
+		// The last run time is only written when a run is claimed
+		if result := db.Omit("last_run_at").Save(&schedule); result.Error != nil {
+			log.Printf("Error updating report schedule: %v", result.Error)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not update report schedule")
+		}
+
+		return response.OK(c, toReportScheduleResponse(schedule))
+	}
+}
+
+// DeleteReportSchedule
+// @Summary Delete a report schedule
+// @Description Delete a report schedule by its ID. Reports it already generated are kept.
+// @Tags Reports
+// @Produce json
+// @Param id path string true "Report Schedule ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} response.Response "Invalid report schedule ID"
+// @Failure 404 {object} response.Response "Report schedule not found"
+// @Failure 500 {object} response.Response "Could not delete report schedule"
+// @Security ApiKeyAuth
+// @Router /report-schedules/{id} [delete]
+func DeleteReportSchedule(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		schedule, err := findUserReportSchedule(c, db)
+		if schedule == nil {
+			return err
+		}
+
+		if result := db.Delete(&models.ReportSchedule{}, "id = ?", schedule.ID); result.Error != nil {
+			log.Printf("Error deleting report schedule: %v", result.Error)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not delete report schedule")
+		}
+
+		return c.Status(fiber.StatusNoContent).SendString("")
+	}
+}
+
+// RunReportSchedule
+// @Summary Run a report schedule now
+// @Description Queue a report from the schedule immediately; it is delivered like a scheduled run once generated
+// @Tags Reports
+// @Produce json
+// @Param id path string true "Report Schedule ID"
+// @Success 202 {object} response.Response{data=models.Report}
+// @Failure 400 {object} response.Response "Invalid report schedule ID"
+// @Failure 404 {object} response.Response "Report schedule not found"
+// @Failure 500 {object} response.Response "Could not run report schedule"
+// @Security ApiKeyAuth
+// @Router /report-schedules/{id}/run [post]
+func RunReportSchedule(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		schedule, err := findUserReportSchedule(c, db)
+		if schedule == nil {
+			return err
+		}
+
+		report, err := reportschedule.Run(db, *schedule)
+		if err != nil {
+			log.Printf("Error running report schedule %s: %v", schedule.ID, err)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not run report schedule")
+		}
+
+		return response.Accepted(c, report)
+	}
+}
+
+// prepareReportSchedule validates a schedule from a request and applies the
+// defaults. It returns a client error message, or "" if the schedule is valid.
+func prepareReportSchedule(c *fiber.Ctx, db *gorm.DB, schedule *models.ReportSchedule) string {
+	if err := validate.V.Struct(schedule); err != nil {
+		return err.Error()
+	}
+
+	if schedule.Timezone == "" {
+		schedule.Timezone = "UTC"
+	}
+	if schedule.Format == "" {
+		schedule.Format = "CSV"
+	}
+	if schedule.DeliveryMode == "" {
+		schedule.DeliveryMode = messaging.DeliveryAttachment
+	}
+	if schedule.RangeDays == 0 {
+		schedule.RangeDays = 7
+	}
+
+	if err := reportschedule.Validate(*schedule); err != nil {
+		return err.Error()
+	}
+
+	if len(schedule.TargetGroups) > 0 && !reportgen.SupportsGroups(schedule.ReportType) {
+		return schedule.ReportType + " reports cannot be limited to target_groups"
+	}
+
+	if len(schedule.Recipients) == 0 && len(schedule.ChannelIDs) == 0 {
+		return "At least one recipient or channel is required"
+	}
+
+	if len(schedule.Recipients) > 0 && c.Locals("user_role") != "admin" {
+		user := models.User{}
+		if err := db.Select("email").First(&user, "id = ?", c.Locals("user_id").(uuid.UUID)).Error; err != nil {
+			return "User not found"
+		}
+		for _, recipient := range schedule.Recipients {
+			if !strings.EqualFold(recipient, user.Email) {
+				return "Only admins can deliver reports to other email addresses than their own"
+			}
+		}
+	}
+
+	if len(schedule.ChannelIDs) > 0 {
+		if c.Locals("user_role") != "admin" {
+			return "Only admins can deliver reports to notification channels"
+		}
+		var count int64
+		if err := db.Model(&models.NotificationChannel{}).Where("id IN ?", schedule.ChannelIDs).Count(&count).Error; err != nil || int(count) != len(schedule.ChannelIDs) {
+			return "Notification channel not found"
+		}
+	}
+	return ""
+}
+
+// findUserReportSchedule loads the schedule in the :id path parameter if the
+// current user owns it or is an admin. A nil schedule means the error
+// response has already been written.
+func findUserReportSchedule(c *fiber.Ctx, db *gorm.DB) (*models.ReportSchedule, error) {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, response.Fail(c, fiber.StatusBadRequest, response.CodeInvalidParam, "Invalid report schedule ID", nil)
+	}
+
+	query := db.Where("id = ?", id)
+	if c.Locals("user_role") != "admin" {
+		query = query.Where("user_id = ?", c.Locals("user_id").(uuid.UUID))
+	}
+
+	schedule := models.ReportSchedule{}
+	err = query.First(&schedule).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, response.Error(c, fiber.StatusNotFound, "Report schedule not found")
+	}
+	if err != nil {
+		log.Printf("Error fetching report schedule: %v", err)
+		return nil, response.Error(c, fiber.StatusInternalServerError, "Could not retrieve report schedule")
+	}
+	return &schedule, nil
+}
@@ -9,7 +9,9 @@ import (
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 
+	"monitron-server/internal/pagecomponents"
 	"monitron-server/models"
+	"monitron-server/utils/response"
 )
 
 // CreateService
@@ -19,16 +21,16 @@ import (
 // @Accept json
 // @Produce json
 // @Param service body models.Service true "Service object to be created"
-// @Success 201 {object} models.Service
-// @Failure 400 {object} map[string]string "error": "Cannot parse JSON"
-// @Failure 500 {object} map[string]string "error": "Could not create service"
+// @Success 201 {object} response.Response{data=models.Service}
+// @Failure 400 {object} response.Response "Cannot parse JSON"
+// @Failure 500 {object} response.Response "Could not create service"
 // @Security ApiKeyAuth
 // @Router /services [post]
 func CreateService(db *gorm.DB) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		service := new(models.Service)
 		if err := c.BodyParser(service); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON"})
+			return response.InvalidBody(c)
 		}
 
 		service.ID = uuid.New()
@@ -37,10 +39,10 @@ func CreateService(db *gorm.DB) fiber.Handler {
 
 		if result := db.Create(&service); result.Error != nil {
 			log.Printf("Error creating service: %v", result.Error)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not create service"})
+			return response.Error(c, fiber.StatusInternalServerError, "Could not create service")
 		}
 
-		return c.Status(fiber.StatusCreated).JSON(service)
+		return response.Created(c, service)
 	}
 }
 
@@ -49,8 +51,8 @@ func CreateService(db *gorm.DB) fiber.Handler {
 // @Description Retrieve a list of all monitoring services
 // @Tags Services
 // @Produce json
-// @Success 200 {array} models.Service
-// @Failure 500 {object} map[string]string "error": "Could not retrieve services"
+// @Success 200 {object} response.Response{data=[]models.Service}
+// @Failure 500 {object} response.Response "Could not retrieve services"
 // @Security ApiKeyAuth
 // @Router /services [get]
 func GetServices(db *gorm.DB) fiber.Handler {
@@ -58,10 +60,10 @@ func GetServices(db *gorm.DB) fiber.Handler {
 		services := []models.Service{}
 		if result := db.Find(&services); result.Error != nil {
 			log.Printf("Error fetching services: %v", result.Error)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not retrieve services"})
+			return response.Error(c, fiber.StatusInternalServerError, "Could not retrieve services")
 		}
 
-		return c.JSON(services)
+		return response.OK(c, services)
 	}
 }
 
@@ -71,10 +73,10 @@ func GetServices(db *gorm.DB) fiber.Handler {
 // @Tags Services
 // @Produce json
 // @Param id path string true "Service ID"
-// @Success 200 {object} models.Service
-// @Failure 400 {object} map[string]string "error": "Invalid service ID"
-// @Failure 404 {object} map[string]string "error": "Service not found"
-// @Failure 500 {object} map[string]string "error": "Could not retrieve service"
+// @Success 200 {object} response.Response{data=models.Service}
+// @Failure 400 {object} response.Response "Invalid service ID"
+// @Failure 404 {object} response.Response "Service not found"
+// @Failure 500 {object} response.Response "Could not retrieve service"
 // @Security ApiKeyAuth
 // @Router /services/{id} [get]
 func GetService(db *gorm.DB) fiber.Handler {
@@ -82,19 +84,19 @@ func GetService(db *gorm.DB) fiber.Handler {
 		id := c.Params("id")
 		uuidID, err := uuid.Parse(id)
 		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid service ID"})
+			return response.Fail(c, fiber.StatusBadRequest, response.CodeInvalidParam, "Invalid service ID", nil)
 		}
 
 		service := models.Service{}
 		if result := db.First(&service, "id = ?", uuidID); result.Error != nil {
 			if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Service not found"})
+				return response.Error(c, fiber.StatusNotFound, "Service not found")
 			}
 			log.Printf("Error fetching service: %v", result.Error)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not retrieve service"})
+			return response.Error(c, fiber.StatusInternalServerError, "Could not retrieve service")
 		}
 
-		return c.JSON(service)
+		return response.OK(c, service)
 	}
 }
 
@@ -106,10 +108,10 @@ func GetService(db *gorm.DB) fiber.Handler {
 // @Produce json
 // @Param id path string true "Service ID"
 // @Param service body models.Service true "Service object with updated fields"
-// @Success 200 {object} models.Service
-// @Failure 400 {object} map[string]string "error": "Invalid service ID" or "Cannot parse JSON"
-// @Failure 404 {object} map[string]string "error": "Service not found"
-// @Failure 500 {object} map[string]string "error": "Could not update service"
+// @Success 200 {object} response.Response{data=models.Service}
+// @Failure 400 {object} response.Response "Invalid service ID" or "Cannot parse JSON"
+// @Failure 404 {object} response.Response "Service not found"
+// @Failure 500 {object} response.Response "Could not update service"
 // @Security ApiKeyAuth
 // @Router /services/{id} [put]
 func UpdateService(db *gorm.DB) fiber.Handler {
@@ -117,29 +119,29 @@ func UpdateService(db *gorm.DB) fiber.Handler {
 		id := c.Params("id")
 		uuidID, err := uuid.Parse(id)
 		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid service ID"})
+			return response.Fail(c, fiber.StatusBadRequest, response.CodeInvalidParam, "Invalid service ID", nil)
 		}
 
 		service := new(models.Service)
 		if err := c.BodyParser(service); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON"})
+			return response.InvalidBody(c)
 		}
 
 		var existingService models.Service
 		if result := db.First(&existingService, "id = ?", uuidID); result.Error != nil {
 			if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Service not found"})
+				return response.Error(c, fiber.StatusNotFound, "Service not found")
 			}
 			log.Printf("Error finding service for update: %v", result.Error)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not update service"})
+			return response.Error(c, fiber.StatusInternalServerError, "Could not update service")
 		}
 
 		if result := db.Model(&existingService).Updates(service); result.Error != nil {
 			log.Printf("Error updating service: %v", result.Error)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not update service"})
+			return response.Error(c, fiber.StatusInternalServerError, "Could not update service")
 		}
 
-		return c.JSON(existingService)
+		return response.OK(c, existingService)
 	}
 }
 
@@ -150,9 +152,9 @@ func UpdateService(db *gorm.DB) fiber.Handler {
 // @Produce json
 // @Param id path string true "Service ID"
 // @Success 204 "No Content"
-// @Failure 400 {object} map[string]string "error": "Invalid service ID"
-// @Failure 404 {object} map[string]string "error": "Service not found"
-// @Failure 500 {object} map[string]string "error": "Could not delete service"
+// @Failure 400 {object} response.Response "Invalid service ID"
+// @Failure 404 {object} response.Response "Service not found"
+// @Failure 500 {object} response.Response "Could not delete service"
 // @Security ApiKeyAuth
 // @Router /services/{id} [delete]
 func DeleteService(db *gorm.DB) fiber.Handler {
@@ -160,16 +162,18 @@ func DeleteService(db *gorm.DB) fiber.Handler {
 		id := c.Params("id")
 		uuidID, err := uuid.Parse(id)
 		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid service ID"})
+			return response.Fail(c, fiber.StatusBadRequest, response.CodeInvalidParam, "Invalid service ID", nil)
 		}
 
 		if result := db.Delete(&models.Service{}, "id = ?", uuidID); result.Error != nil {
 			log.Printf("Error deleting service: %v", result.Error)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not delete service"})
+			return response.Error(c, fiber.StatusInternalServerError, "Could not delete service")
 		} else if result.RowsAffected == 0 {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Service not found"})
+			return response.Error(c, fiber.StatusNotFound, "Service not found")
 		}
 
+		removeTargetComponents(db, pagecomponents.TypeService, uuidID)
+
 		return c.Status(fiber.StatusNoContent).SendString("")
 	}
 }
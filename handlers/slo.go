@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"monitron-server/internal/slo"
+	"monitron-server/models"
+	"monitron-server/utils/response"
+	"monitron-server/utils/validate"
+)
+
+// sloResponse is an SLO together with its current status
+type sloResponse struct {
+	models.SLO
+	Status *slo.Status `json:"status,omitempty"`
+}
+
+// GetSLOs
+// @Summary Get all SLOs
+// @Description Retrieve all SLOs with their current attainment, error budget and burn rate. Filter by target with target_type and target_id.
+// @Tags SLOs
+// @Produce json
+// @Param target_type query string false "service or operational_page"
+// @Param target_id query string false "Target ID"
+// @Success 200 {object} response.Response{data=[]sloResponse}
+// @Failure 500 {object} response.Response "Could not retrieve SLOs"
+// @Security ApiKeyAuth
+// @Router /slos [get]
+func GetSLOs(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		query := db.Order("name ASC")
+		if targetType := c.Query("target_type"); targetType != "" {
+			query = query.Where("target_type = ?", targetType)
+		}
+		if targetID := c.Query("target_id"); targetID != "" {
+			id, err := uuid.Parse(targetID)
+			if err != nil {
+				return response.Fail(c, fiber.StatusBadRequest, response.CodeInvalidParam, "Invalid target ID", nil)
+			}
+			query = query.Where("target_id = ?", id)
+		}
+
+		slos := []models.SLO{}
+		if err := query.Find(&slos).Error; err != nil {
+			log.Printf("Error fetching SLOs: %v", err)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not retrieve SLOs")
+		}
+
+		now := time.Now()
+		views := make([]sloResponse, len(slos))
+		for i, s := range slos {
+			status, err := slo.Compute(db, s, now)
+			if err != nil {
+				log.Printf("Error computing SLO %s: %v", s.ID, err)
+				return response.Error(c, fiber.StatusInternalServerError, "Could not retrieve SLOs")
+			}
+			views[i] = sloResponse{SLO: s, Status: &status}
+		}
+		return response.OK(c, views)
+	}
+}
+
+// GetSLO
+// @Summary Get SLO by ID
+// @Description Retrieve a single SLO with its current attainment, error budget and burn rate
+// @Tags SLOs
+// @Produce json
+// @Param id path string true "SLO ID"
+// @Success 200 {object} response.Response{data=sloResponse}
+// @Failure 400 {object} response.Response "Invalid SLO ID"
+// @Failure 404 {object} response.Response "SLO not found"
+// @Failure 500 {object} response.Response "Could not retrieve SLO"
+// @Security ApiKeyAuth
+// @Router /slos/{id} [get]
+func GetSLO(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uuidID, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return response.Fail(c, fiber.StatusBadRequest, response.CodeInvalidParam, "Invalid SLO ID", nil)
+		}
+
+		s := models.SLO{}
+		if result := db.First(&s, "id = ?", uuidID); result.Error != nil {
+			if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+				return response.Error(c, fiber.StatusNotFound, "SLO not found")
+			}
+			log.Printf("Error fetching SLO: %v", result.Error)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not retrieve SLO")
+		}
+
+		status, err := slo.Compute(db, s, time.Now())
+		if err != nil {
+			log.Printf("Error computing SLO %s: %v", s.ID, err)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not retrieve SLO")
+		}
+
+		return response.OK(c, sloResponse{SLO: s, Status: &status})
+	}
+}
+
+// CreateSLO
+// @Summary Create a new SLO
+// @Description Define an objective on a service or operational page over a rolling 30 day or calendar month window, optionally with a latency threshold
+// @Tags SLOs
+// @Accept json
+// @Produce json
+// @Param slo body models.SLO true "SLO to create"
+// @Success 201 {object} response.Response{data=models.SLO}
+// @Failure 400 {object} response.Response "Cannot parse JSON"
+// @Failure 404 {object} response.Response "SLO target not found"
+// @Failure 500 {object} response.Response "Could not create SLO"
+// @Security ApiKeyAuth
+// @Router /slos [post]
+func CreateSLO(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		s := new(models.SLO)
+		if err := c.BodyParser(s); err != nil {
+			return response.InvalidBody(c)
+		}
+
+		if status, msg := prepareSLO(db, s); msg != "" {
+			return response.Error(c, status, msg)
+		}
+
+		s.ID = uuid.New()
+		s.AlertFiring = false
+		s.CreatedAt = time.Now()
+		s.UpdatedAt = time.Now()
+
+		if result := db.Create(s); result.Error != nil {
+			log.Printf("Error creating SLO: %v", result.Error)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not create SLO")
+		}
+
+		return response.Created(c, s)
+	}
+}
+
+// UpdateSLO
+// @Summary Update an existing SLO
+// @Description Update an SLO by its ID
+// @Tags SLOs
+// @Accept json
+// @Produce json
+// @Param id path string true "SLO ID"
+// @Param slo body models.SLO true "SLO with updated fields"
+// @Success 200 {object} response.Response{data=models.SLO}
+// @Failure 400 {object} response.Response "Invalid SLO ID" or "Cannot parse JSON"
+// @Failure 404 {object} response.Response "SLO not found"
+// @Failure 500 {object} response.Response "Could not update SLO"
+// @Security ApiKeyAuth
+// @Router /slos/{id} [put]
+func UpdateSLO(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uuidID, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return response.Fail(c, fiber.StatusBadRequest, response.CodeInvalidParam, "Invalid SLO ID", nil)
+		}
+
+		var existing models.SLO
+		if result := db.First(&existing, "id = ?", uuidID); result.Error != nil {
+			if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+				return response.Error(c, fiber.StatusNotFound, "SLO not found")
+			}
+			log.Printf("Error finding SLO for update: %v", result.Error)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not update SLO")
+		}
+
+		s := new(models.SLO)
+		if err := c.BodyParser(s); err != nil {
+			return response.InvalidBody(c)
+		}
+
+		if status, msg := prepareSLO(db, s); msg != "" {
+			return response.Error(c, status, msg)
+		}
+
+		s.ID = existing.ID
+		s.AlertFiring = existing.AlertFiring
+		s.CreatedAt = existing.CreatedAt
+		s.UpdatedAt = time.Now()
+
+		if result := db.Save(s); result.Error != nil {
+			log.Printf("Error updating SLO: %v", result.Error)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not update SLO")
+		}
+
+		return response.OK(c, s)
+	}
+}
+
+// DeleteSLO
+// @Summary Delete an SLO
+// @Description Delete an SLO by its ID
+// @Tags SLOs
+// @Produce json
+// @Param id path string true "SLO ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} response.Response "Invalid SLO ID"
+// @Failure 404 {object} response.Response "SLO not found"
+// @Failure 500 {object} response.Response "Could not delete SLO"
+// @Security ApiKeyAuth
+// @Router /slos/{id} [delete]
+func DeleteSLO(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uuidID, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return response.Fail(c, fiber.StatusBadRequest, response.CodeInvalidParam, "Invalid SLO ID", nil)
+		}
+
+		result := db.Delete(&models.SLO{}, "id = ?", uuidID)
+		if result.Error != nil {
+			log.Printf("Error deleting SLO: %v", result.Error)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not delete SLO")
+		}
+
+		if result.RowsAffected == 0 {
+			return response.Error(c, fiber.StatusNotFound, "SLO not found")
+		}
+
+		return c.Status(fiber.StatusNoContent).SendString("")
+	}
+}
+
+// prepareSLO validates an SLO from a request, checks that its target exists
+// and applies defaults. It returns a status and message on failure.
+func prepareSLO(db *gorm.DB, s *models.SLO) (int, string) {
+	if err := validate.V.Struct(s); err != nil {
+		return fiber.StatusBadRequest, err.Error()
+	}
+
+	var target interface{} = &models.Service{}
+	if s.TargetType == "operational_page" {
+		target = &models.OperationalPage{}
+	}
+	if err := db.First(target, "id = ?", s.TargetID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.StatusNotFound, "SLO target not found"
+		}
+		log.Printf("Error checking SLO target: %v", err)
+		return fiber.StatusInternalServerError, "Could not check SLO target"
+	}
+
+	if s.BurnRateThreshold == 0 {
+		s.BurnRateThreshold = slo.DefaultBurnRateThreshold
+	}
+	return 0, ""
+}
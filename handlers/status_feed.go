@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"monitron-server/config"
+	"monitron-server/internal/statuspage"
+	"monitron-server/models"
+)
+
+// Cache lifetimes of the machine-readable status page endpoints, in seconds
+const (
+	statusSummaryMaxAge = 60
+	statusFeedMaxAge    = 300
+	statusBadgeMaxAge   = 300
+)
+
+// StatusPageAtomFeed
+// @Summary Status page Atom feed
+// @Description Atom feed of the incidents and maintenance windows of a public status page over the past 30 days, plus those still open. Responses carry an ETag and honour If-None-Match.
+// @Tags Operational Pages
+// @Produce xml
+// @Param slug path string true "Operational page slug"
+// @Success 200 {string} string "Atom feed"
+// @Success 304 "Not Modified"
+// @Failure 404 {string} string "Status page not found"
+// @Router /status/{slug}/feed.atom [get]
+func StatusPageAtomFeed(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		view, pageURL, err := publicStatusView(c, db)
+		if view == nil {
+			return err
+		}
+
+		body, err := statuspage.RenderAtom(view, pageURL, pageURL+"/feed.atom")
+		if err != nil {
+			log.Printf("Error rendering Atom feed of status page %s: %v", view.Page.Slug, err)
+			return c.Status(fiber.StatusInternalServerError).SendString("Could not render feed")
+		}
+		return sendCached(c, body, "application/atom+xml; charset=utf-8", statusFeedMaxAge)
+	}
+}
+
+// StatusPageRSSFeed
+// @Summary Status page RSS feed
+// @Description RSS 2.0 feed of the incidents and maintenance windows of a public status page over the past 30 days, plus those still open. Responses carry an ETag and honour If-None-Match.
+// @Tags Operational Pages
+// @Produce xml
+// @Param slug path string true "Operational page slug"
+// @Success 200 {string} string "RSS feed"
+// @Success 304 "Not Modified"
+// @Failure 404 {string} string "Status page not found"
+// @Router /status/{slug}/feed.rss [get]
+func StatusPageRSSFeed(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		view, pageURL, err := publicStatusView(c, db)
+		if view == nil {
+			return err
+		}
+
+		body, err := statuspage.RenderRSS(view, pageURL)
+		if err != nil {
+			log.Printf("Error rendering RSS feed of status page %s: %v", view.Page.Slug, err)
+			return c.Status(fiber.StatusInternalServerError).SendString("Could not render feed")
+		}
+		return sendCached(c, body, "application/rss+xml; charset=utf-8", statusFeedMaxAge)
+	}
+}
+
+// StatusPageSummary
+// @Summary Status page summary
+// @Description Summary of a public status page in the shape of the Statuspage.io /api/v2/summary.json endpoint: overall status indicator, components, unresolved incidents and upcoming or ongoing maintenance. Responses carry an ETag and honour If-None-Match.
+// @Tags Operational Pages
+// @Produce json
+// @Param slug path string true "Operational page slug"
+// @Success 200 {object} statuspage.Summary
+// @Success 304 "Not Modified"
+// @Failure 404 {object} map[string]string
+// @Router /status/{slug}/api/v2/summary.json [get]
+func StatusPageSummary(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		view, pageURL, err := publicStatusView(c, db)
+		if view == nil {
+			return err
+		}
+
+		body, err := json.Marshal(statuspage.BuildSummary(view, pageURL))
+		if err != nil {
+			log.Printf("Error encoding summary of status page %s: %v", view.Page.Slug, err)
+			return c.Status(fiber.StatusInternalServerError).SendString("Could not render summary")
+		}
+		c.Set(fiber.HeaderAccessControlAllowOrigin, "*")
+		return sendCached(c, body, fiber.MIMEApplicationJSONCharsetUTF8, statusSummaryMaxAge)
+	}
+}
+
+// StatusComponentBadge
+// @Summary Component status badge
+// @Description SVG badge with the name and current status of a component of a public status page
+// @Tags Operational Pages
+// @Produce image/svg+xml
+// @Param slug path string true "Operational page slug"
+// @Param componentID path string true "Page component ID"
+// @Success 200 {string} string "SVG badge"
+// @Success 304 "Not Modified"
+// @Failure 404 {string} string "Component not found"
+// @Router /status/{slug}/badges/{componentID}/status.svg [get]
+func StatusComponentBadge(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		componentID, err := uuid.Parse(c.Params("componentID"))
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).SendString("Component not found")
+		}
+
+		view, _, err := publicStatusView(c, db)
+		if view == nil {
+			return err
+		}
+
+		component, ok := view.FindComponent(componentID)
+		if !ok {
+			return c.Status(fiber.StatusNotFound).SendString("Component not found")
+		}
+		return sendCached(c, statuspage.StatusBadge(component), "image/svg+xml", statusBadgeMaxAge)
+	}
+}
+
+// StatusComponentUptimeBadge
+// @Summary Component uptime badge
+// @Description SVG badge with the uptime of a component of a public status page over a period of hours or days
+// @Tags Operational Pages
+// @Produce image/svg+xml
+// @Param slug path string true "Operational page slug"
+// @Param componentID path string true "Page component ID"
+// @Param period query string false "Period such as 24h or 7d, at most 90d (default 30d)"
+// @Success 200 {string} string "SVG badge"
+// @Success 304 "Not Modified"
+// @Failure 400 {string} string "Invalid period"
+// @Failure 404 {string} string "Component not found"
+// @Router /status/{slug}/badges/{componentID}/uptime.svg [get]
+func StatusComponentUptimeBadge(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		period, err := statuspage.ParsePeriod(c.Query("period"))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+		}
+		componentID, err := uuid.Parse(c.Params("componentID"))
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).SendString("Component not found")
+		}
+
+		page, err := findPublicStatusPage(c, db)
+		if page == nil {
+			return err
+		}
+
+		component := models.OperationalPageComponent{}
+		err = db.First(&component, "id = ? AND page_id = ?", componentID, page.ID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).SendString("Component not found")
+		}
+		if err != nil {
+			log.Printf("Error fetching status page component: %v", err)
+			return c.Status(fiber.StatusInternalServerError).SendString("Could not render badge")
+		}
+
+		uptime, ok, err := statuspage.ComponentUptime(db, component, time.Now().Add(-period))
+		if err != nil {
+			log.Printf("Error computing uptime of status page component %s: %v", component.ID, err)
+			return c.Status(fiber.StatusInternalServerError).SendString("Could not render badge")
+		}
+		return sendCached(c, statuspage.UptimeBadge(uptime, ok, period), "image/svg+xml", statusBadgeMaxAge)
+	}
+}
+
+// findPublicStatusPage loads the public page of the slug parameter. Private
+// pages are reported as missing, since these endpoints are meant to be
+// embedded and cached anonymously.
+func findPublicStatusPage(c *fiber.Ctx, db *gorm.DB) (*models.OperationalPage, error) {
+	page, err := statuspage.FindPage(db, c.Params("slug"))
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && !page.IsPublic) {
+		return nil, c.Status(fiber.StatusNotFound).SendString("Status page not found")
+	}
+	if err != nil {
+		log.Printf("Error fetching status page: %v", err)
+		return nil, c.Status(fiber.StatusInternalServerError).SendString("Could not load status page")
+	}
+	return &page, nil
+}
+
+// publicStatusView builds the view of the public page of the slug parameter
+// and returns it with the page's public URL
+func publicStatusView(c *fiber.Ctx, db *gorm.DB) (*statuspage.View, string, error) {
+	page, err := findPublicStatusPage(c, db)
+	if page == nil {
+		return nil, "", err
+	}
+
+	view, err := statuspage.Build(db, *page, time.Now())
+	if err != nil {
+		log.Printf("Error building status page %s: %v", page.Slug, err)
+		return nil, "", c.Status(fiber.StatusInternalServerError).SendString("Could not load status page")
+	}
+	return view, statuspage.PageURL(config.LoadConfig().App.PublicURL, *page), nil
+}
+
+// sendCached sends a public, cacheable response with an ETag derived from the
+// body, or 304 when the client already has it
+func sendCached(c *fiber.Ctx, body []byte, contentType string, maxAge int) error {
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	c.Set(fiber.HeaderCacheControl, fmt.Sprintf("public, max-age=%d", maxAge))
+	c.Set(fiber.HeaderETag, etag)
+	if etagMatches(c.Get(fiber.HeaderIfNoneMatch), etag) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	c.Set(fiber.HeaderContentType, contentType)
+	return c.Send(body)
+}
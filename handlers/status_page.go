@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"monitron-server/internal/statuspage"
+	"monitron-server/utils/response"
+	"monitron-server/utils/validate"
+)
+
+// statusPageMaxAge is how long browsers and proxies may cache a public page
+const statusPageMaxAge = 60
+
+// StatusPage
+// @Summary Public status page
+// @Description Server-rendered status page of an operational page with component status, 30-day uptime bars, average response time, active incidents and scheduled maintenance. Private pages require a JWT as Bearer token or "token" cookie. Responses carry an ETag and honour If-None-Match.
+// @Tags Operational Pages
+// @Produce html
+// @Param slug path string true "Operational page slug"
+// @Param theme query string false "auto, light or dark"
+// @Success 200 {string} string "HTML page"
+// @Success 304 "Not Modified"
+// @Failure 401 {string} string "Sign-in required"
+// @Failure 404 {string} string "Status page not found"
+// @Router /status/{slug} [get]
+func StatusPage(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		page, err := statuspage.FindPage(db, c.Params("slug"))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).SendString("Status page not found")
+		}
+		if err != nil {
+			log.Printf("Error fetching status page: %v", err)
+			return c.Status(fiber.StatusInternalServerError).SendString("Could not load status page")
+		}
+
+		if !page.IsPublic && c.Locals("user_id") == nil {
+			return c.Status(fiber.StatusUnauthorized).SendString("Sign in to view this status page")
+		}
+
+		view, err := statuspage.Build(db, page, time.Now())
+		if err != nil {
+			log.Printf("Error building status page %s: %v", page.Slug, err)
+			return c.Status(fiber.StatusInternalServerError).SendString("Could not load status page")
+		}
+
+		etag, err := statusPageETag(view, c.Query("theme"))
+		if err != nil {
+			log.Printf("Error hashing status page %s: %v", page.Slug, err)
+			return c.Status(fiber.StatusInternalServerError).SendString("Could not render status page")
+		}
+
+		if page.IsPublic {
+			c.Set(fiber.HeaderCacheControl, fmt.Sprintf("public, max-age=%d", statusPageMaxAge))
+		} else {
+			c.Set(fiber.HeaderCacheControl, "private, no-cache")
+		}
+		c.Set(fiber.HeaderVary, "Authorization, Cookie")
+		c.Set(fiber.HeaderETag, etag)
+
+		if etagMatches(c.Get(fiber.HeaderIfNoneMatch), etag) {
+			return c.SendStatus(fiber.StatusNotModified)
+		}
+
+		body, err := statuspage.Render(view, c.Query("theme"))
+		if err != nil {
+			log.Printf("Error rendering status page %s: %v", page.Slug, err)
+			return c.Status(fiber.StatusInternalServerError).SendString("Could not render status page")
+		}
+
+		c.Type("html", "utf-8")
+		return c.Send(body)
+	}
+}
+
+// ReportStatusPageProblem
+// @Summary Report a problem from a status page
+// @Description Lets a visitor report a problem with one of the page's reasons and an optional comment. Limited to 10 reports per IP and 1000 per page within 24 hours. The "website" field is a honeypot and must stay empty; filled-in submissions are accepted but discarded. Private pages require a JWT as Bearer token or "token" cookie.
+// @Tags Operational Pages
+// @Accept json
+// @Produce json
+// @Param slug path string true "Operational page slug"
+// @Param report body object{reason=string,comment=string,website=string} true "Problem report"
+// @Success 202 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 429 {object} response.Response
+// @Router /status/{slug}/reports [post]
+func ReportStatusPageProblem(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		request := struct {
+			Reason  string `json:"reason" form:"reason" validate:"required"`
+			Comment string `json:"comment" form:"comment"`
+			Website string `json:"website" form:"website"` // Honeypot
+		}{}
+		if err := c.BodyParser(&request); err != nil {
+			return response.InvalidBody(c)
+		}
+
+		if err := validate.V.Struct(request); err != nil {
+			return response.Invalid(c, err)
+		}
+
+		page, err := statuspage.FindPage(db, c.Params("slug"))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return response.Error(c, fiber.StatusNotFound, "Status page not found")
+		}
+		if err != nil {
+			log.Printf("Error fetching status page: %v", err)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not submit report")
+		}
+
+		if !page.IsPublic && c.Locals("user_id") == nil {
+			return response.Error(c, fiber.StatusUnauthorized, "Sign in to report a problem on this page")
+		}
+
+		const accepted = "Thanks, your report has been received"
+		if request.Website != "" {
+			log.Printf("Discarding problem report for status page %s from %s: honeypot filled", page.Slug, c.IP())
+			return response.Send(c, fiber.StatusAccepted, accepted, nil)
+		}
+
+		_, err = statuspage.SubmitReport(db, page, request.Reason, request.Comment, c.IP(), c.Get(fiber.HeaderUserAgent), time.Now())
+		switch {
+		case errors.Is(err, statuspage.ErrInvalidReason):
+			return response.Error(c, fiber.StatusBadRequest, "Unknown reason")
+		case errors.Is(err, statuspage.ErrIPLimitReached), errors.Is(err, statuspage.ErrPageLimitReached):
+			return response.Error(c, fiber.StatusTooManyRequests, "Too many problem reports, please try again later")
+		case err != nil:
+			log.Printf("Error submitting problem report for status page %s: %v", page.Slug, err)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not submit report")
+		}
+
+		return response.Send(c, fiber.StatusAccepted, accepted, nil)
+	}
+}
+
+// statusPageETag hashes the page data and theme. The generation time and the
+// start of the problem report window are left out so the ETag only changes
+// when the underlying data does.
+func statusPageETag(view *statuspage.View, theme string) (string, error) {
+	stable := *view
+	stable.GeneratedAt = time.Time{}
+	stable.Problems.Since = time.Time{}
+	data, err := json.Marshal(struct {
+		View  statuspage.View
+		Theme string
+	}{stable, statuspage.NormalizeTheme(theme)})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`, nil
+}
+
+// etagMatches reports whether an If-None-Match header matches an ETag
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,281 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"monitron-server/config"
+	"monitron-server/internal/notifier"
+	"monitron-server/internal/pagesubscribers"
+	"monitron-server/internal/statuspage"
+	"monitron-server/models"
+	"monitron-server/utils"
+	"monitron-server/utils/response"
+	"monitron-server/utils/validate"
+)
+
+// statusSubscribeIPLimiter bounds how many subscriptions one address can
+// request, since each email subscription sends a confirmation email
+var statusSubscribeIPLimiter = utils.NewRateLimiter(10, time.Hour)
+
+// statusSubscriberRequest is the body of subscribe requests. Secret is only
+// used by webhook subscribers to sign deliveries.
+type statusSubscriberRequest struct {
+	models.StatusSubscriber
+	Secret string `json:"secret" validate:"max=255"`
+}
+
+// SubscribeToStatusPage
+// @Summary Subscribe to a status page
+// @Description Subscribe to component status changes and incident updates of a status page by email or webhook, optionally only for some components. Email subscriptions must be confirmed through the emailed link. Webhook subscriptions must point to a public http(s) address and are active right away, are signed like webhook notification channels when a secret is given, and the response carries their unsubscribe link. Private pages require a JWT as Bearer token or "token" cookie.
+// @Tags Operational Pages
+// @Accept json
+// @Produce json
+// @Param slug path string true "Operational page slug"
+// @Param subscriber body statusSubscriberRequest true "Subscription"
+// @Success 201 {object} response.Response{data=map[string]interface{}}
+// @Success 202 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 429 {object} response.Response
+// @Router /status/{slug}/subscribers [post]
+func SubscribeToStatusPage(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		req := new(statusSubscriberRequest)
+		if err := c.BodyParser(req); err != nil {
+			return response.InvalidBody(c)
+		}
+
+		if err := validate.V.Struct(req); err != nil {
+			return response.Invalid(c, err)
+		}
+
+		page, err := statuspage.FindPage(db, c.Params("slug"))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return response.Error(c, fiber.StatusNotFound, "Status page not found")
+		}
+		if err != nil {
+			log.Printf("Error fetching status page: %v", err)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not subscribe")
+		}
+
+		if !page.IsPublic && c.Locals("user_id") == nil {
+			return response.Error(c, fiber.StatusUnauthorized, "Sign in to subscribe to this page")
+		}
+		if msg := checkPageComponents(db, page.ID, req.ComponentIDs); msg != "" {
+			return response.Error(c, fiber.StatusBadRequest, msg)
+		}
+		if !statusSubscribeIPLimiter.Allow(c.IP()) {
+			return response.Error(c, fiber.StatusTooManyRequests, "Too many subscription requests")
+		}
+
+		subscriber, err := pagesubscribers.Subscribe(db, page, req.StatusSubscriber, req.Secret)
+		if errors.Is(err, notifier.ErrNonPublicURL) {
+			return response.Fail(c, fiber.StatusBadRequest, response.CodeValidationFailed, "Validation failed", map[string]string{"webhook_url": "must be a public http or https URL"})
+		}
+		if err != nil {
+			log.Printf("Error subscribing to status page %s: %v", page.Slug, err)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not subscribe")
+		}
+
+		if subscriber.Type == pagesubscribers.TypeWebhook {
+			return response.Created(c, fiber.Map{
+				"subscriber":      subscriber,
+				"unsubscribe_url": pagesubscribers.UnsubscribeLink(config.LoadConfig(), page, subscriber.ID),
+			})
+		}
+		return response.Send(c, fiber.StatusAccepted, "Check your inbox to confirm the subscription", nil)
+	}
+}
+
+// ConfirmStatusSubscription
+// @Summary Confirm a status page email subscription
+// @Description Target of the link in the confirmation email
+// @Tags Operational Pages
+// @Produce plain
+// @Param slug path string true "Operational page slug"
+// @Param subscriberID path string true "Subscriber ID"
+// @Param token query string true "Confirmation token"
+// @Success 200 {string} string "Subscription confirmed"
+// @Failure 400 {string} string "Invalid or expired link"
+// @Router /status/{slug}/subscribers/{subscriberID}/confirm [get]
+func ConfirmStatusSubscription(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		page, subscriberID, err := statusSubscriberLink(c, db)
+		if page == nil {
+			return err
+		}
+
+		err = pagesubscribers.Confirm(db, *page, subscriberID, c.Query("token"))
+		if errors.Is(err, pagesubscribers.ErrInvalidToken) {
+			return c.Status(fiber.StatusBadRequest).SendString("This confirmation link is invalid or has expired")
+		}
+		if err != nil {
+			log.Printf("Error confirming status subscriber %s: %v", subscriberID, err)
+			return c.Status(fiber.StatusInternalServerError).SendString("Could not confirm the subscription")
+		}
+
+		return c.SendString("You are now subscribed to " + page.Name + " status updates")
+	}
+}
+
+// UnsubscribeFromStatusPageForm
+// @Summary Confirm unsubscribing from a status page
+// @Description Target of the unsubscribe link in every status email and of webhook unsubscribe URLs. Renders a page whose form posts to the same URL, so link scanners and prefetchers do not remove the subscription.
+// @Tags Operational Pages
+// @Produce html
+// @Param slug path string true "Operational page slug"
+// @Param subscriberID path string true "Subscriber ID"
+// @Param token query string true "Unsubscribe token"
+// @Success 200 {string} string "Confirmation page"
+// @Failure 400 {string} string "Invalid or expired link"
+// @Router /status/{slug}/subscribers/{subscriberID}/unsubscribe [get]
+func UnsubscribeFromStatusPageForm(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		page, subscriberID, err := statusSubscriberLink(c, db)
+		if page == nil {
+			return err
+		}
+
+		token := c.Query("token")
+		if err := pagesubscribers.CheckUnsubscribeToken(subscriberID, token); err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString("This unsubscribe link is invalid or was already used")
+		}
+
+		body, err := statuspage.RenderUnsubscribe(page.Name, c.Path(), token)
+		if err != nil {
+			log.Printf("Error rendering unsubscribe page of %s: %v", page.Slug, err)
+			return c.Status(fiber.StatusInternalServerError).SendString("Could not render unsubscribe page")
+		}
+
+		c.Set(fiber.HeaderCacheControl, "no-store")
+		c.Type("html", "utf-8")
+		return c.Send(body)
+	}
+}
+
+// UnsubscribeFromStatusPage
+// @Summary Unsubscribe from a status page
+// @Description Removes a status page subscription. Posted by the unsubscribe page form with the token as a form field, or by one-click unsubscribe with the token in the query.
+// @Tags Operational Pages
+// @Accept x-www-form-urlencoded
+// @Produce plain
+// @Param slug path string true "Operational page slug"
+// @Param subscriberID path string true "Subscriber ID"
+// @Param token query string false "Unsubscribe token, if not posted as a form field"
+// @Success 200 {string} string "Unsubscribed"
+// @Failure 400 {string} string "Invalid or expired link"
+// @Router /status/{slug}/subscribers/{subscriberID}/unsubscribe [post]
+func UnsubscribeFromStatusPage(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		page, subscriberID, err := statusSubscriberLink(c, db)
+		if page == nil {
+			return err
+		}
+
+		token := c.Query("token")
+		if token == "" {
+			token = c.FormValue("token")
+		}
+
+		err = pagesubscribers.Unsubscribe(db, *page, subscriberID, token)
+		if errors.Is(err, pagesubscribers.ErrInvalidToken) {
+			return c.Status(fiber.StatusBadRequest).SendString("This unsubscribe link is invalid or was already used")
+		}
+		if err != nil {
+			log.Printf("Error unsubscribing status subscriber %s: %v", subscriberID, err)
+			return c.Status(fiber.StatusInternalServerError).SendString("Could not unsubscribe")
+		}
+
+		return c.SendString("You will no longer receive " + page.Name + " status updates")
+	}
+}
+
+// GetStatusSubscribers
+// @Summary Get status page subscribers (Admin Only)
+// @Description Retrieve the email and webhook subscribers of an operational page, newest first
+// @Tags Operational Pages
+// @Produce json
+// @Param pageID path string true "Operational Page ID"
+// @Success 200 {object} response.Response{data=[]models.StatusSubscriber}
+// @Failure 400 {object} response.Response "Invalid page ID"
+// @Failure 500 {object} response.Response "Could not retrieve subscribers"
+// @Security ApiKeyAuth
+// @Router /operational-pages/{pageID}/subscribers [get]
+func GetStatusSubscribers(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uuidPageID, err := uuid.Parse(c.Params("pageID"))
+		if err != nil {
+			return response.Fail(c, fiber.StatusBadRequest, response.CodeInvalidParam, "Invalid page ID", nil)
+		}
+
+		subscribers := []models.StatusSubscriber{}
+		if err := db.Where("page_id = ?", uuidPageID).Order("created_at DESC").Find(&subscribers).Error; err != nil {
+			log.Printf("Error fetching status subscribers: %v", err)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not retrieve subscribers")
+		}
+		return response.OK(c, subscribers)
+	}
+}
+
+// DeleteStatusSubscriber
+// @Summary Remove a status page subscriber (Admin Only)
+// @Description Remove a subscriber from an operational page
+// @Tags Operational Pages
+// @Param pageID path string true "Operational Page ID"
+// @Param subscriberID path string true "Subscriber ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} response.Response "Invalid subscriber ID"
+// @Failure 404 {object} response.Response "Subscriber not found"
+// @Failure 500 {object} response.Response "Could not remove subscriber"
+// @Security ApiKeyAuth
+// @Router /operational-pages/{pageID}/subscribers/{subscriberID} [delete]
+func DeleteStatusSubscriber(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uuidPageID, err := uuid.Parse(c.Params("pageID"))
+		if err != nil {
+			return response.Fail(c, fiber.StatusBadRequest, response.CodeInvalidParam, "Invalid page ID", nil)
+		}
+		uuidSubscriberID, err := uuid.Parse(c.Params("subscriberID"))
+		if err != nil {
+			return response.Fail(c, fiber.StatusBadRequest, response.CodeInvalidParam, "Invalid subscriber ID", nil)
+		}
+
+		result := db.Where("page_id = ? AND id = ?", uuidPageID, uuidSubscriberID).Delete(&models.StatusSubscriber{})
+		if result.Error != nil {
+			log.Printf("Error removing status subscriber: %v", result.Error)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not remove subscriber")
+		}
+
+		if result.RowsAffected == 0 {
+			return response.Error(c, fiber.StatusNotFound, "Subscriber not found")
+		}
+
+		return c.Status(fiber.StatusNoContent).SendString("")
+	}
+}
+
+// statusSubscriberLink resolves the page and subscriber ID of a confirm or
+// unsubscribe link, writing a plain-text error response itself when it cannot
+func statusSubscriberLink(c *fiber.Ctx, db *gorm.DB) (*models.OperationalPage, uuid.UUID, error) {
+	subscriberID, err := uuid.Parse(c.Params("subscriberID"))
+	if err != nil {
+		return nil, uuid.Nil, c.Status(fiber.StatusBadRequest).SendString("This link is invalid")
+	}
+
+	page, err := statuspage.FindPage(db, c.Params("slug"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, uuid.Nil, c.Status(fiber.StatusNotFound).SendString("Status page not found")
+	}
+	if err != nil {
+		log.Printf("Error fetching status page: %v", err)
+		return nil, uuid.Nil, c.Status(fiber.StatusInternalServerError).SendString("Could not load status page")
+	}
+	return &page, subscriberID, nil
+}
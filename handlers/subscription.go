@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"monitron-server/internal/incidents"
+	"monitron-server/models"
+	"monitron-server/utils/response"
+	"monitron-server/utils/validate"
+)
+
+// GetMySubscriptions
+// @Summary Get my subscriptions
+// @Description Retrieve the targets the authenticated user receives alerts for
+// @Tags Notifications
+// @Produce json
+// @Success 200 {object} response.Response{data=[]models.UserSubscription}
+// @Failure 500 {object} response.Response "Could not retrieve subscriptions"
+// @Security ApiKeyAuth
+// @Router /user/subscriptions [get]
+func GetMySubscriptions(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uuid.UUID)
+
+		subscriptions := []models.UserSubscription{}
+		if result := db.Where("user_id = ?", userID).Order("created_at ASC").Find(&subscriptions); result.Error != nil {
+			log.Printf("Error fetching subscriptions: %v", result.Error)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not retrieve subscriptions")
+		}
+
+		return response.OK(c, subscriptions)
+	}
+}
+
+// CreateMySubscription
+// @Summary Subscribe to a target
+// @Description Subscribe the authenticated user to a service, instance, domain/SSL entry, group or operational page
+// @Tags Notifications
+// @Accept json
+// @Produce json
+// @Param subscription body models.UserSubscription true "Subscription target"
+// @Success 201 {object} response.Response{data=models.UserSubscription}
+// @Failure 400 {object} response.Response "Cannot parse JSON"
+// @Failure 404 {object} response.Response "Subscription target not found"
+// @Failure 500 {object} response.Response "Could not create subscription"
+// @Security ApiKeyAuth
+// @Router /user/subscriptions [post]
+func CreateMySubscription(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uuid.UUID)
+
+		subscription := new(models.UserSubscription)
+		if err := c.BodyParser(subscription); err != nil {
+			return response.InvalidBody(c)
+		}
+
+		if err := validate.V.Struct(subscription); err != nil {
+			return response.Invalid(c, err)
+		}
+
+		if subscription.TargetType == "group" {
+			if subscription.GroupName == "" {
+				return response.Error(c, fiber.StatusBadRequest, "group_name is required for group subscriptions")
+			}
+			subscription.TargetID = nil
+		} else {
+			if subscription.TargetID == nil {
+				return response.Error(c, fiber.StatusBadRequest, "target_id is required")
+			}
+			subscription.GroupName = ""
+
+			var err error
+			if subscription.TargetType == "operational_page" {
+				err = db.First(&models.OperationalPage{}, "id = ?", *subscription.TargetID).Error
+			} else {
+				_, err = incidents.LoadTarget(db, subscription.TargetType, *subscription.TargetID)
+			}
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return response.Error(c, fiber.StatusNotFound, "Subscription target not found")
+			}
+			if err != nil {
+				log.Printf("Error checking subscription target: %v", err)
+				return response.Error(c, fiber.StatusInternalServerError, "Could not create subscription")
+			}
+		}
+
+		subscription.ID = uuid.New()
+		subscription.UserID = userID
+		subscription.CreatedAt = time.Now()
+
+		if result := db.Create(subscription); result.Error != nil {
+			log.Printf("Error creating subscription: %v", result.Error)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not create subscription")
+		}
+
+		return response.Created(c, subscription)
+	}
+}
+
+// DeleteMySubscription
+// @Summary Unsubscribe from a target
+// @Description Delete one of the authenticated user's subscriptions
+// @Tags Notifications
+// @Produce json
+// @Param id path string true "Subscription ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} response.Response "Invalid subscription ID"
+// @Failure 404 {object} response.Response "Subscription not found"
+// @Failure 500 {object} response.Response "Could not delete subscription"
+// @Security ApiKeyAuth
+// @Router /user/subscriptions/{id} [delete]
+func DeleteMySubscription(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uuid.UUID)
+
+		uuidID, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return response.Fail(c, fiber.StatusBadRequest, response.CodeInvalidParam, "Invalid subscription ID", nil)
+		}
+
+		result := db.Where("id = ? AND user_id = ?", uuidID, userID).Delete(&models.UserSubscription{})
+		if result.Error != nil {
+			log.Printf("Error deleting subscription: %v", result.Error)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not delete subscription")
+		}
+
+		if result.RowsAffected == 0 {
+			return response.Error(c, fiber.StatusNotFound, "Subscription not found")
+		}
+
+		return c.Status(fiber.StatusNoContent).SendString("")
+	}
+}
+
+// GetMyNotificationPreferences
+// @Summary Get my notification preferences
+// @Description Retrieve the authenticated user's channels per severity and quiet hours
+// @Tags Notifications
+// @Produce json
+// @Success 200 {object} response.Response{data=models.UserNotificationPreference}
+// @Failure 500 {object} response.Response "Could not retrieve notification preferences"
+// @Security ApiKeyAuth
+// @Router /user/notification-preferences [get]
+func GetMyNotificationPreferences(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uuid.UUID)
+
+		pref := models.UserNotificationPreference{}
+		if result := db.First(&pref, "user_id = ?", userID); result.Error != nil {
+			if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+				return response.OK(c, incidents.DefaultPreference(userID))
+			}
+			log.Printf("Error fetching notification preferences: %v", result.Error)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not retrieve notification preferences")
+		}
+
+		return response.OK(c, pref)
+	}
+}
+
+// UpdateMyNotificationPreferences
+// @Summary Update my notification preferences
+// @Description Set the channels used per severity ("email" targets the user's own address) and quiet hours with a timezone
+// @Tags Notifications
+// @Accept json
+// @Produce json
+// @Param preferences body models.UserNotificationPreference true "Notification preferences"
+// @Success 200 {object} response.Response{data=models.UserNotificationPreference}
+// @Failure 400 {object} response.Response "Cannot parse JSON"
+// @Failure 500 {object} response.Response "Could not update notification preferences"
+// @Security ApiKeyAuth
+// @Router /user/notification-preferences [put]
+func UpdateMyNotificationPreferences(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uuid.UUID)
+
+		pref := new(models.UserNotificationPreference)
+		if err := c.BodyParser(pref); err != nil {
+			return response.InvalidBody(c)
+		}
+
+		if err := validate.V.Struct(pref); err != nil {
+			return response.Invalid(c, err)
+		}
+
+		if pref.QuietHoursEnabled && (pref.QuietHoursStart == "" || pref.QuietHoursEnd == "") {
+			return response.Error(c, fiber.StatusBadRequest, "quiet_hours_start and quiet_hours_end are required when quiet hours are enabled")
+		}
+
+		for severity, channels := range pref.SeverityChannels {
+			if severity != incidents.SeverityInfo && severity != incidents.SeverityWarning && severity != incidents.SeverityCritical {
+				return response.Error(c, fiber.StatusBadRequest, "Unknown severity: "+severity)
+			}
+			for _, channel := range channels {
+				if channel == incidents.EmailChannel {
+					continue
+				}
+				channelID, err := uuid.Parse(channel)
+				if err != nil {
+					return response.Error(c, fiber.StatusBadRequest, "Invalid channel ID: "+channel)
+				}
+				if err := db.First(&models.NotificationChannel{}, "id = ?", channelID).Error; err != nil {
+					return response.Error(c, fiber.StatusBadRequest, "Notification channel not found: "+channel)
+				}
+			}
+		}
+
+		if pref.Timezone == "" {
+			pref.Timezone = "UTC"
+		}
+		pref.UserID = userID
+		pref.UpdatedAt = time.Now()
+
+		if result := db.Save(pref); result.Error != nil {
+			log.Printf("Error saving notification preferences: %v", result.Error)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not update notification preferences")
+		}
+
+		return response.OK(c, pref)
+	}
+}
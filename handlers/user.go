@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"log"
+	"net/url"
+	"strings"
 	"time"
 
 	"errors"
@@ -11,7 +13,12 @@ import (
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 
+	"monitron-server/config"
+	"monitron-server/internal/notifier"
+	"monitron-server/messaging"
 	"monitron-server/models"
+	"monitron-server/utils"
+	"monitron-server/utils/response"
 	"monitron-server/utils/validate"
 )
 
@@ -20,8 +27,8 @@ import (
 // @Description Retrieve a list of all registered users
 // @Tags User Management
 // @Produce json
-// @Success 200 {array} models.User
-// @Failure 500 {object} map[string]string "error": "Could not retrieve users"
+// @Success 200 {object} response.Response{data=[]models.User}
+// @Failure 500 {object} response.Response "Could not retrieve users"
 // @Security ApiKeyAuth
 // @Router /users [get]
 func GetUsers(db *gorm.DB) fiber.Handler {
@@ -29,10 +36,10 @@ func GetUsers(db *gorm.DB) fiber.Handler {
 		users := []models.User{}
 		if result := db.Select("id", "username", "email", "role", "status", "last_login", "created_at", "updated_at").Find(&users); result.Error != nil {
 			log.Printf("Error fetching users: %v", result.Error)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not retrieve users"})
+			return response.Error(c, fiber.StatusInternalServerError, "Could not retrieve users")
 		}
 
-		return c.JSON(users)
+		return response.OK(c, users)
 	}
 }
 
@@ -42,10 +49,10 @@ func GetUsers(db *gorm.DB) fiber.Handler {
 // @Tags User Management
 // @Produce json
 // @Param id path string true "User ID"
-// @Success 200 {object} models.User
-// @Failure 400 {object} map[string]string "error": "Invalid user ID"
-// @Failure 404 {object} map[string]string "error": "User not found"
-// @Failure 500 {object} map[string]string "error": "Could not retrieve user"
+// @Success 200 {object} response.Response{data=models.User}
+// @Failure 400 {object} response.Response "Invalid user ID"
+// @Failure 404 {object} response.Response "User not found"
+// @Failure 500 {object} response.Response "Could not retrieve user"
 // @Security ApiKeyAuth
 // @Router /users/{id} [get]
 func GetUser(db *gorm.DB) fiber.Handler {
@@ -53,19 +60,19 @@ func GetUser(db *gorm.DB) fiber.Handler {
 		id := c.Params("id")
 		uuidID, err := uuid.Parse(id)
 		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+			return response.Fail(c, fiber.StatusBadRequest, response.CodeInvalidParam, "Invalid user ID", nil)
 		}
 
 		user := models.User{}
 		if result := db.Select("id", "username", "email", "role", "status", "last_login", "created_at", "updated_at").First(&user, "id = ?", uuidID); result.Error != nil {
 			if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+				return response.Error(c, fiber.StatusNotFound, "User not found")
 			}
 			log.Printf("Error fetching user: %v", result.Error)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not retrieve user"})
+			return response.Error(c, fiber.StatusInternalServerError, "Could not retrieve user")
 		}
 
-		return c.JSON(user)
+		return response.OK(c, user)
 	}
 }
 
@@ -77,10 +84,10 @@ func GetUser(db *gorm.DB) fiber.Handler {
 // @Produce json
 // @Param id path string true "User ID"
 // @Param user body models.User true "User object with updated fields"
-// @Success 200 {object} models.User
-// @Failure 400 {object} map[string]string "error": "Invalid user ID" or "Cannot parse JSON"
-// @Failure 404 {object} map[string]string "error": "User not found"
-// @Failure 500 {object} map[string]string "error": "Could not update user"
+// @Success 200 {object} response.Response{data=models.User}
+// @Failure 400 {object} response.Response "Invalid user ID" or "Cannot parse JSON"
+// @Failure 404 {object} response.Response "User not found"
+// @Failure 500 {object} response.Response "Could not update user"
 // @Security ApiKeyAuth
 // @Router /users/{id} [put]
 func UpdateUser(db *gorm.DB) fiber.Handler {
@@ -88,33 +95,33 @@ func UpdateUser(db *gorm.DB) fiber.Handler {
 		id := c.Params("id")
 		uuidID, err := uuid.Parse(id)
 		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+			return response.Fail(c, fiber.StatusBadRequest, response.CodeInvalidParam, "Invalid user ID", nil)
 		}
 
 		user := new(models.User)
 		if err := c.BodyParser(user); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON"})
+			return response.InvalidBody(c)
 		}
 
 		var existingUser models.User
 		if result := db.First(&existingUser, "id = ?", uuidID); result.Error != nil {
 			if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+				return response.Error(c, fiber.StatusNotFound, "User not found")
 			}
 			log.Printf("Error finding user for update: %v", result.Error)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not update user"})
+			return response.Error(c, fiber.StatusInternalServerError, "Could not update user")
 		}
 
 		if err := validate.V.Struct(user); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+			return response.Invalid(c, err)
 		}
 
 		if result := db.Model(&existingUser).Updates(user); result.Error != nil {
 			log.Printf("Error updating user: %v", result.Error)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not update user"})
+			return response.Error(c, fiber.StatusInternalServerError, "Could not update user")
 		}
 
-		return c.JSON(existingUser)
+		return response.OK(c, existingUser)
 	}
 }
 
@@ -125,9 +132,9 @@ func UpdateUser(db *gorm.DB) fiber.Handler {
 // @Produce json
 // @Param id path string true "User ID"
 // @Success 204 "No Content"
-// @Failure 400 {object} map[string]string "error": "Invalid user ID"
-// @Failure 404 {object} map[string]string "error": "User not found"
-// @Failure 500 {object} map[string]string "error": "Could not delete user"
+// @Failure 400 {object} response.Response "Invalid user ID"
+// @Failure 404 {object} response.Response "User not found"
+// @Failure 500 {object} response.Response "Could not delete user"
 // @Security ApiKeyAuth
 // @Router /users/{id} [delete]
 func DeleteUser(db *gorm.DB) fiber.Handler {
@@ -135,14 +142,14 @@ func DeleteUser(db *gorm.DB) fiber.Handler {
 		id := c.Params("id")
 		uuidID, err := uuid.Parse(id)
 		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+			return response.Fail(c, fiber.StatusBadRequest, response.CodeInvalidParam, "Invalid user ID", nil)
 		}
 
 		if result := db.Delete(&models.User{}, "id = ?", uuidID); result.Error != nil {
 			log.Printf("Error deleting user: %v", result.Error)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not delete user"})
+			return response.Error(c, fiber.StatusInternalServerError, "Could not delete user")
 		} else if result.RowsAffected == 0 {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+			return response.Error(c, fiber.StatusNotFound, "User not found")
 		}
 
 		return c.Status(fiber.StatusNoContent).SendString("")
@@ -156,10 +163,10 @@ func DeleteUser(db *gorm.DB) fiber.Handler {
 // @Accept json
 // @Produce json
 // @Param passwordChange body object{current_password:string,new_password:string} true "Current and new passwords"
-// @Success 200 {object} map[string]string "message": "Password changed successfully"
-// @Failure 400 {object} map[string]string "error": "Cannot parse JSON"
-// @Failure 401 {object} map[string]string "error": "Invalid current password"
-// @Failure 500 {object} map[string]string "error": "Could not change password"
+// @Success 200 {object} response.Response "Password changed successfully"
+// @Failure 400 {object} response.Response "Cannot parse JSON"
+// @Failure 401 {object} response.Response "Invalid current password"
+// @Failure 500 {object} response.Response "Could not change password"
 // @Security ApiKeyAuth
 // @Router /user/change-password [put]
 func ChangePassword(db *gorm.DB) fiber.Handler {
@@ -172,25 +179,25 @@ func ChangePassword(db *gorm.DB) fiber.Handler {
 		}{}
 
 		if err := validate.V.Struct(passwordChange); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+			return response.Invalid(c, err)
 		}
 
 		user := models.User{}
 		if result := db.First(&user, "id = ?", userID); result.Error != nil {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User not found"})
+			return response.Error(c, fiber.StatusUnauthorized, "User not found")
 		}
 
 		// Verify current password
 		err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(passwordChange.CurrentPassword))
 		if err != nil {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid current password"})
+			return response.Error(c, fiber.StatusUnauthorized, "Invalid current password")
 		}
 
 		// Hash new password
 		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(passwordChange.NewPassword), bcrypt.DefaultCost)
 		if err != nil {
 			log.Printf("Error hashing new password: %v", err)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not hash new password"})
+			return response.Error(c, fiber.StatusInternalServerError, "Could not hash new password")
 		}
 
 		// Update password in DB
@@ -198,13 +205,22 @@ func ChangePassword(db *gorm.DB) fiber.Handler {
 		user.UpdatedAt = time.Now()
 		if result := db.Save(&user); result.Error != nil {
 			log.Printf("Error updating password: %v", result.Error)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not change password"})
+			return response.Error(c, fiber.StatusInternalServerError, "Could not change password")
 		}
 
-		return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Password changed successfully"})
+		return response.Send(c, fiber.StatusOK, "Password changed successfully", nil)
 	}
 }
 
+// Password reset request limits, applied per email and per client IP
+var (
+	passwordResetEmailLimiter = utils.NewRateLimiter(3, time.Hour)
+	passwordResetIPLimiter    = utils.NewRateLimiter(10, time.Hour)
+)
+
+// passwordResetTokenTTL is how long an emailed reset link stays valid
+const passwordResetTokenTTL = 30 * time.Minute
+
 // ForgotPassword
 // @Summary Initiate password reset
 // @Description Sends a password reset link to the user\'s email
@@ -212,49 +228,97 @@ func ChangePassword(db *gorm.DB) fiber.Handler {
 // @Accept json
 // @Produce json
 // @Param email body object{email:string} true "User email"
-// @Success 200 {object} map[string]string "message": "If an account with that email exists, a password reset link has been sent."
-// @Failure 400 {object} map[string]string "error": "Cannot parse JSON"
-// @Failure 500 {object} map[string]string "error": "Could not initiate password reset"
+// @Success 200 {object} response.Response "If an account with that email exists, a password reset link has been sent."
+// @Failure 400 {object} response.Response "Cannot parse JSON"
+// @Failure 429 {object} response.Response "Too many password reset requests"
+// @Failure 500 {object} response.Response "Could not initiate password reset"
 // @Router /password/forgot [post]
 func ForgotPassword(db *gorm.DB) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		resetRequest := struct {
-			Email string `json:"email"`
+			Email string `json:"email" validate:"required,email"`
 		}{}
 
+		if err := c.BodyParser(&resetRequest); err != nil {
+			return response.InvalidBody(c)
+		}
+
 		if err := validate.V.Struct(resetRequest); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+			return response.Invalid(c, err)
+		}
+
+		email := strings.ToLower(strings.TrimSpace(resetRequest.Email))
+		if !passwordResetIPLimiter.Allow(c.IP()) || !passwordResetEmailLimiter.Allow(email) {
+			return response.Error(c, fiber.StatusTooManyRequests, "Too many password reset requests")
 		}
 
 		user := models.User{}
-		if result := db.First(&user, "email = ?", resetRequest.Email); result.Error != nil {
+		if result := db.First(&user, "LOWER(email) = ?", email); result.Error != nil {
 			// For security, always return a generic success message even if user not found
-			log.Printf("Forgot password request for non-existent email: %s", resetRequest.Email)
-			return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "If an account with that email exists, a password reset link has been sent."})
+			log.Printf("Forgot password request for non-existent email: %s", email)
+			return response.Send(c, fiber.StatusOK, "If an account with that email exists, a password reset link has been sent.", nil)
 		}
 
-		// Generate token
-		token := uuid.New().String()
-		expiresAt := time.Now().Add(30 * time.Minute) // Token valid for 30 minutes
+		// Generate token; only its hash is stored
+		token, err := utils.GenerateToken(32)
+		if err != nil {
+			log.Printf("Error generating password reset token: %v", err)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not initiate password reset")
+		}
 
 		passwordResetToken := models.PasswordResetToken{
 			ID:        uuid.New(),
 			UserID:    user.ID,
-			Token:     token,
-			ExpiresAt: expiresAt,
+			TokenHash: utils.HashToken(token),
+			ExpiresAt: time.Now().Add(passwordResetTokenTTL),
 			CreatedAt: time.Now(),
 		}
 
-		if result := db.Create(&passwordResetToken); result.Error != nil {
-			log.Printf("Error saving password reset token: %v", result.Error)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not initiate password reset"})
+		// Issuing a new token invalidates all earlier ones for the user
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("user_id = ?", user.ID).Delete(&models.PasswordResetToken{}).Error; err != nil {
+				return err
+			}
+			return tx.Create(&passwordResetToken).Error
+		})
+		if err != nil {
+			log.Printf("Error saving password reset token: %v", err)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not initiate password reset")
 		}
 
-		// TODO: Send email with reset link (e.g., http://your-frontend/reset-password?token=TOKEN)
-		log.Printf("Password reset token for %s: %s", user.Email, token)
+		if err := sendPasswordResetEmail(db, user, token); err != nil {
+			log.Printf("Error queueing password reset email for %s: %v", user.Email, err)
+			return response.Error(c, fiber.StatusInternalServerError, "Could not initiate password reset")
+		}
+
+		return response.Send(c, fiber.StatusOK, "If an account with that email exists, a password reset link has been sent.", nil)
+	}
+}
+
+// sendPasswordResetEmail renders the password reset template and queues it
+func sendPasswordResetEmail(db *gorm.DB, user models.User, token string) error {
+	cfg := config.LoadConfig()
+	data := notifier.TemplateData{
+		User:      notifier.TemplateUser{Username: user.Username, Email: user.Email},
+		Link:      strings.TrimRight(cfg.App.FrontendURL, "/") + "/reset-password?token=" + url.QueryEscape(token),
+		ExpiresIn: passwordResetTokenTTL.String(),
+	}
 
-		return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "If an account with that email exists, a password reset link has been sent."})
+	htmlMsg, err := notifier.Render(db, notifier.EventPasswordReset, notifier.FormatHTML, data)
+	if err != nil {
+		return err
 	}
+	textMsg, err := notifier.Render(db, notifier.EventPasswordReset, notifier.FormatPlain, data)
+	if err != nil {
+		return err
+	}
+
+	return messaging.PublishEmail(messaging.EmailTask{
+		To:       user.Email,
+		Subject:  htmlMsg.Subject,
+		Body:     htmlMsg.Body,
+		TextBody: textMsg.Body,
+	})
 }
 
 // ResetPassword
@@ -264,48 +328,48 @@ func ForgotPassword(db *gorm.DB) fiber.Handler {
 // @Accept json
 // @Produce json
 // @Param resetRequest body object{token:string,new_password:string} true "Token and new password"
-// @Success 200 {object} map[string]string "message": "Password reset successfully"
-// @Failure 400 {object} map[string]string "error": "Invalid or expired token" or "Cannot parse JSON"
-// @Failure 500 {object} map[string]string "error": "Could not reset password"
+// @Success 200 {object} response.Response "Password reset successfully"
+// @Failure 400 {object} response.Response "Invalid or expired token" or "Cannot parse JSON"
+// @Failure 500 {object} response.Response "Could not reset password"
 // @Router /password/reset [post]
 func ResetPassword(db *gorm.DB) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		resetRequest := struct {
-			Token       string `json:"token"`
-			NewPassword string `json:"new_password"`
+			Token       string `json:"token" validate:"required"`
+			NewPassword string `json:"new_password" validate:"required,min=8"`
 		}{}
 
 		if err := c.BodyParser(&resetRequest); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON"})
+			return response.InvalidBody(c)
 		}
 
 		if err := validate.V.Struct(resetRequest); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+			return response.Invalid(c, err)
 		}
 		passwordResetToken := models.PasswordResetToken{}
-		if result := db.First(&passwordResetToken, "token = ? AND expires_at > ?", resetRequest.Token, time.Now()); result.Error != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid or expired token"})
+		if result := db.First(&passwordResetToken, "token_hash = ? AND expires_at > ?", utils.HashToken(resetRequest.Token), time.Now()); result.Error != nil {
+			return response.Error(c, fiber.StatusBadRequest, "Invalid or expired token")
 		}
 
 		// Hash new password
 		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(resetRequest.NewPassword), bcrypt.DefaultCost)
 		if err != nil {
 			log.Printf("Error hashing new password: %v", err)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not reset password"})
+			return response.Error(c, fiber.StatusInternalServerError, "Could not reset password")
 		}
 
 		// Update user\'s password
 		var user models.User
 		if result := db.First(&user, "id = ?", passwordResetToken.UserID); result.Error != nil {
 			log.Printf("Error finding user to reset password: %v", result.Error)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not reset password"})
+			return response.Error(c, fiber.StatusInternalServerError, "Could not reset password")
 		}
 
 		user.Password = string(hashedPassword)
 		user.UpdatedAt = time.Now()
 		if result := db.Save(&user); result.Error != nil {
 			log.Printf("Error updating user password: %v", result.Error)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not reset password"})
+			return response.Error(c, fiber.StatusInternalServerError, "Could not reset password")
 		}
 
 		// Invalidate.V the token
@@ -313,6 +377,6 @@ func ResetPassword(db *gorm.DB) fiber.Handler {
 			log.Printf("Error deleting password reset token: %v", result.Error)
 		}
 
-		return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Password reset successfully"})
+		return response.Send(c, fiber.StatusOK, "Password reset successfully", nil)
 	}
 }
@@ -0,0 +1,98 @@
+package incidents
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+
+	"monitron-server/models"
+)
+
+// EvaluateInterval is how often Evaluate runs
+const EvaluateInterval = time.Minute
+
+// evaluateWindow is how recent the latest check of a target must be for
+// Evaluate to act on it, so targets that are no longer checked keep their
+// incident state
+const evaluateWindow = 15 * time.Minute
+
+// evaluateLockKey is the Postgres advisory lock taken by Evaluate, so only one
+// server replica opens and resolves incidents at a time
+const evaluateLockKey = 7770030
+
+// Evaluate opens an incident for every target whose latest check failed and
+// resolves the open incident of every target whose latest check passed. It
+// returns right away while another replica is evaluating.
+func Evaluate(db *gorm.DB, now time.Time) error {
+	return db.Connection(func(conn *gorm.DB) error {
+		locked := false
+		if err := conn.Raw("SELECT pg_try_advisory_lock(?)", evaluateLockKey).Scan(&locked).Error; err != nil {
+			return fmt.Errorf("could not take incident evaluation lock: %w", err)
+		}
+		if !locked {
+			return nil
+		}
+		defer func() {
+			if err := conn.Exec("SELECT pg_advisory_unlock(?)", evaluateLockKey).Error; err != nil {
+				log.Printf("Error releasing incident evaluation lock: %v", err)
+			}
+		}()
+		return evaluate(db, now)
+	})
+}
+
+func evaluate(db *gorm.DB, now time.Time) error {
+	latest := []models.CheckResult{}
+	err := db.Raw(`SELECT DISTINCT ON (target_type, target_id) * FROM check_results
+		WHERE checked_at > ? ORDER BY target_type, target_id, checked_at DESC`,
+		now.Add(-evaluateWindow)).Scan(&latest).Error
+	if err != nil {
+		return fmt.Errorf("could not load latest checks: %w", err)
+	}
+
+	open := []models.Incident{}
+	if err := db.Where("status = ?", StatusOpen).Find(&open).Error; err != nil {
+		return fmt.Errorf("could not load open incidents: %w", err)
+	}
+	hasOpen := map[string]bool{}
+	for _, incident := range open {
+		hasOpen[incident.TargetType+"/"+incident.TargetID.String()] = true
+	}
+
+	for _, result := range latest {
+		key := result.TargetType + "/" + result.TargetID.String()
+		switch {
+		case result.Status != "up" && !hasOpen[key]:
+			if _, err := Open(db, result.TargetType, result.TargetID, checkTitle(result), SeverityCritical); err != nil {
+				log.Printf("Error opening incident for %s: %v", key, err)
+			}
+		case result.Status == "up" && hasOpen[key]:
+			if _, err := Resolve(db, result.TargetType, result.TargetID); err != nil {
+				log.Printf("Error resolving incident of %s: %v", key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// targetNouns names target types in incident titles
+var targetNouns = map[string]string{
+	"service":    "Service",
+	"instance":   "Instance",
+	"domain_ssl": "Domain/SSL",
+}
+
+// checkTitle names the incident of a failed check
+func checkTitle(result models.CheckResult) string {
+	noun, ok := targetNouns[result.TargetType]
+	if !ok {
+		noun = result.TargetType
+	}
+	title := noun + " check failed"
+	if result.Message != "" {
+		title += ": " + result.Message
+	}
+	return title
+}
@@ -0,0 +1,108 @@
+package incidents
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"monitron-server/internal/pagestats"
+	"monitron-server/internal/pagesubscribers"
+	"monitron-server/internal/statuspage"
+	"monitron-server/models"
+)
+
+// Incident severities
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Incident statuses
+const (
+	StatusOpen     = "open"
+	StatusResolved = "resolved"
+)
+
+// Open records a new incident for a target and notifies its subscribers.
+// If the target already has an open incident, that incident is returned
+// unchanged and nobody is notified again.
+func Open(db *gorm.DB, targetType string, targetID uuid.UUID, title, severity string) (models.Incident, error) {
+	existing := models.Incident{}
+	err := db.First(&existing, "target_type = ? AND target_id = ? AND status = ?", targetType, targetID, StatusOpen).Error
+	if err == nil {
+		return existing, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return existing, fmt.Errorf("could not look up open incident: %w", err)
+	}
+
+	now := time.Now()
+	incident := models.Incident{
+		ID:         uuid.New(),
+		TargetType: targetType,
+		TargetID:   targetID,
+		Title:      title,
+		Severity:   severity,
+		Status:     StatusOpen,
+		StartedAt:  now,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if err := db.Create(&incident).Error; err != nil {
+		return incident, fmt.Errorf("could not create incident: %w", err)
+	}
+
+	log.Printf("Incident %s opened for %s %s: %s", incident.ID, targetType, targetID, title)
+	if err := pagestats.RefreshTarget(db, targetType, targetID); err != nil {
+		log.Printf("Error refreshing operational page stats for incident %s: %v", incident.ID, err)
+	}
+	status := statuspage.StatusDegraded
+	if severity == SeverityCritical {
+		status = statuspage.StatusMajorOutage
+	}
+	if err := pagesubscribers.NotifyComponentChange(db, targetType, targetID, status); err != nil {
+		log.Printf("Error notifying status page subscribers of incident %s: %v", incident.ID, err)
+	}
+	if err := NotifySubscribers(db, incident, EventOpened); err != nil {
+		log.Printf("Error notifying subscribers of incident %s: %v", incident.ID, err)
+	}
+	return incident, nil
+}
+
+// Resolve closes the open incident of a target, if any, and notifies its
+// subscribers. It returns nil when the target had no open incident.
+func Resolve(db *gorm.DB, targetType string, targetID uuid.UUID) (*models.Incident, error) {
+	incident := models.Incident{}
+	err := db.First(&incident, "target_type = ? AND target_id = ? AND status = ?", targetType, targetID, StatusOpen).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not look up open incident: %w", err)
+	}
+
+	now := time.Now()
+	incident.Status = StatusResolved
+	incident.ResolvedAt = &now
+	incident.UpdatedAt = now
+	if err := db.Save(&incident).Error; err != nil {
+		return nil, fmt.Errorf("could not resolve incident: %w", err)
+	}
+
+	log.Printf("Incident %s resolved for %s %s", incident.ID, targetType, targetID)
+	if err := pagestats.RefreshTarget(db, targetType, targetID); err != nil {
+		log.Printf("Error refreshing operational page stats for incident %s: %v", incident.ID, err)
+	}
+	if err := pagesubscribers.NotifyComponentChange(db, targetType, targetID, statuspage.StatusOperational); err != nil {
+		log.Printf("Error notifying status page subscribers of incident %s: %v", incident.ID, err)
+	}
+	if err := NotifySubscribers(db, incident, EventResolved); err != nil {
+		log.Printf("Error notifying subscribers of incident %s: %v", incident.ID, err)
+	}
+	return &incident, nil
+}
@@ -0,0 +1,258 @@
+package incidents
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"monitron-server/config"
+	"monitron-server/internal/notifier"
+	"monitron-server/messaging"
+	"monitron-server/models"
+)
+
+// Incident notification events
+const (
+	EventOpened   = notifier.EventIncidentOpened
+	EventResolved = notifier.EventIncidentResolved
+)
+
+// EmailChannel is the pseudo channel ID that routes to a user's own address
+const EmailChannel = "email"
+
+// Target is the monitored object an incident belongs to
+type Target struct {
+	Type  string
+	ID    uuid.UUID
+	Name  string
+	Group string
+}
+
+// LoadTarget resolves the display name and group of an incident target
+func LoadTarget(db *gorm.DB, targetType string, targetID uuid.UUID) (Target, error) {
+	target := Target{Type: targetType, ID: targetID}
+
+	switch targetType {
+	case "service":
+		service := models.Service{}
+		if err := db.First(&service, "id = ?", targetID).Error; err != nil {
+			return target, err
+		}
+		target.Name, target.Group = service.Name, service.Group
+	case "instance":
+		instance := models.Instance{}
+		if err := db.First(&instance, "id = ?", targetID).Error; err != nil {
+			return target, err
+		}
+		target.Name, target.Group = instance.Name, instance.Group
+	case "domain_ssl":
+		domainSSL := models.DomainSSL{}
+		if err := db.First(&domainSSL, "id = ?", targetID).Error; err != nil {
+			return target, err
+		}
+		target.Name = domainSSL.Domain
+	default:
+		return target, fmt.Errorf("unknown target type: %s", targetType)
+	}
+	return target, nil
+}
+
+// DefaultPreference applies to users who never saved their preferences:
+// every severity goes to their own email and quiet hours are off.
+func DefaultPreference(userID uuid.UUID) models.UserNotificationPreference {
+	return models.UserNotificationPreference{
+		UserID: userID,
+		SeverityChannels: map[string][]string{
+			SeverityInfo:     {EmailChannel},
+			SeverityWarning:  {EmailChannel},
+			SeverityCritical: {EmailChannel},
+		},
+		Timezone:       "UTC",
+		CriticalBypass: true,
+	}
+}
+
+// InQuietHours reports whether t falls within the user's quiet hours. Ranges
+// that wrap past midnight (e.g. 22:00-07:00) are supported.
+func InQuietHours(pref models.UserNotificationPreference, t time.Time) bool {
+	if !pref.QuietHoursEnabled || pref.QuietHoursStart == "" || pref.QuietHoursEnd == "" {
+		return false
+	}
+
+	loc, err := time.LoadLocation(pref.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	start, err := time.Parse("15:04", pref.QuietHoursStart)
+	if err != nil {
+		return false
+	}
+	end, err := time.Parse("15:04", pref.QuietHoursEnd)
+	if err != nil {
+		return false
+	}
+
+	local := t.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	startMinute := start.Hour()*60 + start.Minute()
+	endMinute := end.Hour()*60 + end.Minute()
+
+	if startMinute <= endMinute {
+		return minute >= startMinute && minute < endMinute
+	}
+	return minute >= startMinute || minute < endMinute
+}
+
+// subscriberIDs returns the users subscribed to the target directly, through
+// its group or through an operational page that shows it
+func subscriberIDs(db *gorm.DB, target Target) ([]uuid.UUID, error) {
+	pageIDs := []uuid.UUID{}
+	if err := db.Model(&models.OperationalPageComponent{}).
+		Where("component_type = ? AND component_id = ?", target.Type, target.ID).
+		Distinct().Pluck("page_id", &pageIDs).Error; err != nil {
+		return nil, err
+	}
+
+	query := db.Model(&models.UserSubscription{}).
+		Where("target_type = ? AND target_id = ?", target.Type, target.ID)
+	if target.Group != "" {
+		query = query.Or("target_type = ? AND group_name = ?", "group", target.Group)
+	}
+	if len(pageIDs) > 0 {
+		query = query.Or("target_type = ? AND target_id IN ?", "operational_page", pageIDs)
+	}
+
+	userIDs := []uuid.UUID{}
+	if err := query.Distinct().Pluck("user_id", &userIDs).Error; err != nil {
+		return nil, err
+	}
+	return userIDs, nil
+}
+
+// NotifySubscribers routes an incident event to every subscribed user's
+// channels for the incident severity, honouring quiet hours. Shared channels
+// receive a single message even if several users route to them.
+func NotifySubscribers(db *gorm.DB, incident models.Incident, event string) error {
+	target, err := LoadTarget(db, incident.TargetType, incident.TargetID)
+	if err != nil {
+		return fmt.Errorf("could not load incident target: %w", err)
+	}
+
+	userIDs, err := subscriberIDs(db, target)
+	if err != nil {
+		return fmt.Errorf("could not load subscribers: %w", err)
+	}
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	users := []models.User{}
+	if err := db.Where("id IN ? AND status = ?", userIDs, "active").Find(&users).Error; err != nil {
+		return fmt.Errorf("could not load subscribed users: %w", err)
+	}
+
+	stored := []models.UserNotificationPreference{}
+	if err := db.Where("user_id IN ?", userIDs).Find(&stored).Error; err != nil {
+		return fmt.Errorf("could not load notification preferences: %w", err)
+	}
+	prefs := make(map[uuid.UUID]models.UserNotificationPreference, len(stored))
+	for _, pref := range stored {
+		prefs[pref.UserID] = pref
+	}
+
+	data := templateData(target, incident)
+	now := time.Now()
+	channelIDs := map[uuid.UUID]bool{}
+
+	for _, user := range users {
+		pref, ok := prefs[user.ID]
+		if !ok {
+			pref = DefaultPreference(user.ID)
+		}
+
+		if InQuietHours(pref, now) && !(pref.CriticalBypass && incident.Severity == SeverityCritical) {
+			log.Printf("Skipping incident %s notification for user %s during quiet hours", incident.ID, user.ID)
+			continue
+		}
+
+		for _, channel := range pref.SeverityChannels[incident.Severity] {
+			if channel == EmailChannel {
+				data.User = notifier.TemplateUser{Username: user.Username, Email: user.Email}
+				if err := sendEmail(db, user.Email, event, data); err != nil {
+					log.Printf("Error queueing incident email to %s: %v", user.Email, err)
+				}
+				continue
+			}
+
+			channelID, err := uuid.Parse(channel)
+			if err != nil {
+				log.Printf("Ignoring invalid channel %q in preferences of user %s", channel, user.ID)
+				continue
+			}
+			channelIDs[channelID] = true
+		}
+	}
+
+	if len(channelIDs) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(channelIDs))
+	for id := range channelIDs {
+		ids = append(ids, id)
+	}
+	channels := []models.NotificationChannel{}
+	if err := db.Where("id IN ? AND is_enabled = ?", ids, true).Find(&channels).Error; err != nil {
+		return fmt.Errorf("could not load notification channels: %w", err)
+	}
+
+	data.User = notifier.TemplateUser{}
+	for _, channel := range channels {
+		msg, err := notifier.Render(db, event, notifier.FormatForChannel(channel.Type), data)
+		if err != nil {
+			log.Printf("Error rendering incident notification for channel %s: %v", channel.ID, err)
+			continue
+		}
+		if err := messaging.PublishNotification(channel.ID, msg); err != nil {
+			log.Printf("Error queueing incident notification for channel %s: %v", channel.ID, err)
+		}
+	}
+	return nil
+}
+
+// sendEmail renders an incident event as HTML and plain text and queues it
+func sendEmail(db *gorm.DB, to, event string, data notifier.TemplateData) error {
+	htmlMsg, err := notifier.Render(db, event, notifier.FormatHTML, data)
+	if err != nil {
+		return err
+	}
+	textMsg, err := notifier.Render(db, event, notifier.FormatPlain, data)
+	if err != nil {
+		return err
+	}
+	return messaging.PublishEmail(messaging.EmailTask{To: to, Subject: htmlMsg.Subject, Body: htmlMsg.Body, TextBody: textMsg.Body})
+}
+
+// templateData builds the template variables for an incident
+func templateData(target Target, incident models.Incident) notifier.TemplateData {
+	data := notifier.TemplateData{
+		Target: notifier.TemplateTarget{Type: target.Type, ID: target.ID.String(), Name: target.Name},
+		Incident: notifier.TemplateIncident{
+			ID:        incident.ID.String(),
+			Title:     incident.Title,
+			Severity:  incident.Severity,
+			Status:    incident.Status,
+			StartedAt: incident.StartedAt,
+		},
+		Link: strings.TrimRight(config.LoadConfig().App.FrontendURL, "/") + "/incidents/" + incident.ID.String(),
+	}
+	if incident.ResolvedAt != nil {
+		data.Incident.ResolvedAt = *incident.ResolvedAt
+		data.Incident.Duration = incident.ResolvedAt.Sub(incident.StartedAt).Round(time.Second).String()
+	}
+	return data
+}
@@ -0,0 +1,154 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+
+	"monitron-server/config"
+	"monitron-server/models"
+	"monitron-server/utils"
+)
+
+// Supported notification channel types
+const (
+	ChannelEmail    = "email"
+	ChannelTelegram = "telegram"
+	ChannelDiscord  = "discord"
+	ChannelSlack    = "slack"
+	ChannelWebhook  = "webhook"
+)
+
+// defaultTimeout bounds every outbound provider request
+const defaultTimeout = 10 * time.Second
+
+// Message is a rendered notification ready to be delivered
+type Message struct {
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// Provider delivers messages to a single notification channel
+type Provider interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// PartialError is returned by providers with several recipients when only
+// some of them could not be reached. Retries should only send to Failed.
+type PartialError struct {
+	Failed []string
+	Err    error
+}
+
+func (e *PartialError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PartialError) Unwrap() error {
+	return e.Err
+}
+
+// OnlyRecipients restricts an email provider to those of its recipients that
+// are in recipients, such as the failed ones of a PartialError. Other
+// providers are returned unchanged.
+func OnlyRecipients(provider Provider, recipients []string) Provider {
+	email, ok := provider.(*EmailProvider)
+	if !ok {
+		return provider
+	}
+	wanted := map[string]bool{}
+	for _, recipient := range recipients {
+		wanted[recipient] = true
+	}
+	restricted := &EmailProvider{cfg: email.cfg}
+	for _, recipient := range email.recipients {
+		if wanted[recipient] {
+			restricted.recipients = append(restricted.recipients, recipient)
+		}
+	}
+	return restricted
+}
+
+// New builds the provider for the given channel type and configuration
+func New(channelType string, channelCfg models.NotificationChannelConfig, cfg *config.Config) (Provider, error) {
+	client := resty.New().SetTimeout(defaultTimeout)
+
+	switch channelType {
+	case ChannelEmail:
+		if len(channelCfg.Recipients) == 0 {
+			return nil, fmt.Errorf("email channel requires at least one recipient")
+		}
+		return &EmailProvider{cfg: cfg, recipients: channelCfg.Recipients}, nil
+	case ChannelTelegram:
+		if channelCfg.BotToken == "" || channelCfg.ChatID == "" {
+			return nil, fmt.Errorf("telegram channel requires bot_token and chat_id")
+		}
+		return NewTelegramProvider(client, channelCfg.BaseURL, channelCfg.BotToken, channelCfg.ChatID), nil
+	case ChannelDiscord:
+		if channelCfg.WebhookURL == "" {
+			return nil, fmt.Errorf("discord channel requires webhook_url")
+		}
+		return &DiscordProvider{client: client, webhookURL: channelCfg.WebhookURL}, nil
+	case ChannelSlack:
+		if channelCfg.WebhookURL == "" {
+			return nil, fmt.Errorf("slack channel requires webhook_url")
+		}
+		return &SlackProvider{client: client, webhookURL: channelCfg.WebhookURL}, nil
+	case ChannelWebhook:
+		if channelCfg.WebhookURL == "" {
+			return nil, fmt.Errorf("webhook channel requires webhook_url")
+		}
+		return &WebhookProvider{client: client, url: channelCfg.WebhookURL, secret: channelCfg.Secret}, nil
+	default:
+		return nil, fmt.Errorf("unsupported notification channel type: %s", channelType)
+	}
+}
+
+// FromChannel decrypts the channel configuration and builds its provider
+func FromChannel(channel models.NotificationChannel, cfg *config.Config) (Provider, error) {
+	channelCfg, err := DecryptConfig(channel.Config, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return New(channel.Type, channelCfg, cfg)
+}
+
+// EncryptConfig serializes and encrypts a channel configuration for storage
+func EncryptConfig(channelCfg models.NotificationChannelConfig, cfg *config.Config) (string, error) {
+	raw, err := json.Marshal(channelCfg)
+	if err != nil {
+		return "", fmt.Errorf("could not marshal channel config: %w", err)
+	}
+	return utils.Encrypt(raw, cfg)
+}
+
+// DecryptConfig decrypts and deserializes a stored channel configuration
+func DecryptConfig(encrypted string, cfg *config.Config) (models.NotificationChannelConfig, error) {
+	channelCfg := models.NotificationChannelConfig{}
+	if encrypted == "" {
+		return channelCfg, nil
+	}
+
+	raw, err := utils.Decrypt(encrypted, cfg)
+	if err != nil {
+		return channelCfg, fmt.Errorf("could not decrypt channel config: %w", err)
+	}
+	if err := json.Unmarshal(raw, &channelCfg); err != nil {
+		return channelCfg, fmt.Errorf("could not unmarshal channel config: %w", err)
+	}
+	return channelCfg, nil
+}
+
+// checkResponse converts a non-2xx provider response into an error
+func checkResponse(provider string, resp *resty.Response, err error) error {
+	if err != nil {
+		return fmt.Errorf("failed to send %s notification: %w", provider, err)
+	}
+	if resp.IsError() {
+		return fmt.Errorf("%s returned non-OK status: %s", provider, resp.Status())
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package notifier
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+
+	"monitron-server/config"
+	"monitron-server/utils"
+)
+
+const defaultTelegramBaseURL = "https://api.telegram.org"
+
+// EmailProvider sends notifications through the configured SMTP server
+type EmailProvider struct {
+	cfg        *config.Config
+	recipients []string
+}
+
+// Send emails every recipient separately. Recipients that fail do not stop
+// the others and are listed in a *PartialError.
+func (p *EmailProvider) Send(ctx context.Context, msg Message) error {
+	emails := make([]utils.Email, 0, len(p.recipients))
+	for _, to := range p.recipients {
+		emails = append(emails, utils.Email{To: []string{to}, Subject: msg.Subject, HTMLBody: msg.Body})
+	}
+	err := utils.NewMailer(p.cfg).SendBulk(emails)
+	var bulkErr *utils.BulkError
+	if errors.As(err, &bulkErr) {
+		failed := make([]string, 0, len(bulkErr.Failed))
+		for _, email := range bulkErr.Failed {
+			failed = append(failed, email.To...)
+		}
+		return &PartialError{Failed: failed, Err: err}
+	}
+	return err
+}
+
+// TelegramProvider sends notifications through the Telegram Bot API
+type TelegramProvider struct {
+	client  *resty.Client
+	baseURL string
+	token   string
+	chatID  string
+}
+
+// NewTelegramProvider creates a Telegram provider, defaulting to the public Bot API
+func NewTelegramProvider(client *resty.Client, baseURL, token, chatID string) *TelegramProvider {
+	if baseURL == "" {
+		baseURL = defaultTelegramBaseURL
+	}
+	return &TelegramProvider{client: client, baseURL: strings.TrimRight(baseURL, "/"), token: token, chatID: chatID}
+}
+
+func (p *TelegramProvider) Send(ctx context.Context, msg Message) error {
+	text := msg.Body
+	if msg.Subject != "" {
+		text = msg.Subject + "\n\n" + msg.Body
+	}
+
+	resp, err := p.client.R().
+		SetContext(ctx).
+		SetBody(map[string]interface{}{
+			"chat_id": p.chatID,
+			"text":    text,
+		}).
+		Post(fmt.Sprintf("%s/bot%s/sendMessage", p.baseURL, p.token))
+	return checkResponse("Telegram", resp, err)
+}
+
+// DiscordProvider sends notifications to a Discord webhook
+type DiscordProvider struct {
+	client     *resty.Client
+	webhookURL string
+}
+
+func (p *DiscordProvider) Send(ctx context.Context, msg Message) error {
+	content := msg.Body
+	if msg.Subject != "" {
+		content = "**" + msg.Subject + "**\n" + msg.Body
+	}
+
+	resp, err := p.client.R().
+		SetContext(ctx).
+		SetBody(map[string]interface{}{"content": content}).
+		Post(p.webhookURL)
+	return checkResponse("Discord", resp, err)
+}
+
+// SlackProvider sends notifications to a Slack incoming webhook
+type SlackProvider struct {
+	client     *resty.Client
+	webhookURL string
+}
+
+func (p *SlackProvider) Send(ctx context.Context, msg Message) error {
+	text := msg.Body
+	if msg.Subject != "" {
+		text = "*" + msg.Subject + "*\n" + msg.Body
+	}
+
+	resp, err := p.client.R().
+		SetContext(ctx).
+		SetBody(map[string]interface{}{"text": text}).
+		Post(p.webhookURL)
+	return checkResponse("Slack", resp, err)
+}
+
+// WebhookProvider posts a signed JSON payload to an arbitrary URL.
+// When a secret is configured, the request carries X-Monitron-Timestamp and
+// X-Monitron-Signature (hex HMAC SHA-256 over "<timestamp>.<body>").
+type WebhookProvider struct {
+	client *resty.Client
+	url    string
+	secret string
+}
+
+func (p *WebhookProvider) Send(ctx context.Context, msg Message) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"subject":   msg.Subject,
+		"body":      msg.Body,
+		"timestamp": time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("could not marshal webhook payload: %w", err)
+	}
+
+	req := p.client.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetBody(body)
+
+	if p.secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.SetHeader("X-Monitron-Timestamp", timestamp)
+		req.SetHeader("X-Monitron-Signature", "sha256="+Sign(p.secret, timestamp, body))
+	}
+
+	resp, err := req.Post(p.url)
+	return checkResponse("Webhook", resp, err)
+}
+
+// Sign computes the webhook signature for a timestamp and raw body
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
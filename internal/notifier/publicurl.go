@@ -0,0 +1,71 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// ErrNonPublicURL rejects webhook URLs that are not http(s) or point to
+// loopback, private or link-local addresses
+var ErrNonPublicURL = errors.New("webhook_url must be a public http or https URL")
+
+// CheckPublicURL checks that a URL set by an untrusted caller is http(s) and
+// that its host only resolves to public addresses
+func CheckPublicURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return ErrNonPublicURL
+	}
+
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrNonPublicURL, err)
+	}
+	for _, ip := range ips {
+		if !isPublic(ip.IP) {
+			return ErrNonPublicURL
+		}
+	}
+	return nil
+}
+
+// NewPublicWebhookProvider returns a webhook provider for a URL set by an
+// untrusted caller. Addresses are checked again when connecting, as DNS
+// answers and redirects may differ from the subscription.
+func NewPublicWebhookProvider(webhookURL, secret string) *WebhookProvider {
+	dialer := &net.Dialer{
+		Timeout: defaultTimeout,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublic(ip) {
+				return ErrNonPublicURL
+			}
+			return nil
+		},
+	}
+	transport := &http.Transport{
+		DialContext:         dialer.DialContext,
+		TLSHandshakeTimeout: defaultTimeout,
+		IdleConnTimeout:     90 * time.Second,
+	}
+	client := resty.New().SetTimeout(defaultTimeout).SetTransport(transport)
+	return &WebhookProvider{client: client, url: webhookURL, secret: secret}
+}
+
+func isPublic(ip net.IP) bool {
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !ip.IsLoopback() && !ip.IsLinkLocalUnicast() && !sharedAddressSpace.Contains(ip)
+}
+
+// sharedAddressSpace is the carrier-grade NAT range of RFC 6598
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
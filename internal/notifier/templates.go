@@ -0,0 +1,435 @@
+package notifier
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	texttemplate "text/template"
+	"time"
+
+	"gorm.io/gorm"
+
+	"monitron-server/models"
+)
+
+// Notification event types
+const (
+	EventIncidentOpened   = "incident_opened"
+	EventIncidentResolved = "incident_resolved"
+	EventCertExpiring     = "cert_expiring"
+	EventReportReady      = "report_ready"
+	EventPasswordReset    = "password_reset"
+
+	// Sent to status page subscribers
+	EventStatusSubscriptionConfirm = "status_subscription_confirm"
+	EventStatusComponentChanged    = "status_component_changed"
+	EventStatusIncidentUpdated     = "status_incident_updated"
+)
+
+// Template output formats
+const (
+	FormatHTML     = "html"
+	FormatMarkdown = "markdown"
+	FormatPlain    = "plain"
+)
+
+// EventTypes lists every event type that has default templates
+var EventTypes = []string{
+	EventIncidentOpened, EventIncidentResolved, EventCertExpiring, EventReportReady, EventPasswordReset,
+	EventStatusSubscriptionConfirm, EventStatusComponentChanged, EventStatusIncidentUpdated,
+}
+
+// Formats lists every supported template output format
+var Formats = []string{FormatHTML, FormatMarkdown, FormatPlain}
+
+// FormatForChannel returns the template format a channel type renders best
+func FormatForChannel(channelType string) string {
+	switch channelType {
+	case ChannelEmail:
+		return FormatHTML
+	case ChannelTelegram, ChannelDiscord, ChannelSlack:
+		return FormatMarkdown
+	default:
+		return FormatPlain
+	}
+}
+
+// TemplateTarget describes the monitored object a notification is about
+type TemplateTarget struct {
+	Type string // service, instance, domain_ssl or operational_page
+	ID   string
+	Name string
+}
+
+// TemplateIncident describes the incident a notification is about
+type TemplateIncident struct {
+	ID         string
+	Title      string
+	Severity   string
+	Status     string
+	StartedAt  time.Time
+	ResolvedAt time.Time
+	Duration   string
+}
+
+// TemplateCertificate describes an expiring certificate
+type TemplateCertificate struct {
+	Domain   string
+	Issuer   string
+	Expiry   time.Time
+	DaysLeft int
+}
+
+// TemplateReport describes a generated report
+type TemplateReport struct {
+	ID     string
+	Name   string
+	Type   string
+	Format string
+}
+
+// TemplateUser describes the recipient user
+type TemplateUser struct {
+	Username string
+	Email    string
+}
+
+// TemplatePage describes the operational page of a status page notification
+type TemplatePage struct {
+	Name string
+	Slug string
+	Link string // Public status page URL
+}
+
+// TemplateComponent describes a status page component whose status changed
+type TemplateComponent struct {
+	Name   string
+	Status string // Human-readable, e.g. "Major outage"
+}
+
+// TemplateUpdate describes the latest update of a status page incident
+type TemplateUpdate struct {
+	Status    string
+	Impact    string
+	Message   string
+	CreatedAt time.Time
+}
+
+// TemplateData is the set of variables available to notification templates
+type TemplateData struct {
+	Target          TemplateTarget
+	Incident        TemplateIncident
+	Certificate     TemplateCertificate
+	Report          TemplateReport
+	User            TemplateUser
+	Page            TemplatePage
+	Component       TemplateComponent
+	Update          TemplateUpdate
+	Link            string // Deep link into the UI, report download or reset page
+	ExpiresIn       string // Validity of the link, e.g. for password resets
+	UnsubscribeLink string // Status page subscription removal
+}
+
+// Template is a subject/body pair for one event type and format
+type Template struct {
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// DefaultTemplates are used when no override is stored in the database
+var DefaultTemplates = map[string]map[string]Template{
+	EventIncidentOpened: {
+		FormatHTML: {
+			Subject: "[{{.Incident.Severity}}] {{.Target.Name}} is down",
+			Body: `<h2>Incident opened: {{.Target.Name}}</h2>
+<p>{{.Incident.Title}}</p>
+<ul>
+<li>Target: {{.Target.Type}} {{.Target.Name}}</li>
+<li>Severity: {{.Incident.Severity}}</li>
+<li>Started at: {{.Incident.StartedAt.Format "2006-01-02 15:04:05 MST"}}</li>
+</ul>
+{{if .Link}}<p><a href="{{.Link}}">View incident</a></p>{{end}}`,
+		},
+		FormatMarkdown: {
+			Subject: "[{{.Incident.Severity}}] {{.Target.Name}} is down",
+			Body: `*Incident opened:* {{.Target.Name}}
+{{.Incident.Title}}
+Severity: {{.Incident.Severity}}
+Started at: {{.Incident.StartedAt.Format "2006-01-02 15:04:05 MST"}}{{if .Link}}
+{{.Link}}{{end}}`,
+		},
+		FormatPlain: {
+			Subject: "[{{.Incident.Severity}}] {{.Target.Name}} is down",
+			Body: `Incident opened for {{.Target.Type}} {{.Target.Name}}: {{.Incident.Title}}
+Severity: {{.Incident.Severity}}
+Started at: {{.Incident.StartedAt.Format "2006-01-02 15:04:05 MST"}}{{if .Link}}
+{{.Link}}{{end}}`,
+		},
+	},
+	EventIncidentResolved: {
+		FormatHTML: {
+			Subject: "[Resolved] {{.Target.Name}} is back up",
+			Body: `<h2>Incident resolved: {{.Target.Name}}</h2>
+<p>{{.Incident.Title}}</p>
+<ul>
+<li>Resolved at: {{.Incident.ResolvedAt.Format "2006-01-02 15:04:05 MST"}}</li>
+<li>Duration: {{.Incident.Duration}}</li>
+</ul>
+{{if .Link}}<p><a href="{{.Link}}">View incident</a></p>{{end}}`,
+		},
+		FormatMarkdown: {
+			Subject: "[Resolved] {{.Target.Name}} is back up",
+			Body: `*Incident resolved:* {{.Target.Name}}
+{{.Incident.Title}}
+Duration: {{.Incident.Duration}}{{if .Link}}
+{{.Link}}{{end}}`,
+		},
+		FormatPlain: {
+			Subject: "[Resolved] {{.Target.Name}} is back up",
+			Body: `Incident resolved for {{.Target.Type}} {{.Target.Name}}: {{.Incident.Title}}
+Duration: {{.Incident.Duration}}{{if .Link}}
+{{.Link}}{{end}}`,
+		},
+	},
+	EventCertExpiring: {
+		FormatHTML: {
+			Subject: "SSL certificate for {{.Certificate.Domain}} expires in {{.Certificate.DaysLeft}} days",
+			Body: `<h2>Certificate expiring: {{.Certificate.Domain}}</h2>
+<ul>
+<li>Issuer: {{.Certificate.Issuer}}</li>
+<li>Expires at: {{.Certificate.Expiry.Format "2006-01-02"}}</li>
+<li>Days left: {{.Certificate.DaysLeft}}</li>
+</ul>
+{{if .Link}}<p><a href="{{.Link}}">View domain</a></p>{{end}}`,
+		},
+		FormatMarkdown: {
+			Subject: "SSL certificate for {{.Certificate.Domain}} expires in {{.Certificate.DaysLeft}} days",
+			Body: `*Certificate expiring:* {{.Certificate.Domain}}
+Issuer: {{.Certificate.Issuer}}
+Expires at: {{.Certificate.Expiry.Format "2006-01-02"}} ({{.Certificate.DaysLeft}} days left){{if .Link}}
+{{.Link}}{{end}}`,
+		},
+		FormatPlain: {
+			Subject: "SSL certificate for {{.Certificate.Domain}} expires in {{.Certificate.DaysLeft}} days",
+			Body: `The SSL certificate for {{.Certificate.Domain}} issued by {{.Certificate.Issuer}} expires at {{.Certificate.Expiry.Format "2006-01-02"}} ({{.Certificate.DaysLeft}} days left).{{if .Link}}
+{{.Link}}{{end}}`,
+		},
+	},
+	EventReportReady: {
+		FormatHTML: {
+			Subject: "Report ready: {{.Report.Name}}",
+			Body: `<h2>Your report is ready</h2>
+<p>{{.Report.Name}} ({{.Report.Type}}, {{.Report.Format}}) has been generated.</p>
+{{if .Link}}<p><a href="{{.Link}}">Download report</a></p>{{end}}`,
+		},
+		FormatMarkdown: {
+			Subject: "Report ready: {{.Report.Name}}",
+			Body: `*Report ready:* {{.Report.Name}} ({{.Report.Type}}, {{.Report.Format}}){{if .Link}}
+{{.Link}}{{end}}`,
+		},
+		FormatPlain: {
+			Subject: "Report ready: {{.Report.Name}}",
+			Body: `Report {{.Report.Name}} ({{.Report.Type}}, {{.Report.Format}}) has been generated.{{if .Link}}
+{{.Link}}{{end}}`,
+		},
+	},
+	EventPasswordReset: {
+		FormatHTML: {
+			Subject: "Reset your Monitron password",
+			Body: `<p>Hi {{.User.Username}},</p>
+<p>We received a request to reset your password. Click the link below to choose a new one. The link expires in {{.ExpiresIn}}.</p>
+<p><a href="{{.Link}}">Reset password</a></p>
+<p>If you did not request a password reset, you can ignore this email.</p>`,
+		},
+		FormatMarkdown: {
+			Subject: "Reset your Monitron password",
+			Body: `Hi {{.User.Username}}, use the link below to reset your password. It expires in {{.ExpiresIn}}.
+{{.Link}}`,
+		},
+		FormatPlain: {
+			Subject: "Reset your Monitron password",
+			Body: `Hi {{.User.Username}},
+
+We received a request to reset your password. Open the link below to choose a new one. The link expires in {{.ExpiresIn}}.
+
+{{.Link}}
+
+If you did not request a password reset, you can ignore this email.`,
+		},
+	},
+	EventStatusSubscriptionConfirm: {
+		FormatHTML: {
+			Subject: "Confirm your subscription to {{.Page.Name}} status",
+			Body: `<p>Please confirm that you want to receive status updates for <a href="{{.Page.Link}}">{{.Page.Name}}</a>.</p>
+<p><a href="{{.Link}}">Confirm subscription</a></p>
+<p>If you did not subscribe, you can ignore this email.</p>`,
+		},
+		FormatMarkdown: {
+			Subject: "Confirm your subscription to {{.Page.Name}} status",
+			Body: `Confirm your subscription to status updates for {{.Page.Name}}:
+{{.Link}}`,
+		},
+		FormatPlain: {
+			Subject: "Confirm your subscription to {{.Page.Name}} status",
+			Body: `Please confirm that you want to receive status updates for {{.Page.Name}} by opening the link below.
+
+{{.Link}}
+
+If you did not subscribe, you can ignore this email.`,
+		},
+	},
+	EventStatusComponentChanged: {
+		FormatHTML: {
+			Subject: "[{{.Page.Name}}] {{.Component.Name}}: {{.Component.Status}}",
+			Body: `<h2>{{.Component.Name}} is now {{.Component.Status}}</h2>
+<p><a href="{{.Page.Link}}">View the {{.Page.Name}} status page</a></p>
+{{if .UnsubscribeLink}}<p><small><a href="{{.UnsubscribeLink}}">Unsubscribe</a></small></p>{{end}}`,
+		},
+		FormatMarkdown: {
+			Subject: "[{{.Page.Name}}] {{.Component.Name}}: {{.Component.Status}}",
+			Body: `*{{.Component.Name}}* is now {{.Component.Status}}
+{{.Page.Link}}`,
+		},
+		FormatPlain: {
+			Subject: "[{{.Page.Name}}] {{.Component.Name}}: {{.Component.Status}}",
+			Body: `{{.Component.Name}} is now {{.Component.Status}}.
+{{.Page.Link}}{{if .UnsubscribeLink}}
+
+Unsubscribe: {{.UnsubscribeLink}}{{end}}`,
+		},
+	},
+	EventStatusIncidentUpdated: {
+		FormatHTML: {
+			Subject: "[{{.Page.Name}}] {{.Incident.Title}} - {{.Update.Status}}",
+			Body: `<h2>{{.Incident.Title}}</h2>
+<p><strong>{{.Update.Status}}</strong> &middot; {{.Update.Impact}} impact &middot; {{.Update.CreatedAt.Format "2006-01-02 15:04 MST"}}</p>
+<p>{{.Update.Message}}</p>
+<p><a href="{{.Page.Link}}">View the {{.Page.Name}} status page</a></p>
+{{if .UnsubscribeLink}}<p><small><a href="{{.UnsubscribeLink}}">Unsubscribe</a></small></p>{{end}}`,
+		},
+		FormatMarkdown: {
+			Subject: "[{{.Page.Name}}] {{.Incident.Title}} - {{.Update.Status}}",
+			Body: `*{{.Incident.Title}}* ({{.Update.Status}}, {{.Update.Impact}} impact)
+{{.Update.Message}}
+{{.Page.Link}}`,
+		},
+		FormatPlain: {
+			Subject: "[{{.Page.Name}}] {{.Incident.Title}} - {{.Update.Status}}",
+			Body: `{{.Incident.Title}}
+Status: {{.Update.Status}}, {{.Update.Impact}} impact, {{.Update.CreatedAt.Format "2006-01-02 15:04 MST"}}
+
+{{.Update.Message}}
+
+{{.Page.Link}}{{if .UnsubscribeLink}}
+
+Unsubscribe: {{.UnsubscribeLink}}{{end}}`,
+		},
+	},
+}
+
+// SampleTemplateData returns representative data used to preview templates
+func SampleTemplateData() TemplateData {
+	now := time.Now()
+	return TemplateData{
+		Target: TemplateTarget{Type: "service", ID: "00000000-0000-0000-0000-000000000001", Name: "Payments API"},
+		Incident: TemplateIncident{
+			ID:         "00000000-0000-0000-0000-000000000002",
+			Title:      "HTTP health check returned 503",
+			Severity:   "critical",
+			Status:     "open",
+			StartedAt:  now.Add(-42 * time.Minute),
+			ResolvedAt: now,
+			Duration:   "42m0s",
+		},
+		Certificate: TemplateCertificate{Domain: "example.com", Issuer: "Let's Encrypt", Expiry: now.AddDate(0, 0, 7), DaysLeft: 7},
+		Report:      TemplateReport{ID: "00000000-0000-0000-0000-000000000003", Name: "Weekly uptime", Type: "service_uptime", Format: "PDF"},
+		User:        TemplateUser{Username: "jane", Email: "jane@example.com"},
+		Page:        TemplatePage{Name: "Acme Cloud", Slug: "acme", Link: "https://status.example.com/status/acme"},
+		Component:   TemplateComponent{Name: "Payments API", Status: "Major outage"},
+		Update: TemplateUpdate{
+			Status:    "identified",
+			Impact:    "major",
+			Message:   "We found the cause and are rolling out a fix.",
+			CreatedAt: now,
+		},
+		Link:            "https://monitron.example.com/",
+		ExpiresIn:       "30 minutes",
+		UnsubscribeLink: "https://status.example.com/status/acme/subscribers/00000000-0000-0000-0000-000000000004/unsubscribe?token=sample",
+	}
+}
+
+// LoadTemplate returns the stored override for an event type and format,
+// falling back to the built-in default
+func LoadTemplate(db *gorm.DB, eventType, format string) (Template, error) {
+	if db != nil {
+		stored := models.NotificationTemplate{}
+		err := db.First(&stored, "event_type = ? AND format = ?", eventType, format).Error
+		if err == nil {
+			return Template{Subject: stored.Subject, Body: stored.Body}, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return Template{}, fmt.Errorf("could not load notification template: %w", err)
+		}
+	}
+
+	tmpl, ok := DefaultTemplates[eventType][format]
+	if !ok {
+		return Template{}, fmt.Errorf("no template for event %q and format %q", eventType, format)
+	}
+	return tmpl, nil
+}
+
+// Render loads and renders the template for an event type and format
+func Render(db *gorm.DB, eventType, format string, data TemplateData) (Message, error) {
+	tmpl, err := LoadTemplate(db, eventType, format)
+	if err != nil {
+		return Message{}, err
+	}
+	return RenderTemplate(tmpl, format, data)
+}
+
+// RenderTemplate executes a template. HTML bodies are rendered with
+// html/template so variables are escaped; everything else uses text/template.
+func RenderTemplate(tmpl Template, format string, data TemplateData) (Message, error) {
+	subject, err := executeText("subject", tmpl.Subject, data)
+	if err != nil {
+		return Message{}, err
+	}
+
+	var body string
+	if format == FormatHTML {
+		body, err = executeHTML("body", tmpl.Body, data)
+	} else {
+		body, err = executeText("body", tmpl.Body, data)
+	}
+	if err != nil {
+		return Message{}, err
+	}
+
+	return Message{Subject: subject, Body: body}, nil
+}
+
+func executeText(name, text string, data TemplateData) (string, error) {
+	t, err := texttemplate.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("could not parse %s template: %w", name, err)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("could not render %s template: %w", name, err)
+	}
+	return buf.String(), nil
+}
+
+func executeHTML(name, text string, data TemplateData) (string, error) {
+	t, err := htmltemplate.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("could not parse %s template: %w", name, err)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("could not render %s template: %w", name, err)
+	}
+	return buf.String(), nil
+}
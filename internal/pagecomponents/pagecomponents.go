@@ -0,0 +1,188 @@
+package pagecomponents
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"monitron-server/models"
+)
+
+// Component types. Groups reference no monitored target: their ComponentID is
+// their own ID and other components join them through ParentID.
+const (
+	TypeService   = "service"
+	TypeInstance  = "instance"
+	TypeDomainSSL = "domain_ssl"
+	TypeGroup     = "group"
+)
+
+// targetTables maps monitored component types to the table of their targets
+var targetTables = map[string]string{
+	TypeService:   "services",
+	TypeInstance:  "instances",
+	TypeDomainSSL: "domain_ssl",
+}
+
+// Validation errors, worded for API responses
+var (
+	ErrUnknownTarget   = errors.New("referenced component does not exist")
+	ErrAlreadyOnPage   = errors.New("component is already on this page")
+	ErrInvalidParent   = errors.New("parent must be a group on the same page")
+	ErrNestedGroup     = errors.New("groups cannot be placed inside other groups")
+	ErrUnknownOnPage   = errors.New("component is not on this page")
+	ErrDuplicateInList = errors.New("component is listed more than once")
+)
+
+// Monitored scopes a components query to those referencing monitored
+// targets, leaving out groups
+func Monitored(db *gorm.DB) *gorm.DB {
+	return db.Where("component_type <> ?", TypeGroup)
+}
+
+// Validate checks that a new component references an existing target that
+// is not on the page yet, and that its parent is a group of the same page
+func Validate(db *gorm.DB, component models.OperationalPageComponent) error {
+	if table, ok := targetTables[component.ComponentType]; ok {
+		var count int64
+		if err := db.Table(table).Where("id = ?", component.ComponentID).Count(&count).Error; err != nil {
+			return fmt.Errorf("could not look up component target: %w", err)
+		}
+		if count == 0 {
+			return ErrUnknownTarget
+		}
+
+		err := db.Model(&models.OperationalPageComponent{}).
+			Where("page_id = ? AND component_id = ?", component.PageID, component.ComponentID).
+			Count(&count).Error
+		if err != nil {
+			return fmt.Errorf("could not look up page components: %w", err)
+		}
+		if count > 0 {
+			return ErrAlreadyOnPage
+		}
+	}
+
+	if component.ParentID == nil {
+		return nil
+	}
+	if component.ComponentType == TypeGroup {
+		return ErrNestedGroup
+	}
+	return checkParent(db, component.PageID, *component.ParentID)
+}
+
+// OrderItem is the new position of one page component
+type OrderItem struct {
+	ID           uuid.UUID  `json:"id" validate:"required"`
+	DisplayOrder int        `json:"display_order"`
+	ParentID     *uuid.UUID `json:"parent_id"` // Group to place the component in, null for the top level
+}
+
+// Reorder sets the display order and group of the listed components of a
+// page in one transaction. Components that are not listed keep their place.
+func Reorder(db *gorm.DB, pageID uuid.UUID, items []OrderItem) error {
+	components := []models.OperationalPageComponent{}
+	if err := db.Where("page_id = ?", pageID).Find(&components).Error; err != nil {
+		return fmt.Errorf("could not load page components: %w", err)
+	}
+	byID := map[uuid.UUID]models.OperationalPageComponent{}
+	for _, component := range components {
+		byID[component.ID] = component
+	}
+
+	seen := map[uuid.UUID]bool{}
+	for _, item := range items {
+		component, ok := byID[item.ID]
+		if !ok {
+			return fmt.Errorf("%s: %w", item.ID, ErrUnknownOnPage)
+		}
+		if seen[item.ID] {
+			return fmt.Errorf("%s: %w", item.ID, ErrDuplicateInList)
+		}
+		seen[item.ID] = true
+
+		if item.ParentID == nil {
+			continue
+		}
+		if component.ComponentType == TypeGroup {
+			return fmt.Errorf("%s: %w", item.ID, ErrNestedGroup)
+		}
+		if parent, ok := byID[*item.ParentID]; !ok || parent.ComponentType != TypeGroup {
+			return fmt.Errorf("%s: %w", item.ID, ErrInvalidParent)
+		}
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, item := range items {
+			err := tx.Model(&models.OperationalPageComponent{}).
+				Where("page_id = ? AND id = ?", pageID, item.ID).
+				Updates(map[string]interface{}{"display_order": item.DisplayOrder, "parent_id": item.ParentID}).Error
+			if err != nil {
+				return fmt.Errorf("could not reorder components: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// RemoveTarget deletes the components of every page that show a monitored
+// target, once the target itself was deleted. It returns the affected pages.
+func RemoveTarget(db *gorm.DB, targetType string, targetID uuid.UUID) ([]uuid.UUID, error) {
+	components := []models.OperationalPageComponent{}
+	err := db.Where("component_type = ? AND component_id = ?", targetType, targetID).
+		Clauses(clause.Returning{}).Delete(&components).Error
+	if err != nil {
+		return nil, fmt.Errorf("could not remove page components: %w", err)
+	}
+	return pageIDs(components), nil
+}
+
+// RemoveDangling deletes components whose monitored target no longer exists,
+// such as those left behind by deletions made outside the API. It returns
+// the affected pages.
+func RemoveDangling(db *gorm.DB) ([]uuid.UUID, error) {
+	var removed []models.OperationalPageComponent
+	for componentType, table := range targetTables {
+		components := []models.OperationalPageComponent{}
+		err := db.Where("component_type = ?", componentType).
+			Where("NOT EXISTS (SELECT 1 FROM " + table + " t WHERE t.id = operational_page_components.component_id)").
+			Clauses(clause.Returning{}).Delete(&components).Error
+		if err != nil {
+			return nil, fmt.Errorf("could not remove dangling %s components: %w", componentType, err)
+		}
+		removed = append(removed, components...)
+	}
+	return pageIDs(removed), nil
+}
+
+// checkParent verifies that parentID is a group component of the page
+func checkParent(db *gorm.DB, pageID, parentID uuid.UUID) error {
+	parent := models.OperationalPageComponent{}
+	err := db.First(&parent, "id = ? AND page_id = ?", parentID, pageID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrInvalidParent
+	}
+	if err != nil {
+		return fmt.Errorf("could not look up parent component: %w", err)
+	}
+	if parent.ComponentType != TypeGroup {
+		return ErrInvalidParent
+	}
+	return nil
+}
+
+func pageIDs(components []models.OperationalPageComponent) []uuid.UUID {
+	ids := []uuid.UUID{}
+	seen := map[uuid.UUID]bool{}
+	for _, component := range components {
+		if !seen[component.PageID] {
+			seen[component.PageID] = true
+			ids = append(ids, component.PageID)
+		}
+	}
+	return ids
+}
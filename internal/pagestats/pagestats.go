@@ -0,0 +1,281 @@
+package pagestats
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"monitron-server/internal/pagecomponents"
+	"monitron-server/models"
+)
+
+// HistoryDays is the length of the uptime history kept per page
+const HistoryDays = 30
+
+// ComponentDay is the uptime of one component on one day
+type ComponentDay struct {
+	ComponentID     uuid.UUID `json:"component_id"`
+	Checks          int64     `json:"checks"`
+	Uptime          *float64  `json:"uptime"`            // Percentage, null without checks
+	AvgResponseTime *float64  `json:"avg_response_time"` // Milliseconds, null without checks
+}
+
+// Day is one entry of a page's uptime history. Page-level values are the
+// component values weighted by component weight.
+type Day struct {
+	Date            string         `json:"date"` // UTC day, YYYY-MM-DD
+	Uptime          *float64       `json:"uptime"`
+	AvgResponseTime *float64       `json:"avg_response_time"`
+	Components      []ComponentDay `json:"components"`
+}
+
+// Refresh recomputes and stores the stats of a page over the last
+// HistoryDays UTC days, today included
+func Refresh(db *gorm.DB, pageID uuid.UUID, now time.Time) (models.OperationalPageStats, error) {
+	stats := models.OperationalPageStats{PageID: pageID, LastUpdated: now}
+
+	components := []models.OperationalPageComponent{}
+	if err := pagecomponents.Monitored(db).Where("page_id = ?", pageID).Order("display_order ASC").Find(&components).Error; err != nil {
+		return stats, fmt.Errorf("could not load components: %w", err)
+	}
+
+	utc := now.UTC()
+	today := time.Date(utc.Year(), utc.Month(), utc.Day(), 0, 0, 0, 0, time.UTC)
+	from := today.AddDate(0, 0, -(HistoryDays - 1))
+
+	idsByType := map[string][]uuid.UUID{}
+	for _, component := range components {
+		idsByType[component.ComponentType] = append(idsByType[component.ComponentType], component.ComponentID)
+	}
+
+	daily := map[string]map[time.Time]DailyRow{}
+	for componentType, ids := range idsByType {
+		if err := DailyChecks(db, componentType, ids, from, daily); err != nil {
+			return stats, fmt.Errorf("could not load check history: %w", err)
+		}
+
+		var incidents int64
+		err := db.Model(&models.Incident{}).
+			Where("target_type = ? AND target_id IN ?", componentType, ids).
+			Where("started_at >= ? OR status = ?", from, "open").
+			Count(&incidents).Error
+		if err != nil {
+			return stats, fmt.Errorf("could not count incidents: %w", err)
+		}
+		stats.IncidentsTotal += int(incidents)
+	}
+
+	history := make([]Day, HistoryDays)
+	for i := range history {
+		history[i] = Day{Date: from.AddDate(0, 0, i).Format("2006-01-02"), Components: []ComponentDay{}}
+	}
+
+	dayUptime := make([]weightedMean, HistoryDays)
+	dayResponse := make([]weightedMean, HistoryDays)
+	for _, component := range components {
+		weight := componentWeight(component)
+		rows := daily[TargetKey(component.ComponentType, component.ComponentID)]
+		for i := range history {
+			row := rows[from.AddDate(0, 0, i)]
+			day := ComponentDay{ComponentID: component.ID, Checks: row.Checks}
+			if row.Checks > 0 {
+				day.Uptime = percentage(row.UpChecks, row.Checks)
+				day.AvgResponseTime = average(row.ResponseTime, row.Checks)
+				dayUptime[i].add(*day.Uptime, weight)
+				dayResponse[i].add(*day.AvgResponseTime, weight)
+			}
+			history[i].Components = append(history[i].Components, day)
+		}
+	}
+
+	for i := range history {
+		history[i].Uptime = dayUptime[i].value()
+		history[i].AvgResponseTime = dayResponse[i].value()
+	}
+	if uptime, response := Totals(components, daily, from); uptime != nil {
+		stats.OverallUptime, stats.AverageResponseTime = *uptime, *response
+	}
+
+	encoded, err := json.Marshal(history)
+	if err != nil {
+		return stats, fmt.Errorf("could not encode uptime history: %w", err)
+	}
+	stats.UptimeHistory = string(encoded)
+
+	err = db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "page_id"}},
+		UpdateAll: true,
+	}).Create(&stats).Error
+	if err != nil {
+		return stats, fmt.Errorf("could not save stats: %w", err)
+	}
+	return stats, nil
+}
+
+// RefreshAll recomputes the stats of every operational page
+func RefreshAll(db *gorm.DB) error {
+	pageIDs := []uuid.UUID{}
+	if err := db.Model(&models.OperationalPage{}).Pluck("id", &pageIDs).Error; err != nil {
+		return fmt.Errorf("could not load operational pages: %w", err)
+	}
+
+	now := time.Now()
+	failed := 0
+	for _, pageID := range pageIDs {
+		if _, err := Refresh(db, pageID, now); err != nil {
+			log.Printf("Error refreshing stats of operational page %s: %v", pageID, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d operational pages could not be refreshed", failed, len(pageIDs))
+	}
+	return nil
+}
+
+// RefreshTarget recomputes the stats of every page showing a monitored target.
+// It is called when the status of the target changes.
+func RefreshTarget(db *gorm.DB, targetType string, targetID uuid.UUID) error {
+	pageIDs := []uuid.UUID{}
+	err := db.Model(&models.OperationalPageComponent{}).
+		Where("component_type = ? AND component_id = ?", targetType, targetID).
+		Distinct().Pluck("page_id", &pageIDs).Error
+	if err != nil {
+		return fmt.Errorf("could not load operational pages: %w", err)
+	}
+
+	now := time.Now()
+	for _, pageID := range pageIDs {
+		if _, err := Refresh(db, pageID, now); err != nil {
+			return fmt.Errorf("could not refresh operational page %s: %w", pageID, err)
+		}
+	}
+	return nil
+}
+
+// Totals returns the uptime percentage and average response time of a page
+// over the history starting at from, the values of its monitored components
+// weighted by component weight. Both are nil without checks. Status pages
+// and the stored stats use it so they show the same numbers.
+func Totals(components []models.OperationalPageComponent, daily map[string]map[time.Time]DailyRow, from time.Time) (uptime, responseTime *float64) {
+	var uptimeMean, responseMean weightedMean
+	for _, component := range components {
+		if component.ComponentType == pagecomponents.TypeGroup {
+			continue
+		}
+		rows := daily[TargetKey(component.ComponentType, component.ComponentID)]
+
+		var checks, up int64
+		var response float64
+		for i := 0; i < HistoryDays; i++ {
+			row := rows[from.AddDate(0, 0, i)]
+			checks += row.Checks
+			up += row.UpChecks
+			response += row.ResponseTime
+		}
+		if checks > 0 {
+			weight := componentWeight(component)
+			uptimeMean.add(*percentage(up, checks), weight)
+			responseMean.add(*average(response, checks), weight)
+		}
+	}
+	return uptimeMean.value(), responseMean.value()
+}
+
+func componentWeight(component models.OperationalPageComponent) float64 {
+	if component.Weight <= 0 {
+		return 1
+	}
+	return component.Weight
+}
+
+// Decode parses the uptime history stored with a page's stats
+func Decode(stats models.OperationalPageStats) ([]Day, error) {
+	history := []Day{}
+	if stats.UptimeHistory == "" {
+		return history, nil
+	}
+	err := json.Unmarshal([]byte(stats.UptimeHistory), &history)
+	return history, err
+}
+
+// weightedMean accumulates a mean of values weighted by component weight
+type weightedMean struct {
+	sum    float64
+	weight float64
+}
+
+func (m *weightedMean) add(value, weight float64) {
+	m.sum += value * weight
+	m.weight += weight
+}
+
+// value returns the mean, or nil if nothing was added
+func (m weightedMean) value() *float64 {
+	if m.weight == 0 {
+		return nil
+	}
+	value := round(m.sum / m.weight)
+	return &value
+}
+
+func percentage(part, total int64) *float64 {
+	value := round(float64(part) / float64(total) * 100)
+	return &value
+}
+
+func average(sum float64, count int64) *float64 {
+	value := round(sum / float64(count))
+	return &value
+}
+
+// round keeps two decimals, the precision of the stats columns
+func round(value float64) float64 {
+	return float64(int64(value*100+0.5)) / 100
+}
+
+// TargetKey identifies a monitored target in the maps of DailyChecks
+func TargetKey(targetType string, targetID uuid.UUID) string {
+	return targetType + "/" + targetID.String()
+}
+
+// DailyRow is the aggregate of a target's checks on one day
+type DailyRow struct {
+	TargetID     uuid.UUID
+	Day          time.Time
+	Checks       int64
+	UpChecks     int64
+	ResponseTime float64 // Sum of response times
+}
+
+// DailyChecks loads per-day check aggregates of each target since from,
+// keyed by TargetKey and UTC day
+func DailyChecks(db *gorm.DB, targetType string, ids []uuid.UUID, from time.Time, into map[string]map[time.Time]DailyRow) error {
+	rows := []DailyRow{}
+	err := db.Model(&models.CheckResult{}).
+		Select(`target_id,
+			date_trunc('day', checked_at AT TIME ZONE 'UTC') AS day,
+			COUNT(*) AS checks,
+			COUNT(*) FILTER (WHERE status = 'up') AS up_checks,
+			COALESCE(SUM(response_time), 0) AS response_time`).
+		Where("target_type = ? AND target_id IN ? AND checked_at >= ?", targetType, ids, from).
+		Group("target_id, day").
+		Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		key := TargetKey(targetType, row.TargetID)
+		if into[key] == nil {
+			into[key] = map[time.Time]DailyRow{}
+		}
+		day := row.Day
+		into[key][time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)] = row
+	}
+	return nil
+}
@@ -0,0 +1,320 @@
+package pagesubscribers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"monitron-server/config"
+	"monitron-server/internal/notifier"
+	"monitron-server/internal/statuspage"
+	"monitron-server/messaging"
+	"monitron-server/models"
+	"monitron-server/utils"
+)
+
+// Subscriber types
+const (
+	TypeEmail   = "email"
+	TypeWebhook = "webhook"
+)
+
+// PendingTTL is how long an unconfirmed email subscription is kept
+const PendingTTL = 7 * 24 * time.Hour
+
+// ErrInvalidToken is returned for unknown subscribers and wrong tokens alike
+var ErrInvalidToken = errors.New("invalid or expired link")
+
+// Subscribe adds a subscriber to a page. Webhook subscribers are active
+// right away if their URL is public, otherwise notifier.ErrNonPublicURL is
+// returned. Email subscribers get a confirmation link first; subscribing an
+// address again resends the link while it is pending and changes nothing
+// once it is confirmed, so the response never reveals who is subscribed.
+func Subscribe(db *gorm.DB, page models.OperationalPage, subscriber models.StatusSubscriber, secret string) (models.StatusSubscriber, error) {
+	cfg := config.LoadConfig()
+	now := time.Now()
+
+	subscriber.ID = uuid.New()
+	subscriber.PageID = page.ID
+	subscriber.Confirmed = false
+	subscriber.ConfirmedAt = nil
+	subscriber.ConfirmTokenHash = ""
+	subscriber.Secret = ""
+	subscriber.CreatedAt = now
+	subscriber.UpdatedAt = now
+
+	if subscriber.Type == TypeWebhook {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := notifier.CheckPublicURL(ctx, subscriber.WebhookURL); err != nil {
+			return subscriber, err
+		}
+
+		subscriber.Email = ""
+		if secret != "" {
+			encrypted, err := utils.Encrypt([]byte(secret), cfg)
+			if err != nil {
+				return subscriber, fmt.Errorf("could not encrypt webhook secret: %w", err)
+			}
+			subscriber.Secret = encrypted
+		}
+		subscriber.Confirmed = true
+		subscriber.ConfirmedAt = &now
+		if err := db.Create(&subscriber).Error; err != nil {
+			return subscriber, fmt.Errorf("could not save subscriber: %w", err)
+		}
+		return subscriber, nil
+	}
+
+	subscriber.WebhookURL = ""
+	subscriber.Email = strings.ToLower(strings.TrimSpace(subscriber.Email))
+
+	existing := models.StatusSubscriber{}
+	err := db.First(&existing, "page_id = ? AND type = ? AND email = ?", page.ID, TypeEmail, subscriber.Email).Error
+	switch {
+	case err == nil && existing.Confirmed:
+		return existing, nil
+	case err == nil:
+		existing.ComponentIDs = subscriber.ComponentIDs
+		existing.UpdatedAt = now
+		subscriber = existing
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return subscriber, fmt.Errorf("could not look up subscriber: %w", err)
+	}
+
+	token, err := utils.GenerateToken(32)
+	if err != nil {
+		return subscriber, fmt.Errorf("could not generate confirmation token: %w", err)
+	}
+	subscriber.ConfirmTokenHash = utils.HashToken(token)
+
+	if err := db.Save(&subscriber).Error; err != nil {
+		return subscriber, fmt.Errorf("could not save subscriber: %w", err)
+	}
+
+	data := notifier.TemplateData{
+		Page: templatePage(cfg, page),
+		Link: subscriberLink(cfg, page, subscriber.ID, "confirm", token),
+	}
+	if err := sendEmail(db, subscriber.Email, notifier.EventStatusSubscriptionConfirm, data); err != nil {
+		return subscriber, fmt.Errorf("could not queue confirmation email: %w", err)
+	}
+	return subscriber, nil
+}
+
+// Confirm activates a pending email subscription
+func Confirm(db *gorm.DB, page models.OperationalPage, subscriberID uuid.UUID, token string) error {
+	subscriber := models.StatusSubscriber{}
+	err := db.First(&subscriber, "id = ? AND page_id = ? AND type = ?", subscriberID, page.ID, TypeEmail).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrInvalidToken
+	}
+	if err != nil {
+		return fmt.Errorf("could not look up subscriber: %w", err)
+	}
+	if subscriber.Confirmed {
+		return nil
+	}
+	if subscriber.ConfirmTokenHash == "" || !hmac.Equal([]byte(subscriber.ConfirmTokenHash), []byte(utils.HashToken(token))) {
+		return ErrInvalidToken
+	}
+
+	now := time.Now()
+	subscriber.Confirmed = true
+	subscriber.ConfirmedAt = &now
+	subscriber.ConfirmTokenHash = ""
+	subscriber.UpdatedAt = now
+	if err := db.Save(&subscriber).Error; err != nil {
+		return fmt.Errorf("could not confirm subscriber: %w", err)
+	}
+	return nil
+}
+
+// CheckUnsubscribeToken returns ErrInvalidToken unless token is the
+// unsubscribe token of the subscriber
+func CheckUnsubscribeToken(subscriberID uuid.UUID, token string) error {
+	if !hmac.Equal([]byte(UnsubscribeToken(config.LoadConfig(), subscriberID)), []byte(token)) {
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+// Unsubscribe removes a subscriber given its unsubscribe token
+func Unsubscribe(db *gorm.DB, page models.OperationalPage, subscriberID uuid.UUID, token string) error {
+	if err := CheckUnsubscribeToken(subscriberID, token); err != nil {
+		return err
+	}
+
+	result := db.Where("id = ? AND page_id = ?", subscriberID, page.ID).Delete(&models.StatusSubscriber{})
+	if result.Error != nil {
+		return fmt.Errorf("could not delete subscriber: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+// UnsubscribeToken derives the token of a subscriber's unsubscribe link, so
+// it can be put in every message without being stored
+func UnsubscribeToken(cfg *config.Config, subscriberID uuid.UUID) string {
+	mac := hmac.New(sha256.New, []byte(cfg.EncryptionKey))
+	mac.Write([]byte("status-unsubscribe:" + subscriberID.String()))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// UnsubscribeLink returns the public unsubscribe URL of a subscriber
+func UnsubscribeLink(cfg *config.Config, page models.OperationalPage, subscriberID uuid.UUID) string {
+	return subscriberLink(cfg, page, subscriberID, "unsubscribe", UnsubscribeToken(cfg, subscriberID))
+}
+
+// CleanupPending deletes email subscriptions left unconfirmed since before
+func CleanupPending(db *gorm.DB, before time.Time) (int64, error) {
+	result := db.Where("type = ? AND confirmed = ? AND created_at < ?", TypeEmail, false, before).Delete(&models.StatusSubscriber{})
+	return result.RowsAffected, result.Error
+}
+
+// NotifyComponentChange tells the subscribers of every page showing a
+// monitored target that its status changed
+func NotifyComponentChange(db *gorm.DB, targetType string, targetID uuid.UUID, status string) error {
+	components := []models.OperationalPageComponent{}
+	if err := db.Where("component_type = ? AND component_id = ?", targetType, targetID).Find(&components).Error; err != nil {
+		return fmt.Errorf("could not load page components: %w", err)
+	}
+
+	cfg := config.LoadConfig()
+	for _, component := range components {
+		page := models.OperationalPage{}
+		if err := db.First(&page, "id = ?", component.PageID).Error; err != nil {
+			return fmt.Errorf("could not load operational page: %w", err)
+		}
+
+		data := notifier.TemplateData{
+			Page:      templatePage(cfg, page),
+			Component: notifier.TemplateComponent{Name: component.ComponentName, Status: statuspage.StatusLabel(status)},
+		}
+		if err := notify(db, cfg, page, []string{component.ID.String()}, notifier.EventStatusComponentChanged, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NotifyIncident tells the subscribers of a page that a status page incident
+// was posted or received an update
+func NotifyIncident(db *gorm.DB, incident models.PageIncident, update models.PageIncidentUpdate) error {
+	page := models.OperationalPage{}
+	if err := db.First(&page, "id = ?", incident.PageID).Error; err != nil {
+		return fmt.Errorf("could not load operational page: %w", err)
+	}
+
+	cfg := config.LoadConfig()
+	data := notifier.TemplateData{
+		Page: templatePage(cfg, page),
+		Incident: notifier.TemplateIncident{
+			ID:        incident.ID.String(),
+			Title:     incident.Title,
+			Severity:  incident.Impact,
+			Status:    incident.Status,
+			StartedAt: incident.StartedAt,
+		},
+		Update: notifier.TemplateUpdate{
+			Status:    update.Status,
+			Impact:    incident.Impact,
+			Message:   update.Message,
+			CreatedAt: update.CreatedAt,
+		},
+	}
+	if incident.ResolvedAt != nil {
+		data.Incident.ResolvedAt = *incident.ResolvedAt
+		data.Incident.Duration = incident.ResolvedAt.Sub(incident.StartedAt).Round(time.Second).String()
+	}
+	return notify(db, cfg, page, incident.ComponentIDs, notifier.EventStatusIncidentUpdated, data)
+}
+
+// notify renders an event for every confirmed subscriber of a page whose
+// component filter matches and queues it by email or webhook. An empty
+// componentIDs list concerns the whole page.
+func notify(db *gorm.DB, cfg *config.Config, page models.OperationalPage, componentIDs []string, event string, data notifier.TemplateData) error {
+	subscribers := []models.StatusSubscriber{}
+	if err := db.Where("page_id = ? AND confirmed = ?", page.ID, true).Find(&subscribers).Error; err != nil {
+		return fmt.Errorf("could not load subscribers: %w", err)
+	}
+
+	var webhookMsg *notifier.Message
+	for _, subscriber := range subscribers {
+		if !matches(subscriber.ComponentIDs, componentIDs) {
+			continue
+		}
+
+		if subscriber.Type == TypeWebhook {
+			if webhookMsg == nil {
+				msg, err := notifier.Render(db, event, notifier.FormatPlain, data)
+				if err != nil {
+					return fmt.Errorf("could not render webhook message: %w", err)
+				}
+				webhookMsg = &msg
+			}
+			if err := messaging.PublishStatusWebhook(subscriber.ID, *webhookMsg); err != nil {
+				log.Printf("Error queueing status webhook for subscriber %s: %v", subscriber.ID, err)
+			}
+			continue
+		}
+
+		data.UnsubscribeLink = UnsubscribeLink(cfg, page, subscriber.ID)
+		if err := sendEmail(db, subscriber.Email, event, data); err != nil {
+			log.Printf("Error queueing status email to %s: %v", subscriber.Email, err)
+		}
+	}
+	return nil
+}
+
+// matches reports whether a subscriber's component filter covers an update
+func matches(filter, componentIDs []string) bool {
+	if len(filter) == 0 || len(componentIDs) == 0 {
+		return true
+	}
+	for _, id := range componentIDs {
+		for _, wanted := range filter {
+			if id == wanted {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sendEmail renders an event as HTML and plain text and queues it
+func sendEmail(db *gorm.DB, to, event string, data notifier.TemplateData) error {
+	htmlMsg, err := notifier.Render(db, event, notifier.FormatHTML, data)
+	if err != nil {
+		return err
+	}
+	textMsg, err := notifier.Render(db, event, notifier.FormatPlain, data)
+	if err != nil {
+		return err
+	}
+	return messaging.PublishEmail(messaging.EmailTask{To: to, Subject: htmlMsg.Subject, Body: htmlMsg.Body, TextBody: textMsg.Body})
+}
+
+func templatePage(cfg *config.Config, page models.OperationalPage) notifier.TemplatePage {
+	return notifier.TemplatePage{Name: page.Name, Slug: page.Slug, Link: pageLink(cfg, page)}
+}
+
+func pageLink(cfg *config.Config, page models.OperationalPage) string {
+	return statuspage.PageURL(cfg.App.PublicURL, page)
+}
+
+func subscriberLink(cfg *config.Config, page models.OperationalPage, subscriberID uuid.UUID, action, token string) string {
+	return pageLink(cfg, page) + "/subscribers/" + subscriberID.String() + "/" + action + "?token=" + url.QueryEscape(token)
+}
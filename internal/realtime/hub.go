@@ -0,0 +1,155 @@
+package realtime
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Event types. Lagged tells a subscriber how many events were dropped because
+// it did not keep up.
+const (
+	EventStatusChanged = "status_changed"
+	EventCheckResult   = "check_result"
+	EventMetric        = "metric"
+	EventIncident      = "incident"
+	EventLagged        = "lagged"
+)
+
+// SubscriberBuffer is the number of events queued for a subscriber before
+// newer events are dropped
+const SubscriberBuffer = 64
+
+// Event is one message delivered to subscribers. Topic is the subscribed
+// topic the event matched.
+type Event struct {
+	Type  string      `json:"type"`
+	Topic string      `json:"topic,omitempty"`
+	Data  interface{} `json:"data,omitempty"`
+	Time  time.Time   `json:"time"`
+}
+
+// Subscriber receives the events of the topics it joined
+type Subscriber struct {
+	events  chan Event
+	topics  map[string]bool
+	dropped atomic.Int64
+	closed  bool
+}
+
+// Events is the queue of the subscriber. It is closed when the subscriber is.
+func (s *Subscriber) Events() <-chan Event {
+	return s.events
+}
+
+// TakeDropped returns the number of events dropped since the last call
+func (s *Subscriber) TakeDropped() int64 {
+	return s.dropped.Swap(0)
+}
+
+// Hub fans events out to the subscribers of their topics. Publishing never
+// blocks: events for a subscriber whose queue is full are dropped and counted.
+type Hub struct {
+	mu     sync.RWMutex
+	topics map[string]map[*Subscriber]bool
+}
+
+// NewHub returns an empty hub
+func NewHub() *Hub {
+	return &Hub{topics: map[string]map[*Subscriber]bool{}}
+}
+
+// Default is the hub fed by the Watcher and used by the WebSocket endpoint
+var Default = NewHub()
+
+// Subscribe returns a subscriber without topics
+func (h *Hub) Subscribe() *Subscriber {
+	return &Subscriber{events: make(chan Event, SubscriberBuffer), topics: map[string]bool{}}
+}
+
+// Join adds a topic to a subscriber
+func (h *Hub) Join(s *Subscriber, topic string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if s.closed {
+		return
+	}
+	if h.topics[topic] == nil {
+		h.topics[topic] = map[*Subscriber]bool{}
+	}
+	h.topics[topic][s] = true
+	s.topics[topic] = true
+}
+
+// Leave removes a topic from a subscriber
+func (h *Hub) Leave(s *Subscriber, topic string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.leave(s, topic)
+}
+
+// Topics returns the number of topics a subscriber joined
+func (h *Hub) Topics(s *Subscriber) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(s.topics)
+}
+
+// Close removes a subscriber from all of its topics and closes its queue
+func (h *Hub) Close(s *Subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if s.closed {
+		return
+	}
+	for topic := range s.topics {
+		h.leave(s, topic)
+	}
+	s.closed = true
+	close(s.events)
+}
+
+// Subscribers returns the number of subscribers of a topic
+func (h *Hub) Subscribers(topic string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.topics[topic])
+}
+
+// Publish delivers an event to the subscribers of any of the topics. A
+// subscriber of several of them receives the event once, tagged with the
+// first topic it matched.
+func (h *Hub) Publish(event Event, topics ...string) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	delivered := map[*Subscriber]bool{}
+	for _, topic := range topics {
+		for s := range h.topics[topic] {
+			if delivered[s] {
+				continue
+			}
+			delivered[s] = true
+
+			event.Topic = topic
+			select {
+			case s.events <- event:
+			default:
+				s.dropped.Add(1)
+			}
+		}
+	}
+}
+
+func (h *Hub) leave(s *Subscriber, topic string) {
+	delete(s.topics, topic)
+	if subscribers := h.topics[topic]; subscribers != nil {
+		delete(subscribers, s)
+		if len(subscribers) == 0 {
+			delete(h.topics, topic)
+		}
+	}
+}
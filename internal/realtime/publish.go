@@ -0,0 +1,186 @@
+package realtime
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"monitron-server/internal/incidents"
+	"monitron-server/internal/pagecomponents"
+	"monitron-server/internal/statuspage"
+	"monitron-server/models"
+)
+
+// TargetStatus is the data of status_changed events on target, group and all
+// topics, sent when a target's check status flips
+type TargetStatus struct {
+	TargetType     string    `json:"target_type"`
+	TargetID       uuid.UUID `json:"target_id"`
+	Status         string    `json:"status"` // "up" or "down"
+	PreviousStatus string    `json:"previous_status"`
+	CheckedAt      time.Time `json:"checked_at"`
+}
+
+// IncidentChange is the data of incident events
+type IncidentChange struct {
+	Event    string          `json:"event"` // incident.opened or incident.resolved
+	Incident models.Incident `json:"incident"`
+}
+
+// ComponentStatus is the data of status_changed events on page topics. Status
+// is a status page status such as operational or major_outage.
+type ComponentStatus struct {
+	PageID        uuid.UUID `json:"page_id"`
+	ComponentID   uuid.UUID `json:"component_id"`
+	ComponentName string    `json:"component_name"`
+	Status        string    `json:"status"`
+}
+
+// ComponentMetric is the data of metric events on page topics. Pages only
+// receive response times, never check messages or instance metrics.
+type ComponentMetric struct {
+	PageID        uuid.UUID `json:"page_id"`
+	ComponentID   uuid.UUID `json:"component_id"`
+	ComponentName string    `json:"component_name"`
+	Metric        string    `json:"metric"`
+	Value         float64   `json:"value"`
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+// route is where the events of one target go
+type route struct {
+	topics     []string // Target, group and all topics
+	components []models.OperationalPageComponent
+}
+
+// publisher publishes target events to the hub, caching the routes of the
+// targets it has seen. A publisher is meant for one poll: routes go stale
+// when targets change group or page components are added.
+type publisher struct {
+	db     *gorm.DB
+	hub    *Hub
+	routes map[string]route
+}
+
+func newPublisher(db *gorm.DB, hub *Hub) *publisher {
+	return &publisher{db: db, hub: hub, routes: map[string]route{}}
+}
+
+// checkResult publishes a check result, plus its response time to pages
+func (p *publisher) checkResult(result models.CheckResult) error {
+	r, err := p.route(result.TargetType, result.TargetID)
+	if err != nil {
+		return err
+	}
+	p.hub.Publish(Event{Type: EventCheckResult, Data: result, Time: result.CheckedAt}, r.topics...)
+	for _, component := range r.components {
+		p.hub.Publish(Event{Type: EventMetric, Data: ComponentMetric{
+			PageID:        component.PageID,
+			ComponentID:   component.ID,
+			ComponentName: component.ComponentName,
+			Metric:        "response_time",
+			Value:         result.ResponseTime,
+			Timestamp:     result.CheckedAt,
+		}, Time: result.CheckedAt}, PageTopic(component.PageID))
+	}
+	return nil
+}
+
+// statusChange publishes a flip of a target's check status
+func (p *publisher) statusChange(result models.CheckResult, previous string) error {
+	r, err := p.route(result.TargetType, result.TargetID)
+	if err != nil {
+		return err
+	}
+	p.hub.Publish(Event{Type: EventStatusChanged, Data: TargetStatus{
+		TargetType:     result.TargetType,
+		TargetID:       result.TargetID,
+		Status:         result.Status,
+		PreviousStatus: previous,
+		CheckedAt:      result.CheckedAt,
+	}, Time: result.CheckedAt}, r.topics...)
+
+	status := statuspage.StatusOperational
+	if result.Status != "up" {
+		status = statuspage.StatusMajorOutage
+	}
+	p.componentStatus(r, status, result.CheckedAt)
+	return nil
+}
+
+// metric publishes an instance metric. Pages do not receive it.
+func (p *publisher) metric(metric models.InstanceMetric) error {
+	r, err := p.route(pagecomponents.TypeInstance, metric.InstanceID)
+	if err != nil {
+		return err
+	}
+	p.hub.Publish(Event{Type: EventMetric, Data: metric, Time: metric.Timestamp}, r.topics...)
+	return nil
+}
+
+// incident publishes an incident that was opened or resolved, and the
+// resulting component status to pages, matching incidents.Open and Resolve
+func (p *publisher) incident(incident models.Incident) error {
+	r, err := p.route(incident.TargetType, incident.TargetID)
+	if err != nil {
+		return err
+	}
+
+	event, status, at := incidents.EventOpened, statuspage.StatusDegraded, incident.StartedAt
+	if incident.Severity == incidents.SeverityCritical {
+		status = statuspage.StatusMajorOutage
+	}
+	if incident.Status == incidents.StatusResolved {
+		event, status = incidents.EventResolved, statuspage.StatusOperational
+		if incident.ResolvedAt != nil {
+			at = *incident.ResolvedAt
+		}
+	}
+	p.hub.Publish(Event{Type: EventIncident, Data: IncidentChange{Event: event, Incident: incident}, Time: at}, r.topics...)
+	p.componentStatus(r, status, at)
+	return nil
+}
+
+func (p *publisher) componentStatus(r route, status string, at time.Time) {
+	for _, component := range r.components {
+		p.hub.Publish(Event{Type: EventStatusChanged, Data: ComponentStatus{
+			PageID:        component.PageID,
+			ComponentID:   component.ID,
+			ComponentName: component.ComponentName,
+			Status:        status,
+		}, Time: at}, PageTopic(component.PageID))
+	}
+}
+
+// route resolves the topics and page components of a target
+func (p *publisher) route(targetType string, targetID uuid.UUID) (route, error) {
+	key := TargetTopic(targetType, targetID)
+	if r, ok := p.routes[key]; ok {
+		return r, nil
+	}
+
+	r := route{topics: []string{key, TopicAll}}
+	var groups []string
+	switch targetType {
+	case pagecomponents.TypeService:
+		if err := p.db.Model(&models.Service{}).Where("id = ?", targetID).Pluck(`"group"`, &groups).Error; err != nil {
+			return r, fmt.Errorf("could not look up service group: %w", err)
+		}
+	case pagecomponents.TypeInstance:
+		if err := p.db.Model(&models.Instance{}).Where("id = ?", targetID).Pluck(`"group"`, &groups).Error; err != nil {
+			return r, fmt.Errorf("could not look up instance group: %w", err)
+		}
+	}
+	if len(groups) > 0 && groups[0] != "" {
+		r.topics = append(r.topics, GroupTopic(groups[0]))
+	}
+
+	err := p.db.Where("component_type = ? AND component_id = ?", targetType, targetID).Find(&r.components).Error
+	if err != nil {
+		return r, fmt.Errorf("could not load page components: %w", err)
+	}
+	p.routes[key] = r
+	return r, nil
+}
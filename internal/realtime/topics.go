@@ -0,0 +1,140 @@
+package realtime
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"monitron-server/internal/pagecomponents"
+	"monitron-server/models"
+)
+
+// TopicAll receives the events of every target
+const TopicAll = "all"
+
+// Subscription errors, worded for API responses
+var (
+	ErrUnknownTopic = errors.New("topic must be all, target:<type>:<id>, group:<name> or page:<id or slug>")
+	ErrUnauthorized = errors.New("authentication required")
+	ErrForbidden    = errors.New("admin access required")
+	ErrPageNotFound = errors.New("operational page not found")
+)
+
+// Viewer is the caller subscribing to a topic
+type Viewer struct {
+	Authenticated bool
+	UserID        uuid.UUID
+	Role          string
+}
+
+// TargetTopic receives the events of one monitored target
+func TargetTopic(targetType string, targetID uuid.UUID) string {
+	return "target:" + targetType + ":" + targetID.String()
+}
+
+// GroupTopic receives the events of the services and instances of a group
+func GroupTopic(group string) string {
+	return "group:" + group
+}
+
+// PageTopic receives the component events of an operational page
+func PageTopic(pageID uuid.UUID) string {
+	return "page:" + pageID.String()
+}
+
+// Authorize checks that the viewer may subscribe to a topic and returns its
+// canonical form. Public page topics are open to anyone, private page,
+// target and group topics need a signed-in user and all needs an admin.
+func Authorize(db *gorm.DB, topic string, viewer Viewer) (string, error) {
+	kind, rest, _ := strings.Cut(topic, ":")
+	switch kind {
+	case TopicAll:
+		if rest != "" {
+			return "", ErrUnknownTopic
+		}
+		if !viewer.Authenticated {
+			return "", ErrUnauthorized
+		}
+		if viewer.Role != "admin" {
+			return "", ErrForbidden
+		}
+		return TopicAll, nil
+	case "target":
+		targetType, id, _ := strings.Cut(rest, ":")
+		targetID, err := uuid.Parse(id)
+		if err != nil || !isTargetType(targetType) {
+			return "", ErrUnknownTopic
+		}
+		if !viewer.Authenticated {
+			return "", ErrUnauthorized
+		}
+		return TargetTopic(targetType, targetID), nil
+	case "group":
+		if rest == "" {
+			return "", ErrUnknownTopic
+		}
+		if !viewer.Authenticated {
+			return "", ErrUnauthorized
+		}
+		return GroupTopic(rest), nil
+	case "page":
+		page, err := findPage(db, rest)
+		if err != nil {
+			return "", err
+		}
+		if !page.IsPublic && !viewer.Authenticated {
+			return "", ErrUnauthorized
+		}
+		return PageTopic(page.ID), nil
+	}
+	return "", ErrUnknownTopic
+}
+
+// Canonical returns the canonical form of a topic without checking what the
+// viewer may see, for leaving topics. Page IDs are taken as they are, so
+// topics of deleted pages can be left by the ID of the subscribed reply; only
+// slugs are looked up.
+func Canonical(db *gorm.DB, topic string) (string, error) {
+	kind, rest, _ := strings.Cut(topic, ":")
+	if kind == "page" {
+		if id, err := uuid.Parse(rest); err == nil {
+			return PageTopic(id), nil
+		}
+		page, err := findPage(db, rest)
+		if err != nil {
+			return "", err
+		}
+		return PageTopic(page.ID), nil
+	}
+	// The other topics only need a signed-in user or admin, and are
+	// canonicalized without a lookup
+	return Authorize(db, topic, Viewer{Authenticated: true, Role: "admin"})
+}
+
+// findPage looks up an operational page by ID or slug
+func findPage(db *gorm.DB, ref string) (models.OperationalPage, error) {
+	page := models.OperationalPage{}
+	query := db.Where("slug = ?", ref)
+	if id, err := uuid.Parse(ref); err == nil {
+		query = db.Where("id = ?", id)
+	}
+	err := query.First(&page).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return page, ErrPageNotFound
+	}
+	if err != nil {
+		return page, fmt.Errorf("could not look up operational page: %w", err)
+	}
+	return page, nil
+}
+
+func isTargetType(targetType string) bool {
+	switch targetType {
+	case pagecomponents.TypeService, pagecomponents.TypeInstance, pagecomponents.TypeDomainSSL:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,154 @@
+package realtime
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+
+	"monitron-server/models"
+)
+
+// PollInterval is how often the Watcher looks for new rows
+const PollInterval = 2 * time.Second
+
+// pollLookback is how far behind its cursor the Watcher reads, so rows
+// committed after newer ones are still published. Rows already published are
+// skipped.
+const pollLookback = 15 * time.Second
+
+// targetState is the latest known check status of a target
+type targetState struct {
+	status    string
+	checkedAt time.Time
+}
+
+// Watcher feeds a hub from the check results, instance metrics and incidents
+// written to the database, so every server replica publishes every event no
+// matter which process wrote the row
+type Watcher struct {
+	db       *gorm.DB
+	hub      *Hub
+	since    time.Time
+	seen     map[string]time.Time
+	statuses map[string]targetState
+}
+
+// NewWatcher returns a watcher publishing rows written from now on
+func NewWatcher(db *gorm.DB, hub *Hub) *Watcher {
+	return &Watcher{
+		db:       db,
+		hub:      hub,
+		since:    time.Now(),
+		seen:     map[string]time.Time{},
+		statuses: map[string]targetState{},
+	}
+}
+
+// Run polls at the given interval until the process exits
+func (w *Watcher) Run(interval time.Duration) {
+	if err := w.seed(); err != nil {
+		log.Printf("Error loading target statuses for realtime events: %v", err)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := w.Poll(); err != nil {
+			log.Printf("Error polling for realtime events: %v", err)
+		}
+	}
+}
+
+// Poll publishes the rows written since the previous poll
+func (w *Watcher) Poll() error {
+	now := time.Now()
+	from := w.since.Add(-pollLookback)
+	p := newPublisher(w.db, w.hub)
+
+	results := []models.CheckResult{}
+	if err := w.db.Where("checked_at > ?", from).Order("checked_at").Find(&results).Error; err != nil {
+		return fmt.Errorf("could not load check results: %w", err)
+	}
+	for _, result := range results {
+		if !w.markSeen("check:"+result.ID.String(), result.CheckedAt) {
+			continue
+		}
+		if err := p.checkResult(result); err != nil {
+			return err
+		}
+
+		key := TargetTopic(result.TargetType, result.TargetID)
+		previous, known := w.statuses[key]
+		if known && result.CheckedAt.Before(previous.checkedAt) {
+			continue
+		}
+		w.statuses[key] = targetState{status: result.Status, checkedAt: result.CheckedAt}
+		if known && previous.status != result.Status {
+			if err := p.statusChange(result, previous.status); err != nil {
+				return err
+			}
+		}
+	}
+
+	metrics := []models.InstanceMetric{}
+	if err := w.db.Where("timestamp > ?", from).Order("timestamp").Find(&metrics).Error; err != nil {
+		return fmt.Errorf("could not load instance metrics: %w", err)
+	}
+	for _, metric := range metrics {
+		key := fmt.Sprintf("metric:%s:%s:%d", metric.InstanceID, metric.MetricType, metric.Timestamp.UnixNano())
+		if !w.markSeen(key, metric.Timestamp) {
+			continue
+		}
+		if err := p.metric(metric); err != nil {
+			return err
+		}
+	}
+
+	changed := []models.Incident{}
+	if err := w.db.Where("updated_at > ?", from).Order("updated_at").Find(&changed).Error; err != nil {
+		return fmt.Errorf("could not load incidents: %w", err)
+	}
+	for _, incident := range changed {
+		if !w.markSeen("incident:"+incident.ID.String()+":"+incident.Status, incident.UpdatedAt) {
+			continue
+		}
+		if err := p.incident(incident); err != nil {
+			return err
+		}
+	}
+
+	for key, at := range w.seen {
+		if at.Before(from) {
+			delete(w.seen, key)
+		}
+	}
+	w.since = now
+	return nil
+}
+
+// markSeen records a row as published and reports whether it was new
+func (w *Watcher) markSeen(key string, at time.Time) bool {
+	if _, ok := w.seen[key]; ok {
+		return false
+	}
+	w.seen[key] = at
+	return true
+}
+
+// seed loads the latest check status of each target checked in the past day,
+// so the first result after a restart can already be a status change
+func (w *Watcher) seed() error {
+	latest := []models.CheckResult{}
+	err := w.db.Raw(`SELECT DISTINCT ON (target_type, target_id) target_type, target_id, status, checked_at
+		FROM check_results WHERE checked_at > ? ORDER BY target_type, target_id, checked_at DESC`,
+		time.Now().Add(-24*time.Hour)).Scan(&latest).Error
+	if err != nil {
+		return err
+	}
+	for _, result := range latest {
+		w.statuses[TargetTopic(result.TargetType, result.TargetID)] = targetState{status: result.Status, checkedAt: result.CheckedAt}
+	}
+	return nil
+}
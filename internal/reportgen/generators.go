@@ -0,0 +1,526 @@
+package reportgen
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+
+	"monitron-server/internal/pagecomponents"
+	"monitron-server/models"
+)
+
+// uptimeRow is the per-target aggregate of check results over a period
+type uptimeRow struct {
+	TargetID        string
+	Checks          int64
+	UpChecks        int64
+	AvgResponseTime float64
+	MaxResponseTime float64
+}
+
+func (r uptimeRow) uptime() float64 {
+	if r.Checks == 0 {
+		return 0
+	}
+	return float64(r.UpChecks) / float64(r.Checks) * 100
+}
+
+// filterTargets restricts a query on a table with id and "group" columns
+func filterTargets(query *gorm.DB, params Params, hasGroup bool) *gorm.DB {
+	if len(params.TargetIDs) > 0 {
+		query = query.Where("id IN ?", params.TargetIDs)
+	}
+	if hasGroup && len(params.Groups) > 0 {
+		query = query.Where(`"group" IN ?`, params.Groups)
+	}
+	return query
+}
+
+// checkAggregates returns check result aggregates keyed by target ID
+func checkAggregates(db *gorm.DB, targetType string, targetIDs []string, params Params) (map[string]uptimeRow, error) {
+	rows := []uptimeRow{}
+	if len(targetIDs) == 0 {
+		return map[string]uptimeRow{}, nil
+	}
+
+	err := db.Model(&models.CheckResult{}).
+		Select(`target_id::text AS target_id,
+			COUNT(*) AS checks,
+			COUNT(*) FILTER (WHERE status = 'up') AS up_checks,
+			COALESCE(AVG(response_time), 0) AS avg_response_time,
+			COALESCE(MAX(response_time), 0) AS max_response_time`).
+		Where("target_type = ? AND target_id IN ? AND checked_at >= ? AND checked_at < ?", targetType, targetIDs, params.From, params.To).
+		Group("target_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]uptimeRow, len(rows))
+	for _, row := range rows {
+		result[row.TargetID] = row
+	}
+	return result, nil
+}
+
+// incidentCounts returns the number of incidents started in the period per target ID
+func incidentCounts(db *gorm.DB, targetType string, targetIDs []string, params Params) (map[string]int64, error) {
+	rows := []struct {
+		TargetID string
+		Count    int64
+	}{}
+	if len(targetIDs) == 0 {
+		return map[string]int64{}, nil
+	}
+
+	err := db.Model(&models.Incident{}).
+		Select("target_id::text AS target_id, COUNT(*) AS count").
+		Where("target_type = ? AND target_id IN ? AND started_at >= ? AND started_at < ?", targetType, targetIDs, params.From, params.To).
+		Group("target_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		result[row.TargetID] = row.Count
+	}
+	return result, nil
+}
+
+// dailyRow is the aggregate of check results for one day
+type dailyRow struct {
+	Day          time.Time
+	Checks       int64
+	UpChecks     int64
+	ResponseTime float64 // sum of response times, divided out in dailyCharts
+}
+
+// dailyChecks returns per-day check aggregates keyed by day, so results for
+// several target types can be merged before charting
+func dailyChecks(db *gorm.DB, targetType string, targetIDs []string, params Params, into map[time.Time]dailyRow) error {
+	if len(targetIDs) == 0 {
+		return nil
+	}
+
+	rows := []dailyRow{}
+	err := db.Model(&models.CheckResult{}).
+		Select(`date_trunc('day', checked_at) AS day,
+			COUNT(*) AS checks,
+			COUNT(*) FILTER (WHERE status = 'up') AS up_checks,
+			COALESCE(SUM(response_time), 0) AS response_time`).
+		Where("target_type = ? AND target_id IN ? AND checked_at >= ? AND checked_at < ?", targetType, targetIDs, params.From, params.To).
+		Group("day").
+		Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		day := row.Day.UTC()
+		merged := into[day]
+		merged.Day = day
+		merged.Checks += row.Checks
+		merged.UpChecks += row.UpChecks
+		merged.ResponseTime += row.ResponseTime
+		into[day] = merged
+	}
+	return nil
+}
+
+// dailyCharts turns per-day aggregates into uptime and latency charts
+func dailyCharts(days map[time.Time]dailyRow) []Chart {
+	if len(days) == 0 {
+		return nil
+	}
+
+	keys := make([]time.Time, 0, len(days))
+	for day := range days {
+		keys = append(keys, day)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Before(keys[j]) })
+
+	uptime := Chart{Title: "Daily uptime", Kind: "bar", Unit: "%"}
+	latency := Chart{Title: "Daily average response time", Kind: "line", Unit: "ms"}
+	for _, day := range keys {
+		row := days[day]
+		label := day.Format("01-02")
+		var up, avg float64
+		if row.Checks > 0 {
+			up = float64(row.UpChecks) / float64(row.Checks) * 100
+			avg = row.ResponseTime / float64(row.Checks)
+		}
+		uptime.Labels = append(uptime.Labels, label)
+		uptime.Values = append(uptime.Values, up)
+		latency.Labels = append(latency.Labels, label)
+		latency.Values = append(latency.Values, avg)
+	}
+	return []Chart{uptime, latency}
+}
+
+func percent(v float64) string {
+	return fmt.Sprintf("%.2f%%", v)
+}
+
+func instanceSummary(db *gorm.DB, params Params) (*Dataset, error) {
+	instances := []models.Instance{}
+	if err := filterTargets(db, params, true).Order("name ASC").Find(&instances).Error; err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(instances))
+	for i, instance := range instances {
+		ids[i] = instance.ID.String()
+	}
+
+	type metricRow struct {
+		InstanceID string
+		MetricType string
+		Avg        float64
+		Max        float64
+		Samples    int64
+	}
+	metricRows := []metricRow{}
+	if len(ids) > 0 {
+		err := db.Model(&models.InstanceMetric{}).
+			Select("instance_id::text AS instance_id, metric_type, AVG(value) AS avg, MAX(value) AS max, COUNT(*) AS samples").
+			Where("instance_id IN ? AND timestamp >= ? AND timestamp < ?", ids, params.From, params.To).
+			Group("instance_id, metric_type").
+			Scan(&metricRows).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+	metrics := map[string]map[string]metricRow{}
+	for _, row := range metricRows {
+		if metrics[row.InstanceID] == nil {
+			metrics[row.InstanceID] = map[string]metricRow{}
+		}
+		metrics[row.InstanceID][row.MetricType] = row
+	}
+
+	checks, err := checkAggregates(db, "instance", ids, params)
+	if err != nil {
+		return nil, err
+	}
+	incidents, err := incidentCounts(db, "instance", ids, params)
+	if err != nil {
+		return nil, err
+	}
+
+	table := Table{
+		Title:   "Instances",
+		Columns: []string{"Instance", "Host", "Group", "Uptime %", "Avg CPU %", "Max CPU %", "Avg Memory %", "Max Memory %", "Avg Disk %", "Max Disk %", "Incidents"},
+	}
+	var totalUptime float64
+	for _, instance := range instances {
+		id := instance.ID.String()
+		m := metrics[id]
+		table.Rows = append(table.Rows, []interface{}{
+			instance.Name, instance.Host, instance.Group, checks[id].uptime(),
+			m["cpu_usage"].Avg, m["cpu_usage"].Max,
+			m["memory_usage"].Avg, m["memory_usage"].Max,
+			m["disk_usage"].Avg, m["disk_usage"].Max,
+			incidents[id],
+		})
+		totalUptime += checks[id].uptime()
+	}
+
+	summary := []KPI{{Label: "Instances", Value: fmt.Sprint(len(instances))}}
+	if len(instances) > 0 {
+		summary = append(summary, KPI{Label: "Average uptime", Value: percent(totalUptime / float64(len(instances)))})
+	}
+
+	days := map[time.Time]dailyRow{}
+	if err := dailyChecks(db, "instance", ids, params, days); err != nil {
+		return nil, err
+	}
+	charts := dailyCharts(days)
+
+	if len(ids) > 0 {
+		cpuRows := []struct {
+			Day time.Time
+			Avg float64
+		}{}
+		err := db.Model(&models.InstanceMetric{}).
+			Select("date_trunc('day', timestamp) AS day, AVG(value) AS avg").
+			Where("instance_id IN ? AND metric_type = ? AND timestamp >= ? AND timestamp < ?", ids, "cpu_usage", params.From, params.To).
+			Group("day").Order("day ASC").
+			Scan(&cpuRows).Error
+		if err != nil {
+			return nil, err
+		}
+		if len(cpuRows) > 0 {
+			cpu := Chart{Title: "Daily average CPU usage", Kind: "line", Unit: "%"}
+			for _, row := range cpuRows {
+				cpu.Labels = append(cpu.Labels, row.Day.UTC().Format("01-02"))
+				cpu.Values = append(cpu.Values, row.Avg)
+			}
+			charts = append(charts, cpu)
+		}
+	}
+
+	return &Dataset{Title: "Instance Summary", Summary: summary, Tables: []Table{table}, Charts: charts}, nil
+}
+
+func serviceUptime(db *gorm.DB, params Params) (*Dataset, error) {
+	services := []models.Service{}
+	if err := filterTargets(db, params, true).Order("name ASC").Find(&services).Error; err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(services))
+	for i, service := range services {
+		ids[i] = service.ID.String()
+	}
+
+	checks, err := checkAggregates(db, "service", ids, params)
+	if err != nil {
+		return nil, err
+	}
+	incidents, err := incidentCounts(db, "service", ids, params)
+	if err != nil {
+		return nil, err
+	}
+
+	table := Table{
+		Title:   "Services",
+		Columns: []string{"Service", "Type", "Group", "Checks", "Failed Checks", "Uptime %", "Avg Response (ms)", "Max Response (ms)", "Incidents"},
+	}
+	var totalChecks, totalUp, totalIncidents int64
+	var weightedResponse float64
+	for _, service := range services {
+		id := service.ID.String()
+		row := checks[id]
+		table.Rows = append(table.Rows, []interface{}{
+			service.Name, service.APIType, service.Group, row.Checks, row.Checks - row.UpChecks,
+			row.uptime(), row.AvgResponseTime, row.MaxResponseTime, incidents[id],
+		})
+		totalChecks += row.Checks
+		totalUp += row.UpChecks
+		totalIncidents += incidents[id]
+		weightedResponse += row.AvgResponseTime * float64(row.Checks)
+	}
+
+	summary := []KPI{
+		{Label: "Services", Value: fmt.Sprint(len(services))},
+		{Label: "Incidents", Value: fmt.Sprint(totalIncidents)},
+	}
+	if totalChecks > 0 {
+		summary = append(summary,
+			KPI{Label: "Overall uptime", Value: percent(float64(totalUp) / float64(totalChecks) * 100)},
+			KPI{Label: "Average response time", Value: fmt.Sprintf("%.2f ms", weightedResponse/float64(totalChecks))},
+		)
+	}
+
+	days := map[time.Time]dailyRow{}
+	if err := dailyChecks(db, "service", ids, params, days); err != nil {
+		return nil, err
+	}
+
+	return &Dataset{Title: "Service Uptime", Summary: summary, Tables: []Table{table}, Charts: dailyCharts(days)}, nil
+}
+
+func domainSSLExpiry(db *gorm.DB, params Params) (*Dataset, error) {
+	domains := []models.DomainSSL{}
+	if err := filterTargets(db, params, false).Order("expiry ASC").Find(&domains).Error; err != nil {
+		return nil, err
+	}
+
+	table := Table{
+		Title:   "Certificates",
+		Columns: []string{"Domain", "Label", "Issuer", "Valid From", "Expiry", "Days Left", "Status"},
+	}
+	var expiring, expired int
+	for _, domain := range domains {
+		daysLeft := int(math.Floor(domain.Expiry.Sub(params.To).Hours() / 24))
+		status := "ok"
+		switch {
+		case domain.Expiry.IsZero():
+			status = "unknown"
+		case daysLeft <= domain.ExpiryThreshold:
+			status = "expired"
+			expired++
+		case daysLeft <= domain.WarningThreshold:
+			status = "warning"
+			expiring++
+		}
+		table.Rows = append(table.Rows, []interface{}{
+			domain.Domain, domain.Label, domain.Issuer, domain.ValidFrom, domain.Expiry, daysLeft, status,
+		})
+	}
+
+	summary := []KPI{
+		{Label: "Domains", Value: fmt.Sprint(len(domains))},
+		{Label: "Expiring soon", Value: fmt.Sprint(expiring)},
+		{Label: "Expired", Value: fmt.Sprint(expired)},
+	}
+	return &Dataset{Title: "Domain & SSL Expiry", Summary: summary, Tables: []Table{table}}, nil
+}
+
+func operationalPageSLA(db *gorm.DB, params Params) (*Dataset, error) {
+	pages := []models.OperationalPage{}
+	query := db.Order("name ASC")
+	if len(params.TargetIDs) > 0 {
+		query = query.Where("id IN ?", params.TargetIDs)
+	}
+	if err := query.Find(&pages).Error; err != nil {
+		return nil, err
+	}
+
+	pageTable := Table{
+		Title:   "Operational Pages",
+		Columns: []string{"Page", "Slug", "Components", "Uptime %", "Avg Response (ms)", "Incidents"},
+	}
+	componentTable := Table{
+		Title:   "Components",
+		Columns: []string{"Page", "Component", "Type", "Checks", "Uptime %", "Avg Response (ms)", "Incidents"},
+	}
+
+	days := map[time.Time]dailyRow{}
+	for _, page := range pages {
+		components := []models.OperationalPageComponent{}
+		if err := pagecomponents.Monitored(db).Where("page_id = ?", page.ID).Order("display_order ASC").Find(&components).Error; err != nil {
+			return nil, err
+		}
+
+		idsByType := map[string][]string{}
+		for _, component := range components {
+			idsByType[component.ComponentType] = append(idsByType[component.ComponentType], component.ComponentID.String())
+		}
+		checks := map[string]map[string]uptimeRow{}
+		incidents := map[string]map[string]int64{}
+		for componentType, ids := range idsByType {
+			var err error
+			if checks[componentType], err = checkAggregates(db, componentType, ids, params); err != nil {
+				return nil, err
+			}
+			if incidents[componentType], err = incidentCounts(db, componentType, ids, params); err != nil {
+				return nil, err
+			}
+			if err := dailyChecks(db, componentType, ids, params, days); err != nil {
+				return nil, err
+			}
+		}
+
+		var pageChecks, pageUp, pageIncidents int64
+		var weightedResponse float64
+		for _, component := range components {
+			id := component.ComponentID.String()
+			row := checks[component.ComponentType][id]
+			count := incidents[component.ComponentType][id]
+			componentTable.Rows = append(componentTable.Rows, []interface{}{
+				page.Name, component.ComponentName, component.ComponentType, row.Checks, row.uptime(), row.AvgResponseTime, count,
+			})
+			pageChecks += row.Checks
+			pageUp += row.UpChecks
+			pageIncidents += count
+			weightedResponse += row.AvgResponseTime * float64(row.Checks)
+		}
+
+		var uptime, avgResponse float64
+		if pageChecks > 0 {
+			uptime = float64(pageUp) / float64(pageChecks) * 100
+			avgResponse = weightedResponse / float64(pageChecks)
+		}
+		pageTable.Rows = append(pageTable.Rows, []interface{}{page.Name, page.Slug, len(components), uptime, avgResponse, pageIncidents})
+	}
+
+	summary := []KPI{{Label: "Pages", Value: fmt.Sprint(len(pages))}}
+	return &Dataset{Title: "Operational Page SLA", Summary: summary, Tables: []Table{pageTable, componentTable}, Charts: dailyCharts(days)}, nil
+}
+
+func incidentSummary(db *gorm.DB, params Params) (*Dataset, error) {
+	query := db.Where("started_at >= ? AND started_at < ?", params.From, params.To).Order("started_at DESC")
+	if len(params.TargetIDs) > 0 {
+		query = query.Where("target_id IN ?", params.TargetIDs)
+	}
+	if len(params.Groups) > 0 {
+		query = query.Where(`(target_id IN (?) OR target_id IN (?))`,
+			db.Model(&models.Service{}).Select("id").Where(`"group" IN ?`, params.Groups),
+			db.Model(&models.Instance{}).Select("id").Where(`"group" IN ?`, params.Groups),
+		)
+	}
+
+	incidents := []models.Incident{}
+	if err := query.Find(&incidents).Error; err != nil {
+		return nil, err
+	}
+
+	names, err := targetNames(db, incidents)
+	if err != nil {
+		return nil, err
+	}
+
+	table := Table{
+		Title:   "Incidents",
+		Columns: []string{"Target", "Type", "Title", "Severity", "Status", "Started At", "Resolved At", "Duration (min)"},
+	}
+	bySeverity := map[string]int{}
+	var resolved int
+	var totalDuration time.Duration
+	for _, incident := range incidents {
+		var duration interface{}
+		if incident.ResolvedAt != nil {
+			d := incident.ResolvedAt.Sub(incident.StartedAt)
+			duration = d.Minutes()
+			totalDuration += d
+			resolved++
+		}
+		table.Rows = append(table.Rows, []interface{}{
+			names[incident.TargetID.String()], incident.TargetType, incident.Title, incident.Severity, incident.Status,
+			incident.StartedAt, incident.ResolvedAt, duration,
+		})
+		bySeverity[incident.Severity]++
+	}
+
+	summary := []KPI{
+		{Label: "Incidents", Value: fmt.Sprint(len(incidents))},
+		{Label: "Critical", Value: fmt.Sprint(bySeverity["critical"])},
+		{Label: "Warning", Value: fmt.Sprint(bySeverity["warning"])},
+		{Label: "Open", Value: fmt.Sprint(len(incidents) - resolved)},
+	}
+	if resolved > 0 {
+		summary = append(summary, KPI{Label: "Mean time to resolve", Value: (totalDuration / time.Duration(resolved)).Round(time.Second).String()})
+	}
+
+	return &Dataset{Title: "Incident Summary", Summary: summary, Tables: []Table{table}}, nil
+}
+
+// targetNames resolves display names for the targets of the given incidents
+func targetNames(db *gorm.DB, incidents []models.Incident) (map[string]string, error) {
+	idsByType := map[string][]string{}
+	for _, incident := range incidents {
+		idsByType[incident.TargetType] = append(idsByType[incident.TargetType], incident.TargetID.String())
+	}
+
+	names := map[string]string{}
+	for targetType, ids := range idsByType {
+		rows := []struct {
+			ID   string
+			Name string
+		}{}
+		var query *gorm.DB
+		switch targetType {
+		case "service":
+			query = db.Model(&models.Service{}).Select("id::text AS id, name")
+		case "instance":
+			query = db.Model(&models.Instance{}).Select("id::text AS id, name")
+		case "domain_ssl":
+			query = db.Model(&models.DomainSSL{}).Select("id::text AS id, domain AS name")
+		default:
+			continue
+		}
+		if err := query.Where("id IN ?", ids).Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			names[row.ID] = row.Name
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,98 @@
+package reportgen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"monitron-server/internal/storage"
+	"monitron-server/models"
+)
+
+// Report statuses
+const (
+	StatusPending    = "pending"
+	StatusGenerating = "generating"
+	StatusCompleted  = "completed"
+	StatusFailed     = "failed"
+)
+
+// Process moves a queued report through generating to completed or failed,
+// recording the stored file or the error on the report row. Reports that
+// already completed are left alone, so redelivered messages are harmless.
+func Process(ctx context.Context, db *gorm.DB, store storage.Storage, reportID uuid.UUID) error {
+	report := models.Report{}
+	if err := db.First(&report, "id = ?", reportID).Error; err != nil {
+		return fmt.Errorf("could not load report %s: %w", reportID, err)
+	}
+	if report.Status == StatusCompleted {
+		return nil
+	}
+
+	if err := setStatus(db, report.ID, map[string]interface{}{"status": StatusGenerating, "error_message": ""}); err != nil {
+		return err
+	}
+
+	key, size, err := GenerateReportFile(ctx, db, store, report)
+	if err != nil {
+		if updateErr := setStatus(db, report.ID, map[string]interface{}{"status": StatusFailed, "error_message": err.Error()}); updateErr != nil {
+			log.Printf("Error marking report %s as failed: %v", report.ID, updateErr)
+		}
+		return err
+	}
+
+	// A file from an earlier attempt is replaced by the new one
+	if report.FilePath != "" && report.FilePath != key {
+		if err := store.Delete(ctx, report.FilePath); err != nil {
+			log.Printf("Error deleting previous file of report %s: %v", report.ID, err)
+		}
+	}
+
+	return setStatus(db, report.ID, map[string]interface{}{
+		"status":        StatusCompleted,
+		"error_message": "",
+		"file_path":     key,
+		"file_size":     size,
+		"generated_at":  time.Now(),
+	})
+}
+
+// setStatus updates the lifecycle columns of a report
+func setStatus(db *gorm.DB, reportID uuid.UUID, columns map[string]interface{}) error {
+	columns["updated_at"] = time.Now()
+	if err := db.Model(&models.Report{}).Where("id = ?", reportID).Updates(columns).Error; err != nil {
+		return fmt.Errorf("could not update report %s: %w", reportID, err)
+	}
+	return nil
+}
+
+// CleanupExpired deletes finished reports created before the cutoff together
+// with their stored files. Reports whose file cannot be deleted are kept and
+// retried on the next run.
+func CleanupExpired(ctx context.Context, db *gorm.DB, store storage.Storage, before time.Time) (int, error) {
+	reports := []models.Report{}
+	if err := db.Where("created_at < ? AND status IN ?", before, []string{StatusCompleted, StatusFailed}).Find(&reports).Error; err != nil {
+		return 0, fmt.Errorf("could not load expired reports: %w", err)
+	}
+
+	deleted := 0
+	for _, report := range reports {
+		if report.FilePath != "" {
+			if err := store.Delete(ctx, report.FilePath); err != nil && !errors.Is(err, storage.ErrNotFound) {
+				log.Printf("Error deleting file of expired report %s: %v", report.ID, err)
+				continue
+			}
+		}
+		if err := db.Delete(&models.Report{}, "id = ?", report.ID).Error; err != nil {
+			log.Printf("Error deleting expired report %s: %v", report.ID, err)
+			continue
+		}
+		deleted++
+	}
+	return deleted, nil
+}
@@ -0,0 +1,313 @@
+package reportgen
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// PDF layout, in millimetres on a landscape A4 page
+const (
+	pdfMargin      = 10.0
+	pdfLineHeight  = 6.0
+	pdfKPIPerRow   = 4
+	pdfKPIHeight   = 16.0
+	pdfChartHeight = 60.0
+	pdfMaxXLabels  = 15
+)
+
+// PDFRenderer lays out the summary KPIs, charts and tables of a dataset on
+// landscape A4 pages. Charts are drawn with PDF primitives so no image
+// libraries or external tools are needed.
+type PDFRenderer struct{}
+
+func (PDFRenderer) Extension() string   { return "pdf" }
+func (PDFRenderer) ContentType() string { return "application/pdf" }
+
+func (PDFRenderer) Render(w io.Writer, dataset *Dataset) error {
+	pdf := gofpdf.New("L", "mm", "A4", "")
+	pdf.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	pdf.SetAutoPageBreak(true, pdfMargin+5)
+	pdf.SetTitle(dataset.Title, true)
+	pdf.SetCreator("Monitron", true)
+	pdf.AliasNbPages("")
+
+	doc := &pdfDoc{Fpdf: pdf, tr: pdf.UnicodeTranslatorFromDescriptor("")}
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-pdfMargin - 3)
+		pdf.SetFont("Helvetica", "I", 8)
+		pdf.SetTextColor(120, 120, 120)
+		pdf.CellFormat(0, 5, fmt.Sprintf("Page %d/{nb}", pdf.PageNo()), "", 0, "R", false, 0, "")
+	})
+	pdf.AddPage()
+
+	doc.header(dataset)
+	doc.kpis(dataset.Summary)
+	for _, chart := range dataset.Charts {
+		doc.chart(chart)
+	}
+	for _, table := range dataset.Tables {
+		doc.table(table)
+	}
+
+	if err := pdf.Error(); err != nil {
+		return err
+	}
+	return pdf.Output(w)
+}
+
+// pdfDoc wraps gofpdf with the report layout helpers
+type pdfDoc struct {
+	*gofpdf.Fpdf
+	tr func(string) string
+}
+
+// contentWidth is the usable page width between the margins
+func (d *pdfDoc) contentWidth() float64 {
+	width, _ := d.GetPageSize()
+	return width - 2*pdfMargin
+}
+
+// ensureSpace starts a new page unless height millimetres fit on this one
+func (d *pdfDoc) ensureSpace(height float64) {
+	_, pageHeight := d.GetPageSize()
+	if d.GetY()+height > pageHeight-pdfMargin-5 {
+		d.AddPage()
+	}
+}
+
+// fit truncates text so it fits within width at the current font
+func (d *pdfDoc) fit(text string, width float64) string {
+	text = d.tr(text)
+	if d.GetStringWidth(text) <= width {
+		return text
+	}
+	for len(text) > 0 && d.GetStringWidth(text+"...") > width {
+		text = text[:len(text)-1]
+	}
+	return text + "..."
+}
+
+func (d *pdfDoc) header(dataset *Dataset) {
+	d.SetFont("Helvetica", "B", 18)
+	d.SetTextColor(30, 30, 30)
+	d.CellFormat(0, 10, d.tr(dataset.Title), "", 1, "L", false, 0, "")
+
+	d.SetFont("Helvetica", "", 10)
+	d.SetTextColor(100, 100, 100)
+	period := fmt.Sprintf("%s - %s  (generated %s)",
+		dataset.From.Format("2006-01-02 15:04"), dataset.To.Format("2006-01-02 15:04"), time.Now().Format("2006-01-02 15:04"))
+	d.CellFormat(0, pdfLineHeight, period, "", 1, "L", false, 0, "")
+	d.Ln(4)
+}
+
+func (d *pdfDoc) kpis(kpis []KPI) {
+	if len(kpis) == 0 {
+		return
+	}
+
+	gap := 4.0
+	boxWidth := (d.contentWidth() - gap*(pdfKPIPerRow-1)) / pdfKPIPerRow
+	for i, kpi := range kpis {
+		col := i % pdfKPIPerRow
+		if col == 0 {
+			if i > 0 {
+				d.Ln(pdfKPIHeight + gap)
+			}
+			d.ensureSpace(pdfKPIHeight)
+		}
+		x, y := pdfMargin+float64(col)*(boxWidth+gap), d.GetY()
+
+		d.SetFillColor(240, 244, 248)
+		d.Rect(x, y, boxWidth, pdfKPIHeight, "F")
+
+		d.SetXY(x+3, y+2)
+		d.SetFont("Helvetica", "", 8)
+		d.SetTextColor(100, 100, 100)
+		d.CellFormat(boxWidth-6, 4, d.fit(kpi.Label, boxWidth-6), "", 0, "L", false, 0, "")
+
+		d.SetXY(x+3, y+7)
+		d.SetFont("Helvetica", "B", 14)
+		d.SetTextColor(30, 30, 30)
+		d.CellFormat(boxWidth-6, 7, d.fit(kpi.Value, boxWidth-6), "", 0, "L", false, 0, "")
+
+		d.SetXY(pdfMargin, y)
+	}
+	d.Ln(pdfKPIHeight + 8)
+}
+
+func (d *pdfDoc) chart(chart Chart) {
+	if len(chart.Values) == 0 {
+		return
+	}
+	d.ensureSpace(pdfChartHeight + 12)
+
+	d.SetFont("Helvetica", "B", 11)
+	d.SetTextColor(30, 30, 30)
+	d.CellFormat(0, 7, d.tr(chart.Title), "", 1, "L", false, 0, "")
+
+	axisWidth := 14.0
+	x0, top := pdfMargin+axisWidth, d.GetY()+2
+	width, height := d.contentWidth()-axisWidth, pdfChartHeight-12
+	bottom := top + height
+
+	maxValue := chartMax(chart)
+
+	// Horizontal grid lines with y axis labels
+	d.SetFont("Helvetica", "", 7)
+	d.SetTextColor(100, 100, 100)
+	d.SetLineWidth(0.1)
+	for i := 0; i <= 4; i++ {
+		value := maxValue * float64(i) / 4
+		y := bottom - height*float64(i)/4
+		d.SetDrawColor(220, 220, 220)
+		d.Line(x0, y, x0+width, y)
+		d.SetXY(pdfMargin, y-2)
+		d.CellFormat(axisWidth-2, 4, fmt.Sprintf("%.0f%s", value, chart.Unit), "", 0, "R", false, 0, "")
+	}
+
+	step := width / float64(len(chart.Values))
+	labelEvery := int(math.Ceil(float64(len(chart.Labels)) / pdfMaxXLabels))
+	for i, label := range chart.Labels {
+		if i%labelEvery != 0 {
+			continue
+		}
+		d.SetXY(x0+step*float64(i), bottom+1)
+		d.CellFormat(step*float64(labelEvery), 4, d.tr(label), "", 0, "L", false, 0, "")
+	}
+
+	scale := func(value float64) float64 {
+		return bottom - height*math.Min(value, maxValue)/maxValue
+	}
+
+	switch chart.Kind {
+	case "line":
+		d.SetDrawColor(37, 99, 235)
+		d.SetFillColor(37, 99, 235)
+		d.SetLineWidth(0.5)
+		for i, value := range chart.Values {
+			x, y := x0+step*(float64(i)+0.5), scale(value)
+			if i > 0 {
+				d.Line(x0+step*(float64(i)-0.5), scale(chart.Values[i-1]), x, y)
+			}
+			d.Circle(x, y, 0.6, "F")
+		}
+	default:
+		barWidth := step * 0.7
+		for i, value := range chart.Values {
+			y := scale(value)
+			// Uptime bars turn red below 99%, so bad days stand out
+			if chart.Unit == "%" && value < 99 {
+				d.SetFillColor(220, 38, 38)
+			} else {
+				d.SetFillColor(22, 163, 74)
+			}
+			d.Rect(x0+step*float64(i)+(step-barWidth)/2, y, barWidth, bottom-y, "F")
+		}
+	}
+
+	d.SetDrawColor(120, 120, 120)
+	d.SetLineWidth(0.2)
+	d.Line(x0, bottom, x0+width, bottom)
+	d.Line(x0, top, x0, bottom)
+
+	d.SetXY(pdfMargin, bottom+8)
+	d.Ln(4)
+}
+
+// chartMax returns the top of the y axis for a chart
+func chartMax(chart Chart) float64 {
+	if chart.Unit == "%" {
+		return 100
+	}
+	var maxValue float64
+	for _, value := range chart.Values {
+		maxValue = math.Max(maxValue, value)
+	}
+	if maxValue <= 0 {
+		return 1
+	}
+	// Round up to a multiple of 1, 2 or 5 times a power of ten
+	magnitude := math.Pow(10, math.Floor(math.Log10(maxValue)))
+	for _, factor := range []float64{1, 2, 5, 10} {
+		if maxValue <= factor*magnitude {
+			return factor * magnitude
+		}
+	}
+	return maxValue
+}
+
+func (d *pdfDoc) table(table Table) {
+	if len(table.Columns) == 0 {
+		return
+	}
+	d.ensureSpace(3 * pdfLineHeight)
+
+	d.SetFont("Helvetica", "B", 11)
+	d.SetTextColor(30, 30, 30)
+	d.CellFormat(0, 8, d.tr(table.Title), "", 1, "L", false, 0, "")
+
+	colWidth := d.contentWidth() / float64(len(table.Columns))
+	header := func() {
+		d.SetFont("Helvetica", "B", 8)
+		d.SetFillColor(226, 232, 240)
+		d.SetTextColor(30, 30, 30)
+		for _, column := range table.Columns {
+			d.CellFormat(colWidth, pdfLineHeight, d.fit(column, colWidth-2), "", 0, "L", true, 0, "")
+		}
+		d.Ln(-1)
+	}
+	header()
+
+	if len(table.Rows) == 0 {
+		d.SetFont("Helvetica", "I", 8)
+		d.CellFormat(0, pdfLineHeight, "No data for this period", "", 1, "L", false, 0, "")
+	}
+
+	d.SetFont("Helvetica", "", 8)
+	for i, row := range table.Rows {
+		_, pageHeight := d.GetPageSize()
+		if d.GetY()+pdfLineHeight > pageHeight-pdfMargin-5 {
+			d.AddPage()
+			header()
+			d.SetFont("Helvetica", "", 8)
+		}
+
+		d.SetFillColor(248, 250, 252)
+		for j := range table.Columns {
+			var cell interface{}
+			if j < len(row) {
+				cell = row[j]
+			}
+			align := "L"
+			switch cell.(type) {
+			case int, int64, float64:
+				align = "R"
+			}
+			d.CellFormat(colWidth, pdfLineHeight, d.fit(pdfCell(cell), colWidth-2), "", 0, align, i%2 == 1, 0, "")
+		}
+		d.Ln(-1)
+	}
+	d.Ln(6)
+}
+
+// pdfCell formats a table cell more compactly than FormatCell for print
+func pdfCell(cell interface{}) string {
+	switch v := cell.(type) {
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		return v.Format("2006-01-02 15:04")
+	case *time.Time:
+		if v == nil {
+			return ""
+		}
+		return v.Format("2006-01-02 15:04")
+	default:
+		return FormatCell(cell)
+	}
+}
@@ -0,0 +1,63 @@
+package reportgen
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Renderer writes a dataset in one output format
+type Renderer interface {
+	Extension() string
+	ContentType() string
+	Render(w io.Writer, dataset *Dataset) error
+}
+
+// RendererFor returns the renderer for a models.Report.Format value. The
+// lookup is case-insensitive and an empty format falls back to CSV.
+func RendererFor(format string) (Renderer, error) {
+	switch strings.ToLower(format) {
+	case "", "csv":
+		return CSVRenderer{}, nil
+	case "excel", "xlsx":
+		return XLSXRenderer{}, nil
+	case "pdf":
+		return PDFRenderer{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported report format: %s", format)
+	}
+}
+
+// CSVRenderer writes the summary and every table as consecutive CSV sections
+type CSVRenderer struct{}
+
+func (CSVRenderer) Extension() string   { return "csv" }
+func (CSVRenderer) ContentType() string { return "text/csv" }
+
+func (CSVRenderer) Render(w io.Writer, dataset *Dataset) error {
+	writer := csv.NewWriter(w)
+
+	writer.Write([]string{dataset.Title})
+	writer.Write([]string{"From", dataset.From.Format(time.RFC3339), "To", dataset.To.Format(time.RFC3339)})
+	for _, kpi := range dataset.Summary {
+		writer.Write([]string{kpi.Label, kpi.Value})
+	}
+
+	for _, table := range dataset.Tables {
+		writer.Write(nil)
+		writer.Write([]string{table.Title})
+		writer.Write(table.Columns)
+		for _, row := range table.Rows {
+			record := make([]string, len(row))
+			for i, cell := range row {
+				record[i] = FormatCell(cell)
+			}
+			writer.Write(record)
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
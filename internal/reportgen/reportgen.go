@@ -1,43 +1,178 @@
 package reportgen
 
 import (
-	"encoding/csv"
+	"bytes"
+	"context"
 	"fmt"
 	"log"
-	"os"
-	"path/filepath"
 	"strings"
 	"time"
 
+	"gorm.io/gorm"
+
+	"monitron-server/internal/storage"
 	"monitron-server/models"
 )
 
-// GenerateReportFile simulates generating a report file (CSV for now)
-func GenerateReportFile(report models.Report) (string, error) {
-	// In a real application, this would fetch data from the database
-	// based on report.ReportType and other criteria.
+// defaultRange is the reporting period used when a report has no RangeStart
+const defaultRange = 30 * 24 * time.Hour
 
-	fileName := fmt.Sprintf("%s_%s.csv", strings.ReplaceAll(report.Name, " ", "_"), time.Now().Format("20060102150405"))
-	filePath := filepath.Join("reports", fileName)
+// KPI is a single headline figure shown in the report summary
+type KPI struct {
+	Label string
+	Value string
+}
 
-	file, err := os.Create(filePath)
-	if err != nil {
-		return "", fmt.Errorf("failed to create report file: %w", err)
+// Table is a titled grid of report rows. Cells keep their Go types
+// (string, int, float64, time.Time) so renderers can format them.
+type Table struct {
+	Title   string
+	Columns []string
+	Rows    [][]interface{}
+}
+
+// Chart is a single data series drawn by renderers that support graphics
+type Chart struct {
+	Title  string
+	Kind   string // "bar" or "line"
+	Unit   string
+	Labels []string
+	Values []float64
+}
+
+// Dataset is the data behind a report, independent of its output format
+type Dataset struct {
+	Title   string
+	From    time.Time
+	To      time.Time
+	Summary []KPI
+	Tables  []Table
+	Charts  []Chart
+}
+
+// Params are the resolved report parameters passed to generators
+type Params struct {
+	From      time.Time
+	To        time.Time
+	TargetIDs []string
+	Groups    []string
+}
+
+// Generator builds the dataset for one report type
+type Generator func(db *gorm.DB, params Params) (*Dataset, error)
+
+// generators maps models.Report.ReportType to its generator
+var generators = map[string]Generator{
+	"instance_summary":     instanceSummary,
+	"service_uptime":       serviceUptime,
+	"domain_ssl_expiry":    domainSSLExpiry,
+	"operational_page_sla": operationalPageSLA,
+	"incident_summary":     incidentSummary,
+}
+
+// ReportTypes lists every supported report type
+func ReportTypes() []string {
+	types := make([]string, 0, len(generators))
+	for reportType := range generators {
+		types = append(types, reportType)
 	}
-	defer file.Close()
+	return types
+}
 
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
+// SupportsGroups reports whether a report type can be limited to target
+// groups. Operational pages have no group.
+func SupportsGroups(reportType string) bool {
+	return reportType != "operational_page_sla"
+}
 
-	// Write CSV header
-	writer.Write([]string{"Metric", "Value", "Timestamp"})
+// ParamsFor resolves a report's parameters, applying the default time range
+func ParamsFor(report models.Report) Params {
+	params := Params{To: time.Now(), TargetIDs: report.TargetIDs, Groups: report.TargetGroups}
+	if report.RangeEnd != nil {
+		params.To = *report.RangeEnd
+	}
+	params.From = params.To.Add(-defaultRange)
+	if report.RangeStart != nil {
+		params.From = *report.RangeStart
+	}
+	return params
+}
 
-	// Write some dummy data
-	writer.Write([]string{"CPU Usage", "50%", time.Now().Format(time.RFC3339)})
-	writer.Write([]string{"Memory Usage", "70%", time.Now().Format(time.RFC3339)})
+// Generate queries the database and builds the dataset for a report
+func Generate(db *gorm.DB, report models.Report) (*Dataset, error) {
+	generator, ok := generators[report.ReportType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported report type: %s", report.ReportType)
+	}
 
-	log.Printf("Generated report file: %s", filePath)
-	return filePath, nil
+	params := ParamsFor(report)
+	if !params.From.Before(params.To) {
+		return nil, fmt.Errorf("report range start must be before range end")
+	}
+	if len(params.Groups) > 0 && !SupportsGroups(report.ReportType) {
+		return nil, fmt.Errorf("%s reports cannot be limited to target groups", report.ReportType)
+	}
+
+	dataset, err := generator(db, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate %s report: %w", report.ReportType, err)
+	}
+	dataset.From, dataset.To = params.From, params.To
+	if report.Name != "" {
+		dataset.Title = report.Name
+	}
+	return dataset, nil
 }
 
+// GenerateReportFile generates a report in its format and saves it to
+// storage. It returns the storage key and the file size in bytes.
+func GenerateReportFile(ctx context.Context, db *gorm.DB, store storage.Storage, report models.Report) (string, int64, error) {
+	renderer, err := RendererFor(report.Format)
+	if err != nil {
+		return "", 0, err
+	}
 
+	dataset, err := Generate(db, report)
+	if err != nil {
+		return "", 0, err
+	}
+
+	var buf bytes.Buffer
+	if err := renderer.Render(&buf, dataset); err != nil {
+		return "", 0, fmt.Errorf("failed to render report file: %w", err)
+	}
+
+	name := strings.NewReplacer(" ", "_", "/", "_", "\\", "_").Replace(report.Name)
+	key := fmt.Sprintf("%s/%s_%s.%s", report.ID, name, time.Now().Format("20060102150405"), renderer.Extension())
+	size := int64(buf.Len())
+	if err := store.Put(ctx, key, &buf, size, renderer.ContentType()); err != nil {
+		return "", 0, fmt.Errorf("failed to store report file: %w", err)
+	}
+
+	log.Printf("Generated report file: %s (%d bytes)", key, size)
+	return key, size, nil
+}
+
+// FormatCell renders a table cell as text
+func FormatCell(cell interface{}) string {
+	switch v := cell.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return fmt.Sprintf("%.2f", v)
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		return v.Format(time.RFC3339)
+	case *time.Time:
+		if v == nil {
+			return ""
+		}
+		return v.Format(time.RFC3339)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,177 @@
+package reportgen
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// summarySheet is the first worksheet of every XLSX report
+const summarySheet = "Summary"
+
+// XLSXRenderer writes the summary to a first sheet and every table to its own
+// sheet, keeping numbers and timestamps as typed cells
+type XLSXRenderer struct{}
+
+func (XLSXRenderer) Extension() string { return "xlsx" }
+func (XLSXRenderer) ContentType() string {
+	return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+}
+
+func (XLSXRenderer) Render(w io.Writer, dataset *Dataset) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	styles, err := newXLSXStyles(f)
+	if err != nil {
+		return err
+	}
+
+	if err := f.SetSheetName("Sheet1", summarySheet); err != nil {
+		return err
+	}
+	rows := [][]interface{}{
+		{dataset.Title},
+		{"From", dataset.From},
+		{"To", dataset.To},
+		{},
+	}
+	for _, kpi := range dataset.Summary {
+		rows = append(rows, []interface{}{kpi.Label, kpi.Value})
+	}
+	for i, row := range rows {
+		if err := writeXLSXRow(f, styles, summarySheet, i+1, row); err != nil {
+			return err
+		}
+	}
+	if err := f.SetCellStyle(summarySheet, "A1", "A1", styles.header); err != nil {
+		return err
+	}
+	if err := f.SetColWidth(summarySheet, "A", "B", 28); err != nil {
+		return err
+	}
+
+	used := map[string]bool{summarySheet: true}
+	for _, table := range dataset.Tables {
+		sheet := sheetName(table.Title, used)
+		if _, err := f.NewSheet(sheet); err != nil {
+			return err
+		}
+
+		header := make([]interface{}, len(table.Columns))
+		for i, column := range table.Columns {
+			header[i] = column
+		}
+		if err := f.SetSheetRow(sheet, "A1", &header); err != nil {
+			return err
+		}
+		if len(table.Columns) > 0 {
+			lastCell, _ := excelize.CoordinatesToCellName(len(table.Columns), 1)
+			if err := f.SetCellStyle(sheet, "A1", lastCell, styles.header); err != nil {
+				return err
+			}
+			lastCol, _ := excelize.ColumnNumberToName(len(table.Columns))
+			if err := f.SetColWidth(sheet, "A", lastCol, 18); err != nil {
+				return err
+			}
+		}
+		if err := f.SetPanes(sheet, &excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"}); err != nil {
+			return err
+		}
+
+		for i, row := range table.Rows {
+			if err := writeXLSXRow(f, styles, sheet, i+2, row); err != nil {
+				return err
+			}
+		}
+	}
+
+	return f.Write(w)
+}
+
+// xlsxStyles are the cell styles shared by every sheet of a workbook
+type xlsxStyles struct {
+	header int
+	number int
+	date   int
+}
+
+func newXLSXStyles(f *excelize.File) (xlsxStyles, error) {
+	var styles xlsxStyles
+	var err error
+	if styles.header, err = f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}}); err != nil {
+		return styles, err
+	}
+	if styles.number, err = f.NewStyle(&excelize.Style{NumFmt: 2}); err != nil {
+		return styles, err
+	}
+	dateFormat := "yyyy-mm-dd hh:mm"
+	if styles.date, err = f.NewStyle(&excelize.Style{CustomNumFmt: &dateFormat}); err != nil {
+		return styles, err
+	}
+	return styles, nil
+}
+
+// writeXLSXRow writes one row of typed cells starting at column A
+func writeXLSXRow(f *excelize.File, styles xlsxStyles, sheet string, rowNum int, row []interface{}) error {
+	for i, cell := range row {
+		name, err := excelize.CoordinatesToCellName(i+1, rowNum)
+		if err != nil {
+			return err
+		}
+
+		style := 0
+		switch v := cell.(type) {
+		case nil:
+			continue
+		case *time.Time:
+			if v == nil {
+				continue
+			}
+			cell, style = *v, styles.date
+		case time.Time:
+			if v.IsZero() {
+				continue
+			}
+			style = styles.date
+		case float64:
+			style = styles.number
+		}
+
+		if err := f.SetCellValue(sheet, name, cell); err != nil {
+			return err
+		}
+		if style != 0 {
+			if err := f.SetCellStyle(sheet, name, name, style); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// sheetName turns a table title into a unique, valid worksheet name
+func sheetName(title string, used map[string]bool) string {
+	name := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`:\/?*[]`, r) {
+			return '-'
+		}
+		return r
+	}, title)
+	if name == "" {
+		name = "Table"
+	}
+	if runes := []rune(name); len(runes) > excelize.MaxSheetNameLength-4 {
+		name = string(runes[:excelize.MaxSheetNameLength-4])
+	}
+
+	unique := name
+	for i := 2; used[unique]; i++ {
+		unique = fmt.Sprintf("%s (%d)", name, i)
+	}
+	used[unique] = true
+	return unique
+}
@@ -0,0 +1,186 @@
+package reportschedule
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/robfig/cron/v3"
+	"gorm.io/gorm"
+
+	"monitron-server/internal/reportgen"
+	"monitron-server/messaging"
+	"monitron-server/models"
+)
+
+// defaultRangeDays is the report period of schedules without RangeDays
+const defaultRangeDays = 7
+
+// MinInterval is the shortest time allowed between two runs of a schedule
+const MinInterval = time.Hour
+
+// intervalSamples is how many upcoming runs are compared with MinInterval.
+// Runs repeat the same times on every matching day, so this covers at least
+// one full day of any schedule that respects it.
+const intervalSamples = 100
+
+// TickInterval is how often Tick runs
+const TickInterval = time.Minute
+
+// tickLookback is how far back Tick looks for runs that are due. It spans a
+// few ticks so late ticks do not miss runs, and is shorter than MinInterval
+// so it holds at most one run of a schedule.
+const tickLookback = 5 * time.Minute
+
+// Spec returns the cron spec of a schedule, including its timezone
+func Spec(schedule models.ReportSchedule) string {
+	timezone := schedule.Timezone
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	return "CRON_TZ=" + timezone + " " + strings.TrimSpace(schedule.CronExpression)
+}
+
+// Validate checks the cron expression, including its minimum interval, and
+// the timezone of a schedule
+func Validate(schedule models.ReportSchedule) error {
+	if strings.HasPrefix(strings.TrimSpace(schedule.CronExpression), "CRON_TZ=") || strings.HasPrefix(strings.TrimSpace(schedule.CronExpression), "TZ=") {
+		return errors.New("set the timezone field instead of a CRON_TZ prefix")
+	}
+	if _, err := time.LoadLocation(schedule.Timezone); err != nil {
+		return fmt.Errorf("invalid timezone: %s", schedule.Timezone)
+	}
+	parsed, err := cron.ParseStandard(Spec(schedule))
+	if err != nil {
+		return fmt.Errorf("invalid cron expression: %w", err)
+	}
+	previous := parsed.Next(time.Now())
+	for i := 0; i < intervalSamples && !previous.IsZero(); i++ {
+		next := parsed.Next(previous)
+		if !next.IsZero() && next.Sub(previous) < MinInterval {
+			return fmt.Errorf("cron expression must not run more often than every %s", MinInterval)
+		}
+		previous = next
+	}
+	if _, err := reportgen.RendererFor(schedule.Format); err != nil {
+		return err
+	}
+	return nil
+}
+
+// NextRun returns the next time an enabled schedule runs, or nil
+func NextRun(schedule models.ReportSchedule) *time.Time {
+	if !schedule.IsEnabled {
+		return nil
+	}
+	parsed, err := cron.ParseStandard(Spec(schedule))
+	if err != nil {
+		return nil
+	}
+	next := parsed.Next(time.Now())
+	if next.IsZero() {
+		return nil
+	}
+	return &next
+}
+
+// Tick runs every enabled schedule with a run due since tickLookback. The
+// schedules are read from the database on every tick, so edits apply to all
+// server replicas, and each run is claimed by setting the schedule's last run
+// time, so only one replica queues it.
+func Tick(db *gorm.DB, now time.Time) error {
+	schedules := []models.ReportSchedule{}
+	if err := db.Where("is_enabled = ?", true).Find(&schedules).Error; err != nil {
+		return fmt.Errorf("could not load report schedules: %w", err)
+	}
+
+	for _, schedule := range schedules {
+		parsed, err := cron.ParseStandard(Spec(schedule))
+		if err != nil {
+			log.Printf("Error parsing report schedule %s: %v", schedule.ID, err)
+			continue
+		}
+		due := parsed.Next(now.Add(-tickLookback))
+		if due.IsZero() || due.After(now) || due.Before(schedule.CreatedAt) {
+			continue
+		}
+
+		claimed, err := claim(db, schedule.ID, due)
+		if err != nil {
+			log.Printf("Error claiming run of report schedule %s: %v", schedule.ID, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		if _, err := queue(db, schedule, now); err != nil {
+			log.Printf("Error running report schedule %s: %v", schedule.ID, err)
+		}
+	}
+	return nil
+}
+
+// claim records due as the last run of a schedule unless it already ran since
+// then, and reports whether it did
+func claim(db *gorm.DB, scheduleID uuid.UUID, due time.Time) (bool, error) {
+	result := db.Model(&models.ReportSchedule{}).
+		Where("id = ? AND is_enabled = ? AND (last_run_at IS NULL OR last_run_at < ?)", scheduleID, true, due).
+		Update("last_run_at", due)
+	return result.RowsAffected == 1, result.Error
+}
+
+// Run creates a report from a schedule right away, records the run and queues
+// the report for generation. The report worker delivers it to the schedule's
+// recipients once it completes.
+func Run(db *gorm.DB, schedule models.ReportSchedule) (models.Report, error) {
+	now := time.Now()
+	if err := db.Model(&models.ReportSchedule{}).Where("id = ?", schedule.ID).Update("last_run_at", now).Error; err != nil {
+		log.Printf("Error updating last run of report schedule %s: %v", schedule.ID, err)
+	}
+	return queue(db, schedule, now)
+}
+
+// queue creates a report of a schedule's run at now and queues it for
+// generation
+func queue(db *gorm.DB, schedule models.ReportSchedule, now time.Time) (models.Report, error) {
+	rangeDays := schedule.RangeDays
+	if rangeDays == 0 {
+		rangeDays = defaultRangeDays
+	}
+	rangeStart := now.AddDate(0, 0, -rangeDays)
+
+	format := schedule.Format
+	if format == "" {
+		format = "CSV"
+	}
+
+	report := models.Report{
+		ID:           uuid.New(),
+		Name:         fmt.Sprintf("%s %s", schedule.Name, now.Format("2006-01-02")),
+		ReportType:   schedule.ReportType,
+		Format:       format,
+		Status:       reportgen.StatusPending,
+		UserID:       schedule.UserID,
+		ScheduleID:   &schedule.ID,
+		RangeStart:   &rangeStart,
+		RangeEnd:     &now,
+		TargetIDs:    schedule.TargetIDs,
+		TargetGroups: schedule.TargetGroups,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if err := db.Create(&report).Error; err != nil {
+		return report, fmt.Errorf("could not create report: %w", err)
+	}
+
+	if err := messaging.PublishReport(report); err != nil {
+		db.Model(&report).Updates(map[string]interface{}{"status": reportgen.StatusFailed, "error_message": "Could not queue report for generation"})
+		return report, fmt.Errorf("could not queue report: %w", err)
+	}
+
+	log.Printf("Report schedule %s queued report %s", schedule.ID, report.ID)
+	return report, nil
+}
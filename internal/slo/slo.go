@@ -0,0 +1,225 @@
+package slo
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+
+	"monitron-server/alertmanager"
+	"monitron-server/config"
+	"monitron-server/internal/pagecomponents"
+	"monitron-server/models"
+)
+
+// SLO windows
+const (
+	WindowRolling30d    = "rolling_30d"
+	WindowCalendarMonth = "calendar_month"
+)
+
+// DefaultBurnRateThreshold pages when the error budget of a 30 day window
+// would be gone in about two days (the common 2% of budget per hour rule)
+const DefaultBurnRateThreshold = 14.4
+
+// Burn-rate alerting windows. An alert fires only if both the long and the
+// short window burn too fast, so it also resolves soon after recovery.
+const (
+	alertLongWindow  = time.Hour
+	alertShortWindow = 5 * time.Minute
+)
+
+// AlertInterval is how often EvaluateAlerts runs. Firing alerts are sent
+// again on every run and expire after three intervals, so Alertmanager keeps
+// them firing while the burn lasts and resolves them if evaluations stop.
+const AlertInterval = 5 * time.Minute
+
+// Status is the computed state of an SLO at a point in time
+type Status struct {
+	WindowStart          time.Time `json:"window_start"`
+	WindowEnd            time.Time `json:"window_end"`
+	TotalChecks          int64     `json:"total_checks"`
+	GoodChecks           int64     `json:"good_checks"`
+	Availability         float64   `json:"availability"`           // Percentage of checks that were up
+	Attainment           float64   `json:"attainment"`             // Percentage of good checks
+	ErrorBudget          float64   `json:"error_budget"`           // Allowed bad checks in the window so far
+	ErrorBudgetRemaining float64   `json:"error_budget_remaining"` // Percentage of the budget left, negative when exhausted
+	BurnRate1h           float64   `json:"burn_rate_1h"`
+	BurnRate6h           float64   `json:"burn_rate_6h"`
+	Met                  bool      `json:"met"`
+}
+
+// WindowBounds returns the compliance period of an SLO ending at now
+func WindowBounds(slo models.SLO, now time.Time) (time.Time, time.Time) {
+	if slo.Window == WindowCalendarMonth {
+		now = now.UTC()
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC), now
+	}
+	return now.Add(-30 * 24 * time.Hour), now
+}
+
+// counts are the check totals of an SLO target over a period
+type counts struct {
+	Total int64
+	Up    int64
+	Good  int64
+}
+
+// targetScope restricts a check_results query to the targets of an SLO. An
+// operational page covers the checks of all of its components.
+func targetScope(db *gorm.DB, slo models.SLO) (*gorm.DB, error) {
+	if slo.TargetType != "operational_page" {
+		return db.Where("target_type = ? AND target_id = ?", slo.TargetType, slo.TargetID), nil
+	}
+
+	components := []models.OperationalPageComponent{}
+	if err := pagecomponents.Monitored(db).Where("page_id = ?", slo.TargetID).Find(&components).Error; err != nil {
+		return nil, err
+	}
+	if len(components) == 0 {
+		return db.Where("1 = 0"), nil
+	}
+
+	scope := db.Where("1 = 0")
+	for _, component := range components {
+		scope = scope.Or("target_type = ? AND target_id = ?", component.ComponentType, component.ComponentID)
+	}
+	return db.Where(scope), nil
+}
+
+// count aggregates the checks of an SLO target between from and to
+func count(db *gorm.DB, slo models.SLO, from, to time.Time) (counts, error) {
+	result := counts{}
+	scope, err := targetScope(db, slo)
+	if err != nil {
+		return result, err
+	}
+
+	good := "COUNT(*) FILTER (WHERE status = 'up')"
+	if slo.LatencyThreshold > 0 {
+		good = fmt.Sprintf("COUNT(*) FILTER (WHERE status = 'up' AND response_time <= %f)", slo.LatencyThreshold)
+	}
+
+	err = scope.Model(&models.CheckResult{}).
+		Select("COUNT(*) AS total, COUNT(*) FILTER (WHERE status = 'up') AS up, "+good+" AS good").
+		Where("checked_at >= ? AND checked_at < ?", from, to).
+		Scan(&result).Error
+	return result, err
+}
+
+// burnRate is how many times faster than sustainable the budget is spent
+func burnRate(slo models.SLO, c counts) float64 {
+	allowed := 1 - slo.Objective/100
+	if c.Total == 0 || allowed <= 0 {
+		return 0
+	}
+	return (float64(c.Total-c.Good) / float64(c.Total)) / allowed
+}
+
+// Compute calculates attainment, error budget and burn rates of an SLO
+func Compute(db *gorm.DB, slo models.SLO, now time.Time) (Status, error) {
+	start, end := WindowBounds(slo, now)
+	status := Status{WindowStart: start, WindowEnd: end}
+
+	window, err := count(db, slo, start, end)
+	if err != nil {
+		return status, err
+	}
+	lastHour, err := count(db, slo, now.Add(-time.Hour), now)
+	if err != nil {
+		return status, err
+	}
+	last6h, err := count(db, slo, now.Add(-6*time.Hour), now)
+	if err != nil {
+		return status, err
+	}
+
+	status.TotalChecks, status.GoodChecks = window.Total, window.Good
+	status.Availability, status.Attainment = 100, 100
+	if window.Total > 0 {
+		status.Availability = float64(window.Up) / float64(window.Total) * 100
+		status.Attainment = float64(window.Good) / float64(window.Total) * 100
+	}
+
+	status.ErrorBudget = float64(window.Total) * (1 - slo.Objective/100)
+	status.ErrorBudgetRemaining = 100
+	if status.ErrorBudget > 0 {
+		status.ErrorBudgetRemaining = (1 - float64(window.Total-window.Good)/status.ErrorBudget) * 100
+	}
+
+	status.BurnRate1h = burnRate(slo, lastHour)
+	status.BurnRate6h = burnRate(slo, last6h)
+	status.Met = status.Attainment >= slo.Objective
+	return status, nil
+}
+
+// EvaluateAlerts checks the burn rate of every SLO, sends its alert to
+// Alertmanager while it fires and resolves it once the burn stops
+func EvaluateAlerts(db *gorm.DB) error {
+	slos := []models.SLO{}
+	if err := db.Find(&slos).Error; err != nil {
+		return fmt.Errorf("could not load SLOs: %w", err)
+	}
+
+	alertmanagerURL := config.LoadConfig().Alertmanager.URL
+	now := time.Now()
+	for _, slo := range slos {
+		threshold := slo.BurnRateThreshold
+		if threshold == 0 {
+			threshold = DefaultBurnRateThreshold
+		}
+
+		long, err := count(db, slo, now.Add(-alertLongWindow), now)
+		if err != nil {
+			log.Printf("Error evaluating SLO %s: %v", slo.ID, err)
+			continue
+		}
+		short, err := count(db, slo, now.Add(-alertShortWindow), now)
+		if err != nil {
+			log.Printf("Error evaluating SLO %s: %v", slo.ID, err)
+			continue
+		}
+
+		longRate, shortRate := burnRate(slo, long), burnRate(slo, short)
+		firing := longRate >= threshold && shortRate >= threshold
+		if !firing && !slo.AlertFiring {
+			continue
+		}
+
+		alert := alertmanager.Alert{
+			Labels: map[string]string{
+				"alertname":   "SLOBurnRateHigh",
+				"severity":    "critical",
+				"slo_id":      slo.ID.String(),
+				"slo":         slo.Name,
+				"target_type": slo.TargetType,
+				"target_id":   slo.TargetID.String(),
+			},
+			Annotations: map[string]string{
+				"summary": fmt.Sprintf("SLO %s is burning its error budget %.1fx faster than sustainable", slo.Name, longRate),
+				"description": fmt.Sprintf("Burn rate %.2f over %s and %.2f over %s exceeds %.2f for an objective of %.3f%%.",
+					longRate, alertLongWindow, shortRate, alertShortWindow, threshold, slo.Objective),
+			},
+			StartsAt: now,
+			EndsAt:   now.Add(3 * AlertInterval),
+			Status:   "firing",
+		}
+		if !firing {
+			alert.Status = "resolved"
+			alert.EndsAt = now
+		}
+
+		if err := alertmanager.SendAlert(alertmanagerURL, alert); err != nil {
+			log.Printf("Error sending burn-rate alert for SLO %s: %v", slo.ID, err)
+			continue
+		}
+		if firing == slo.AlertFiring {
+			continue
+		}
+		if err := db.Model(&models.SLO{}).Where("id = ?", slo.ID).Update("alert_firing", firing).Error; err != nil {
+			log.Printf("Error updating alert state of SLO %s: %v", slo.ID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package statuspage
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"monitron-server/models"
+)
+
+// Page incident statuses, in the order an incident usually goes through them
+const (
+	IncidentInvestigating = "investigating"
+	IncidentIdentified    = "identified"
+	IncidentMonitoring    = "monitoring"
+	IncidentResolved      = "resolved"
+)
+
+// Page incident impact levels
+const (
+	ImpactNone     = "none"
+	ImpactMinor    = "minor"
+	ImpactMajor    = "major"
+	ImpactCritical = "critical"
+)
+
+// Maintenance states, derived from the announced window
+const (
+	MaintenanceScheduled  = "scheduled"
+	MaintenanceInProgress = "in_progress"
+	MaintenanceCompleted  = "completed"
+)
+
+// impactStatus is the component status implied by an incident's impact
+var impactStatus = map[string]string{
+	ImpactNone:     StatusOperational,
+	ImpactMinor:    StatusDegraded,
+	ImpactMajor:    StatusMajorOutage,
+	ImpactCritical: StatusMajorOutage,
+}
+
+// Update is one message of a page incident's timeline
+type Update struct {
+	ID        uuid.UUID
+	Status    string
+	Message   string
+	CreatedAt time.Time
+}
+
+// Announcement is a staff-written incident shown on the page
+type Announcement struct {
+	ID         uuid.UUID
+	Title      string
+	Status     string
+	Impact     string
+	Components []string
+	StartedAt  time.Time
+	ResolvedAt *time.Time
+	Updates    []Update // Newest first
+
+	componentIDs []string
+}
+
+// MaintenanceStatus returns the state of a maintenance window at now
+func MaintenanceStatus(maintenance models.PageMaintenance, now time.Time) string {
+	switch {
+	case now.Before(maintenance.StartsAt):
+		return MaintenanceScheduled
+	case now.Before(maintenance.EndsAt):
+		return MaintenanceInProgress
+	default:
+		return MaintenanceCompleted
+	}
+}
+
+// loadAnnouncements adds the page incidents and maintenance windows of a page
+// to the view: unresolved incidents and upcoming or ongoing maintenance as
+// current, and those that ended within the history period as history
+func loadAnnouncements(db *gorm.DB, view *View, components []models.OperationalPageComponent, from, now time.Time) error {
+	names := map[string]string{}
+	for _, component := range components {
+		names[component.ID.String()] = component.ComponentName
+	}
+
+	incidents := []models.PageIncident{}
+	err := db.Where("page_id = ? AND (resolved_at IS NULL OR resolved_at >= ?)", view.Page.ID, from).
+		Order("started_at DESC").Find(&incidents).Error
+	if err != nil {
+		return fmt.Errorf("could not load page incidents: %w", err)
+	}
+
+	updatesByIncident := map[uuid.UUID][]Update{}
+	if len(incidents) > 0 {
+		ids := make([]uuid.UUID, len(incidents))
+		for i, incident := range incidents {
+			ids[i] = incident.ID
+		}
+		updates := []models.PageIncidentUpdate{}
+		if err := db.Where("incident_id IN ?", ids).Order("created_at DESC").Find(&updates).Error; err != nil {
+			return fmt.Errorf("could not load page incident updates: %w", err)
+		}
+		for _, update := range updates {
+			updatesByIncident[update.IncidentID] = append(updatesByIncident[update.IncidentID], Update{
+				ID:        update.ID,
+				Status:    update.Status,
+				Message:   update.Message,
+				CreatedAt: update.CreatedAt,
+			})
+		}
+	}
+
+	for _, incident := range incidents {
+		announcement := Announcement{
+			ID:         incident.ID,
+			Title:      incident.Title,
+			Status:     incident.Status,
+			Impact:     incident.Impact,
+			Components: componentNames(incident.ComponentIDs, names),
+			StartedAt:  incident.StartedAt,
+			ResolvedAt: incident.ResolvedAt,
+			Updates:    updatesByIncident[incident.ID],
+
+			componentIDs: incident.ComponentIDs,
+		}
+		if incident.Status == IncidentResolved {
+			view.PastIncidents = append(view.PastIncidents, announcement)
+		} else {
+			view.Announcements = append(view.Announcements, announcement)
+		}
+	}
+
+	maintenances := []models.PageMaintenance{}
+	err = db.Where("page_id = ? AND ends_at >= ?", view.Page.ID, from).
+		Order("starts_at ASC").Find(&maintenances).Error
+	if err != nil {
+		return fmt.Errorf("could not load maintenance: %w", err)
+	}
+	for _, maintenance := range maintenances {
+		item := Maintenance{
+			ID:          maintenance.ID,
+			Title:       maintenance.Title,
+			Description: maintenance.Description,
+			Status:      MaintenanceStatus(maintenance, now),
+			StartsAt:    maintenance.StartsAt,
+			EndsAt:      maintenance.EndsAt,
+			Components:  componentNames(maintenance.ComponentIDs, names),
+
+			componentIDs: maintenance.ComponentIDs,
+		}
+		if item.Status == MaintenanceCompleted {
+			view.PastMaintenance = append([]Maintenance{item}, view.PastMaintenance...)
+		} else {
+			view.Maintenance = append(view.Maintenance, item)
+		}
+	}
+	return nil
+}
+
+// applyAnnouncements lowers the status of the components affected by
+// unresolved page incidents, of the groups containing them, and of the whole
+// page
+func applyAnnouncements(view *View) {
+	for _, announcement := range view.Announcements {
+		status := impactStatus[announcement.Impact]
+		if status == "" || status == StatusOperational {
+			continue
+		}
+
+		affected := map[string]bool{}
+		for _, id := range announcement.componentIDs {
+			affected[id] = true
+		}
+		for i := range view.Components {
+			component := &view.Components[i]
+			for j := range component.Children {
+				child := &component.Children[j]
+				if affected[child.ID.String()] {
+					child.Status = worse(child.Status, status)
+				}
+				component.Status = worse(component.Status, child.Status)
+			}
+			if affected[component.ID.String()] {
+				component.Status = worse(component.Status, status)
+			}
+		}
+		view.Status = worse(view.Status, status)
+	}
+}
+
+// componentNames maps page component IDs to their display names, skipping
+// components that were removed from the page
+func componentNames(ids []string, names map[string]string) []string {
+	result := []string{}
+	for _, id := range ids {
+		if name, ok := names[id]; ok {
+			result = append(result, name)
+		}
+	}
+	return result
+}
@@ -0,0 +1,169 @@
+package statuspage
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"monitron-server/internal/pagecomponents"
+	"monitron-server/models"
+)
+
+// Uptime badge periods
+const (
+	DefaultBadgePeriod = 30 * 24 * time.Hour
+	MaxBadgePeriod     = 90 * 24 * time.Hour
+)
+
+// ErrInvalidPeriod is returned for badge periods that are not a number of
+// hours or days between one hour and MaxBadgePeriod
+var ErrInvalidPeriod = errors.New("period must look like 24h or 30d and be at most 90d")
+
+// Badge colours, as used by shields.io
+const (
+	badgeGreen       = "#4c1"
+	badgeYellowGreen = "#a4a61d"
+	badgeYellow      = "#dfb317"
+	badgeRed         = "#e05d44"
+	badgeGrey        = "#9f9f9f"
+	badgeLabel       = "#555"
+)
+
+var statusBadgeColors = map[string]string{
+	StatusOperational: badgeGreen,
+	StatusUnknown:     badgeGrey,
+	StatusDegraded:    badgeYellow,
+	StatusMajorOutage: badgeRed,
+}
+
+// ParsePeriod parses a badge period such as "24h" or "30d". An empty period
+// means DefaultBadgePeriod.
+func ParsePeriod(period string) (time.Duration, error) {
+	if period == "" {
+		return DefaultBadgePeriod, nil
+	}
+	if len(period) < 2 {
+		return 0, ErrInvalidPeriod
+	}
+
+	n, err := strconv.Atoi(period[:len(period)-1])
+	if err != nil || n < 1 {
+		return 0, ErrInvalidPeriod
+	}
+
+	var d time.Duration
+	switch period[len(period)-1] {
+	case 'h':
+		d = time.Duration(n) * time.Hour
+	case 'd':
+		d = time.Duration(n) * 24 * time.Hour
+	default:
+		return 0, ErrInvalidPeriod
+	}
+	if d > MaxBadgePeriod {
+		return 0, ErrInvalidPeriod
+	}
+	return d, nil
+}
+
+// FormatPeriod is the short form of a badge period, e.g. "30d" or "12h"
+func FormatPeriod(period time.Duration) string {
+	if period%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%dd", int(period/(24*time.Hour)))
+	}
+	return fmt.Sprintf("%dh", int(period/time.Hour))
+}
+
+// ComponentUptime returns the uptime percentage of a page component's target
+// since from, or of all of its children's targets for a group. ok is false
+// when there are no checks in the period.
+func ComponentUptime(db *gorm.DB, component models.OperationalPageComponent, from time.Time) (uptime float64, ok bool, err error) {
+	targets := []models.OperationalPageComponent{component}
+	if component.ComponentType == pagecomponents.TypeGroup {
+		targets = nil
+		if err := db.Where("parent_id = ?", component.ID).Find(&targets).Error; err != nil {
+			return 0, false, fmt.Errorf("could not load group components: %w", err)
+		}
+		if len(targets) == 0 {
+			return 0, false, nil
+		}
+	}
+
+	scope := db.Where("1 = 0")
+	for _, target := range targets {
+		scope = scope.Or("target_type = ? AND target_id = ?", target.ComponentType, target.ComponentID)
+	}
+
+	totals := struct {
+		Checks   int64
+		UpChecks int64
+	}{}
+	err = db.Model(&models.CheckResult{}).
+		Select("COUNT(*) AS checks, COUNT(*) FILTER (WHERE status = 'up') AS up_checks").
+		Where(scope).
+		Where("checked_at >= ?", from).
+		Scan(&totals).Error
+	if err != nil {
+		return 0, false, fmt.Errorf("could not load checks: %w", err)
+	}
+	if totals.Checks == 0 {
+		return 0, false, nil
+	}
+	return float64(totals.UpChecks) / float64(totals.Checks) * 100, true, nil
+}
+
+// StatusBadge renders a badge with a component's name and current status
+func StatusBadge(component Component) []byte {
+	return RenderBadge(component.Name, StatusLabel(component.Status), statusBadgeColors[component.Status])
+}
+
+// UptimeBadge renders a badge with a component's uptime over a period
+func UptimeBadge(uptime float64, ok bool, period time.Duration) []byte {
+	label := "uptime " + FormatPeriod(period)
+	if !ok {
+		return RenderBadge(label, "no data", badgeGrey)
+	}
+
+	color := badgeRed
+	switch {
+	case uptime >= 99.9:
+		color = badgeGreen
+	case uptime >= 99:
+		color = badgeYellowGreen
+	case uptime >= 95:
+		color = badgeYellow
+	}
+	return RenderBadge(label, strconv.FormatFloat(uptime, 'f', 2, 64)+"%", color)
+}
+
+// RenderBadge renders a flat shields.io style SVG badge. Text widths are
+// estimated from the character count, which is close enough for Verdana 11px.
+func RenderBadge(label, message, color string) []byte {
+	labelWidth := badgeTextWidth(label)
+	messageWidth := badgeTextWidth(message)
+	width := labelWidth + messageWidth
+	label = html.EscapeString(label)
+	message = html.EscapeString(message)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="20" role="img" aria-label="%s: %s">`, width, label, message)
+	fmt.Fprintf(&b, `<title>%s: %s</title>`, label, message)
+	b.WriteString(`<linearGradient id="s" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>`)
+	fmt.Fprintf(&b, `<clipPath id="r"><rect width="%d" height="20" rx="3" fill="#fff"/></clipPath>`, width)
+	fmt.Fprintf(&b, `<g clip-path="url(#r)"><rect width="%d" height="20" fill="%s"/><rect x="%d" width="%d" height="20" fill="%s"/><rect width="%d" height="20" fill="url(#s)"/></g>`,
+		labelWidth, badgeLabel, labelWidth, messageWidth, color, width)
+	b.WriteString(`<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">`)
+	fmt.Fprintf(&b, `<text x="%d" y="15" fill="#010101" fill-opacity=".3">%s</text><text x="%d" y="14">%s</text>`, labelWidth/2, label, labelWidth/2, label)
+	fmt.Fprintf(&b, `<text x="%d" y="15" fill="#010101" fill-opacity=".3">%s</text><text x="%d" y="14">%s</text>`, labelWidth+messageWidth/2, message, labelWidth+messageWidth/2, message)
+	b.WriteString(`</g></svg>`)
+	return []byte(b.String())
+}
+
+func badgeTextWidth(text string) int {
+	return len([]rune(text))*7 + 10
+}
@@ -0,0 +1,104 @@
+package statuspage
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"monitron-server/config"
+	"monitron-server/models"
+)
+
+// NormalizeHost lowercases a hostname and strips any port and trailing dot,
+// so Host headers and configured custom domains compare equal
+func NormalizeHost(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(host, ".")
+}
+
+// NormalizeDomains normalizes a page's custom domains and drops duplicates
+func NormalizeDomains(domains []string) []string {
+	if domains == nil {
+		return nil
+	}
+	result := []string{}
+	seen := map[string]bool{}
+	for _, domain := range domains {
+		domain = NormalizeHost(domain)
+		if !seen[domain] {
+			seen[domain] = true
+			result = append(result, domain)
+		}
+	}
+	return result
+}
+
+// ReservedHost reports whether host is the host of this server or of the UI,
+// which pages cannot take as a custom domain
+func ReservedHost(cfg *config.Config, host string) bool {
+	host = NormalizeHost(host)
+	for _, base := range []string{cfg.App.PublicURL, cfg.App.FrontendURL} {
+		if u, err := url.Parse(base); err == nil && u.Hostname() != "" && NormalizeHost(u.Hostname()) == host {
+			return true
+		}
+	}
+	return false
+}
+
+// ReservedDomain returns the first of domains that is a reserved host, or an
+// empty string when there is none
+func ReservedDomain(cfg *config.Config, domains []string) string {
+	for _, domain := range domains {
+		if ReservedHost(cfg, domain) {
+			return domain
+		}
+	}
+	return ""
+}
+
+// FindPageByHost loads the operational page serving a custom domain
+func FindPageByHost(db *gorm.DB, host string) (models.OperationalPage, error) {
+	page := models.OperationalPage{}
+	err := db.Where("custom_domains::jsonb @> ?::jsonb", hostJSON(NormalizeHost(host))).First(&page).Error
+	return page, err
+}
+
+// DomainInUse returns the first of domains already served by a page other
+// than pageID, or an empty string when all of them are free
+func DomainInUse(db *gorm.DB, domains []string, pageID uuid.UUID) (string, error) {
+	for _, domain := range domains {
+		var count int64
+		err := db.Model(&models.OperationalPage{}).
+			Where("id <> ? AND custom_domains::jsonb @> ?::jsonb", pageID, hostJSON(domain)).
+			Count(&count).Error
+		if err != nil {
+			return "", fmt.Errorf("could not check custom domains: %w", err)
+		}
+		if count > 0 {
+			return domain, nil
+		}
+	}
+	return "", nil
+}
+
+// PageURL returns the public address of a status page: its first custom
+// domain when it has one, or its slug path under publicURL
+func PageURL(publicURL string, page models.OperationalPage) string {
+	if len(page.CustomDomains) > 0 {
+		return "https://" + page.CustomDomains[0]
+	}
+	return strings.TrimRight(publicURL, "/") + "/status/" + url.PathEscape(page.Slug)
+}
+
+func hostJSON(host string) string {
+	data, _ := json.Marshal([]string{host})
+	return string(data)
+}
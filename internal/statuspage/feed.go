@@ -0,0 +1,197 @@
+package statuspage
+
+import (
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FeedEntry is one incident or maintenance window in a page's feed
+type FeedEntry struct {
+	ID        string
+	Title     string
+	Link      string
+	Published time.Time
+	Updated   time.Time
+	Content   string
+}
+
+// FeedEntries lists the staff incidents and maintenance windows of a view,
+// most recently updated first
+func FeedEntries(view *View, pageURL string) []FeedEntry {
+	entries := []FeedEntry{}
+	incidents := append(append([]Announcement{}, view.Announcements...), view.PastIncidents...)
+	for _, incident := range incidents {
+		entry := FeedEntry{
+			ID:        "urn:uuid:" + incident.ID.String(),
+			Title:     fmt.Sprintf("%s (%s)", incident.Title, incident.Status),
+			Link:      pageURL,
+			Published: incident.StartedAt,
+			Updated:   incident.StartedAt,
+		}
+		var content strings.Builder
+		if len(incident.Components) > 0 {
+			fmt.Fprintf(&content, "Affected components: %s\n\n", strings.Join(incident.Components, ", "))
+		}
+		for _, update := range incident.Updates {
+			if update.CreatedAt.After(entry.Updated) {
+				entry.Updated = update.CreatedAt
+			}
+			fmt.Fprintf(&content, "%s - %s: %s\n", update.CreatedAt.UTC().Format(time.RFC1123), update.Status, update.Message)
+		}
+		entry.Content = strings.TrimSpace(content.String())
+		entries = append(entries, entry)
+	}
+
+	maintenances := append(append([]Maintenance{}, view.Maintenance...), view.PastMaintenance...)
+	for _, maintenance := range maintenances {
+		var content strings.Builder
+		fmt.Fprintf(&content, "Scheduled from %s to %s.",
+			maintenance.StartsAt.UTC().Format(time.RFC1123), maintenance.EndsAt.UTC().Format(time.RFC1123))
+		if len(maintenance.Components) > 0 {
+			fmt.Fprintf(&content, "\n\nAffected components: %s", strings.Join(maintenance.Components, ", "))
+		}
+		if maintenance.Description != "" {
+			content.WriteString("\n\n" + maintenance.Description)
+		}
+		entries = append(entries, FeedEntry{
+			ID:        "urn:uuid:" + maintenance.ID.String(),
+			Title:     fmt.Sprintf("%s (maintenance %s)", maintenance.Title, maintenanceLabels[maintenance.Status]),
+			Link:      pageURL,
+			Published: maintenance.StartsAt,
+			Updated:   maintenance.StartsAt,
+			Content:   content.String(),
+		})
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Updated.After(entries[j].Updated)
+	})
+	return entries
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type atomEntry struct {
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Link      atomLink    `xml:"link"`
+	Published string      `xml:"published"`
+	Updated   string      `xml:"updated"`
+	Content   atomContent `xml:"content"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+// RenderAtom renders a page's incidents and maintenance as an Atom feed.
+// feedURL is the address the feed is served from.
+func RenderAtom(view *View, pageURL, feedURL string) ([]byte, error) {
+	entries := FeedEntries(view, pageURL)
+	feed := atomFeed{
+		ID:      pageURL,
+		Title:   view.Page.Name + " status",
+		Updated: feedUpdated(view, entries).Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: pageURL, Rel: "alternate", Type: "text/html"},
+			{Href: feedURL, Rel: "self", Type: "application/atom+xml"},
+		},
+	}
+	for _, entry := range entries {
+		feed.Entries = append(feed.Entries, atomEntry{
+			ID:        entry.ID,
+			Title:     entry.Title,
+			Link:      atomLink{Href: entry.Link, Rel: "alternate", Type: "text/html"},
+			Published: entry.Published.UTC().Format(time.RFC3339),
+			Updated:   entry.Updated.UTC().Format(time.RFC3339),
+			Content:   atomContent{Type: "text", Body: entry.Content},
+		})
+	}
+	return marshalFeed(feed)
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+	Description string  `xml:"description"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+// RenderRSS renders a page's incidents and maintenance as an RSS 2.0 feed
+func RenderRSS(view *View, pageURL string) ([]byte, error) {
+	entries := FeedEntries(view, pageURL)
+	feed := rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:         view.Page.Name + " status",
+			Link:          pageURL,
+			Description:   "Incidents and maintenance of " + view.Page.Name,
+			LastBuildDate: feedUpdated(view, entries).Format(time.RFC1123Z),
+		},
+	}
+	for _, entry := range entries {
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:       entry.Title,
+			Link:        entry.Link,
+			GUID:        rssGUID{Value: entry.ID},
+			PubDate:     entry.Updated.UTC().Format(time.RFC1123Z),
+			Description: entry.Content,
+		})
+	}
+	return marshalFeed(feed)
+}
+
+// feedUpdated is the time of the newest entry, or of the last page change
+// when there are no entries, so it only moves when the feed content does
+func feedUpdated(view *View, entries []FeedEntry) time.Time {
+	updated := view.Page.UpdatedAt
+	if len(entries) > 0 && entries[0].Updated.After(updated) {
+		updated = entries[0].Updated
+	}
+	return updated.UTC()
+}
+
+func marshalFeed(feed interface{}) ([]byte, error) {
+	body, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
@@ -0,0 +1,175 @@
+package statuspage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"monitron-server/config"
+	"monitron-server/models"
+)
+
+// Problem report limits from the spec, counted over the last 24 hours
+const (
+	ReportsPerIPPerDay   = 10
+	ReportsPerPagePerDay = 1000
+)
+
+// The "users are reporting problems" signal turns on when at least
+// ProblemSignalThreshold distinct visitors reported within ProblemSignalWindow
+const (
+	ProblemSignalWindow    = 30 * time.Minute
+	ProblemSignalThreshold = 3
+)
+
+// maxCommentLength caps the free-text part of a report
+const maxCommentLength = 1000
+
+// DefaultReportReasons are offered on pages without their own reason list
+var DefaultReportReasons = []string{
+	"Site is down",
+	"Slow performance",
+	"Errors or failed requests",
+	"Login problems",
+	"Other",
+}
+
+// Problem report errors
+var (
+	ErrInvalidReason    = errors.New("invalid reason")
+	ErrIPLimitReached   = errors.New("daily report limit reached for this address")
+	ErrPageLimitReached = errors.New("daily report limit reached for this page")
+)
+
+// ProblemSignal summarises recent problem reports of a page
+type ProblemSignal struct {
+	Active    bool      `json:"active"`
+	Reporters int64     `json:"reporters"` // Distinct visitors within the window
+	Reports   int64     `json:"reports"`
+	TopReason string    `json:"top_reason"`
+	Since     time.Time `json:"since"`
+}
+
+// ReportReasons returns the reasons visitors can pick on a page
+func ReportReasons(page models.OperationalPage) []string {
+	if len(page.ReportReasons) > 0 {
+		return page.ReportReasons
+	}
+	return DefaultReportReasons
+}
+
+// SubmitReport validates and stores a visitor's problem report, enforcing
+// the per-IP and per-page daily limits
+func SubmitReport(db *gorm.DB, page models.OperationalPage, reason, comment, ip, userAgent string, now time.Time) (models.ProblemReport, error) {
+	report := models.ProblemReport{
+		ID:        uuid.New(),
+		PageID:    page.ID,
+		Reason:    strings.TrimSpace(reason),
+		Comment:   truncate(strings.TrimSpace(comment), maxCommentLength),
+		IPHash:    ipHash(config.LoadConfig(), ip),
+		UserAgent: truncate(userAgent, 512),
+		CreatedAt: now,
+	}
+
+	valid := false
+	for _, candidate := range ReportReasons(page) {
+		if candidate == report.Reason {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return report, ErrInvalidReason
+	}
+
+	since := now.Add(-24 * time.Hour)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Serialize submissions of the same address and page until commit, so
+		// concurrent ones cannot all pass the limits. Locks are always taken
+		// in this order.
+		for _, key := range []string{"problem_reports:ip:" + report.IPHash, "problem_reports:page:" + page.ID.String()} {
+			if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", key).Error; err != nil {
+				return fmt.Errorf("could not lock reports: %w", err)
+			}
+		}
+
+		var byIP, byPage int64
+		if err := tx.Model(&models.ProblemReport{}).Where("ip_hash = ? AND created_at >= ?", report.IPHash, since).Count(&byIP).Error; err != nil {
+			return fmt.Errorf("could not count reports: %w", err)
+		}
+		if byIP >= ReportsPerIPPerDay {
+			return ErrIPLimitReached
+		}
+		if err := tx.Model(&models.ProblemReport{}).Where("page_id = ? AND created_at >= ?", page.ID, since).Count(&byPage).Error; err != nil {
+			return fmt.Errorf("could not count reports: %w", err)
+		}
+		if byPage >= ReportsPerPagePerDay {
+			return ErrPageLimitReached
+		}
+
+		if err := tx.Create(&report).Error; err != nil {
+			return fmt.Errorf("could not save report: %w", err)
+		}
+		return nil
+	})
+	return report, err
+}
+
+// ipHash keys the hash of a visitor IP with the encryption key, as the IPv4
+// space is small enough to reverse a plain hash
+func ipHash(cfg *config.Config, ip string) string {
+	mac := hmac.New(sha256.New, []byte(cfg.EncryptionKey))
+	mac.Write([]byte("problem-report-ip:" + ip))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Signal aggregates the problem reports of a page within ProblemSignalWindow
+func Signal(db *gorm.DB, pageID uuid.UUID, now time.Time) (ProblemSignal, error) {
+	signal := ProblemSignal{Since: now.Add(-ProblemSignalWindow)}
+
+	totals := struct {
+		Reports   int64
+		Reporters int64
+	}{}
+	err := db.Model(&models.ProblemReport{}).
+		Select("COUNT(*) AS reports, COUNT(DISTINCT ip_hash) AS reporters").
+		Where("page_id = ? AND created_at >= ?", pageID, signal.Since).
+		Scan(&totals).Error
+	if err != nil {
+		return signal, fmt.Errorf("could not aggregate reports: %w", err)
+	}
+	signal.Reports, signal.Reporters = totals.Reports, totals.Reporters
+	if signal.Reports == 0 {
+		return signal, nil
+	}
+
+	err = db.Model(&models.ProblemReport{}).
+		Select("reason").
+		Where("page_id = ? AND created_at >= ?", pageID, signal.Since).
+		Group("reason").
+		Order("COUNT(*) DESC, reason").
+		Limit(1).
+		Scan(&signal.TopReason).Error
+	if err != nil {
+		return signal, fmt.Errorf("could not aggregate reports: %w", err)
+	}
+
+	signal.Active = signal.Reporters >= ProblemSignalThreshold
+	return signal, nil
+}
+
+// truncate shortens s to at most max runes
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max])
+}
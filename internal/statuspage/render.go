@@ -0,0 +1,104 @@
+package statuspage
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+)
+
+// Themes supported by the status page. ThemeAuto follows the visitor's
+// system preference.
+const (
+	ThemeAuto  = "auto"
+	ThemeLight = "light"
+	ThemeDark  = "dark"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+var statusLabels = map[string]string{
+	StatusOperational: "Operational",
+	StatusUnknown:     "No data",
+	StatusDegraded:    "Degraded performance",
+	StatusMajorOutage: "Major outage",
+}
+
+var maintenanceLabels = map[string]string{
+	MaintenanceScheduled:  "Scheduled",
+	MaintenanceInProgress: "In progress",
+	MaintenanceCompleted:  "Completed",
+}
+
+var pageTemplate = template.Must(template.New("status.html").Funcs(template.FuncMap{
+	"statusLabel":      StatusLabel,
+	"maintenanceLabel": func(status string) string { return maintenanceLabels[status] },
+	"percent":          func(v float64) string { return fmt.Sprintf("%.2f%%", v) },
+	"ms":               func(v float64) string { return fmt.Sprintf("%.0f ms", v) },
+	"date":             func(t time.Time) string { return t.UTC().Format("Jan 2, 2006") },
+	"datetime":         func(t time.Time) string { return t.UTC().Format("Jan 2, 2006 15:04 UTC") },
+	"customCSS":        customCSS,
+	"title": func(s string) string {
+		if s == "" {
+			return s
+		}
+		return strings.ToUpper(s[:1]) + s[1:]
+	},
+}).ParseFS(templateFS, "templates/status.html"))
+
+var unsubscribeTemplate = template.Must(template.ParseFS(templateFS, "templates/unsubscribe.html"))
+
+// StatusLabel returns the human-readable form of a component or page status
+func StatusLabel(status string) string {
+	return statusLabels[status]
+}
+
+// customCSS marks a page's custom CSS as safe to embed in a style element.
+// "<" never occurs in valid CSS outside strings, where the escape is
+// equivalent, so replacing it keeps the stylesheet from closing the element.
+func customCSS(css string) template.CSS {
+	return template.CSS(strings.ReplaceAll(css, "<", `\3c `))
+}
+
+// NormalizeTheme maps a requested theme to a supported one
+func NormalizeTheme(theme string) string {
+	switch strings.ToLower(theme) {
+	case ThemeLight:
+		return ThemeLight
+	case ThemeDark:
+		return ThemeDark
+	default:
+		return ThemeAuto
+	}
+}
+
+// Render renders a status page as HTML in the given theme
+func Render(view *View, theme string) ([]byte, error) {
+	var buf bytes.Buffer
+	err := pageTemplate.Execute(&buf, struct {
+		*View
+		Theme string
+	}{View: view, Theme: NormalizeTheme(theme)})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// RenderUnsubscribe renders the page confirming the removal of a status page
+// subscription, whose form posts the token to action
+func RenderUnsubscribe(pageName, action, token string) ([]byte, error) {
+	var buf bytes.Buffer
+	err := unsubscribeTemplate.Execute(&buf, struct {
+		PageName string
+		Action   string
+		Token    string
+	}{PageName: pageName, Action: action, Token: token})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,391 @@
+package statuspage
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"monitron-server/internal/pagecomponents"
+	"monitron-server/internal/pagestats"
+	"monitron-server/models"
+)
+
+// Component and page statuses, from best to worst
+const (
+	StatusOperational = "operational"
+	StatusUnknown     = "unknown"
+	StatusDegraded    = "degraded"
+	StatusMajorOutage = "major_outage"
+)
+
+// statusRank orders statuses so the worst component decides the page status
+var statusRank = map[string]int{
+	StatusOperational: 0,
+	StatusUnknown:     1,
+	StatusDegraded:    2,
+	StatusMajorOutage: 3,
+}
+
+// HistoryDays is the number of daily uptime bars shown per component
+const HistoryDays = pagestats.HistoryDays
+
+// Day is one bar of a component's uptime history
+type Day struct {
+	Date    time.Time
+	Checks  int64
+	Uptime  float64
+	HasData bool
+}
+
+// Level classifies a day for colouring: "none", "good", "warn" or "bad"
+func (d Day) Level() string {
+	switch {
+	case !d.HasData:
+		return "none"
+	case d.Uptime >= 99.9:
+		return "good"
+	case d.Uptime >= 99:
+		return "warn"
+	default:
+		return "bad"
+	}
+}
+
+// Component is a page component with its current state and history
+type Component struct {
+	ID              uuid.UUID
+	Name            string
+	Description     string
+	Type            string
+	TargetID        uuid.UUID
+	Status          string
+	LastChecked     *time.Time
+	Uptime          float64 // Over the history period
+	HasUptime       bool
+	AvgResponseTime float64 // In milliseconds, over the history period
+	History         []Day
+	Children        []Component // Components of a group, in display order
+}
+
+// childNouns names component types in group summaries, singular and plural
+var childNouns = map[string][2]string{
+	pagecomponents.TypeService:   {"service", "services"},
+	pagecomponents.TypeInstance:  {"instance", "instances"},
+	pagecomponents.TypeDomainSSL: {"domain", "domains"},
+}
+
+// IsGroup reports whether the component is a group of other components
+func (c Component) IsGroup() bool {
+	return c.Type == pagecomponents.TypeGroup
+}
+
+// ChildSummary describes the children of a group, such as "3 services"
+func (c Component) ChildSummary() string {
+	noun := [2]string{"component", "components"}
+	for i, child := range c.Children {
+		if i == 0 {
+			if n, ok := childNouns[child.Type]; ok {
+				noun = n
+			}
+		} else if child.Type != c.Children[0].Type {
+			noun = [2]string{"component", "components"}
+			break
+		}
+	}
+	if len(c.Children) == 1 {
+		return "1 " + noun[0]
+	}
+	return fmt.Sprintf("%d %s", len(c.Children), noun[1])
+}
+
+// Incident is an open incident affecting a component of the page
+type Incident struct {
+	ID        uuid.UUID
+	Title     string
+	Severity  string
+	Status    string
+	Component string
+	StartedAt time.Time
+}
+
+// Maintenance is a scheduled maintenance window shown on the page
+type Maintenance struct {
+	ID          uuid.UUID
+	Title       string
+	Description string
+	Status      string // scheduled, in_progress or completed
+	StartsAt    time.Time
+	EndsAt      time.Time
+	Components  []string
+
+	componentIDs []string
+}
+
+// View is everything rendered on a status page
+type View struct {
+	Page            models.OperationalPage
+	Status          string
+	Components      []Component
+	Incidents       []Incident
+	Announcements   []Announcement // Unresolved page incidents
+	Maintenance     []Maintenance  // Upcoming and ongoing
+	PastIncidents   []Announcement // Resolved within the history period, newest first
+	PastMaintenance []Maintenance  // Completed within the history period, newest first
+	Uptime          float64
+	HasUptime       bool
+	AvgResponseTime float64
+	Problems        ProblemSignal
+	ReportReasons   []string
+	GeneratedAt     time.Time
+}
+
+// FindComponent looks up a component or group of the view by ID
+func (v *View) FindComponent(id uuid.UUID) (Component, bool) {
+	for _, component := range v.Components {
+		if component.ID == id {
+			return component, true
+		}
+		for _, child := range component.Children {
+			if child.ID == id {
+				return child, true
+			}
+		}
+	}
+	return Component{}, false
+}
+
+// FindPage loads an operational page by slug
+func FindPage(db *gorm.DB, slug string) (models.OperationalPage, error) {
+	page := models.OperationalPage{}
+	err := db.Where("slug = ?", slug).First(&page).Error
+	return page, err
+}
+
+// Build collects the current state of a page's components from check results,
+// incidents, staff announcements and visitor problem reports
+func Build(db *gorm.DB, page models.OperationalPage, now time.Time) (*View, error) {
+	view := &View{Page: page, Status: StatusOperational, ReportReasons: ReportReasons(page), GeneratedAt: now}
+
+	problems, err := Signal(db, page.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	view.Problems = problems
+
+	components := []models.OperationalPageComponent{}
+	if err := db.Where("page_id = ?", page.ID).Order("display_order ASC").Find(&components).Error; err != nil {
+		return nil, fmt.Errorf("could not load components: %w", err)
+	}
+
+	utc := now.UTC()
+	today := time.Date(utc.Year(), utc.Month(), utc.Day(), 0, 0, 0, 0, time.UTC)
+	from := today.AddDate(0, 0, -(HistoryDays - 1))
+
+	if err := loadAnnouncements(db, view, components, from, now); err != nil {
+		return nil, err
+	}
+	if len(components) == 0 {
+		applyAnnouncements(view)
+		return view, nil
+	}
+
+	idsByType := map[string][]uuid.UUID{}
+	for _, component := range components {
+		if component.ComponentType == pagecomponents.TypeGroup {
+			continue
+		}
+		idsByType[component.ComponentType] = append(idsByType[component.ComponentType], component.ComponentID)
+	}
+
+	latest := map[string]models.CheckResult{}
+	daily := map[string]map[time.Time]pagestats.DailyRow{}
+	openIncidents := []models.Incident{}
+	for componentType, ids := range idsByType {
+		if err := latestChecks(db, componentType, ids, latest); err != nil {
+			return nil, fmt.Errorf("could not load latest checks: %w", err)
+		}
+		if err := pagestats.DailyChecks(db, componentType, ids, from, daily); err != nil {
+			return nil, fmt.Errorf("could not load check history: %w", err)
+		}
+
+		incidents := []models.Incident{}
+		if err := db.Where("target_type = ? AND target_id IN ? AND status = ?", componentType, ids, "open").
+			Order("started_at DESC").Find(&incidents).Error; err != nil {
+			return nil, fmt.Errorf("could not load incidents: %w", err)
+		}
+		openIncidents = append(openIncidents, incidents...)
+	}
+
+	incidentsByTarget := map[string][]models.Incident{}
+	for _, incident := range openIncidents {
+		key := pagestats.TargetKey(incident.TargetType, incident.TargetID)
+		incidentsByTarget[key] = append(incidentsByTarget[key], incident)
+	}
+
+	items := map[uuid.UUID]Component{}
+	history := map[uuid.UUID][]pagestats.DailyRow{}
+	for _, component := range components {
+		if component.ComponentType == pagecomponents.TypeGroup {
+			continue
+		}
+		key := pagestats.TargetKey(component.ComponentType, component.ComponentID)
+		item := Component{
+			ID:          component.ID,
+			Name:        component.ComponentName,
+			Description: component.Description,
+			Type:        component.ComponentType,
+			TargetID:    component.ComponentID,
+			Status:      StatusUnknown,
+		}
+
+		if check, ok := latest[key]; ok {
+			checkedAt := check.CheckedAt
+			item.LastChecked = &checkedAt
+			item.Status = StatusOperational
+			if check.Status != "up" {
+				item.Status = StatusMajorOutage
+			}
+		}
+		for _, incident := range incidentsByTarget[key] {
+			status := StatusDegraded
+			if incident.Severity == "critical" {
+				status = StatusMajorOutage
+			}
+			item.Status = worse(item.Status, status)
+			view.Incidents = append(view.Incidents, Incident{
+				ID:        incident.ID,
+				Title:     incident.Title,
+				Severity:  incident.Severity,
+				Status:    incident.Status,
+				Component: component.ComponentName,
+				StartedAt: incident.StartedAt,
+			})
+		}
+
+		rows := make([]pagestats.DailyRow, HistoryDays)
+		for i := range rows {
+			rows[i] = daily[key][from.AddDate(0, 0, i)]
+		}
+		setHistory(&item, rows, from)
+
+		view.Status = worse(view.Status, item.Status)
+		items[component.ID] = item
+		history[component.ID] = rows
+	}
+	view.Components = nestComponents(components, items, history, from)
+
+	// Page totals weight components like the stats endpoint does
+	if uptime, response := pagestats.Totals(components, daily, from); uptime != nil {
+		view.HasUptime = true
+		view.Uptime, view.AvgResponseTime = *uptime, *response
+	}
+	applyAnnouncements(view)
+	return view, nil
+}
+
+// setHistory fills in a component's daily bars and overall uptime and
+// response time from its daily check aggregates
+func setHistory(item *Component, rows []pagestats.DailyRow, from time.Time) {
+	var checks, up int64
+	var response float64
+	item.History = nil
+	for i, row := range rows {
+		day := Day{Date: from.AddDate(0, 0, i), Checks: row.Checks}
+		if row.Checks > 0 {
+			day.HasData = true
+			day.Uptime = float64(row.UpChecks) / float64(row.Checks) * 100
+		}
+		item.History = append(item.History, day)
+		checks += row.Checks
+		up += row.UpChecks
+		response += row.ResponseTime
+	}
+	if checks > 0 {
+		item.HasUptime = true
+		item.Uptime = float64(up) / float64(checks) * 100
+		item.AvgResponseTime = response / float64(checks)
+	}
+}
+
+// nestComponents arranges the monitored components under their groups, in
+// display order. A group's status is that of its worst child and its history
+// combines the checks of all children. Components whose group is gone are
+// shown at the top level.
+func nestComponents(components []models.OperationalPageComponent, items map[uuid.UUID]Component, history map[uuid.UUID][]pagestats.DailyRow, from time.Time) []Component {
+	groups := map[uuid.UUID]bool{}
+	for _, component := range components {
+		if component.ComponentType == pagecomponents.TypeGroup {
+			groups[component.ID] = true
+		}
+	}
+
+	children := map[uuid.UUID][]uuid.UUID{}
+	for _, component := range components {
+		if _, ok := items[component.ID]; ok && component.ParentID != nil && groups[*component.ParentID] {
+			children[*component.ParentID] = append(children[*component.ParentID], component.ID)
+		}
+	}
+
+	result := []Component{}
+	for _, component := range components {
+		if component.ComponentType != pagecomponents.TypeGroup {
+			if component.ParentID == nil || !groups[*component.ParentID] {
+				result = append(result, items[component.ID])
+			}
+			continue
+		}
+
+		group := Component{
+			ID:          component.ID,
+			Name:        component.ComponentName,
+			Description: component.Description,
+			Type:        component.ComponentType,
+			Status:      StatusUnknown,
+		}
+		rows := make([]pagestats.DailyRow, HistoryDays)
+		for i, childID := range children[component.ID] {
+			child := items[childID]
+			if i == 0 {
+				group.Status = child.Status
+			}
+			group.Status = worse(group.Status, child.Status)
+			if child.LastChecked != nil && (group.LastChecked == nil || child.LastChecked.After(*group.LastChecked)) {
+				group.LastChecked = child.LastChecked
+			}
+			for day, row := range history[childID] {
+				rows[day].Checks += row.Checks
+				rows[day].UpChecks += row.UpChecks
+				rows[day].ResponseTime += row.ResponseTime
+			}
+			group.Children = append(group.Children, child)
+		}
+		setHistory(&group, rows, from)
+		result = append(result, group)
+	}
+	return result
+}
+
+// worse returns the more severe of two statuses
+func worse(a, b string) string {
+	if statusRank[b] > statusRank[a] {
+		return b
+	}
+	return a
+}
+
+// latestChecks loads the most recent check result of each target
+func latestChecks(db *gorm.DB, targetType string, ids []uuid.UUID, into map[string]models.CheckResult) error {
+	results := []models.CheckResult{}
+	err := db.Raw(`SELECT DISTINCT ON (target_id) * FROM check_results
+		WHERE target_type = ? AND target_id IN ?
+		ORDER BY target_id, checked_at DESC`, targetType, ids).Scan(&results).Error
+	if err != nil {
+		return err
+	}
+	for _, result := range results {
+		into[pagestats.TargetKey(result.TargetType, result.TargetID)] = result
+	}
+	return nil
+}
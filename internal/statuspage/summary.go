@@ -0,0 +1,265 @@
+package statuspage
+
+import (
+	"time"
+)
+
+// Summary follows the shape of the Statuspage.io /api/v2/summary.json
+// endpoint so existing widgets and integrations can read it
+type Summary struct {
+	Page                  SummaryPage          `json:"page"`
+	Status                SummaryStatus        `json:"status"`
+	Components            []SummaryComponent   `json:"components"`
+	Incidents             []SummaryIncident    `json:"incidents"`
+	ScheduledMaintenances []SummaryMaintenance `json:"scheduled_maintenances"`
+}
+
+// SummaryPage describes the page itself
+type SummaryPage struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	URL       string    `json:"url"`
+	TimeZone  string    `json:"time_zone"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SummaryStatus is the overall page status. Indicator is one of none,
+// minor, major, critical or maintenance.
+type SummaryStatus struct {
+	Indicator   string `json:"indicator"`
+	Description string `json:"description"`
+}
+
+// SummaryComponent is one page component. Status is one of operational,
+// degraded_performance, partial_outage, major_outage or under_maintenance.
+type SummaryComponent struct {
+	ID                 string     `json:"id"`
+	Name               string     `json:"name"`
+	Status             string     `json:"status"`
+	Description        *string    `json:"description"`
+	Position           int        `json:"position"`
+	Showcase           bool       `json:"showcase"`
+	OnlyShowIfDegraded bool       `json:"only_show_if_degraded"`
+	Group              bool       `json:"group"`
+	GroupID            *string    `json:"group_id"`
+	PageID             string     `json:"page_id"`
+	StartDate          *string    `json:"start_date"`
+	CreatedAt          *time.Time `json:"created_at"`
+	UpdatedAt          *time.Time `json:"updated_at"`
+	Components         []string   `json:"components,omitempty"` // Child IDs of a group
+}
+
+// SummaryIncidentUpdate is one message of an incident or maintenance
+type SummaryIncidentUpdate struct {
+	ID         string    `json:"id"`
+	Status     string    `json:"status"`
+	Body       string    `json:"body"`
+	IncidentID string    `json:"incident_id"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	DisplayAt  time.Time `json:"display_at"`
+}
+
+// SummaryIncident is an unresolved incident
+type SummaryIncident struct {
+	ID              string                  `json:"id"`
+	Name            string                  `json:"name"`
+	Status          string                  `json:"status"`
+	Impact          string                  `json:"impact"`
+	PageID          string                  `json:"page_id"`
+	Shortlink       string                  `json:"shortlink"`
+	StartedAt       time.Time               `json:"started_at"`
+	CreatedAt       time.Time               `json:"created_at"`
+	UpdatedAt       time.Time               `json:"updated_at"`
+	MonitoringAt    *time.Time              `json:"monitoring_at"`
+	ResolvedAt      *time.Time              `json:"resolved_at"`
+	IncidentUpdates []SummaryIncidentUpdate `json:"incident_updates"`
+	Components      []SummaryComponent      `json:"components"`
+}
+
+// SummaryMaintenance is an upcoming or ongoing maintenance window. Status is
+// scheduled, in_progress or completed.
+type SummaryMaintenance struct {
+	ID              string                  `json:"id"`
+	Name            string                  `json:"name"`
+	Status          string                  `json:"status"`
+	Impact          string                  `json:"impact"`
+	PageID          string                  `json:"page_id"`
+	Shortlink       string                  `json:"shortlink"`
+	ScheduledFor    time.Time               `json:"scheduled_for"`
+	ScheduledUntil  time.Time               `json:"scheduled_until"`
+	IncidentUpdates []SummaryIncidentUpdate `json:"incident_updates"`
+	Components      []SummaryComponent      `json:"components"`
+}
+
+// summaryComponentStatus maps page statuses to Statuspage component statuses.
+// Components without data count as degraded, as on the HTML page.
+var summaryComponentStatus = map[string]string{
+	StatusOperational: "operational",
+	StatusUnknown:     "degraded_performance",
+	StatusDegraded:    "degraded_performance",
+	StatusMajorOutage: "major_outage",
+}
+
+// summaryIndicator maps page statuses to Statuspage indicators
+var summaryIndicator = map[string]string{
+	StatusOperational: "none",
+	StatusUnknown:     "minor",
+	StatusDegraded:    "minor",
+	StatusMajorOutage: "major",
+}
+
+// BuildSummary converts a page view to the Statuspage.io summary shape.
+// pageURL is the public address of the HTML status page.
+func BuildSummary(view *View, pageURL string) Summary {
+	pageID := view.Page.ID.String()
+	summary := Summary{
+		Page: SummaryPage{
+			ID:        pageID,
+			Name:      view.Page.Name,
+			URL:       pageURL,
+			TimeZone:  "Etc/UTC",
+			UpdatedAt: view.Page.UpdatedAt,
+		},
+		Status:                SummaryStatus{Indicator: summaryIndicator[view.Status], Description: summaryDescription(view.Status)},
+		Components:            []SummaryComponent{},
+		Incidents:             []SummaryIncident{},
+		ScheduledMaintenances: []SummaryMaintenance{},
+	}
+
+	underMaintenance := map[string]bool{}
+	for _, maintenance := range view.Maintenance {
+		if maintenance.Status == MaintenanceInProgress {
+			for _, id := range maintenance.componentIDs {
+				underMaintenance[id] = true
+			}
+			if view.Status == StatusOperational {
+				summary.Status = SummaryStatus{Indicator: "maintenance", Description: "Service Under Maintenance"}
+			}
+		}
+	}
+
+	byID := map[string]SummaryComponent{}
+	add := func(component Component, groupID *string) {
+		item := SummaryComponent{
+			ID:       component.ID.String(),
+			Name:     component.Name,
+			Status:   summaryComponentStatus[component.Status],
+			Position: len(summary.Components) + 1,
+			Showcase: true,
+			Group:    component.IsGroup(),
+			GroupID:  groupID,
+			PageID:   pageID,
+		}
+		if component.Description != "" {
+			description := component.Description
+			item.Description = &description
+		}
+		if component.Status == StatusOperational && underMaintenance[item.ID] {
+			item.Status = "under_maintenance"
+		}
+		for _, child := range component.Children {
+			item.Components = append(item.Components, child.ID.String())
+		}
+		summary.Components = append(summary.Components, item)
+		byID[item.ID] = item
+	}
+	for _, component := range view.Components {
+		add(component, nil)
+		groupID := component.ID.String()
+		for _, child := range component.Children {
+			add(child, &groupID)
+		}
+	}
+
+	for _, announcement := range view.Announcements {
+		incident := SummaryIncident{
+			ID:              announcement.ID.String(),
+			Name:            announcement.Title,
+			Status:          announcement.Status,
+			Impact:          announcement.Impact,
+			PageID:          pageID,
+			Shortlink:       pageURL,
+			StartedAt:       announcement.StartedAt,
+			CreatedAt:       announcement.StartedAt,
+			UpdatedAt:       announcement.StartedAt,
+			ResolvedAt:      announcement.ResolvedAt,
+			IncidentUpdates: []SummaryIncidentUpdate{},
+			Components:      summaryComponents(announcement.componentIDs, byID),
+		}
+		for _, update := range announcement.Updates {
+			incident.IncidentUpdates = append(incident.IncidentUpdates, SummaryIncidentUpdate{
+				ID:         update.ID.String(),
+				Status:     update.Status,
+				Body:       update.Message,
+				IncidentID: incident.ID,
+				CreatedAt:  update.CreatedAt,
+				UpdatedAt:  update.CreatedAt,
+				DisplayAt:  update.CreatedAt,
+			})
+			if update.CreatedAt.After(incident.UpdatedAt) {
+				incident.UpdatedAt = update.CreatedAt
+			}
+			if update.Status == IncidentMonitoring && incident.MonitoringAt == nil {
+				createdAt := update.CreatedAt
+				incident.MonitoringAt = &createdAt
+			}
+		}
+		summary.Incidents = append(summary.Incidents, incident)
+	}
+
+	for _, maintenance := range view.Maintenance {
+		summary.ScheduledMaintenances = append(summary.ScheduledMaintenances, SummaryMaintenance{
+			ID:              maintenance.ID.String(),
+			Name:            maintenance.Title,
+			Status:          maintenance.Status,
+			Impact:          "maintenance",
+			PageID:          pageID,
+			Shortlink:       pageURL,
+			ScheduledFor:    maintenance.StartsAt,
+			ScheduledUntil:  maintenance.EndsAt,
+			IncidentUpdates: maintenanceUpdates(maintenance),
+			Components:      summaryComponents(maintenance.componentIDs, byID),
+		})
+	}
+	return summary
+}
+
+// summaryDescription is the human-readable overall status
+func summaryDescription(status string) string {
+	switch status {
+	case StatusOperational:
+		return "All Systems Operational"
+	case StatusMajorOutage:
+		return "Major System Outage"
+	default:
+		return "Partial System Outage"
+	}
+}
+
+func summaryComponents(ids []string, byID map[string]SummaryComponent) []SummaryComponent {
+	components := []SummaryComponent{}
+	for _, id := range ids {
+		if component, ok := byID[id]; ok {
+			components = append(components, component)
+		}
+	}
+	return components
+}
+
+// maintenanceUpdates exposes a maintenance description as its only update,
+// since maintenance announcements have no timeline of their own
+func maintenanceUpdates(maintenance Maintenance) []SummaryIncidentUpdate {
+	if maintenance.Description == "" {
+		return []SummaryIncidentUpdate{}
+	}
+	return []SummaryIncidentUpdate{{
+		ID:         maintenance.ID.String(),
+		Status:     maintenance.Status,
+		Body:       maintenance.Description,
+		IncidentID: maintenance.ID.String(),
+		CreatedAt:  maintenance.StartsAt,
+		UpdatedAt:  maintenance.StartsAt,
+		DisplayAt:  maintenance.StartsAt,
+	}}
+}
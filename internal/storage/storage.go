@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"monitron-server/config"
+)
+
+// Storage backends
+const (
+	BackendLocal = "local"
+	BackendS3    = "s3"
+)
+
+// ErrNotFound is returned when an object does not exist in the backend
+var ErrNotFound = errors.New("object not found")
+
+// Storage stores generated files such as reports under slash-separated keys
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Store is the storage backend used by the application
+var Store Storage
+
+// InitStorage initializes the configured storage backend
+func InitStorage(cfg *config.Config) {
+	var err error
+	Store, err = New(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize %s storage: %v", cfg.Storage.Backend, err)
+	}
+	log.Printf("Using %s storage for generated files", cfg.Storage.Backend)
+}
+
+// New returns the storage backend selected by STORAGE_BACKEND
+func New(cfg *config.Config) (Storage, error) {
+	switch strings.ToLower(cfg.Storage.Backend) {
+	case "", BackendLocal:
+		return NewLocalStorage(cfg.Storage.LocalDir), nil
+	case BackendS3:
+		return NewS3Storage(cfg.Storage.S3Endpoint, cfg.Storage.S3AccessKey, cfg.Storage.S3SecretKey,
+			cfg.Storage.S3Bucket, cfg.Storage.S3Region, cfg.Storage.S3UseSSL)
+	default:
+		return nil, fmt.Errorf("unsupported storage backend: %s", cfg.Storage.Backend)
+	}
+}
+
+// validKey rejects keys that are empty, absolute or escape the storage root
+func validKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") {
+		return fmt.Errorf("invalid storage key: %q", key)
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return fmt.Errorf("invalid storage key: %q", key)
+		}
+	}
+	return nil
+}
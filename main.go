@@ -1,19 +1,32 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 	"os/signal"
 	"strconv"
 	"syscall"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/robfig/cron/v3"
 
 	"monitron-server/config"
 	"monitron-server/database"
+	"monitron-server/internal/incidents"
+	"monitron-server/internal/pagecomponents"
+	"monitron-server/internal/pagestats"
+	"monitron-server/internal/pagesubscribers"
+	"monitron-server/internal/realtime"
+	"monitron-server/internal/reportgen"
+	"monitron-server/internal/reportschedule"
+	"monitron-server/internal/slo"
+	"monitron-server/internal/storage"
 	"monitron-server/messaging"
 	"monitron-server/router"
+	"monitron-server/utils"
+	"monitron-server/utils/response"
 )
 
 // @title Monitron API
@@ -37,6 +50,9 @@ import (
 
 func main() {
 	cfg := config.LoadConfig()
+	if err := utils.CheckEmailTLSMode(cfg.Email.TLSMode); err != nil {
+		log.Fatalf("invalid EMAIL_TLS_MODE, error: %v", err)
+	}
 
 	// Initialize database
 	db := database.InitDB(cfg)
@@ -46,15 +62,74 @@ func main() {
 	messaging.InitRabbitMQ(cfg)
 	defer messaging.CloseRabbitMQ()
 
+	// Initialize storage for generated files
+	storage.InitStorage(cfg)
+
 	// Setup and start RabbitMQ consumers in a goroutine
-	go messaging.SetupConsumers()
+	go messaging.SetupConsumers(db)
+
+	// Publish check results, metrics and incidents to realtime subscribers
+	go realtime.NewWatcher(db, realtime.Default).Run(realtime.PollInterval)
 
-	app := fiber.New()
+	// Errors not answered by handlers, such as unknown routes, are sent in
+	// the response envelope
+	app := fiber.New(fiber.Config{ErrorHandler: response.ErrorHandler})
 
 	// Setup API routes
 	router.SetupRoutes(app, db)
 	// Initialize and start cron scheduler
 	c := cron.New()
+	if cfg.Reports.RetentionDays > 0 {
+		c.AddFunc("@daily", func() {
+			before := time.Now().AddDate(0, 0, -cfg.Reports.RetentionDays)
+			deleted, err := reportgen.CleanupExpired(context.Background(), db, storage.Store, before)
+			if err != nil {
+				log.Printf("Error cleaning up expired reports: %v", err)
+				return
+			}
+			log.Printf("Deleted %d expired reports", deleted)
+		})
+	}
+	c.AddFunc("@daily", func() {
+		deleted, err := pagesubscribers.CleanupPending(db, time.Now().Add(-pagesubscribers.PendingTTL))
+		if err != nil {
+			log.Printf("Error cleaning up unconfirmed status subscribers: %v", err)
+			return
+		}
+		log.Printf("Deleted %d unconfirmed status subscribers", deleted)
+	})
+	c.AddFunc("@daily", func() {
+		pageIDs, err := pagecomponents.RemoveDangling(db)
+		if err != nil {
+			log.Printf("Error removing dangling operational page components: %v", err)
+			return
+		}
+		for _, pageID := range pageIDs {
+			if _, err := pagestats.Refresh(db, pageID, time.Now()); err != nil {
+				log.Printf("Error refreshing operational page stats: %v", err)
+			}
+		}
+	})
+	c.AddFunc("@every "+incidents.EvaluateInterval.String(), func() {
+		if err := incidents.Evaluate(db, time.Now()); err != nil {
+			log.Printf("Error evaluating incidents: %v", err)
+		}
+	})
+	c.AddFunc("@every "+slo.AlertInterval.String(), func() {
+		if err := slo.EvaluateAlerts(db); err != nil {
+			log.Printf("Error evaluating SLO burn-rate alerts: %v", err)
+		}
+	})
+	c.AddFunc("@every 5m", func() {
+		if err := pagestats.RefreshAll(db); err != nil {
+			log.Printf("Error refreshing operational page stats: %v", err)
+		}
+	})
+	c.AddFunc("@every "+reportschedule.TickInterval.String(), func() {
+		if err := reportschedule.Tick(db, time.Now()); err != nil {
+			log.Printf("Error running report schedules: %v", err)
+		}
+	})
 	c.Start()
 	defer c.Stop()
 
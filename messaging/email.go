@@ -0,0 +1,69 @@
+package messaging
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"monitron-server/config"
+	"monitron-server/utils"
+)
+
+// EmailQueue is the queue consumed by the email sending worker
+const EmailQueue = "email_sending_queue"
+
+// EmailTask is a queued email. Body is the HTML part; the plain-text part is
+// derived from it unless TextBody is set.
+type EmailTask struct {
+	To          string             `json:"to"`
+	Subject     string             `json:"subject"`
+	Body        string             `json:"body"`
+	TextBody    string             `json:"text_body,omitempty"`
+	Attachments []utils.Attachment `json:"attachments,omitempty"`
+	Attempt     int                `json:"attempt,omitempty"`
+}
+
+// PublishEmail queues an email for background delivery
+func PublishEmail(task EmailTask) error {
+	body, err := json.Marshal(task)
+	if err != nil {
+		return fmt.Errorf("failed to marshal email task: %w", err)
+	}
+	return PublishMessage(EmailQueue, body)
+}
+
+// handleEmail sends a queued email and schedules a retry on failure
+func handleEmail(body []byte) {
+	var task EmailTask
+	if err := json.Unmarshal(body, &task); err != nil {
+		log.Printf("Error unmarshalling email details: %v", err)
+		return
+	}
+
+	cfg := config.LoadConfig()
+	err := utils.NewMailer(cfg).Send(utils.Email{
+		To:          []string{task.To},
+		Subject:     task.Subject,
+		HTMLBody:    task.Body,
+		TextBody:    task.TextBody,
+		Attachments: task.Attachments,
+	})
+	if err != nil {
+		if task.Attempt >= maxNotificationRetries {
+			log.Printf("Giving up on email to %s after %d retries: %v", task.To, task.Attempt, err)
+			return
+		}
+
+		backoff := retryBackoff(task.Attempt)
+		log.Printf("Error sending email to %s (attempt %d), retrying in %s: %v", task.To, task.Attempt+1, backoff, err)
+		task.Attempt++
+		time.AfterFunc(backoff, func() {
+			if err := PublishEmail(task); err != nil {
+				log.Printf("Error requeueing email to %s: %v", task.To, err)
+			}
+		})
+		return
+	}
+	log.Printf("Email sent successfully to: %s", task.To)
+}
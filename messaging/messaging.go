@@ -2,17 +2,14 @@ package messaging
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
 	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
+	"gorm.io/gorm"
 
 	"monitron-server/config"
-	"monitron-server/internal/reportgen"
-	"monitron-server/models"
-	"monitron-server/utils"
 )
 
 // Channel is the RabbitMQ channel
@@ -80,7 +77,8 @@ func PublishMessage(queueName string, body []byte) error {
 	if err != nil {
 		return fmt.Errorf("Failed to publish a message: %w", err)
 	}
-	log.Printf(" [x] Sent %s to %s", body, queueName)
+	// Bodies are not logged, as they can carry reset links and attachments
+	log.Printf(" [x] Sent %d bytes to %s", len(body), queueName)
 	return nil
 }
 
@@ -119,7 +117,7 @@ func ConsumeMessages(queueName string, handler func([]byte)) {
 
 	go func() {
 		for d := range msgs {
-			log.Printf("Received a message from %s: %s", queueName, d.Body)
+			log.Printf("Received a message of %d bytes from %s", len(d.Body), queueName)
 			handler(d.Body)
 			d.Ack(false)
 		}
@@ -130,59 +128,20 @@ func ConsumeMessages(queueName string, handler func([]byte)) {
 }
 
 // SetupConsumers sets up all necessary message consumers
-func SetupConsumers() {
-	// Example: Report generation consumer
-	go ConsumeMessages("report_generation_queue", func(body []byte) {
-		log.Printf("Processing report generation task: %s", string(body))
-		var report models.Report
-		if err := json.Unmarshal(body, &report); err != nil {
-			log.Printf("Error unmarshalling report details: %v", err)
-			return
-		}
-
-		// Simulate report generation and save to file
-		filePath, err := reportgen.GenerateReportFile(report)
-		if err != nil {
-			log.Printf("Error generating report file: %v", err)
-			return
-		}
-
-		// Update report entry in DB with file path
-		// This requires a DB connection, which is not directly available in consumer goroutine.
-		// In a real app, you'd pass the DB connection or use a service layer.
-		// For now, we'll just log it.
-		log.Printf("Report %s generated and saved to: %s", report.ID, filePath)
-
-		// TODO: Update report entry in database with file_path
-		// Example: db.Exec(`UPDATE reports SET file_path = $1 WHERE id = $2`, filePath, report.ID)
-
-		log.Printf("Report generation task completed for: %s", string(body))
-	})
+func SetupConsumers(db *gorm.DB) {
+	// Report generation consumer
+	go ConsumeMessages(ReportQueue, handleReport(db))
 
 	// Email sending consumer
-	go ConsumeMessages("email_sending_queue", func(body []byte) {
-		log.Printf("Processing email sending task: %s", string(body))
-		var emailDetails struct {
-			To      string `json:"to"`
-			Subject string `json:"subject"`
-			Body    string `json:"body"`
-		}
-		if err := json.Unmarshal(body, &emailDetails); err != nil {
-			log.Printf("Error unmarshalling email details: %v", err)
-			return
-		}
+	go ConsumeMessages(EmailQueue, handleEmail)
 
-		cfg := config.LoadConfig()
-		err := utils.SendEmail(cfg, emailDetails.To, emailDetails.Subject, emailDetails.Body)
-		if err != nil {
-			log.Printf("Error sending email: %v", err)
-			// TODO: Implement retry logic or move to DLQ
-			return
-		}
-		log.Printf("Email sent successfully to: %s", emailDetails.To)
-	})
+	// Notification channel delivery consumer
+	go ConsumeMessages(NotificationQueue, handleNotification(db))
+
+	// Status page subscriber webhook consumer
+	go ConsumeMessages(StatusWebhookQueue, handleStatusWebhook(db))
 
-	// TODO: Add more consumers for other background tasks (e.g., health checks, notifications)
+	// TODO: Add more consumers for other background tasks (e.g., health checks)
 }
 
 
@@ -0,0 +1,121 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"monitron-server/config"
+	"monitron-server/internal/notifier"
+	"monitron-server/models"
+)
+
+// NotificationQueue is the queue consumed by the notification delivery worker
+const NotificationQueue = "notification_queue"
+
+// Delivery retry policy: at most maxNotificationRetries retries, starting at
+// a one second backoff that doubles on every attempt up to a minute.
+const (
+	maxNotificationRetries = 10
+	minRetryBackoff        = time.Second
+	maxRetryBackoff        = time.Minute
+)
+
+// NotificationTask is a queued delivery of a message to a notification channel
+type NotificationTask struct {
+	ChannelID uuid.UUID        `json:"channel_id"`
+	Message   notifier.Message `json:"message"`
+	Attempt   int              `json:"attempt"`
+
+	// Recipients of an email channel left to send to on a retry, empty for
+	// all of them
+	Recipients []string `json:"recipients,omitempty"`
+}
+
+// PublishNotification queues a message for delivery to a notification channel
+func PublishNotification(channelID uuid.UUID, msg notifier.Message) error {
+	return publishNotificationTask(NotificationTask{ChannelID: channelID, Message: msg})
+}
+
+func publishNotificationTask(task NotificationTask) error {
+	body, err := json.Marshal(task)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification task: %w", err)
+	}
+	return PublishMessage(NotificationQueue, body)
+}
+
+// retryBackoff returns the delay before the given retry attempt
+func retryBackoff(attempt int) time.Duration {
+	backoff := minRetryBackoff << uint(attempt)
+	if backoff <= 0 || backoff > maxRetryBackoff {
+		return maxRetryBackoff
+	}
+	return backoff
+}
+
+// handleNotification delivers a notification task and schedules a retry on failure
+func handleNotification(db *gorm.DB) func([]byte) {
+	return func(body []byte) {
+		var task NotificationTask
+		if err := json.Unmarshal(body, &task); err != nil {
+			log.Printf("Error unmarshalling notification task: %v", err)
+			return
+		}
+
+		channel := models.NotificationChannel{}
+		if err := db.First(&channel, "id = ?", task.ChannelID).Error; err != nil {
+			log.Printf("Error fetching notification channel %s: %v", task.ChannelID, err)
+			return
+		}
+		if !channel.IsEnabled {
+			log.Printf("Skipping notification for disabled channel %s", channel.ID)
+			return
+		}
+
+		cfg := config.LoadConfig()
+		provider, err := notifier.FromChannel(channel, cfg)
+		if err != nil {
+			log.Printf("Error building provider for channel %s: %v", channel.ID, err)
+			return
+		}
+
+		if len(task.Recipients) > 0 {
+			provider = notifier.OnlyRecipients(provider, task.Recipients)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		if err := provider.Send(ctx, task.Message); err != nil {
+			if task.Attempt >= maxNotificationRetries {
+				log.Printf("Giving up on notification to channel %s after %d retries: %v", channel.ID, task.Attempt, err)
+				return
+			}
+
+			// Recipients that were reached are not sent the message again
+			var partialErr *notifier.PartialError
+			if errors.As(err, &partialErr) {
+				task.Recipients = partialErr.Failed
+			}
+
+			backoff := retryBackoff(task.Attempt)
+			log.Printf("Error sending notification to channel %s (attempt %d), retrying in %s: %v", channel.ID, task.Attempt+1, backoff, err)
+			task.Attempt++
+			time.AfterFunc(backoff, func() {
+				if err := publishNotificationTask(task); err != nil {
+					log.Printf("Error requeueing notification for channel %s: %v", channel.ID, err)
+				}
+			})
+			return
+		}
+
+		log.Printf("Notification delivered to channel %s (%s)", channel.ID, channel.Type)
+	}
+}
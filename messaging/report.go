@@ -0,0 +1,175 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"monitron-server/config"
+	"monitron-server/internal/notifier"
+	"monitron-server/internal/reportgen"
+	"monitron-server/internal/storage"
+	"monitron-server/models"
+	"monitron-server/utils"
+)
+
+// ReportQueue is the queue consumed by the report generation worker
+const ReportQueue = "report_generation_queue"
+
+// Delivery modes of scheduled reports for email recipients
+const (
+	DeliveryAttachment = "attachment"
+	DeliveryLink       = "link"
+)
+
+// maxAttachmentSize is the largest report sent as an attachment; larger
+// files are delivered as a link instead
+const maxAttachmentSize = 10 << 20
+
+// PublishReport queues a report for generation
+func PublishReport(report models.Report) error {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	return PublishMessage(ReportQueue, body)
+}
+
+// handleReport generates a queued report and records the outcome on the
+// report row. Only the report ID is taken from the message; the current row
+// is reloaded from the database.
+func handleReport(db *gorm.DB) func([]byte) {
+	return func(body []byte) {
+		var report models.Report
+		if err := json.Unmarshal(body, &report); err != nil {
+			log.Printf("Error unmarshalling report details: %v", err)
+			return
+		}
+
+		ctx := context.Background()
+		if err := reportgen.Process(ctx, db, storage.Store, report.ID); err != nil {
+			log.Printf("Error generating report %s: %v", report.ID, err)
+			return
+		}
+		log.Printf("Report generation task completed for: %s", report.ID)
+
+		if err := deliverScheduledReport(ctx, db, report.ID); err != nil {
+			log.Printf("Error delivering report %s: %v", report.ID, err)
+		}
+	}
+}
+
+// deliverScheduledReport sends a completed report created by a schedule to
+// the schedule's email recipients and notification channels. Reports of
+// another user than the schedule's owner are not delivered, and each report is
+// delivered once.
+func deliverScheduledReport(ctx context.Context, db *gorm.DB, reportID uuid.UUID) error {
+	report := models.Report{}
+	if err := db.First(&report, "id = ?", reportID).Error; err != nil {
+		return err
+	}
+	if report.ScheduleID == nil {
+		return nil
+	}
+
+	schedule := models.ReportSchedule{}
+	err := db.First(&schedule, "id = ?", *report.ScheduleID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if schedule.UserID != report.UserID {
+		return fmt.Errorf("report %s does not belong to the owner of schedule %s", report.ID, schedule.ID)
+	}
+
+	// Redelivered messages find the report already delivered
+	result := db.Model(&models.Report{}).Where("id = ? AND status = ? AND delivered_at IS NULL", report.ID, reportgen.StatusCompleted).
+		Update("delivered_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil
+	}
+
+	data := notifier.TemplateData{
+		Report: notifier.TemplateReport{ID: report.ID.String(), Name: report.Name, Type: report.ReportType, Format: report.Format},
+		Link:   strings.TrimRight(config.LoadConfig().App.FrontendURL, "/") + "/reports/" + report.ID.String(),
+	}
+
+	if len(schedule.Recipients) > 0 {
+		var attachments []utils.Attachment
+		if schedule.DeliveryMode != DeliveryLink && report.FileSize <= maxAttachmentSize {
+			attachment, err := reportAttachment(ctx, report)
+			if err != nil {
+				log.Printf("Error attaching report %s, sending a link instead: %v", report.ID, err)
+			} else {
+				attachments = []utils.Attachment{attachment}
+			}
+		}
+
+		htmlMsg, err := notifier.Render(db, notifier.EventReportReady, notifier.FormatHTML, data)
+		if err != nil {
+			return err
+		}
+		textMsg, err := notifier.Render(db, notifier.EventReportReady, notifier.FormatPlain, data)
+		if err != nil {
+			return err
+		}
+		for _, recipient := range schedule.Recipients {
+			task := EmailTask{To: recipient, Subject: htmlMsg.Subject, Body: htmlMsg.Body, TextBody: textMsg.Body, Attachments: attachments}
+			if err := PublishEmail(task); err != nil {
+				log.Printf("Error queueing report %s email to %s: %v", report.ID, recipient, err)
+			}
+		}
+	}
+
+	if len(schedule.ChannelIDs) > 0 {
+		channels := []models.NotificationChannel{}
+		if err := db.Where("id IN ? AND is_enabled = ?", schedule.ChannelIDs, true).Find(&channels).Error; err != nil {
+			return err
+		}
+		for _, channel := range channels {
+			msg, err := notifier.Render(db, notifier.EventReportReady, notifier.FormatForChannel(channel.Type), data)
+			if err != nil {
+				log.Printf("Error rendering report notification for channel %s: %v", channel.ID, err)
+				continue
+			}
+			if err := PublishNotification(channel.ID, msg); err != nil {
+				log.Printf("Error queueing report notification for channel %s: %v", channel.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// reportAttachment reads a generated report from storage as an email attachment
+func reportAttachment(ctx context.Context, report models.Report) (utils.Attachment, error) {
+	file, err := storage.Store.Get(ctx, report.FilePath)
+	if err != nil {
+		return utils.Attachment{}, err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return utils.Attachment{}, err
+	}
+
+	contentType := "application/octet-stream"
+	if renderer, err := reportgen.RendererFor(report.Format); err == nil {
+		contentType = renderer.ContentType()
+	}
+	return utils.Attachment{Filename: path.Base(report.FilePath), ContentType: contentType, Data: data}, nil
+}
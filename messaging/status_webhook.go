@@ -0,0 +1,102 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"monitron-server/config"
+	"monitron-server/internal/notifier"
+	"monitron-server/models"
+	"monitron-server/utils"
+)
+
+// StatusWebhookQueue is the queue consumed by the status page webhook worker
+const StatusWebhookQueue = "status_webhook_queue"
+
+// StatusWebhookTask is a queued delivery of a message to a status page
+// subscriber's webhook
+type StatusWebhookTask struct {
+	SubscriberID uuid.UUID        `json:"subscriber_id"`
+	Message      notifier.Message `json:"message"`
+	Attempt      int              `json:"attempt"`
+}
+
+// PublishStatusWebhook queues a message for delivery to a subscriber's webhook
+func PublishStatusWebhook(subscriberID uuid.UUID, msg notifier.Message) error {
+	return publishStatusWebhookTask(StatusWebhookTask{SubscriberID: subscriberID, Message: msg})
+}
+
+func publishStatusWebhookTask(task StatusWebhookTask) error {
+	body, err := json.Marshal(task)
+	if err != nil {
+		return fmt.Errorf("failed to marshal status webhook task: %w", err)
+	}
+	return PublishMessage(StatusWebhookQueue, body)
+}
+
+// handleStatusWebhook delivers a status webhook task with the same signing
+// and retry policy as webhook notification channels, only ever connecting to
+// public addresses. Tasks of subscribers that unsubscribed in the meantime
+// are dropped.
+func handleStatusWebhook(db *gorm.DB) func([]byte) {
+	return func(body []byte) {
+		var task StatusWebhookTask
+		if err := json.Unmarshal(body, &task); err != nil {
+			log.Printf("Error unmarshalling status webhook task: %v", err)
+			return
+		}
+
+		subscriber := models.StatusSubscriber{}
+		if err := db.First(&subscriber, "id = ? AND type = ?", task.SubscriberID, "webhook").Error; err != nil {
+			log.Printf("Skipping status webhook for subscriber %s: %v", task.SubscriberID, err)
+			return
+		}
+
+		cfg := config.LoadConfig()
+		secret := ""
+		if subscriber.Secret != "" {
+			raw, err := utils.Decrypt(subscriber.Secret, cfg)
+			if err != nil {
+				log.Printf("Error decrypting webhook secret of subscriber %s: %v", subscriber.ID, err)
+				return
+			}
+			secret = string(raw)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		// The URL was set by an anonymous visitor, so it is checked again in
+		// case its DNS changed since the subscription
+		if err := notifier.CheckPublicURL(ctx, subscriber.WebhookURL); err != nil {
+			log.Printf("Skipping status webhook for subscriber %s: %v", subscriber.ID, err)
+			return
+		}
+		provider := notifier.NewPublicWebhookProvider(subscriber.WebhookURL, secret)
+
+		if err := provider.Send(ctx, task.Message); err != nil {
+			if task.Attempt >= maxNotificationRetries {
+				log.Printf("Giving up on status webhook to subscriber %s after %d retries: %v", subscriber.ID, task.Attempt, err)
+				return
+			}
+
+			backoff := retryBackoff(task.Attempt)
+			log.Printf("Error sending status webhook to subscriber %s (attempt %d), retrying in %s: %v", subscriber.ID, task.Attempt+1, backoff, err)
+			task.Attempt++
+			time.AfterFunc(backoff, func() {
+				if err := publishStatusWebhookTask(task); err != nil {
+					log.Printf("Error requeueing status webhook for subscriber %s: %v", subscriber.ID, err)
+				}
+			})
+			return
+		}
+
+		log.Printf("Status webhook delivered to subscriber %s", subscriber.ID)
+	}
+}
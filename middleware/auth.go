@@ -1,12 +1,14 @@
 package middleware
 
 import (
+	"net/url"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt/v5"
 
+	"monitron-server/config"
 	"monitron-server/utils"
+	"monitron-server/utils/response"
 )
 
 // JWTAuth middleware to protect routes
@@ -14,24 +16,17 @@ func JWTAuth() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		authHeader := c.Get("Authorization")
 		if authHeader == "" {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Missing or malformed JWT"})
+			return response.Error(c, fiber.StatusUnauthorized, "Missing or malformed JWT")
 		}
 
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Missing or malformed JWT"})
+			return response.Error(c, fiber.StatusUnauthorized, "Missing or malformed JWT")
 		}
 
-		tokenString := parts[1]
-
-		claims := &utils.Claims{}
-		// TODO: Use a secure secret key from config/environment variable
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return []byte("supersecretkey"), nil // Replace with your secret key
-		})
-
-		if err != nil || !token.Valid {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired JWT"})
+		claims, err := utils.ParseJWT(parts[1])
+		if err != nil {
+			return response.Error(c, fiber.StatusUnauthorized, "Invalid or expired JWT")
 		}
 
 		c.Locals("user_id", claims.UserID)
@@ -41,12 +36,66 @@ func JWTAuth() fiber.Handler {
 	}
 }
 
+// OptionalJWTAuth sets the user locals when the request carries a valid JWT,
+// either as a Bearer token or in the "token" cookie used by browser pages,
+// and lets anonymous requests through. Browsers send cookies on cross-site
+// WebSocket upgrades, which CORS does not cover, so the cookie is ignored on
+// upgrades from other origins.
+func OptionalJWTAuth() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		tokenString := ""
+		if !isWebSocketUpgrade(c) || trustedOrigin(c) {
+			tokenString = c.Cookies("token")
+		}
+		if parts := strings.Split(c.Get("Authorization"), " "); len(parts) == 2 && parts[0] == "Bearer" {
+			tokenString = parts[1]
+		}
+
+		if tokenString != "" {
+			if claims, err := utils.ParseJWT(tokenString); err == nil {
+				c.Locals("user_id", claims.UserID)
+				c.Locals("user_role", claims.Role)
+			}
+		}
+
+		return c.Next()
+	}
+}
+
+func isWebSocketUpgrade(c *fiber.Ctx) bool {
+	return strings.EqualFold(c.Get(fiber.HeaderUpgrade), "websocket")
+}
+
+// trustedOrigin reports whether a request comes from this server, one of its
+// custom domains or the UI, or from a client that is not a browser
+func trustedOrigin(c *fiber.Ctx) bool {
+	origin := c.Get(fiber.HeaderOrigin)
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(u.Host, c.Hostname()) {
+		return true
+	}
+
+	cfg := config.LoadConfig()
+	for _, allowed := range []string{cfg.App.PublicURL, cfg.App.FrontendURL} {
+		if a, err := url.Parse(allowed); err == nil && a.Scheme == u.Scheme && strings.EqualFold(a.Host, u.Host) {
+			return true
+		}
+	}
+	return false
+}
+
 // AdminAuth middleware to protect admin routes
 func AdminAuth() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		role := c.Locals("user_role")
 		if role != "admin" {
-			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden: Admin access required"})
+			return response.Error(c, fiber.StatusForbidden, "Forbidden: Admin access required")
 		}
 		return c.Next()
 	}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"monitron-server/config"
+	"monitron-server/internal/statuspage"
+)
+
+// statusHostPaths are the status page paths served at the root of a custom
+// domain, relative to /status/:slug
+var statusHostPaths = []string{"/feed.atom", "/feed.rss", "/api/v2/", "/badges/", "/reports", "/subscribers/"}
+
+// StatusPageHost serves operational pages on their custom domains by
+// rewriting requests for such a host to the page's /status/:slug routes.
+// Other hosts and paths, including /status/:slug itself, are left untouched,
+// as are the hosts of this server and the UI.
+func StatusPageHost(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		path := c.Path()
+		if !isStatusHostPath(path) {
+			return c.Next()
+		}
+
+		if statuspage.ReservedHost(config.LoadConfig(), c.Hostname()) {
+			return c.Next()
+		}
+
+		page, err := statuspage.FindPageByHost(db, c.Hostname())
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Next()
+		}
+		if err != nil {
+			log.Printf("Error resolving status page host %s: %v", c.Hostname(), err)
+			return c.Next()
+		}
+
+		if path == "/" {
+			path = ""
+		}
+		c.Path("/status/" + page.Slug + path)
+		return c.Next()
+	}
+}
+
+func isStatusHostPath(path string) bool {
+	if path == "/" {
+		return true
+	}
+	for _, prefix := range statusHostPaths {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CheckResult is the outcome of a single health check of a monitored target
+type CheckResult struct {
+	ID           uuid.UUID `db:"id" json:"id"`
+	TargetType   string    `db:"target_type" json:"target_type"` // "service", "instance" or "domain_ssl"
+	TargetID     uuid.UUID `db:"target_id" json:"target_id"`
+	Status       string    `db:"status" json:"status"`               // "up" or "down"
+	ResponseTime float64   `db:"response_time" json:"response_time"` // in milliseconds
+	Message      string    `db:"message" json:"message"`             // Error or status detail
+	CheckedAt    time.Time `db:"checked_at" json:"checked_at"`
+}
+
+func (CheckResult) TableName() string {
+	return "check_results"
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Incident is a period during which a monitored target was unhealthy
+type Incident struct {
+	ID         uuid.UUID  `db:"id" json:"id"`
+	TargetType string     `db:"target_type" json:"target_type"` // "service", "instance" or "domain_ssl"
+	TargetID   uuid.UUID  `db:"target_id" json:"target_id"`
+	Title      string     `db:"title" json:"title"`
+	Severity   string     `db:"severity" json:"severity"` // "info", "warning" or "critical"
+	Status     string     `db:"status" json:"status"`     // "open" or "resolved"
+	StartedAt  time.Time  `db:"started_at" json:"started_at"`
+	ResolvedAt *time.Time `db:"resolved_at" json:"resolved_at"`
+	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt  time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+func (Incident) TableName() string {
+	return "incidents"
+}
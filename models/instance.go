@@ -41,11 +41,10 @@ func (InstanceStats) TableName() string {
 }
 
 type InstanceMetric struct {
-	ID          uuid.UUID `db:"id" json:"id"`
-	InstanceID  uuid.UUID `db:"instance_id" json:"instance_id"`
-	MetricName  string    `db:"metric_name" json:"metric_name"`
-	MetricValue float64   `db:"metric_value" json:"metric_value"`
-	Timestamp   time.Time `db:"timestamp" json:"timestamp"`
+	InstanceID uuid.UUID `db:"instance_id" json:"instance_id"`
+	MetricType string    `db:"metric_type" json:"metric_type"` // e.g. "cpu_usage", "memory_usage", "disk_usage"
+	Value      float64   `db:"value" json:"value"`
+	Timestamp  time.Time `db:"timestamp" json:"timestamp"`
 }
 
 func (InstanceMetric) TableName() string {
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// NotificationChannel is an outbound destination for alerts and reports
+type NotificationChannel struct {
+	ID        uuid.UUID `db:"id" json:"id"`
+	Name      string    `db:"name" json:"name" validate:"required"`
+	Type      string    `db:"type" json:"type" validate:"required,oneof=email telegram discord slack webhook"`
+	Config    string    `db:"config" json:"config"` // Encrypted JSON of NotificationChannelConfig
+	IsEnabled bool      `db:"is_enabled" json:"is_enabled"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+func (NotificationChannel) TableName() string {
+	return "notification_channels"
+}
+
+// NotificationChannelConfig holds the provider credentials of a channel.
+// Only the fields relevant to the channel type need to be set.
+type NotificationChannelConfig struct {
+	// Email
+	Recipients []string `json:"recipients,omitempty"`
+
+	// Telegram
+	BotToken string `json:"bot_token,omitempty"`
+	ChatID   string `json:"chat_id,omitempty"`
+
+	// Discord, Slack and generic webhook
+	WebhookURL string `json:"webhook_url,omitempty"`
+	Secret     string `json:"secret,omitempty"` // HMAC SHA-256 signing secret for generic webhooks
+
+	// Overrides the provider API base URL (e.g. for self-hosted or fake endpoints)
+	BaseURL string `json:"base_url,omitempty"`
+}
+
+// NotificationTemplate overrides the built-in message template for an event
+// type and output format
+type NotificationTemplate struct {
+	ID        uuid.UUID `db:"id" json:"id"`
+	EventType string    `db:"event_type" json:"event_type" validate:"required,oneof=incident_opened incident_resolved cert_expiring report_ready password_reset status_subscription_confirm status_component_changed status_incident_updated"`
+	Format    string    `db:"format" json:"format" validate:"required,oneof=html markdown plain"`
+	Subject   string    `db:"subject" json:"subject"`
+	Body      string    `db:"body" json:"body" validate:"required"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+func (NotificationTemplate) TableName() string {
+	return "notification_templates"
+}
@@ -7,13 +7,20 @@ import (
 )
 
 type OperationalPage struct {
-	ID          uuid.UUID `db:"id" json:"id"`
-	Slug        string    `db:"slug" json:"slug"` // Unique slug for the page URL
-	Name        string    `db:"name" json:"name"`
-	Description string    `db:"description" json:"description"`
-	IsPublic    bool      `db:"is_public" json:"is_public"` // True if public, false if private (requires auth)
-	CreatedAt   time.Time `db:"created_at" json:"created_at"`
-	UpdatedAt   time.Time `db:"updated_at" json:"updated_at"`
+	ID            uuid.UUID `db:"id" json:"id"`
+	Slug          string    `db:"slug" json:"slug"` // Unique slug for the page URL
+	Name          string    `db:"name" json:"name"`
+	Description   string    `db:"description" json:"description"`
+	IsPublic      bool      `db:"is_public" json:"is_public"`                                                                               // True if public, false if private (requires auth)
+	ReportReasons []string  `db:"report_reasons" json:"report_reasons" gorm:"serializer:json" validate:"max=20,dive,required,max=255"`      // Problem report reasons, empty for the defaults
+	CustomDomains []string  `db:"custom_domains" json:"custom_domains" gorm:"serializer:json" validate:"max=10,dive,required,fqdn,max=253"` // Hostnames serving the page at their root
+	LogoURL       string    `db:"logo_url" json:"logo_url" validate:"omitempty,url,max=1024"`
+	FaviconURL    string    `db:"favicon_url" json:"favicon_url" validate:"omitempty,url,max=1024"`
+	ColorTheme    string    `db:"color_theme" json:"color_theme" validate:"omitempty,oneof=light_blue orange light_green"` // Accent color, empty for the default
+	CustomCSS     string    `db:"custom_css" json:"custom_css" validate:"max=20000"`
+	FooterText    string    `db:"footer_text" json:"footer_text" validate:"max=1000"`
+	CreatedAt     time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt     time.Time `db:"updated_at" json:"updated_at"`
 }
 
 func (p *OperationalPage) TableName() string {
@@ -21,15 +28,17 @@ func (p *OperationalPage) TableName() string {
 }
 
 type OperationalPageComponent struct {
-	ID            uuid.UUID `db:"id" json:"id"`
-	PageID        uuid.UUID `db:"page_id" json:"page_id"`
-	ComponentType string    `db:"component_type" json:"component_type"` // "service" or "domain_ssl"
-	ComponentID   uuid.UUID `db:"component_id" json:"component_id"`
-	ComponentName string    `db:"component_name" json:"component_name"` // User-defined name for display
-	DisplayOrder  int       `db:"display_order" json:"display_order"`
-	Description   string    `db:"description" json:"description"`
-	CreatedAt     time.Time `db:"created_at" json:"created_at"`
-	UpdatedAt     time.Time `db:"updated_at" json:"updated_at"`
+	ID            uuid.UUID  `db:"id" json:"id"`
+	PageID        uuid.UUID  `db:"page_id" json:"page_id"`
+	ComponentType string     `db:"component_type" json:"component_type" validate:"required,oneof=service instance domain_ssl group"`
+	ComponentID   uuid.UUID  `db:"component_id" json:"component_id"`                                 // Monitored target, or the component's own ID for groups
+	ParentID      *uuid.UUID `db:"parent_id" json:"parent_id"`                                       // Group the component is shown in, nil at the top level
+	ComponentName string     `db:"component_name" json:"component_name" validate:"required,max=255"` // User-defined name for display
+	DisplayOrder  int        `db:"display_order" json:"display_order"`
+	Description   string     `db:"description" json:"description"`
+	Weight        float64    `db:"weight" json:"weight"` // Share in page-level uptime, defaults to 1
+	CreatedAt     time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt     time.Time  `db:"updated_at" json:"updated_at"`
 }
 
 func (c *OperationalPageComponent) TableName() string {
@@ -49,3 +58,18 @@ type OperationalPageStats struct {
 func (s *OperationalPageStats) TableName() string {
 	return "operational_page_stats"
 }
+
+// ProblemReport is a visitor's report of a problem submitted from a status page
+type ProblemReport struct {
+	ID        uuid.UUID `db:"id" json:"id"`
+	PageID    uuid.UUID `db:"page_id" json:"page_id"`
+	Reason    string    `db:"reason" json:"reason"`
+	Comment   string    `db:"comment" json:"comment"`
+	IPHash    string    `db:"ip_hash" json:"ip_hash"` // HMAC-SHA256 of the visitor IP keyed with the encryption key
+	UserAgent string    `db:"user_agent" json:"user_agent"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+func (r *ProblemReport) TableName() string {
+	return "problem_reports"
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PageIncident is an incident written by staff for the visitors of an
+// operational page, as opposed to the automatic incidents of a target
+type PageIncident struct {
+	ID           uuid.UUID  `db:"id" json:"id"`
+	PageID       uuid.UUID  `db:"page_id" json:"page_id"`
+	Title        string     `db:"title" json:"title" validate:"required,max=255"`
+	Status       string     `db:"status" json:"status" validate:"required,oneof=investigating identified monitoring resolved"`
+	Impact       string     `db:"impact" json:"impact" validate:"required,oneof=none minor major critical"`
+	ComponentIDs []string   `db:"component_ids" json:"component_ids" gorm:"serializer:json" validate:"dive,uuid"` // Affected page components
+	CreatedBy    uuid.UUID  `db:"created_by" json:"created_by"`
+	StartedAt    time.Time  `db:"started_at" json:"started_at"`
+	ResolvedAt   *time.Time `db:"resolved_at" json:"resolved_at"`
+	CreatedAt    time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt    time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+func (PageIncident) TableName() string {
+	return "page_incidents"
+}
+
+// PageIncidentUpdate is a timestamped message posted on a page incident
+type PageIncidentUpdate struct {
+	ID         uuid.UUID `db:"id" json:"id"`
+	IncidentID uuid.UUID `db:"incident_id" json:"incident_id"`
+	Status     string    `db:"status" json:"status" validate:"required,oneof=investigating identified monitoring resolved"` // Incident status set by this update
+	Message    string    `db:"message" json:"message" validate:"required"`
+	CreatedBy  uuid.UUID `db:"created_by" json:"created_by"`
+	CreatedAt  time.Time `db:"created_at" json:"created_at"`
+}
+
+func (PageIncidentUpdate) TableName() string {
+	return "page_incident_updates"
+}
+
+// PageMaintenance is a scheduled maintenance announcement on an operational page
+type PageMaintenance struct {
+	ID           uuid.UUID `db:"id" json:"id"`
+	PageID       uuid.UUID `db:"page_id" json:"page_id"`
+	Title        string    `db:"title" json:"title" validate:"required,max=255"`
+	Description  string    `db:"description" json:"description"`
+	StartsAt     time.Time `db:"starts_at" json:"starts_at" validate:"required"`
+	EndsAt       time.Time `db:"ends_at" json:"ends_at" validate:"required,gtfield=StartsAt"`
+	ComponentIDs []string  `db:"component_ids" json:"component_ids" gorm:"serializer:json" validate:"dive,uuid"` // Affected page components
+	CreatedBy    uuid.UUID `db:"created_by" json:"created_by"`
+	CreatedAt    time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt    time.Time `db:"updated_at" json:"updated_at"`
+}
+
+func (PageMaintenance) TableName() string {
+	return "page_maintenances"
+}
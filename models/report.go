@@ -7,22 +7,58 @@ import (
 )
 
 type Report struct {
-	ID          uuid.UUID `db:"id" json:"id"`
-	Name        string    `db:"name" json:"name"`
-	ReportType  string    `db:"report_type" json:"report_type"` // e.g., "instance_summary", "service_uptime"
-	Format      string    `db:"format" json:"format"`           // e.g., "CSV", "PDF", "Excel"
-	Status      string    `db:"status" json:"status"`           // e.g., "pending", "generating", "completed", "failed"
-	GeneratedAt time.Time `db:"generated_at" json:"generated_at"`
-	FilePath    string    `db:"file_path" json:"file_path"` // Path to the generated report file
-	UserID      uuid.UUID `db:"user_id" json:"user_id"`
-	CreatedAt   time.Time `db:"created_at" json:"created_at"`
-	UpdatedAt   time.Time `db:"updated_at" json:"updated_at"`
+	ID           uuid.UUID  `db:"id" json:"id"`
+	Name         string     `db:"name" json:"name" validate:"required"`
+	ReportType   string     `db:"report_type" json:"report_type" validate:"required,oneof=instance_summary service_uptime domain_ssl_expiry operational_page_sla incident_summary"`
+	Format       string     `db:"format" json:"format"` // "CSV", "PDF" or "Excel" (case-insensitive)
+	Status       string     `db:"status" json:"status"` // "pending", "generating", "completed" or "failed"
+	ErrorMessage string     `db:"error_message" json:"error_message,omitempty"`
+	GeneratedAt  *time.Time `db:"generated_at" json:"generated_at"`
+	FilePath     string     `db:"file_path" json:"file_path"` // Storage key of the generated report file
+	FileSize     int64      `db:"file_size" json:"file_size"` // In bytes
+	UserID       uuid.UUID  `db:"user_id" json:"user_id"`
+	ScheduleID   *uuid.UUID `db:"schedule_id" json:"schedule_id,omitempty"`   // Set when created by a ReportSchedule
+	DeliveredAt  *time.Time `db:"delivered_at" json:"delivered_at,omitempty"` // When a scheduled report was sent to the schedule's recipients
+	CreatedAt    time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt    time.Time  `db:"updated_at" json:"updated_at"`
+
+	// Report parameters
+	RangeStart   *time.Time `db:"range_start" json:"range_start"`                                           // Defaults to 30 days before RangeEnd
+	RangeEnd     *time.Time `db:"range_end" json:"range_end"`                                               // Defaults to the generation time
+	TargetIDs    []string   `db:"target_ids" json:"target_ids" gorm:"serializer:json" validate:"dive,uuid"` // Limit to these target IDs
+	TargetGroups []string   `db:"target_groups" json:"target_groups" gorm:"serializer:json"`                // Limit to targets in these groups
 }
 
 func (Report) TableName() string {
 	return "reports"
 }
 
+// ReportSchedule generates a report on a cron schedule and delivers it to
+// email recipients and notification channels once it completes
+type ReportSchedule struct {
+	ID             uuid.UUID  `db:"id" json:"id"`
+	Name           string     `db:"name" json:"name" validate:"required"`
+	CronExpression string     `db:"cron_expression" json:"cron_expression" validate:"required"` // e.g. "0 8 * * 1" or "@daily"
+	Timezone       string     `db:"timezone" json:"timezone"`                                   // IANA name, defaults to UTC
+	ReportType     string     `db:"report_type" json:"report_type" validate:"required,oneof=instance_summary service_uptime domain_ssl_expiry operational_page_sla incident_summary"`
+	Format         string     `db:"format" json:"format"`                                                     // "CSV", "PDF" or "Excel"
+	RangeDays      int        `db:"range_days" json:"range_days" validate:"min=0,max=366"`                    // Each report covers the last N days, defaults to 7
+	TargetIDs      []string   `db:"target_ids" json:"target_ids" gorm:"serializer:json" validate:"dive,uuid"` // Limit to these target IDs
+	TargetGroups   []string   `db:"target_groups" json:"target_groups" gorm:"serializer:json"`                // Limit to targets in these groups
+	Recipients     []string   `db:"recipients" json:"recipients" gorm:"serializer:json" validate:"dive,email"`
+	ChannelIDs     []string   `db:"channel_ids" json:"channel_ids" gorm:"serializer:json" validate:"dive,uuid"`    // Notification channels, which receive a link
+	DeliveryMode   string     `db:"delivery_mode" json:"delivery_mode" validate:"omitempty,oneof=attachment link"` // How email recipients receive the file, defaults to attachment
+	IsEnabled      bool       `db:"is_enabled" json:"is_enabled"`
+	LastRunAt      *time.Time `db:"last_run_at" json:"last_run_at"`
+	UserID         uuid.UUID  `db:"user_id" json:"user_id"`
+	CreatedAt      time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt      time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+func (ReportSchedule) TableName() string {
+	return "report_schedules"
+}
+
 type LogEntry struct {
 	ID        uuid.UUID `db:"id" json:"id"`
 	Level     string    `db:"level" json:"level"` // e.g., "info", "warn", "error"
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SLO is a service level objective on a service or operational page. A check
+// is good when the target is up and, if LatencyThreshold is set, answered
+// within it; Objective is the percentage of good checks over the Window.
+type SLO struct {
+	ID                uuid.UUID `db:"id" json:"id"`
+	Name              string    `db:"name" json:"name" validate:"required"`
+	TargetType        string    `db:"target_type" json:"target_type" validate:"required,oneof=service operational_page"`
+	TargetID          uuid.UUID `db:"target_id" json:"target_id" validate:"required"`
+	Objective         float64   `db:"objective" json:"objective" validate:"required,gt=0,lt=100"`                // e.g. 99.9
+	Window            string    `db:"window" json:"window" validate:"required,oneof=rolling_30d calendar_month"` // Compliance period
+	LatencyThreshold  float64   `db:"latency_threshold" json:"latency_threshold" validate:"min=0"`               // In milliseconds, 0 disables the latency objective
+	BurnRateThreshold float64   `db:"burn_rate_threshold" json:"burn_rate_threshold" validate:"min=0"`           // Alert when the burn rate exceeds this, defaults to 14.4
+	AlertFiring       bool      `db:"alert_firing" json:"alert_firing"`                                          // A burn-rate alert is currently firing
+	CreatedAt         time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt         time.Time `db:"updated_at" json:"updated_at"`
+}
+
+func (SLO) TableName() string {
+	return "slos"
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// StatusSubscriber is a visitor subscribed to the updates of an operational
+// page by email or webhook. Email subscribers only receive updates once they
+// confirmed their address.
+type StatusSubscriber struct {
+	ID               uuid.UUID  `db:"id" json:"id"`
+	PageID           uuid.UUID  `db:"page_id" json:"page_id"`
+	Type             string     `db:"type" json:"type" validate:"required,oneof=email webhook"`
+	Email            string     `db:"email" json:"email,omitempty" validate:"required_if=Type email,omitempty,email,max=255"`
+	WebhookURL       string     `db:"webhook_url" json:"webhook_url,omitempty" validate:"required_if=Type webhook,omitempty,url,max=2048"`
+	Secret           string     `db:"secret" json:"-"`                                                                // Encrypted webhook signing secret
+	ComponentIDs     []string   `db:"component_ids" json:"component_ids" gorm:"serializer:json" validate:"dive,uuid"` // Only updates for these page components, empty for all
+	Confirmed        bool       `db:"confirmed" json:"confirmed"`
+	ConfirmTokenHash string     `db:"confirm_token_hash" json:"-"` // SHA-256 of the pending email confirmation token
+	ConfirmedAt      *time.Time `db:"confirmed_at" json:"confirmed_at"`
+	CreatedAt        time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt        time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+func (StatusSubscriber) TableName() string {
+	return "status_subscribers"
+}
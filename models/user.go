@@ -26,7 +26,7 @@ func (User) TableName() string {
 type PasswordResetToken struct {
 	ID        uuid.UUID `db:"id" json:"id"`
 	UserID    uuid.UUID `db:"user_id" json:"user_id"`
-	Token     string    `db:"token" json:"token"`
+	TokenHash string    `db:"token_hash" json:"-"` // SHA-256 of the token sent by email
 	ExpiresAt time.Time `db:"expires_at" json:"expires_at"`
 	CreatedAt time.Time `db:"created_at" json:"created_at"`
 }
@@ -34,3 +34,36 @@ type PasswordResetToken struct {
 func (PasswordResetToken) TableName() string {
 	return "password_reset_tokens"
 }
+
+// UserSubscription subscribes a user to alerts for a target. Group
+// subscriptions match services and instances by their Group name.
+type UserSubscription struct {
+	ID         uuid.UUID  `db:"id" json:"id"`
+	UserID     uuid.UUID  `db:"user_id" json:"user_id"`
+	TargetType string     `db:"target_type" json:"target_type" validate:"required,oneof=service instance domain_ssl group operational_page"`
+	TargetID   *uuid.UUID `db:"target_id" json:"target_id"`   // Unset for group subscriptions
+	GroupName  string     `db:"group_name" json:"group_name"` // Only for group subscriptions
+	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+}
+
+func (UserSubscription) TableName() string {
+	return "user_subscriptions"
+}
+
+// UserNotificationPreference controls how and when a user is alerted
+type UserNotificationPreference struct {
+	UserID uuid.UUID `db:"user_id" json:"user_id" gorm:"primaryKey"`
+	// Severity ("info", "warning", "critical") to notification channel IDs.
+	// The special value "email" targets the user's own email address.
+	SeverityChannels  map[string][]string `db:"severity_channels" json:"severity_channels" gorm:"serializer:json"`
+	QuietHoursEnabled bool                `db:"quiet_hours_enabled" json:"quiet_hours_enabled"`
+	QuietHoursStart   string              `db:"quiet_hours_start" json:"quiet_hours_start" validate:"omitempty,datetime=15:04"` // Local time, e.g. "22:00"
+	QuietHoursEnd     string              `db:"quiet_hours_end" json:"quiet_hours_end" validate:"omitempty,datetime=15:04"`     // Local time, e.g. "07:00"
+	Timezone          string              `db:"timezone" json:"timezone" validate:"omitempty,timezone"`
+	CriticalBypass    bool                `db:"critical_bypass" json:"critical_bypass"` // Critical alerts ignore quiet hours
+	UpdatedAt         time.Time           `db:"updated_at" json:"updated_at"`
+}
+
+func (UserNotificationPreference) TableName() string {
+	return "user_notification_preferences"
+}
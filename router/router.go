@@ -9,8 +9,26 @@ import (
 )
 
 func SetupRoutes(app *fiber.App, db *gorm.DB) {
+	// Public status pages, rendered as HTML outside the JSON API. Requests for
+	// a page's custom domain are rewritten to its /status/:slug routes.
+	app.Use(middleware.StatusPageHost(db))
+	app.Get("/status/:slug", middleware.OptionalJWTAuth(), handlers.StatusPage(db))
+	app.Get("/status/:slug/feed.atom", handlers.StatusPageAtomFeed(db))
+	app.Get("/status/:slug/feed.rss", handlers.StatusPageRSSFeed(db))
+	app.Get("/status/:slug/api/v2/summary.json", handlers.StatusPageSummary(db))
+	app.Get("/status/:slug/badges/:componentID/status.svg", handlers.StatusComponentBadge(db))
+	app.Get("/status/:slug/badges/:componentID/uptime.svg", handlers.StatusComponentUptimeBadge(db))
+	app.Post("/status/:slug/reports", middleware.OptionalJWTAuth(), handlers.ReportStatusPageProblem(db))
+	app.Post("/status/:slug/subscribers", middleware.OptionalJWTAuth(), handlers.SubscribeToStatusPage(db))
+	app.Get("/status/:slug/subscribers/:subscriberID/confirm", handlers.ConfirmStatusSubscription(db))
+	app.Get("/status/:slug/subscribers/:subscriberID/unsubscribe", handlers.UnsubscribeFromStatusPageForm(db))
+	app.Post("/status/:slug/subscribers/:subscriberID/unsubscribe", handlers.UnsubscribeFromStatusPage(db))
+
 	api := app.Group("/api/v1")
 
+	// Realtime events over WebSocket
+	api.Get("/ws", middleware.OptionalJWTAuth(), handlers.RealtimeUpgrade(db), handlers.RealtimeStream(db))
+
 	// Instance Management Routes
 	instances := api.Group("/instances")
 	instances.Post("/", handlers.CreateInstance(db))
@@ -50,6 +68,11 @@ func SetupRoutes(app *fiber.App, db *gorm.DB) {
 	// Authenticated User Routes
 	userAuth := api.Group("/user", middleware.JWTAuth())
 	userAuth.Put("/change-password", handlers.ChangePassword(db))
+	userAuth.Get("/subscriptions", handlers.GetMySubscriptions(db))
+	userAuth.Post("/subscriptions", handlers.CreateMySubscription(db))
+	userAuth.Delete("/subscriptions/:id", handlers.DeleteMySubscription(db))
+	userAuth.Get("/notification-preferences", handlers.GetMyNotificationPreferences(db))
+	userAuth.Put("/notification-preferences", handlers.UpdateMyNotificationPreferences(db))
 
 	// Password Reset Routes (No Auth Required)
 	passwordReset := api.Group("/password")
@@ -61,6 +84,24 @@ func SetupRoutes(app *fiber.App, db *gorm.DB) {
 	reports.Post("/", handlers.CreateReport(db))
 	reports.Get("/", handlers.GetReports(db))
 	reports.Get("/:id", handlers.GetReport(db))
+	reports.Get("/:id/download", handlers.DownloadReport(db))
+
+	// SLO Routes (writes are Admin Only)
+	slos := api.Group("/slos", middleware.JWTAuth())
+	slos.Get("/", handlers.GetSLOs(db))
+	slos.Get("/:id", handlers.GetSLO(db))
+	slos.Post("/", middleware.AdminAuth(), handlers.CreateSLO(db))
+	slos.Put("/:id", middleware.AdminAuth(), handlers.UpdateSLO(db))
+	slos.Delete("/:id", middleware.AdminAuth(), handlers.DeleteSLO(db))
+
+	// Report Schedule Routes
+	reportSchedules := api.Group("/report-schedules", middleware.JWTAuth())
+	reportSchedules.Get("/", handlers.GetReportSchedules(db))
+	reportSchedules.Post("/", handlers.CreateReportSchedule(db))
+	reportSchedules.Get("/:id", handlers.GetReportSchedule(db))
+	reportSchedules.Put("/:id", handlers.UpdateReportSchedule(db))
+	reportSchedules.Delete("/:id", handlers.DeleteReportSchedule(db))
+	reportSchedules.Post("/:id/run", handlers.RunReportSchedule(db))
 
 	// Log Routes (Admin Only)
 	logs := api.Group("/logs", middleware.JWTAuth(), middleware.AdminAuth())
@@ -68,21 +109,60 @@ func SetupRoutes(app *fiber.App, db *gorm.DB) {
 	logs.Get("/", handlers.GetLogEntries(db))
 	logs.Get("/:id", handlers.GetLogEntry(db))
 
-	// Operational Page Routes
+	// Operational Page Routes, written by admins as pages carry custom domains and branding
 	opPages := api.Group("/operational-pages")
-	opPages.Post("/", middleware.JWTAuth(), handlers.CreateOperationalPage(db))
+	opPages.Post("/", middleware.JWTAuth(), middleware.AdminAuth(), handlers.CreateOperationalPage(db))
 	opPages.Get("/", handlers.GetOperationalPages(db))
 	opPages.Get("/:idOrSlug", handlers.GetOperationalPage(db))
-	opPages.Put("/:idOrSlug", middleware.JWTAuth(), handlers.UpdateOperationalPage(db))
-	opPages.Delete("/:idOrSlug", middleware.JWTAuth(), handlers.DeleteOperationalPage(db))
+	opPages.Get("/:idOrSlug/stats", middleware.OptionalJWTAuth(), handlers.GetOperationalPageStats(db))
+	opPages.Get("/:idOrSlug/problem-reports", middleware.JWTAuth(), middleware.AdminAuth(), handlers.GetProblemReports(db))
+	opPages.Put("/:idOrSlug", middleware.JWTAuth(), middleware.AdminAuth(), handlers.UpdateOperationalPage(db))
+	opPages.Delete("/:idOrSlug", middleware.JWTAuth(), middleware.AdminAuth(), handlers.DeleteOperationalPage(db))
 
 	// Operational Page Components Routes
 	opPageComponents := api.Group("/operational-pages/:pageID/components")
 	opPageComponents.Post("/", middleware.JWTAuth(), handlers.AddComponentToOperationalPage(db))
 	opPageComponents.Get("/", handlers.GetComponentsForOperationalPage(db))
+	opPageComponents.Put("/order", middleware.JWTAuth(), handlers.ReorderOperationalPageComponents(db))
 	opPageComponents.Delete("/:componentID", middleware.JWTAuth(), handlers.RemoveComponentFromOperationalPage(db))
 
+	// Operational Page Incidents and Maintenance Routes, written by staff
+	pageIncidents := api.Group("/operational-pages/:pageID/incidents")
+	pageIncidents.Get("/", middleware.OptionalJWTAuth(), handlers.GetPageIncidents(db))
+	pageIncidents.Get("/:incidentID", middleware.OptionalJWTAuth(), handlers.GetPageIncident(db))
+	pageIncidents.Post("/", middleware.JWTAuth(), middleware.AdminAuth(), handlers.CreatePageIncident(db))
+	pageIncidents.Put("/:incidentID", middleware.JWTAuth(), middleware.AdminAuth(), handlers.UpdatePageIncident(db))
+	pageIncidents.Delete("/:incidentID", middleware.JWTAuth(), middleware.AdminAuth(), handlers.DeletePageIncident(db))
+	pageIncidents.Post("/:incidentID/updates", middleware.JWTAuth(), middleware.AdminAuth(), handlers.AddPageIncidentUpdate(db))
+
+	pageMaintenance := api.Group("/operational-pages/:pageID/maintenance")
+	pageMaintenance.Get("/", middleware.OptionalJWTAuth(), handlers.GetPageMaintenances(db))
+	pageMaintenance.Post("/", middleware.JWTAuth(), middleware.AdminAuth(), handlers.CreatePageMaintenance(db))
+	pageMaintenance.Put("/:maintenanceID", middleware.JWTAuth(), middleware.AdminAuth(), handlers.UpdatePageMaintenance(db))
+	pageMaintenance.Delete("/:maintenanceID", middleware.JWTAuth(), middleware.AdminAuth(), handlers.DeletePageMaintenance(db))
+
+	pageSubscribers := api.Group("/operational-pages/:pageID/subscribers", middleware.JWTAuth(), middleware.AdminAuth())
+	pageSubscribers.Get("/", handlers.GetStatusSubscribers(db))
+	pageSubscribers.Delete("/:subscriberID", handlers.DeleteStatusSubscriber(db))
+
+	// Notification Channel Routes (Admin Only)
+	notificationChannels := api.Group("/notification-channels", middleware.JWTAuth(), middleware.AdminAuth())
+	notificationChannels.Post("/", handlers.CreateNotificationChannel(db))
+	notificationChannels.Get("/", handlers.GetNotificationChannels(db))
+	notificationChannels.Get("/:id", handlers.GetNotificationChannel(db))
+	notificationChannels.Put("/:id", handlers.UpdateNotificationChannel(db))
+	notificationChannels.Delete("/:id", handlers.DeleteNotificationChannel(db))
+	notificationChannels.Post("/:id/test", handlers.TestNotificationChannel(db))
+
+	// Notification Template Routes (Admin Only)
+	notificationTemplates := api.Group("/notification-templates", middleware.JWTAuth(), middleware.AdminAuth())
+	notificationTemplates.Get("/", handlers.GetNotificationTemplates(db))
+	notificationTemplates.Post("/preview", handlers.PreviewNotificationTemplate(db))
+	notificationTemplates.Put("/:eventType/:format", handlers.UpsertNotificationTemplate(db))
+	notificationTemplates.Delete("/:eventType/:format", handlers.DeleteNotificationTemplate(db))
+
 	// GraphQL Route
-	api.Post("/graphql", handlers.GraphQLHandler(db))
+	api.Post("/graphql", middleware.OptionalJWTAuth(), handlers.GraphQLHandler(db))
+	api.Get("/graphql", middleware.OptionalJWTAuth(), handlers.GraphiQL(db), handlers.GraphQLWebSocketUpgrade(), handlers.GraphQLWebSocket(db))
 }
 
@@ -1,31 +1,402 @@
 package utils
 
 import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
 	"net/smtp"
+	"net/textproto"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
 	"monitron-server/config"
 )
 
-// SendEmail sends an email using the configured SMTP server
+// SMTP TLS modes
+const (
+	EmailTLSNone     = "none"     // Plain SMTP, never upgrade
+	EmailTLSStartTLS = "starttls" // Upgrade with STARTTLS, fail if unsupported
+	EmailTLSImplicit = "tls"      // Implicit TLS (SMTPS, usually port 465)
+)
+
+// CheckEmailTLSMode returns an error unless mode is a supported SMTP TLS mode.
+// An empty mode means starttls.
+func CheckEmailTLSMode(mode string) error {
+	switch mode {
+	case "", EmailTLSNone, EmailTLSStartTLS, EmailTLSImplicit:
+		return nil
+	}
+	return fmt.Errorf("unknown SMTP TLS mode %q, use %s, %s or %s", mode, EmailTLSNone, EmailTLSStartTLS, EmailTLSImplicit)
+}
+
+// Attachment is a file attached to an email
+type Attachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Data        []byte `json:"data"`
+}
+
+// Email is a single message to be delivered by a Mailer
+type Email struct {
+	To          []string     `json:"to"`
+	Subject     string       `json:"subject"`
+	HTMLBody    string       `json:"html_body"`
+	TextBody    string       `json:"text_body"` // Derived from HTMLBody when empty
+	Attachments []Attachment `json:"attachments"`
+}
+
+// Mailer delivers emails through an SMTP server
+type Mailer struct {
+	Host          string
+	Port          int
+	Username      string
+	Password      string
+	From          string
+	FromName      string
+	TLSMode       string
+	SkipTLSVerify bool
+	Timeout       time.Duration
+}
+
+// NewMailer creates a Mailer from the email configuration
+func NewMailer(cfg *config.Config) *Mailer {
+	return &Mailer{
+		Host:          cfg.Email.Host,
+		Port:          cfg.Email.Port,
+		Username:      cfg.Email.Username,
+		Password:      cfg.Email.Password,
+		From:          cfg.Email.From,
+		FromName:      cfg.Email.FromName,
+		TLSMode:       cfg.Email.TLSMode,
+		SkipTLSVerify: cfg.Email.SkipTLSVerify,
+		Timeout:       30 * time.Second,
+	}
+}
+
+// SendEmail sends a single HTML email using the configured SMTP server
 func SendEmail(cfg *config.Config, to, subject, body string) error {
-	from := cfg.Email.From
-	password := cfg.Email.Password
+	return NewMailer(cfg).Send(Email{To: []string{to}, Subject: subject, HTMLBody: body})
+}
+
+// Send delivers a single email over a new connection
+func (m *Mailer) Send(email Email) error {
+	err := m.SendBulk([]Email{email})
+	var bulkErr *BulkError
+	if errors.As(err, &bulkErr) {
+		return bulkErr.Err
+	}
+	return err
+}
+
+// BulkError is returned by SendBulk when some emails could not be delivered.
+// The others were delivered and must not be sent again.
+type BulkError struct {
+	Failed []Email
+	Err    error // Error of the first failed email
+}
+
+func (e *BulkError) Error() string {
+	return fmt.Sprintf("failed to send %d email(s): %v", len(e.Failed), e.Err)
+}
 
-	msg := []byte(fmt.Sprintf("To: %s\r\n" +
-		"From: %s\r\n" +
-		"Subject: %s\r\n" +
-		"Content-Type: text/html; charset=UTF-8\r\n" +
-		"\r\n%s\r\n",
-		to, from, subject, body))
+func (e *BulkError) Unwrap() error {
+	return e.Err
+}
 
-	auth := smtp.PlainAuth("", from, password, cfg.Email.Host)
+// SendBulk delivers several emails over a single SMTP connection, resetting
+// the session between messages. A failed email does not stop the others; the
+// failed ones are returned in a *BulkError.
+func (m *Mailer) SendBulk(emails []Email) error {
+	if len(emails) == 0 {
+		return nil
+	}
 
-	err := smtp.SendMail(fmt.Sprintf("%s:%d", cfg.Email.Host, cfg.Email.Port), auth, from, []string{to}, msg)
+	client, err := m.dial()
 	if err != nil {
 		return fmt.Errorf("failed to send email: %w", err)
 	}
+	defer client.Close()
+
+	var failed []Email
+	var firstErr error
+	for i, email := range emails {
+		if i > 0 {
+			if err := client.Reset(); err != nil {
+				// The connection is unusable, so the remaining emails fail too
+				if firstErr == nil {
+					firstErr = fmt.Errorf("failed to reset SMTP session: %w", err)
+				}
+				failed = append(failed, emails[i:]...)
+				break
+			}
+		}
+		if err := m.deliver(client, email); err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("failed to send email to %s: %w", strings.Join(email.To, ", "), err)
+			}
+			failed = append(failed, email)
+		}
+	}
 
-	return nil
+	if len(failed) > 0 {
+		return &BulkError{Failed: failed, Err: firstErr}
+	}
+	return client.Quit()
 }
 
+// dial connects, negotiates TLS according to the mode and authenticates
+func (m *Mailer) dial() (*smtp.Client, error) {
+	addr := net.JoinHostPort(m.Host, strconv.Itoa(m.Port))
+	tlsConfig := &tls.Config{ServerName: m.Host, InsecureSkipVerify: m.SkipTLSVerify}
+	dialer := &net.Dialer{Timeout: m.Timeout}
+	if err := CheckEmailTLSMode(m.TLSMode); err != nil {
+		return nil, err
+	}
+
+	var conn net.Conn
+	var err error
+	if m.TLSMode == EmailTLSImplicit {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to SMTP server: %w", err)
+	}
+
+	client, err := smtp.NewClient(conn, m.Host)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("could not create SMTP client: %w", err)
+	}
+
+	if m.TLSMode == EmailTLSStartTLS || m.TLSMode == "" {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			client.Close()
+			return nil, fmt.Errorf("SMTP server does not support STARTTLS")
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("could not start TLS: %w", err)
+		}
+	}
+
+	if m.Username != "" {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			client.Close()
+			return nil, fmt.Errorf("SMTP server does not support AUTH, but a username is configured")
+		}
+		if err := client.Auth(smtp.PlainAuth("", m.Username, m.Password, m.Host)); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+
+	return client, nil
+}
+
+func (m *Mailer) deliver(client *smtp.Client, email Email) error {
+	msg, err := BuildMessage(m.fromAddress(), email)
+	if err != nil {
+		return err
+	}
+
+	if err := client.Mail(m.envelopeFrom()); err != nil {
+		return err
+	}
+	for _, to := range email.To {
+		if err := client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// fromAddress returns the From header. EMAIL_FROM may already carry a display
+// name ("Monitron <no-reply@example.com>"), which takes precedence over FromName.
+func (m *Mailer) fromAddress() string {
+	if addr, err := mail.ParseAddress(m.From); err == nil {
+		if addr.Name == "" {
+			addr.Name = m.FromName
+		}
+		return addr.String()
+	}
+	return m.From
+}
+
+// envelopeFrom returns the bare address used for the SMTP MAIL FROM command
+func (m *Mailer) envelopeFrom() string {
+	if addr, err := mail.ParseAddress(m.From); err == nil {
+		return addr.Address
+	}
+	return m.From
+}
+
+// BuildMessage renders an email as a MIME message with a plain-text and HTML
+// alternative and optional attachments
+func BuildMessage(from string, email Email) ([]byte, error) {
+	if len(email.To) == 0 {
+		return nil, fmt.Errorf("email has no recipients")
+	}
+
+	textBody := email.TextBody
+	if textBody == "" {
+		textBody = HTMLToText(email.HTMLBody)
+	}
+
+	var buf bytes.Buffer
+	writeHeader(&buf, "From", from)
+	writeHeader(&buf, "To", strings.Join(email.To, ", "))
+	writeHeader(&buf, "Subject", mime.QEncoding.Encode("utf-8", email.Subject))
+	writeHeader(&buf, "Date", time.Now().Format(time.RFC1123Z))
+	writeHeader(&buf, "Message-ID", messageID(from))
+	writeHeader(&buf, "MIME-Version", "1.0")
+
+	if len(email.Attachments) == 0 {
+		if err := writeAlternative(&buf, textBody, email.HTMLBody); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	mixed := multipart.NewWriter(&buf)
+	writeHeader(&buf, "Content-Type", "multipart/mixed; boundary="+mixed.Boundary())
+	buf.WriteString("\r\n")
+
+	altBoundary := multipart.NewWriter(io.Discard).Boundary()
+	part, err := mixed.CreatePart(textproto.MIMEHeader{"Content-Type": {"multipart/alternative; boundary=" + altBoundary}})
+	if err != nil {
+		return nil, err
+	}
+	alt := multipart.NewWriter(part)
+	if err := alt.SetBoundary(altBoundary); err != nil {
+		return nil, err
+	}
+	if err := writeAlternativeParts(alt, textBody, email.HTMLBody); err != nil {
+		return nil, err
+	}
+
+	for _, attachment := range email.Attachments {
+		contentType := attachment.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		part, err := mixed.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {contentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename})},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeBase64(part, attachment.Data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := mixed.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeAlternative writes a top-level multipart/alternative body
+func writeAlternative(buf *bytes.Buffer, textBody, htmlBody string) error {
+	alt := multipart.NewWriter(buf)
+	writeHeader(buf, "Content-Type", "multipart/alternative; boundary="+alt.Boundary())
+	buf.WriteString("\r\n")
+	return writeAlternativeParts(alt, textBody, htmlBody)
+}
+
+func writeAlternativeParts(alt *multipart.Writer, textBody, htmlBody string) error {
+	if err := writeQuotedPrintable(alt, "text/plain; charset=UTF-8", textBody); err != nil {
+		return err
+	}
+	if htmlBody != "" {
+		if err := writeQuotedPrintable(alt, "text/html; charset=UTF-8", htmlBody); err != nil {
+			return err
+		}
+	}
+	return alt.Close()
+}
+
+func writeQuotedPrintable(w *multipart.Writer, contentType, body string) error {
+	part, err := w.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return err
+	}
+	qp := quotedprintable.NewWriter(part)
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// writeBase64 writes data as base64 wrapped at 76 characters per line
+func writeBase64(w io.Writer, data []byte) error {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		if _, err := io.WriteString(w, encoded[:76]+"\r\n"); err != nil {
+			return err
+		}
+		encoded = encoded[76:]
+	}
+	_, err := io.WriteString(w, encoded+"\r\n")
+	return err
+}
+
+func writeHeader(buf *bytes.Buffer, key, value string) {
+	buf.WriteString(key + ": " + value + "\r\n")
+}
+
+func messageID(from string) string {
+	domain := "monitron.local"
+	if addr, err := mail.ParseAddress(from); err == nil {
+		if at := strings.LastIndex(addr.Address, "@"); at >= 0 {
+			domain = addr.Address[at+1:]
+		}
+	}
+	b := make([]byte, 16)
+	rand.Read(b)
+	return fmt.Sprintf("<%d.%s@%s>", time.Now().UnixNano(), hex.EncodeToString(b), domain)
+}
+
+var (
+	htmlBreakRe = regexp.MustCompile(`(?i)<br\s*/?>|</p>|</h[1-6]>|</li>|</div>|</tr>`)
+	htmlTagRe   = regexp.MustCompile(`<[^>]*>`)
+	blankLineRe = regexp.MustCompile(`\n{3,}`)
+)
+
+// HTMLToText produces a readable plain-text fallback from an HTML body
+func HTMLToText(body string) string {
+	text := htmlBreakRe.ReplaceAllString(body, "\n")
+	text = htmlTagRe.ReplaceAllString(text, "")
+	text = html.UnescapeString(text)
+	text = blankLineRe.ReplaceAllString(text, "\n\n")
+	return strings.TrimSpace(text)
+}
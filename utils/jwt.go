@@ -37,3 +37,18 @@ func GenerateJWT(userID uuid.UUID, role string) (string, error) {
 	return tokenString, nil
 }
 
+// ParseJWT validates a token issued by GenerateJWT and returns its claims
+func ParseJWT(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	// TODO: Use a secure secret key from config/environment variable
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte("supersecretkey"), nil // Replace with your secret key
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
+	return claims, nil
+}
@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimiter is an in-memory fixed-window counter keyed by an arbitrary
+// string such as an IP address or email
+type RateLimiter struct {
+	mu      sync.Mutex
+	limit   int
+	window  time.Duration
+	windows map[string]*rateWindow
+	evicted time.Time // Last eviction of expired windows
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+// NewRateLimiter allows up to limit hits per key within each window
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{limit: limit, window: window, windows: make(map[string]*rateWindow), evicted: time.Now()}
+}
+
+// Allow records a hit for key and reports whether it is within the limit
+func (l *RateLimiter) Allow(key string) bool {
+	return l.AllowN(key, 1)
+}
+
+// AllowN records n hits for key, such as the cost of a request, and reports
+// whether they are within the limit. Hits over the limit are not recorded.
+func (l *RateLimiter) AllowN(key string, n int) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.evict(now)
+
+	w, ok := l.windows[key]
+	if !ok || now.Sub(w.start) >= l.window {
+		w = &rateWindow{start: now}
+		l.windows[key] = w
+	}
+	if w.count+n > l.limit {
+		return false
+	}
+	w.count += n
+	return true
+}
+
+// evict drops expired windows so the map does not grow without bound. It
+// scans the map at most once per window, so the map holds the keys of the
+// last two windows at most.
+func (l *RateLimiter) evict(now time.Time) {
+	if now.Sub(l.evicted) < l.window {
+		return
+	}
+	l.evicted = now
+	for key, w := range l.windows {
+		if now.Sub(w.start) >= l.window {
+			delete(l.windows, key)
+		}
+	}
+}
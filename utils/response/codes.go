@@ -0,0 +1,45 @@
+package response
+
+import "fmt"
+
+// Error codes of the response envelope. A code is the HTTP status of the
+// response followed by two digits telling errors of the same status apart, so
+// clients can match on either. Success is 00000.
+const (
+	CodeSuccess Code = 0
+
+	CodeBadRequest       Code = 40000 // The request is invalid, see the message
+	CodeInvalidBody      Code = 40001 // The body could not be parsed
+	CodeValidationFailed Code = 40002 // Fields of the body are invalid, see the error object
+	CodeInvalidParam     Code = 40003 // A path or query parameter, such as an ID, is invalid
+
+	CodeUnauthorized       Code = 40100 // The request carries no valid JWT
+	CodeInvalidCredentials Code = 40101 // The email or password is wrong
+
+	CodeForbidden Code = 40300 // The caller may not access the resource
+
+	CodeNotFound Code = 40400 // The resource or route does not exist
+
+	CodeConflict Code = 40900 // The resource is not in a state allowing the request
+
+	CodeUpgradeRequired Code = 42600 // The endpoint only accepts WebSocket connections
+
+	CodeTooManyRequests Code = 42900 // The caller is rate limited, try again later
+
+	CodeInternal    Code = 50000 // The server failed, details are logged
+	CodeBadGateway  Code = 50200 // A third party, such as a notification provider, failed
+	CodeUnavailable Code = 50300 // The server is shutting down or a dependency is down
+)
+
+// Code is a code of the response envelope, sent as a string of five digits
+type Code int
+
+// MarshalJSON writes the code zero-padded to five digits, such as "00000"
+func (c Code) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf(`"%05d"`, int(c))), nil
+}
+
+// CodeFor returns the generic code of an HTTP status
+func CodeFor(status int) Code {
+	return Code(status * 100)
+}
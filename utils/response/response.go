@@ -0,0 +1,79 @@
+package response
+
+import (
+	"errors"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+// Response is the envelope of every JSON response of the REST API. Data is
+// set on success, and Error maps invalid input fields to their reason.
+type Response struct {
+	Code    Code              `json:"code" swaggertype:"string" example:"00000"`
+	Message string            `json:"message"`
+	Data    interface{}       `json:"data"`
+	Error   map[string]string `json:"error"`
+}
+
+// Send writes a successful response with a status and message
+func Send(c *fiber.Ctx, status int, message string, data interface{}) error {
+	return c.Status(status).JSON(Response{Code: CodeSuccess, Message: message, Data: data, Error: map[string]string{}})
+}
+
+// OK writes data with status 200
+func OK(c *fiber.Ctx, data interface{}) error {
+	return Send(c, fiber.StatusOK, utils.StatusMessage(fiber.StatusOK), data)
+}
+
+// Created writes a created resource with status 201
+func Created(c *fiber.Ctx, data interface{}) error {
+	return Send(c, fiber.StatusCreated, utils.StatusMessage(fiber.StatusCreated), data)
+}
+
+// Accepted writes a resource whose processing has started with status 202
+func Accepted(c *fiber.Ctx, data interface{}) error {
+	return Send(c, fiber.StatusAccepted, utils.StatusMessage(fiber.StatusAccepted), data)
+}
+
+// Error writes an error with the generic code of its status
+func Error(c *fiber.Ctx, status int, message string) error {
+	return Fail(c, status, CodeFor(status), message, nil)
+}
+
+// Fail writes an error with a code of the catalogue and the invalid fields,
+// if any
+func Fail(c *fiber.Ctx, status int, code Code, message string, fields map[string]string) error {
+	if fields == nil {
+		fields = map[string]string{}
+	}
+	return c.Status(status).JSON(Response{Code: code, Message: message, Error: fields})
+}
+
+// InvalidBody writes the error of a body that could not be parsed
+func InvalidBody(c *fiber.Ctx) error {
+	return Fail(c, fiber.StatusBadRequest, CodeInvalidBody, "Cannot parse JSON", nil)
+}
+
+// Invalid writes the error of a failed validation, with a message per field
+func Invalid(c *fiber.Ctx, err error) error {
+	fields := Fields(err)
+	if len(fields) == 0 {
+		return Error(c, fiber.StatusBadRequest, err.Error())
+	}
+	return Fail(c, fiber.StatusBadRequest, CodeValidationFailed, "Validation failed", fields)
+}
+
+// ErrorHandler writes the errors returned by handlers and middleware, such as
+// unknown routes or oversized bodies, in the envelope. It is set as the
+// ErrorHandler of the app.
+func ErrorHandler(c *fiber.Ctx, err error) error {
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return Error(c, fiberErr.Code, fiberErr.Message)
+	}
+
+	log.Printf("Unhandled error on %s %s: %v", c.Method(), c.Path(), err)
+	return Error(c, fiber.StatusInternalServerError, utils.StatusMessage(fiber.StatusInternalServerError))
+}
@@ -0,0 +1,98 @@
+package response
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Fields returns the reason of every invalid field of a validation error,
+// keyed by the JSON path of the field, or nil if err is not one
+func Fields(err error) map[string]string {
+	var errs validator.ValidationErrors
+	if !errors.As(err, &errs) {
+		return nil
+	}
+
+	fields := make(map[string]string, len(errs))
+	for _, fieldErr := range errs {
+		field := fieldErr.Namespace()
+		if i := strings.Index(field, "."); i >= 0 {
+			field = field[i+1:]
+		}
+		if _, ok := fields[field]; !ok {
+			fields[field] = reason(fieldErr)
+		}
+	}
+	return fields
+}
+
+// reason describes the failed rule of a field
+func reason(fieldErr validator.FieldError) string {
+	param := fieldErr.Param()
+	switch fieldErr.Tag() {
+	case "required", "required_if":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "url":
+		return "must be a valid URL"
+	case "uuid":
+		return "must be a valid UUID"
+	case "fqdn":
+		return "must be a valid hostname"
+	case "timezone":
+		return "must be a valid IANA time zone"
+	case "datetime":
+		return "must be a time formatted as " + param
+	case "oneof":
+		return "must be one of " + strings.Join(strings.Fields(param), ", ")
+	case "min":
+		return "must be at least " + size(fieldErr.Kind(), param)
+	case "max":
+		return "must be at most " + size(fieldErr.Kind(), param)
+	case "len":
+		return "must be exactly " + size(fieldErr.Kind(), param)
+	case "gt":
+		return "must be greater than " + param
+	case "gte":
+		return "must be at least " + param
+	case "lt":
+		return "must be less than " + param
+	case "lte":
+		return "must be at most " + param
+	case "gtfield":
+		return "must be after " + snakeCase(param)
+	}
+	return fmt.Sprintf("failed the %s rule", fieldErr.Tag())
+}
+
+// snakeCase names a Go field as JSON does, for rules comparing two fields
+func snakeCase(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// size words a length bound by the kind of the field
+func size(kind reflect.Kind, param string) string {
+	switch kind {
+	case reflect.String:
+		return param + " characters long"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return param + " items"
+	}
+	return param
+}
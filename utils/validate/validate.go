@@ -1,5 +1,27 @@
 package validate
 
-import "github.com/go-playground/validator/v10"
+import (
+	"reflect"
+	"strings"
 
-var V = validator.New()
+	"github.com/go-playground/validator/v10"
+)
+
+var V = newValidator()
+
+// newValidator returns a validator naming fields by their JSON name, which
+// error responses key invalid fields by
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+	return v
+}